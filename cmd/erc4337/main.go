@@ -15,6 +15,7 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/ethereum/go-ethereum/rpc"
 	"github.com/joho/godotenv"
 )
@@ -42,64 +43,6 @@ type Block struct {
 	BaseFeePerGas string `json:"baseFeePerGas"`
 }
 
-// extractNonceKey extracts the nonce key by removing the trailing 8 bytes (64 bits)
-func extractNonceKey(nonce *hexutil.Big) (*big.Int, error) {
-	if nonce == nil {
-		return nil, fmt.Errorf("nonce is nil")
-	}
-
-	nonceBytes := nonce.ToInt().Bytes()
-	if len(nonceBytes) < 8 {
-		return nil, fmt.Errorf("nonce too short to extract key")
-	}
-
-	// Pad to 32 bytes if needed
-	paddedNonce := make([]byte, 32)
-	copy(paddedNonce[32-len(nonceBytes):], nonceBytes)
-
-	// Extract key by taking first 24 bytes (removing trailing 8 bytes)
-	keyBytes := paddedNonce[:24]
-	key := new(big.Int).SetBytes(keyBytes)
-
-	return key, nil
-}
-
-// getCurrentNonce calls the entrypoint contract's getNonce function
-func getCurrentNonce(ctx context.Context, rpcClient *rpc.Client, sender common.Address, key *big.Int) (*big.Int, error) {
-	// Prepare the call data for getNonce(address,uint192)
-	// Function selector: getNonce(address,uint192) = 0x35567e1a
-	callData := "0x35567e1a"
-
-	// Encode sender address (32 bytes)
-	senderBytes := make([]byte, 32)
-	copy(senderBytes[12:], sender.Bytes())
-	callData += fmt.Sprintf("%x", senderBytes)
-
-	// Encode key (32 bytes, but only 24 bytes are significant for uint192)
-	keyBytes := make([]byte, 32)
-	key.FillBytes(keyBytes)
-	callData += fmt.Sprintf("%x", keyBytes)
-
-	// Make the eth_call
-	var result string
-	err := rpcClient.CallContext(ctx, &result, "eth_call", map[string]interface{}{
-		"to":   erc4337.EntryPointV07,
-		"data": callData,
-	}, "latest")
-
-	if err != nil {
-		return nil, fmt.Errorf("failed to call getNonce: %w", err)
-	}
-
-	// Parse the result
-	nonce := new(big.Int)
-	if err := nonce.UnmarshalText([]byte(result)); err != nil {
-		return nil, fmt.Errorf("failed to parse nonce result: %w", err)
-	}
-
-	return nonce, nil
-}
-
 // getMaxFeePerGas fetches the latest block and max priority fee, then calculates maxFeePerGas
 func getMaxFeePerGas(ctx context.Context, rpcClient *rpc.Client) (*big.Int, *big.Int, error) {
 	var blockResult *Block
@@ -206,23 +149,23 @@ func main() {
 	}
 	defer rpcClient.Close()
 
-	// Extract nonce key and get current nonce from entrypoint
-	nonceKey, err := extractNonceKey(userOp.Nonce)
+	// Get the current nonce of the user operation's nonce key from the entrypoint
+	jobNonce, err := erc4337.ParseNonce(userOp.Nonce.ToInt())
 	if err != nil {
-		log.Fatalf("Failed to extract nonce key: %v", err)
+		log.Fatalf("Failed to parse nonce: %v", err)
 	}
 
-	log.Printf("Extracted nonce key: 0x%x", nonceKey)
+	log.Printf("Extracted nonce key: 0x%x", jobNonce.Key)
 
-	currentNonce, err := getCurrentNonce(ctx, rpcClient, userOp.Sender, nonceKey)
+	currentNonce, err := erc4337.GetNonce(ctx, ethclient.NewClient(rpcClient), erc4337.EntryPointV07, userOp.Sender, jobNonce.Key)
 	if err != nil {
 		log.Fatalf("Failed to get current nonce: %v", err)
 	}
 
-	log.Printf("Current nonce from entrypoint: 0x%x", currentNonce)
+	log.Printf("Current nonce from entrypoint: 0x%x", currentNonce.BigInt())
 
 	// Update user operation with current nonce
-	userOp.Nonce = (*hexutil.Big)(currentNonce)
+	userOp.Nonce = (*hexutil.Big)(currentNonce.BigInt())
 
 	// Set paymaster
 	paymaster := common.HexToAddress("0xcD1c62f36A99f306948dB76c35Bbc1A639f92ce8")
@@ -65,20 +65,24 @@ func main() {
 	jobRepo := repository.NewJobRepository(database)
 	jobService := service.NewJobService(jobRepo)
 
+	// Initialize the executor signer of self-bundled chains
+	var executor erc4337.TransactionSigner
+	if config.ExecutorSigner != nil {
+		executor, err = service.NewExecutorSigner(ctx, *config.ExecutorSigner)
+		if err != nil {
+			logger.Fatal().Err(err).Msg("Failed to create executor signer")
+		}
+	}
+
 	// Initialize blockchain service
-	blockchainService := service.NewBlockchainService(service.BlockchainConfig{
-		SepoliaRPCURL:         *config.SepoliaRPCURL,
-		ArbitrumSepoliaRPCURL: *config.ArbitrumSepoliaRPCURL,
-		BaseSepoliaRPCURL:     *config.BaseSepoliaRPCURL,
-		OptimismSepoliaRPCURL: *config.OptimismSepoliaRPCURL,
-		PolygonAmoyRPCURL:     *config.PolygonAmoyRPCURL,
-	})
+	blockchainService := service.NewBlockchainService(config.BlockchainConfig(executor))
 
 	// Initialize execution service
-	executionService, err := service.NewExecutionService(blockchainService, *config.PrivateKey)
+	signer, err := service.NewSigner(ctx, config.SignerConfig())
 	if err != nil {
-		logger.Fatal().Err(err).Msg("Failed to create execution service")
+		logger.Fatal().Err(err).Msg("Failed to create signer")
 	}
+	executionService := service.NewExecutionService(blockchainService, signer)
 
 	// Get job by ID
 	logger.Info().Str("job_id", JOB_ID).Msg("Retrieving job from database")
@@ -97,10 +101,11 @@ func main() {
 	// Execute the job
 	logger.Info().Str("job_id", JOB_ID).Msg("Executing job")
 
-	userOpHash, err := executionService.ExecuteJob(ctx, *job)
+	sent, err := executionService.ExecuteJob(ctx, *job)
 	if err != nil {
 		logger.Fatal().Err(err).Str("job_id", JOB_ID).Msg("Failed to execute job")
 	}
+	userOpHash := sent.UserOpHash
 
 	logger.Info().
 		Str("job_id", JOB_ID).
@@ -109,8 +114,7 @@ func main() {
 
 	// Wait for user operation receipt using blockchain service
 	logger.Info().Str("user_op_hash", userOpHash.Hex()).Msg("Waiting for user operation receipt...")
-	maxAttempts := 60
-	pollInterval := 2 * time.Second
+	timeout := 2 * time.Minute
 
 	// Get bundler client
 	bundlerClient, err := blockchainService.GetBundlerClient(ctx, job.ChainID)
@@ -120,11 +124,17 @@ func main() {
 			Msg("Failed to get bundler client")
 	}
 
-	receipt, err := bundlerClient.(*erc4337.BundlerClient).WaitForUserOpReceipt(ctx, *userOpHash, maxAttempts, pollInterval)
+	receiptWatcher := erc4337.NewReceiptWatcher(bundlerClient, erc4337.ReceiptWatcherConfig{
+		PollInterval: 2 * time.Second,
+		Timeout:      timeout,
+	})
+	defer receiptWatcher.Close()
+
+	receipt, err := receiptWatcher.WaitForReceipt(ctx, userOpHash)
 	if err != nil {
 		logger.Fatal().Err(err).
 			Str("user_op_hash", userOpHash.Hex()).
-			Int("max_attempts", maxAttempts).
+			Dur("timeout", timeout).
 			Msg("Failed to get user operation receipt")
 	}
 
@@ -2,6 +2,8 @@ package erc4337
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"math/big"
 	"time"
@@ -21,7 +23,8 @@ type GasEstimates struct {
 	MaxPriorityFeePerGas          *hexutil.Big `json:"maxPriorityFeePerGas"`
 }
 
-type parsedTransaction struct {
+// UserOperationTransactionReceipt is the receipt of the bundle transaction that included a user operation
+type UserOperationTransactionReceipt struct {
 	BlockHash         common.Hash    `json:"blockHash"`
 	BlockNumber       string         `json:"blockNumber"`
 	From              common.Address `json:"from"`
@@ -35,26 +38,78 @@ type parsedTransaction struct {
 }
 
 type UserOperationReceipt struct {
-	UserOpHash    common.Hash        `json:"userOpHash"`
-	Sender        common.Address     `json:"sender"`
-	Paymaster     common.Address     `json:"paymaster"`
-	Nonce         string             `json:"nonce"`
-	Success       bool               `json:"success"`
-	ActualGasCost string             `json:"actualGasCost"`
-	ActualGasUsed string             `json:"actualGasUsed"`
-	From          common.Address     `json:"from"`
-	Receipt       *parsedTransaction `json:"receipt"`
-	Logs          []*types.Log       `json:"logs"`
+	UserOpHash    common.Hash                      `json:"userOpHash"`
+	Sender        common.Address                   `json:"sender"`
+	Paymaster     common.Address                   `json:"paymaster"`
+	Nonce         string                           `json:"nonce"`
+	Success       bool                             `json:"success"`
+	ActualGasCost string                           `json:"actualGasCost"`
+	ActualGasUsed string                           `json:"actualGasUsed"`
+	From          common.Address                   `json:"from"`
+	Receipt       *UserOperationTransactionReceipt `json:"receipt"`
+	Logs          []*types.Log                     `json:"logs"`
+}
+
+// UserOperationByHash is the result of eth_getUserOperationByHash.
+// Block fields are nil while the user operation is still in the bundler mempool.
+type UserOperationByHash struct {
+	UserOperation   *UserOperation
+	EntryPoint      common.Address
+	BlockNumber     *hexutil.Big
+	BlockHash       *common.Hash
+	TransactionHash *common.Hash
+}
+
+// IsPending reports whether the user operation has not been included in a block yet
+func (r *UserOperationByHash) IsPending() bool {
+	return r.BlockNumber == nil
+}
+
+// BundlingMode is the bundling mode accepted by debug_bundler_setBundlingMode
+type BundlingMode string
+
+const (
+	BundlingModeAuto   BundlingMode = "auto"
+	BundlingModeManual BundlingMode = "manual"
+)
+
+// ReputationEntry is a bundler reputation record for an entity (account, factory or paymaster)
+type ReputationEntry struct {
+	Address     common.Address `json:"address"`
+	OpsSeen     *hexutil.Big   `json:"opsSeen"`
+	OpsIncluded *hexutil.Big   `json:"opsIncluded"`
+	Status      string         `json:"status,omitempty"`
 }
 
 type Bundler interface {
 	ChainId(ctx context.Context) (*big.Int, error)
+	SupportedEntryPoints(ctx context.Context) ([]common.Address, error)
 	EstimateUserOperationGas(ctx context.Context, op *UserOperation, entryPoint common.Address) (*GasEstimates, error)
 	SendUserOperation(ctx context.Context, op *UserOperation, entryPoint common.Address) (common.Hash, error)
 	GetUserOperationReceipt(ctx context.Context, userOpHash common.Hash) (*UserOperationReceipt, error)
+	GetUserOperationByHash(ctx context.Context, userOpHash common.Hash) (*UserOperationByHash, error)
 	Close()
 }
 
+// DebugBundler is a Bundler serving the ERC-7769 debug_bundler_* namespace, usually only enabled on test bundlers.
+// BundlerClient implements it; type-assert a Bundler to use the debug methods.
+type DebugBundler interface {
+	Bundler
+
+	DebugClearState(ctx context.Context) error
+	DebugDumpMempool(ctx context.Context, entryPoint common.Address) ([]*UserOperation, error)
+	DebugSendBundleNow(ctx context.Context) (common.Hash, error)
+	DebugSetBundlingMode(ctx context.Context, mode BundlingMode) error
+	DebugSetReputation(ctx context.Context, entries []ReputationEntry, entryPoint common.Address) error
+	DebugDumpReputation(ctx context.Context, entryPoint common.Address) ([]ReputationEntry, error)
+}
+
+// RPCCaller sends raw JSON-RPC calls, for bundler methods outside the Bundler interface such as gas price
+// suggestions. rpc.Client, BundlerClient and BundlerPool implement it.
+type RPCCaller interface {
+	CallContext(ctx context.Context, result interface{}, method string, args ...interface{}) error
+}
+
 type BundlerClient struct {
 	client *rpc.Client
 }
@@ -71,20 +126,37 @@ func NewBundlerClient(c *rpc.Client) Bundler {
 	return &BundlerClient{c}
 }
 
+// CallContext sends a raw JSON-RPC call to the bundler. Errors are returned as is.
+func (b *BundlerClient) CallContext(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+	return b.client.CallContext(ctx, result, method, args...)
+}
+
 // handleRPCError wraps RPC errors with detailed error information
 func (b *BundlerClient) handleRPCError(err error, operation string) error {
+	return HandleRPCError(err, operation)
+}
+
+// HandleRPCError wraps an error returned by a bundler RPC call with detailed error information.
+// JSON-RPC errors become a *BundlerError. It is shared by every Bundler implementation so callers see the same error shape.
+func HandleRPCError(err error, operation string) error {
 	if err == nil {
 		return nil
 	}
-	if rpcErr, ok := err.(rpc.DataError); ok {
-		if data := rpcErr.ErrorData(); data != nil {
-			return fmt.Errorf("bundler RPC error in %s: %s, data: %v", operation, rpcErr.Error(), data)
-		}
-		return fmt.Errorf("bundler RPC error in %s: %s", operation, rpcErr.Error())
+	var rpcErr rpc.Error
+	if errors.As(err, &rpcErr) {
+		return NewBundlerError(operation, rpcErr)
 	}
 	return fmt.Errorf("bundler call failed in %s: %w", operation, err)
 }
 
+// rpcUserOperation returns the user operation in the RPC format expected for the given entry point
+func rpcUserOperation(op *UserOperation, entryPoint common.Address) interface{} {
+	if version, err := GetEntryPointVersion(entryPoint); err == nil && version == EntryPointVersionV06 {
+		return op.ToV06()
+	}
+	return op
+}
+
 func (b *BundlerClient) ChainId(ctx context.Context) (*big.Int, error) {
 	var result hexutil.Big
 	err := b.client.CallContext(ctx, &result, "eth_chainId", []interface{}{}...)
@@ -94,9 +166,49 @@ func (b *BundlerClient) ChainId(ctx context.Context) (*big.Int, error) {
 	return (*big.Int)(&result), nil
 }
 
+func (b *BundlerClient) SupportedEntryPoints(ctx context.Context) ([]common.Address, error) {
+	var result []common.Address
+	err := b.client.CallContext(ctx, &result, "eth_supportedEntryPoints")
+	if err != nil {
+		return nil, b.handleRPCError(err, "eth_supportedEntryPoints")
+	}
+	return result, nil
+}
+
+// SupportsEntryPoint reports whether the bundler lists the entry point in eth_supportedEntryPoints
+func SupportsEntryPoint(ctx context.Context, bundler Bundler, entryPoint common.Address) (bool, error) {
+	entryPoints, err := bundler.SupportedEntryPoints(ctx)
+	if err != nil {
+		return false, err
+	}
+	for _, ep := range entryPoints {
+		if ep == entryPoint {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// decodeRPCUserOperation decodes a user operation returned by the bundler in the format of the given entry point
+func decodeRPCUserOperation(data json.RawMessage, entryPoint common.Address) (*UserOperation, error) {
+	if version, err := GetEntryPointVersion(entryPoint); err == nil && version == EntryPointVersionV06 {
+		var op UserOperationV06
+		if err := json.Unmarshal(data, &op); err != nil {
+			return nil, err
+		}
+		return op.ToUserOperation(), nil
+	}
+
+	var op UserOperation
+	if err := json.Unmarshal(data, &op); err != nil {
+		return nil, err
+	}
+	return &op, nil
+}
+
 func (b *BundlerClient) EstimateUserOperationGas(ctx context.Context, op *UserOperation, entryPoint common.Address) (*GasEstimates, error) {
 	var result GasEstimates
-	err := b.client.CallContext(ctx, &result, "eth_estimateUserOperationGas", op, entryPoint)
+	err := b.client.CallContext(ctx, &result, "eth_estimateUserOperationGas", rpcUserOperation(op, entryPoint), entryPoint)
 	if err != nil {
 		return nil, b.handleRPCError(err, "eth_estimateUserOperationGas")
 	}
@@ -105,7 +217,7 @@ func (b *BundlerClient) EstimateUserOperationGas(ctx context.Context, op *UserOp
 
 func (b *BundlerClient) SendUserOperation(ctx context.Context, op *UserOperation, entryPoint common.Address) (common.Hash, error) {
 	var result common.Hash
-	err := b.client.CallContext(ctx, &result, "eth_sendUserOperation", op, entryPoint)
+	err := b.client.CallContext(ctx, &result, "eth_sendUserOperation", rpcUserOperation(op, entryPoint), entryPoint)
 	if err != nil {
 		return result, b.handleRPCError(err, "eth_sendUserOperation")
 	}
@@ -127,7 +239,101 @@ func (b *BundlerClient) GetUserOperationReceipt(ctx context.Context, userOpHash
 	return &receipt, nil
 }
 
+func (b *BundlerClient) GetUserOperationByHash(ctx context.Context, userOpHash common.Hash) (*UserOperationByHash, error) {
+	var raw *struct {
+		UserOperation   json.RawMessage `json:"userOperation"`
+		EntryPoint      common.Address  `json:"entryPoint"`
+		BlockNumber     *hexutil.Big    `json:"blockNumber"`
+		BlockHash       *common.Hash    `json:"blockHash"`
+		TransactionHash *common.Hash    `json:"transactionHash"`
+	}
+	err := b.client.CallContext(ctx, &raw, "eth_getUserOperationByHash", userOpHash)
+	if err != nil {
+		return nil, b.handleRPCError(err, "eth_getUserOperationByHash")
+	}
+
+	// A null result means the bundler does not know the user operation (dropped or never sent)
+	if raw == nil {
+		return nil, nil
+	}
+
+	op, err := decodeRPCUserOperation(raw.UserOperation, raw.EntryPoint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode user operation from eth_getUserOperationByHash: %w", err)
+	}
+
+	return &UserOperationByHash{
+		UserOperation:   op,
+		EntryPoint:      raw.EntryPoint,
+		BlockNumber:     raw.BlockNumber,
+		BlockHash:       raw.BlockHash,
+		TransactionHash: raw.TransactionHash,
+	}, nil
+}
+
+func (b *BundlerClient) DebugClearState(ctx context.Context) error {
+	err := b.client.CallContext(ctx, nil, "debug_bundler_clearState")
+	if err != nil {
+		return b.handleRPCError(err, "debug_bundler_clearState")
+	}
+	return nil
+}
+
+func (b *BundlerClient) DebugDumpMempool(ctx context.Context, entryPoint common.Address) ([]*UserOperation, error) {
+	var raw []json.RawMessage
+	err := b.client.CallContext(ctx, &raw, "debug_bundler_dumpMempool", entryPoint)
+	if err != nil {
+		return nil, b.handleRPCError(err, "debug_bundler_dumpMempool")
+	}
+
+	ops := make([]*UserOperation, 0, len(raw))
+	for _, data := range raw {
+		op, err := decodeRPCUserOperation(data, entryPoint)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode user operation from debug_bundler_dumpMempool: %w", err)
+		}
+		ops = append(ops, op)
+	}
+	return ops, nil
+}
+
+func (b *BundlerClient) DebugSendBundleNow(ctx context.Context) (common.Hash, error) {
+	var result common.Hash
+	err := b.client.CallContext(ctx, &result, "debug_bundler_sendBundleNow")
+	if err != nil {
+		return result, b.handleRPCError(err, "debug_bundler_sendBundleNow")
+	}
+	return result, nil
+}
+
+func (b *BundlerClient) DebugSetBundlingMode(ctx context.Context, mode BundlingMode) error {
+	err := b.client.CallContext(ctx, nil, "debug_bundler_setBundlingMode", mode)
+	if err != nil {
+		return b.handleRPCError(err, "debug_bundler_setBundlingMode")
+	}
+	return nil
+}
+
+func (b *BundlerClient) DebugSetReputation(ctx context.Context, entries []ReputationEntry, entryPoint common.Address) error {
+	err := b.client.CallContext(ctx, nil, "debug_bundler_setReputation", entries, entryPoint)
+	if err != nil {
+		return b.handleRPCError(err, "debug_bundler_setReputation")
+	}
+	return nil
+}
+
+func (b *BundlerClient) DebugDumpReputation(ctx context.Context, entryPoint common.Address) ([]ReputationEntry, error) {
+	var result []ReputationEntry
+	err := b.client.CallContext(ctx, &result, "debug_bundler_dumpReputation", entryPoint)
+	if err != nil {
+		return nil, b.handleRPCError(err, "debug_bundler_dumpReputation")
+	}
+	return result, nil
+}
+
 // WaitForUserOpReceipt polls for user operation receipt with retry logic
+//
+// Deprecated: use ReceiptWatcher, which works with any Bundler and backs off between polls.
 func (b *BundlerClient) WaitForUserOpReceipt(ctx context.Context, userOpHash common.Hash, maxAttempts int, pollInterval time.Duration) (*UserOperationReceipt, error) {
 	for attempt := 1; attempt <= maxAttempts; attempt++ {
 		// Get user operation receipt from bundler client
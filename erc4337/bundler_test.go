@@ -0,0 +1,139 @@
+package erc4337
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestRPCClient starts a JSON-RPC server that answers each method with a canned result
+func newTestRPCClient(t *testing.T, results map[string]string) *rpc.Client {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		result, ok := results[req.Method]
+		w.Header().Set("Content-Type", "application/json")
+		if !ok {
+			_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":` + string(req.ID) + `,"error":{"code":-32601,"message":"method not found"}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":` + string(req.ID) + `,"result":` + result + `}`))
+	}))
+	t.Cleanup(server.Close)
+
+	c, err := rpc.DialHTTP(server.URL)
+	require.NoError(t, err)
+	return c
+}
+
+// newTestBundlerClient creates a bundler client backed by canned results
+func newTestBundlerClient(t *testing.T, results map[string]string) Bundler {
+	bundler := NewBundlerClient(newTestRPCClient(t, results))
+	t.Cleanup(bundler.Close)
+	return bundler
+}
+
+func TestBundlerClient_SupportedEntryPoints(t *testing.T) {
+	bundler := newTestBundlerClient(t, map[string]string{
+		"eth_supportedEntryPoints": `["0x0000000071727De22E5E9d8BAf0edAc6f37da032","0x4337084D9E255Ff0702461CF8895CE9E3b5Ff108"]`,
+	})
+	ctx := context.Background()
+
+	entryPoints, err := bundler.SupportedEntryPoints(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []common.Address{EntryPointV07, EntryPointV08}, entryPoints)
+
+	supported, err := SupportsEntryPoint(ctx, bundler, EntryPointV07)
+	require.NoError(t, err)
+	assert.True(t, supported)
+
+	supported, err = SupportsEntryPoint(ctx, bundler, EntryPointV06)
+	require.NoError(t, err)
+	assert.False(t, supported)
+}
+
+func TestBundlerClient_GetUserOperationByHash(t *testing.T) {
+	userOpHash := common.HexToHash("0x01")
+
+	t.Run("not found", func(t *testing.T) {
+		bundler := newTestBundlerClient(t, map[string]string{
+			"eth_getUserOperationByHash": `null`,
+		})
+
+		result, err := bundler.GetUserOperationByHash(context.Background(), userOpHash)
+		require.NoError(t, err)
+		assert.Nil(t, result)
+	})
+
+	t.Run("pending v0.7 user operation", func(t *testing.T) {
+		bundler := newTestBundlerClient(t, map[string]string{
+			"eth_getUserOperationByHash": `{
+				"userOperation": {
+					"sender": "0x1234567890123456789012345678901234567890",
+					"nonce": "0x1",
+					"callData": "0x5678",
+					"signature": "0xdef0"
+				},
+				"entryPoint": "0x0000000071727De22E5E9d8BAf0edAc6f37da032",
+				"blockNumber": null,
+				"blockHash": null,
+				"transactionHash": null
+			}`,
+		})
+
+		result, err := bundler.GetUserOperationByHash(context.Background(), userOpHash)
+		require.NoError(t, err)
+		require.NotNil(t, result)
+		assert.True(t, result.IsPending())
+		assert.Equal(t, EntryPointV07, result.EntryPoint)
+		assert.Equal(t, common.HexToAddress("0x1234567890123456789012345678901234567890"), result.UserOperation.Sender)
+	})
+
+	t.Run("included v0.6 user operation", func(t *testing.T) {
+		bundler := newTestBundlerClient(t, map[string]string{
+			"eth_getUserOperationByHash": `{
+				"userOperation": {
+					"sender": "0x1234567890123456789012345678901234567890",
+					"nonce": "0x1",
+					"initCode": "0xabcdefabcdefabcdefabcdefabcdefabcdefabcd1234",
+					"callData": "0x5678",
+					"callGasLimit": "0x1",
+					"verificationGasLimit": "0x1",
+					"preVerificationGas": "0x1",
+					"maxFeePerGas": "0x1",
+					"maxPriorityFeePerGas": "0x1",
+					"paymasterAndData": "0x",
+					"signature": "0xdef0"
+				},
+				"entryPoint": "0x5FF137D4b0FDCD49DcA30c7CF57E578a026d2789",
+				"blockNumber": "0x10",
+				"blockHash": "0x0000000000000000000000000000000000000000000000000000000000000002",
+				"transactionHash": "0x0000000000000000000000000000000000000000000000000000000000000003"
+			}`,
+		})
+
+		result, err := bundler.GetUserOperationByHash(context.Background(), userOpHash)
+		require.NoError(t, err)
+		require.NotNil(t, result)
+		assert.False(t, result.IsPending())
+		require.NotNil(t, result.UserOperation.Factory)
+		assert.Equal(t, common.HexToAddress("0xabcdefabcdefabcdefabcdefabcdefabcdefabcd"), *result.UserOperation.Factory)
+		assert.Equal(t, []byte{0x12, 0x34}, []byte(result.UserOperation.FactoryData))
+		assert.Nil(t, result.UserOperation.Paymaster)
+		assert.Equal(t, common.HexToHash("0x03"), *result.TransactionHash)
+	})
+}
@@ -0,0 +1,577 @@
+// Package bundlertest provides a programmable in-memory erc4337.Bundler for tests that must run without a network.
+package bundlertest
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/ethaccount/backend/erc4337"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// RPCError is a JSON-RPC error as returned by a real bundler.
+// It implements rpc.Error and rpc.DataError, so erc4337.HandleRPCError treats it like a remote error.
+type RPCError struct {
+	Code    int
+	Message string
+	Data    interface{}
+}
+
+func (e *RPCError) Error() string {
+	return e.Message
+}
+
+func (e *RPCError) ErrorCode() int {
+	return e.Code
+}
+
+func (e *RPCError) ErrorData() interface{} {
+	return e.Data
+}
+
+// SentUserOperation records a user operation accepted by eth_sendUserOperation
+type SentUserOperation struct {
+	UserOpHash    common.Hash
+	UserOperation erc4337.UserOperation
+	EntryPoint    common.Address
+}
+
+// MinReplacementFeeIncreasePercent is the fee increase required to replace a pending user operation
+const MinReplacementFeeIncreasePercent = 10
+
+// Default gas estimates returned by eth_estimateUserOperationGas
+var DefaultGasEstimates = erc4337.GasEstimates{
+	PreVerificationGas:            (*hexutil.Big)(big.NewInt(50000)),
+	VerificationGasLimit:          (*hexutil.Big)(big.NewInt(150000)),
+	CallGasLimit:                  (*hexutil.Big)(big.NewInt(100000)),
+	PaymasterVerificationGasLimit: (*hexutil.Big)(big.NewInt(0)),
+}
+
+// Bundler is an in-memory bundler implementing erc4337.DebugBundler.
+// User operations are kept in a mempool until they are included with IncludeSuccess, IncludeRevert
+// or DebugSendBundleNow, or removed with Drop. Receipts hold the UserOperationEvent of the EntryPoint,
+// and FilterLogs returns the logs of included user operations like the node of the chain would.
+type Bundler struct {
+	mu sync.Mutex
+
+	chainId      *big.Int
+	entryPoints  []common.Address
+	gasEstimates erc4337.GasEstimates
+	bundlingMode erc4337.BundlingMode
+
+	sent       []SentUserOperation
+	mempool    map[common.Hash]*SentUserOperation
+	receipts   map[common.Hash]*erc4337.UserOperationReceipt
+	included   map[common.Hash]*SentUserOperation
+	reputation map[common.Address][]erc4337.ReputationEntry
+	logs       []types.Log
+	errors     map[string]error
+	blockNum   uint64
+	closed     bool
+}
+
+// New creates an in-memory bundler for the given chain that supports every known EntryPoint version
+func New(chainId int64) *Bundler {
+	return &Bundler{
+		chainId:      big.NewInt(chainId),
+		entryPoints:  []common.Address{erc4337.EntryPointV06, erc4337.EntryPointV07, erc4337.EntryPointV08},
+		gasEstimates: DefaultGasEstimates,
+		bundlingMode: erc4337.BundlingModeManual,
+		mempool:      make(map[common.Hash]*SentUserOperation),
+		receipts:     make(map[common.Hash]*erc4337.UserOperationReceipt),
+		included:     make(map[common.Hash]*SentUserOperation),
+		reputation:   make(map[common.Address][]erc4337.ReputationEntry),
+		errors:       make(map[string]error),
+	}
+}
+
+// SetSupportedEntryPoints replaces the entry points returned by eth_supportedEntryPoints
+func (b *Bundler) SetSupportedEntryPoints(entryPoints ...common.Address) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.entryPoints = entryPoints
+}
+
+// SetGasEstimates replaces the result of eth_estimateUserOperationGas
+func (b *Bundler) SetGasEstimates(estimates erc4337.GasEstimates) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.gasEstimates = estimates
+}
+
+// InjectError makes every call of the RPC method (e.g. "eth_sendUserOperation") fail with err until cleared.
+// Use *RPCError to simulate an error returned by the bundler itself.
+func (b *Bundler) InjectError(method string, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.errors[method] = err
+}
+
+// ClearError removes an error injected with InjectError
+func (b *Bundler) ClearError(method string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.errors, method)
+}
+
+// Sent returns every user operation accepted by eth_sendUserOperation, in order
+func (b *Bundler) Sent() []SentUserOperation {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	sent := make([]SentUserOperation, len(b.sent))
+	copy(sent, b.sent)
+	return sent
+}
+
+// IncludeSuccess includes a pending user operation in a new block with a successful receipt
+func (b *Bundler) IncludeSuccess(userOpHash common.Hash) (*erc4337.UserOperationReceipt, error) {
+	return b.include(userOpHash, true, nil)
+}
+
+// IncludeRevert includes a pending user operation in a new block with a failed receipt carrying the given logs
+func (b *Bundler) IncludeRevert(userOpHash common.Hash, logs ...*types.Log) (*erc4337.UserOperationReceipt, error) {
+	return b.include(userOpHash, false, logs)
+}
+
+// IncludeRevertReason includes a pending user operation in a new block with a failed receipt carrying
+// the UserOperationRevertReason event of the given revert data
+func (b *Bundler) IncludeRevertReason(userOpHash common.Hash, revertReason []byte) (*erc4337.UserOperationReceipt, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	sent, ok := b.mempool[userOpHash]
+	if !ok {
+		return nil, fmt.Errorf("user operation %s is not in the mempool", userOpHash.Hex())
+	}
+	data, err := revertReasonArgs.Pack(bigOrZero(sent.UserOperation.Nonce), revertReason)
+	if err != nil {
+		return nil, err
+	}
+	log := &types.Log{
+		Topics: []common.Hash{erc4337.UserOperationRevertReasonTopic, userOpHash, common.BytesToHash(sent.UserOperation.Sender.Bytes())},
+		Data:   data,
+	}
+	return b.includeLocked(userOpHash, false, []*types.Log{log})
+}
+
+// Drop removes a pending user operation from the mempool without including it
+func (b *Bundler) Drop(userOpHash common.Hash) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.mempool, userOpHash)
+}
+
+// injectedError returns the injected error for the method wrapped the same way as erc4337.BundlerClient
+func (b *Bundler) injectedError(method string) error {
+	if err, ok := b.errors[method]; ok {
+		return erc4337.HandleRPCError(err, method)
+	}
+	if b.closed {
+		return erc4337.HandleRPCError(fmt.Errorf("client is closed"), method)
+	}
+	return nil
+}
+
+// findSameNonceLocked returns the pending user operation of the sender with the same nonce
+func (b *Bundler) findSameNonceLocked(op *erc4337.UserOperation, entryPoint common.Address) *SentUserOperation {
+	for _, pending := range b.mempool {
+		if pending.EntryPoint == entryPoint && pending.UserOperation.Sender == op.Sender &&
+			bigOrZero(pending.UserOperation.Nonce).Cmp(bigOrZero(op.Nonce)) == 0 {
+			return pending
+		}
+	}
+	return nil
+}
+
+// isReplacementPriced applies the replacement rule of bundlers: both fees are raised by at least
+// MinReplacementFeeIncreasePercent
+func isReplacementPriced(pending *erc4337.UserOperation, replacement *erc4337.UserOperation) bool {
+	raised := func(pendingFee, replacementFee *hexutil.Big) bool {
+		required := new(big.Int).Mul(bigOrZero(pendingFee), big.NewInt(100+MinReplacementFeeIncreasePercent))
+		return new(big.Int).Mul(bigOrZero(replacementFee), big.NewInt(100)).Cmp(required) >= 0
+	}
+	return raised(pending.MaxFeePerGas, replacement.MaxFeePerGas) &&
+		raised(pending.MaxPriorityFeePerGas, replacement.MaxPriorityFeePerGas)
+}
+
+var (
+	uint256Type, _ = abi.NewType("uint256", "", nil)
+	boolType, _    = abi.NewType("bool", "", nil)
+	bytesType, _   = abi.NewType("bytes", "", nil)
+
+	// Non-indexed arguments of the UserOperationEvent and UserOperationRevertReason events
+	userOperationEventArgs = abi.Arguments{{Type: uint256Type}, {Type: boolType}, {Type: uint256Type}, {Type: uint256Type}}
+	revertReasonArgs       = abi.Arguments{{Type: uint256Type}, {Type: bytesType}}
+)
+
+func bigOrZero(v *hexutil.Big) *big.Int {
+	if v == nil {
+		return new(big.Int)
+	}
+	return v.ToInt()
+}
+
+func (b *Bundler) supportsEntryPoint(entryPoint common.Address) bool {
+	for _, ep := range b.entryPoints {
+		if ep == entryPoint {
+			return true
+		}
+	}
+	return false
+}
+
+func (b *Bundler) include(userOpHash common.Hash, success bool, logs []*types.Log) (*erc4337.UserOperationReceipt, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.includeLocked(userOpHash, success, logs)
+}
+
+func (b *Bundler) includeLocked(userOpHash common.Hash, success bool, logs []*types.Log) (*erc4337.UserOperationReceipt, error) {
+	sent, ok := b.mempool[userOpHash]
+	if !ok {
+		return nil, fmt.Errorf("user operation %s is not in the mempool", userOpHash.Hex())
+	}
+	delete(b.mempool, userOpHash)
+
+	b.blockNum++
+	blockNumber := hexutil.EncodeUint64(b.blockNum)
+	blockHash := crypto.Keccak256Hash([]byte(blockNumber))
+	txHash := crypto.Keccak256Hash(userOpHash.Bytes())
+
+	var paymaster common.Address
+	if sent.UserOperation.Paymaster != nil {
+		paymaster = *sent.UserOperation.Paymaster
+	}
+	nonce := "0x0"
+	if sent.UserOperation.Nonce != nil {
+		nonce = sent.UserOperation.Nonce.String()
+	}
+
+	// The EntryPoint emits the UserOperationEvent after the other events of the user operation
+	eventData, err := userOperationEventArgs.Pack(bigOrZero(sent.UserOperation.Nonce), success, new(big.Int), new(big.Int))
+	if err != nil {
+		return nil, err
+	}
+	logs = append(logs, &types.Log{
+		Topics: []common.Hash{
+			erc4337.UserOperationEventTopic,
+			userOpHash,
+			common.BytesToHash(sent.UserOperation.Sender.Bytes()),
+			common.BytesToHash(paymaster.Bytes()),
+		},
+		Data: eventData,
+	})
+	for i, log := range logs {
+		if log.Address == (common.Address{}) {
+			log.Address = sent.EntryPoint
+		}
+		log.BlockNumber = b.blockNum
+		log.BlockHash = blockHash
+		log.TxHash = txHash
+		log.Index = uint(len(b.logs) + i)
+	}
+	for _, log := range logs {
+		b.logs = append(b.logs, *log)
+	}
+
+	receipt := &erc4337.UserOperationReceipt{
+		UserOpHash:    userOpHash,
+		Sender:        sent.UserOperation.Sender,
+		Paymaster:     paymaster,
+		Nonce:         nonce,
+		Success:       success,
+		ActualGasCost: "0x0",
+		ActualGasUsed: "0x0",
+		From:          sent.UserOperation.Sender,
+		Receipt: &erc4337.UserOperationTransactionReceipt{
+			BlockHash:         blockHash,
+			BlockNumber:       blockNumber,
+			CumulativeGasUsed: "0x0",
+			GasUsed:           "0x0",
+			Logs:              logs,
+			TransactionHash:   txHash,
+			TransactionIndex:  "0x0",
+			EffectiveGasPrice: "0x0",
+		},
+		Logs: logs,
+	}
+
+	b.receipts[userOpHash] = receipt
+	b.included[userOpHash] = sent
+	return receipt, nil
+}
+
+// FilterLogs returns the logs of included user operations matching the block range, addresses and topics
+// of the query. The Bundler can thereby stand in for the node confirming receipts, e.g. as the
+// LogConfirmer of an erc4337.ReceiptWatcher.
+func (b *Bundler) FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]types.Log, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.injectedError("eth_getLogs"); err != nil {
+		return nil, err
+	}
+
+	var logs []types.Log
+	for _, log := range b.logs {
+		if matchesFilter(&log, q) {
+			logs = append(logs, log)
+		}
+	}
+	return logs, nil
+}
+
+// matchesFilter applies the eth_getLogs filter rules to a log
+func matchesFilter(log *types.Log, q ethereum.FilterQuery) bool {
+	if q.BlockHash != nil && log.BlockHash != *q.BlockHash {
+		return false
+	}
+	if q.FromBlock != nil && q.FromBlock.Sign() >= 0 && new(big.Int).SetUint64(log.BlockNumber).Cmp(q.FromBlock) < 0 {
+		return false
+	}
+	if q.ToBlock != nil && q.ToBlock.Sign() >= 0 && new(big.Int).SetUint64(log.BlockNumber).Cmp(q.ToBlock) > 0 {
+		return false
+	}
+	if len(q.Addresses) > 0 {
+		found := false
+		for _, address := range q.Addresses {
+			if log.Address == address {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(q.Topics) > len(log.Topics) {
+		return false
+	}
+	for i, alternatives := range q.Topics {
+		if len(alternatives) == 0 {
+			continue
+		}
+		found := false
+		for _, topic := range alternatives {
+			if log.Topics[i] == topic {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func (b *Bundler) ChainId(ctx context.Context) (*big.Int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.injectedError("eth_chainId"); err != nil {
+		return nil, err
+	}
+	return new(big.Int).Set(b.chainId), nil
+}
+
+func (b *Bundler) SupportedEntryPoints(ctx context.Context) ([]common.Address, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.injectedError("eth_supportedEntryPoints"); err != nil {
+		return nil, err
+	}
+	entryPoints := make([]common.Address, len(b.entryPoints))
+	copy(entryPoints, b.entryPoints)
+	return entryPoints, nil
+}
+
+func (b *Bundler) EstimateUserOperationGas(ctx context.Context, op *erc4337.UserOperation, entryPoint common.Address) (*erc4337.GasEstimates, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.injectedError("eth_estimateUserOperationGas"); err != nil {
+		return nil, err
+	}
+	if !b.supportsEntryPoint(entryPoint) {
+		return nil, erc4337.HandleRPCError(&RPCError{Code: -32602, Message: "unsupported entry point"}, "eth_estimateUserOperationGas")
+	}
+	estimates := b.gasEstimates
+	return &estimates, nil
+}
+
+func (b *Bundler) SendUserOperation(ctx context.Context, op *erc4337.UserOperation, entryPoint common.Address) (common.Hash, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.injectedError("eth_sendUserOperation"); err != nil {
+		return common.Hash{}, err
+	}
+	if !b.supportsEntryPoint(entryPoint) {
+		return common.Hash{}, erc4337.HandleRPCError(&RPCError{Code: -32602, Message: "unsupported entry point"}, "eth_sendUserOperation")
+	}
+
+	userOpHash, err := op.GetUserOpHash(entryPoint, b.chainId)
+	if err != nil {
+		return common.Hash{}, erc4337.HandleRPCError(&RPCError{Code: -32602, Message: err.Error()}, "eth_sendUserOperation")
+	}
+
+	// A pending user operation with the same sender and nonce is replaced if both fees are raised enough
+	if replaced := b.findSameNonceLocked(op, entryPoint); replaced != nil {
+		if !isReplacementPriced(&replaced.UserOperation, op) {
+			return common.Hash{}, erc4337.HandleRPCError(&RPCError{
+				Code:    -32602,
+				Message: fmt.Sprintf("replacement underpriced: maxFeePerGas and maxPriorityFeePerGas must be raised by at least %d%%", MinReplacementFeeIncreasePercent),
+			}, "eth_sendUserOperation")
+		}
+		delete(b.mempool, replaced.UserOpHash)
+	}
+
+	sent := SentUserOperation{
+		UserOpHash:    userOpHash,
+		UserOperation: *op,
+		EntryPoint:    entryPoint,
+	}
+	b.sent = append(b.sent, sent)
+	b.mempool[userOpHash] = &sent
+
+	if b.bundlingMode == erc4337.BundlingModeAuto {
+		if _, err := b.includeLocked(userOpHash, true, nil); err != nil {
+			return common.Hash{}, err
+		}
+	}
+
+	return userOpHash, nil
+}
+
+func (b *Bundler) GetUserOperationReceipt(ctx context.Context, userOpHash common.Hash) (*erc4337.UserOperationReceipt, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.injectedError("eth_getUserOperationReceipt"); err != nil {
+		return nil, err
+	}
+	return b.receipts[userOpHash], nil
+}
+
+func (b *Bundler) GetUserOperationByHash(ctx context.Context, userOpHash common.Hash) (*erc4337.UserOperationByHash, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.injectedError("eth_getUserOperationByHash"); err != nil {
+		return nil, err
+	}
+
+	if sent, ok := b.mempool[userOpHash]; ok {
+		op := sent.UserOperation
+		return &erc4337.UserOperationByHash{
+			UserOperation: &op,
+			EntryPoint:    sent.EntryPoint,
+		}, nil
+	}
+
+	if sent, ok := b.included[userOpHash]; ok {
+		receipt := b.receipts[userOpHash]
+		blockNumber, err := hexutil.DecodeBig(receipt.Receipt.BlockNumber)
+		if err != nil {
+			return nil, err
+		}
+		op := sent.UserOperation
+		return &erc4337.UserOperationByHash{
+			UserOperation:   &op,
+			EntryPoint:      sent.EntryPoint,
+			BlockNumber:     (*hexutil.Big)(blockNumber),
+			BlockHash:       &receipt.Receipt.BlockHash,
+			TransactionHash: &receipt.Receipt.TransactionHash,
+		}, nil
+	}
+
+	return nil, nil
+}
+
+func (b *Bundler) DebugClearState(ctx context.Context) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.injectedError("debug_bundler_clearState"); err != nil {
+		return err
+	}
+	b.mempool = make(map[common.Hash]*SentUserOperation)
+	b.reputation = make(map[common.Address][]erc4337.ReputationEntry)
+	return nil
+}
+
+func (b *Bundler) DebugDumpMempool(ctx context.Context, entryPoint common.Address) ([]*erc4337.UserOperation, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.injectedError("debug_bundler_dumpMempool"); err != nil {
+		return nil, err
+	}
+
+	// Keep send order so the dump is deterministic
+	var ops []*erc4337.UserOperation
+	for _, sent := range b.sent {
+		if _, ok := b.mempool[sent.UserOpHash]; ok && sent.EntryPoint == entryPoint {
+			op := sent.UserOperation
+			ops = append(ops, &op)
+		}
+	}
+	return ops, nil
+}
+
+func (b *Bundler) DebugSendBundleNow(ctx context.Context) (common.Hash, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.injectedError("debug_bundler_sendBundleNow"); err != nil {
+		return common.Hash{}, err
+	}
+
+	var txHash common.Hash
+	for _, sent := range b.sent {
+		if _, ok := b.mempool[sent.UserOpHash]; !ok {
+			continue
+		}
+		receipt, err := b.includeLocked(sent.UserOpHash, true, nil)
+		if err != nil {
+			return common.Hash{}, err
+		}
+		txHash = receipt.Receipt.TransactionHash
+	}
+	return txHash, nil
+}
+
+func (b *Bundler) DebugSetBundlingMode(ctx context.Context, mode erc4337.BundlingMode) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.injectedError("debug_bundler_setBundlingMode"); err != nil {
+		return err
+	}
+	b.bundlingMode = mode
+	return nil
+}
+
+func (b *Bundler) DebugSetReputation(ctx context.Context, entries []erc4337.ReputationEntry, entryPoint common.Address) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.injectedError("debug_bundler_setReputation"); err != nil {
+		return err
+	}
+	b.reputation[entryPoint] = append([]erc4337.ReputationEntry(nil), entries...)
+	return nil
+}
+
+func (b *Bundler) DebugDumpReputation(ctx context.Context, entryPoint common.Address) ([]erc4337.ReputationEntry, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.injectedError("debug_bundler_dumpReputation"); err != nil {
+		return nil, err
+	}
+	return append([]erc4337.ReputationEntry(nil), b.reputation[entryPoint]...), nil
+}
+
+// Close marks the bundler as closed; later calls fail like calls on a closed RPC client
+func (b *Bundler) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+}
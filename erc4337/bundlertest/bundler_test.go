@@ -0,0 +1,348 @@
+package bundlertest
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethaccount/backend/erc4337"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testChainID int64 = 11155111
+
+var testSender = common.HexToAddress("0x47d6a8a65cba9b61b194dac740aa192a7a1e91e1")
+
+func newTestUserOperation(nonce int64, maxFeePerGas int64, maxPriorityFeePerGas int64) *erc4337.UserOperation {
+	return &erc4337.UserOperation{
+		Sender:               testSender,
+		Nonce:                (*hexutil.Big)(big.NewInt(nonce)),
+		CallData:             hexutil.Bytes{0x12, 0x34},
+		MaxFeePerGas:         (*hexutil.Big)(big.NewInt(maxFeePerGas)),
+		MaxPriorityFeePerGas: (*hexutil.Big)(big.NewInt(maxPriorityFeePerGas)),
+	}
+}
+
+func TestBundler_SendUserOperation(t *testing.T) {
+	bundler := New(testChainID)
+	ctx := context.Background()
+
+	op := newTestUserOperation(1, 1000, 100)
+	userOpHash, err := bundler.SendUserOperation(ctx, op, erc4337.EntryPointV07)
+	require.NoError(t, err)
+
+	expectedHash, err := op.GetUserOpHash(erc4337.EntryPointV07, big.NewInt(testChainID))
+	require.NoError(t, err)
+	assert.Equal(t, expectedHash, userOpHash)
+
+	sent := bundler.Sent()
+	require.Len(t, sent, 1)
+	assert.Equal(t, SentUserOperation{UserOpHash: userOpHash, UserOperation: *op, EntryPoint: erc4337.EntryPointV07}, sent[0])
+
+	// The user operation is pending without a receipt
+	pending, err := bundler.GetUserOperationByHash(ctx, userOpHash)
+	require.NoError(t, err)
+	require.NotNil(t, pending)
+	assert.True(t, pending.IsPending())
+	receipt, err := bundler.GetUserOperationReceipt(ctx, userOpHash)
+	require.NoError(t, err)
+	assert.Nil(t, receipt)
+
+	mempool, err := bundler.DebugDumpMempool(ctx, erc4337.EntryPointV07)
+	require.NoError(t, err)
+	assert.Equal(t, []*erc4337.UserOperation{op}, mempool)
+}
+
+func TestBundler_GasEstimatesAndEntryPoints(t *testing.T) {
+	bundler := New(testChainID)
+	ctx := context.Background()
+
+	chainId, err := bundler.ChainId(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, big.NewInt(testChainID), chainId)
+
+	estimates, err := bundler.EstimateUserOperationGas(ctx, newTestUserOperation(1, 1000, 100), erc4337.EntryPointV08)
+	require.NoError(t, err)
+	assert.Equal(t, DefaultGasEstimates, *estimates)
+
+	custom := DefaultGasEstimates
+	custom.CallGasLimit = (*hexutil.Big)(big.NewInt(42))
+	bundler.SetGasEstimates(custom)
+	estimates, err = bundler.EstimateUserOperationGas(ctx, newTestUserOperation(1, 1000, 100), erc4337.EntryPointV08)
+	require.NoError(t, err)
+	assert.Equal(t, big.NewInt(42), estimates.CallGasLimit.ToInt())
+
+	bundler.SetSupportedEntryPoints(erc4337.EntryPointV07)
+	entryPoints, err := bundler.SupportedEntryPoints(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []common.Address{erc4337.EntryPointV07}, entryPoints)
+
+	_, err = bundler.EstimateUserOperationGas(ctx, newTestUserOperation(1, 1000, 100), erc4337.EntryPointV08)
+	assert.ErrorContains(t, err, "unsupported entry point")
+	_, err = bundler.SendUserOperation(ctx, newTestUserOperation(1, 1000, 100), erc4337.EntryPointV08)
+	assert.ErrorContains(t, err, "unsupported entry point")
+	assert.Empty(t, bundler.Sent())
+}
+
+func TestBundler_IncludeSuccess(t *testing.T) {
+	bundler := New(testChainID)
+	ctx := context.Background()
+
+	userOpHash, err := bundler.SendUserOperation(ctx, newTestUserOperation(1, 1000, 100), erc4337.EntryPointV07)
+	require.NoError(t, err)
+
+	receipt, err := bundler.IncludeSuccess(userOpHash)
+	require.NoError(t, err)
+	assert.True(t, receipt.Success)
+	assert.Equal(t, userOpHash, receipt.UserOpHash)
+	assert.Equal(t, testSender, receipt.Sender)
+	assert.Equal(t, "0x1", receipt.Receipt.BlockNumber)
+
+	// The receipt holds the UserOperationEvent emitted by the EntryPoint
+	events, err := receipt.DecodeEvents()
+	require.NoError(t, err)
+	require.NotNil(t, events.UserOperationEvent)
+	assert.True(t, events.UserOperationEvent.Success)
+	assert.Equal(t, testSender, events.UserOperationEvent.Sender)
+	assert.Equal(t, big.NewInt(1), events.UserOperationEvent.Nonce)
+
+	fetched, err := bundler.GetUserOperationReceipt(ctx, userOpHash)
+	require.NoError(t, err)
+	assert.Equal(t, receipt, fetched)
+
+	included, err := bundler.GetUserOperationByHash(ctx, userOpHash)
+	require.NoError(t, err)
+	require.NotNil(t, included)
+	assert.False(t, included.IsPending())
+	assert.Equal(t, receipt.Receipt.TransactionHash, *included.TransactionHash)
+
+	// A user operation is included once
+	_, err = bundler.IncludeSuccess(userOpHash)
+	assert.ErrorContains(t, err, "is not in the mempool")
+}
+
+func TestBundler_IncludeRevert(t *testing.T) {
+	bundler := New(testChainID)
+	ctx := context.Background()
+
+	userOpHash, err := bundler.SendUserOperation(ctx, newTestUserOperation(1, 1000, 100), erc4337.EntryPointV07)
+	require.NoError(t, err)
+
+	// Error(string) "boom"
+	revertReason := hexutil.MustDecode("0x08c379a0" +
+		"0000000000000000000000000000000000000000000000000000000000000020" +
+		"0000000000000000000000000000000000000000000000000000000000000004" +
+		"626f6f6d00000000000000000000000000000000000000000000000000000000")
+	receipt, err := bundler.IncludeRevertReason(userOpHash, revertReason)
+	require.NoError(t, err)
+	assert.False(t, receipt.Success)
+
+	events, err := receipt.DecodeEvents()
+	require.NoError(t, err)
+	require.NotNil(t, events.UserOperationRevertReason)
+	assert.Equal(t, revertReason, events.UserOperationRevertReason.RevertReason)
+	require.NotNil(t, events.UserOperationEvent)
+	assert.False(t, events.UserOperationEvent.Success)
+	assert.Equal(t, "User operation reverted: boom", receipt.FailureReason(nil))
+
+	// Without a revert reason
+	userOpHash, err = bundler.SendUserOperation(ctx, newTestUserOperation(2, 1000, 100), erc4337.EntryPointV07)
+	require.NoError(t, err)
+	receipt, err = bundler.IncludeRevert(userOpHash)
+	require.NoError(t, err)
+	assert.False(t, receipt.Success)
+	assert.Equal(t, "User operation failed on-chain", receipt.FailureReason(nil))
+}
+
+func TestBundler_Drop(t *testing.T) {
+	bundler := New(testChainID)
+	ctx := context.Background()
+
+	userOpHash, err := bundler.SendUserOperation(ctx, newTestUserOperation(1, 1000, 100), erc4337.EntryPointV07)
+	require.NoError(t, err)
+
+	bundler.Drop(userOpHash)
+	userOp, err := bundler.GetUserOperationByHash(ctx, userOpHash)
+	require.NoError(t, err)
+	assert.Nil(t, userOp)
+	receipt, err := bundler.GetUserOperationReceipt(ctx, userOpHash)
+	require.NoError(t, err)
+	assert.Nil(t, receipt)
+
+	_, err = bundler.IncludeSuccess(userOpHash)
+	assert.ErrorContains(t, err, "is not in the mempool")
+}
+
+func TestBundler_Replacement(t *testing.T) {
+	bundler := New(testChainID)
+	ctx := context.Background()
+
+	userOpHash, err := bundler.SendUserOperation(ctx, newTestUserOperation(1, 1000, 100), erc4337.EntryPointV07)
+	require.NoError(t, err)
+
+	// Both fees must be raised by 10%
+	_, err = bundler.SendUserOperation(ctx, newTestUserOperation(1, 1100, 109), erc4337.EntryPointV07)
+	assert.ErrorContains(t, err, "replacement underpriced")
+
+	replacementHash, err := bundler.SendUserOperation(ctx, newTestUserOperation(1, 1100, 110), erc4337.EntryPointV07)
+	require.NoError(t, err)
+	assert.NotEqual(t, userOpHash, replacementHash)
+
+	replaced, err := bundler.GetUserOperationByHash(ctx, userOpHash)
+	require.NoError(t, err)
+	assert.Nil(t, replaced)
+	pending, err := bundler.GetUserOperationByHash(ctx, replacementHash)
+	require.NoError(t, err)
+	require.NotNil(t, pending)
+	assert.True(t, pending.IsPending())
+
+	// Another nonce is not a replacement
+	_, err = bundler.SendUserOperation(ctx, newTestUserOperation(2, 1000, 100), erc4337.EntryPointV07)
+	require.NoError(t, err)
+	assert.Len(t, bundler.Sent(), 3)
+}
+
+func TestBundler_InjectError(t *testing.T) {
+	bundler := New(testChainID)
+	ctx := context.Background()
+
+	bundler.InjectError("eth_sendUserOperation", &RPCError{Code: -32500, Message: "AA21 didn't pay prefund"})
+	_, err := bundler.SendUserOperation(ctx, newTestUserOperation(1, 1000, 100), erc4337.EntryPointV07)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "bundler RPC error in eth_sendUserOperation: AA21 didn't pay prefund")
+	var aaErr *erc4337.AAError
+	require.True(t, errors.As(err, &aaErr))
+	assert.Equal(t, "AA21", aaErr.Code)
+	assert.Empty(t, bundler.Sent())
+
+	// Other methods are not affected
+	_, err = bundler.EstimateUserOperationGas(ctx, newTestUserOperation(1, 1000, 100), erc4337.EntryPointV07)
+	require.NoError(t, err)
+
+	bundler.ClearError("eth_sendUserOperation")
+	_, err = bundler.SendUserOperation(ctx, newTestUserOperation(1, 1000, 100), erc4337.EntryPointV07)
+	require.NoError(t, err)
+
+	bundler.Close()
+	_, err = bundler.ChainId(ctx)
+	assert.ErrorContains(t, err, "client is closed")
+}
+
+func TestBundler_BundlingModes(t *testing.T) {
+	// Driven through the debug namespace only, like a test bundler behind BundlerClient
+	var bundler erc4337.DebugBundler = New(testChainID)
+	ctx := context.Background()
+
+	first, err := bundler.SendUserOperation(ctx, newTestUserOperation(1, 1000, 100), erc4337.EntryPointV07)
+	require.NoError(t, err)
+	second, err := bundler.SendUserOperation(ctx, newTestUserOperation(2, 1000, 100), erc4337.EntryPointV07)
+	require.NoError(t, err)
+
+	txHash, err := bundler.DebugSendBundleNow(ctx)
+	require.NoError(t, err)
+	for _, userOpHash := range []common.Hash{first, second} {
+		receipt, err := bundler.GetUserOperationReceipt(ctx, userOpHash)
+		require.NoError(t, err)
+		require.NotNil(t, receipt)
+		assert.True(t, receipt.Success)
+	}
+	receipt, err := bundler.GetUserOperationReceipt(ctx, second)
+	require.NoError(t, err)
+	assert.Equal(t, txHash, receipt.Receipt.TransactionHash)
+
+	// In auto mode user operations are included as soon as they are sent
+	require.NoError(t, bundler.DebugSetBundlingMode(ctx, erc4337.BundlingModeAuto))
+	userOpHash, err := bundler.SendUserOperation(ctx, newTestUserOperation(3, 1000, 100), erc4337.EntryPointV07)
+	require.NoError(t, err)
+	receipt, err = bundler.GetUserOperationReceipt(ctx, userOpHash)
+	require.NoError(t, err)
+	require.NotNil(t, receipt)
+	assert.True(t, receipt.Success)
+}
+
+func TestBundler_FilterLogs(t *testing.T) {
+	bundler := New(testChainID)
+	ctx := context.Background()
+
+	first, err := bundler.SendUserOperation(ctx, newTestUserOperation(1, 1000, 100), erc4337.EntryPointV07)
+	require.NoError(t, err)
+	second, err := bundler.SendUserOperation(ctx, newTestUserOperation(2, 1000, 100), erc4337.EntryPointV08)
+	require.NoError(t, err)
+	_, err = bundler.IncludeSuccess(first)
+	require.NoError(t, err)
+	secondReceipt, err := bundler.IncludeRevertReason(second, []byte{0x01})
+	require.NoError(t, err)
+
+	logs, err := bundler.FilterLogs(ctx, ethereum.FilterQuery{})
+	require.NoError(t, err)
+	assert.Len(t, logs, 3)
+
+	// The second user operation is in block 2, where it emitted a revert reason and its UserOperationEvent
+	logs, err = bundler.FilterLogs(ctx, ethereum.FilterQuery{FromBlock: big.NewInt(2), ToBlock: big.NewInt(2)})
+	require.NoError(t, err)
+	require.Len(t, logs, 2)
+	for _, log := range logs {
+		assert.Equal(t, erc4337.EntryPointV08, log.Address)
+		assert.Equal(t, uint64(2), log.BlockNumber)
+		assert.Equal(t, secondReceipt.Receipt.TransactionHash, log.TxHash)
+	}
+
+	logs, err = bundler.FilterLogs(ctx, ethereum.FilterQuery{
+		Addresses: []common.Address{erc4337.EntryPointV07, erc4337.EntryPointV08},
+		Topics:    [][]common.Hash{{erc4337.UserOperationEventTopic}, {second}},
+	})
+	require.NoError(t, err)
+	require.Len(t, logs, 1)
+	event, err := erc4337.ParseUserOperationEvent(&logs[0])
+	require.NoError(t, err)
+	assert.Equal(t, second, event.UserOpHash)
+	assert.False(t, event.Success)
+
+	logs, err = bundler.FilterLogs(ctx, ethereum.FilterQuery{Addresses: []common.Address{erc4337.EntryPointV06}})
+	require.NoError(t, err)
+	assert.Empty(t, logs)
+}
+
+func TestBundler_ReceiptWatcher(t *testing.T) {
+	bundler := New(testChainID)
+	ctx := context.Background()
+
+	// The bundler confirms its own receipts like the node of the chain would
+	watcher := erc4337.NewReceiptWatcher(bundler, erc4337.ReceiptWatcherConfig{
+		PollInterval: 5 * time.Millisecond,
+		LogConfirmer: bundler,
+	})
+	t.Cleanup(watcher.Close)
+
+	included, err := bundler.SendUserOperation(ctx, newTestUserOperation(1, 1000, 100), erc4337.EntryPointV07)
+	require.NoError(t, err)
+	dropped, err := bundler.SendUserOperation(ctx, newTestUserOperation(2, 1000, 100), erc4337.EntryPointV07)
+	require.NoError(t, err)
+
+	includedResult := watcher.Watch(included)
+	droppedResult := watcher.Watch(dropped)
+	_, err = bundler.IncludeRevert(included)
+	require.NoError(t, err)
+	bundler.Drop(dropped)
+
+	for _, ch := range []<-chan erc4337.ReceiptResult{includedResult, droppedResult} {
+		select {
+		case result := <-ch:
+			require.NoError(t, result.Err)
+			if result.UserOpHash == included {
+				require.NotNil(t, result.Receipt)
+				assert.False(t, result.Receipt.Success)
+			} else {
+				assert.True(t, result.Dropped)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("no receipt result")
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package erc4337
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// EntryPointVersion identifies a deployed EntryPoint release
+type EntryPointVersion string
+
+const (
+	EntryPointVersionV06 EntryPointVersion = "v0.6"
+	EntryPointVersionV07 EntryPointVersion = "v0.7"
+	EntryPointVersionV08 EntryPointVersion = "v0.8"
+)
+
+// Canonical EntryPoint addresses (same on every chain)
+var (
+	EntryPointV06 = common.HexToAddress("0x5FF137D4b0FDCD49DcA30c7CF57E578a026d2789")
+	EntryPointV07 = common.HexToAddress("0x0000000071727De22E5E9d8BAf0edAc6f37da032")
+	EntryPointV08 = common.HexToAddress("0x4337084D9E255Ff0702461CF8895CE9E3b5Ff108")
+)
+
+// GetEntryPointVersion returns the EntryPoint version deployed at the given address
+func GetEntryPointVersion(entryPoint common.Address) (EntryPointVersion, error) {
+	switch entryPoint {
+	case EntryPointV06:
+		return EntryPointVersionV06, nil
+	case EntryPointV07:
+		return EntryPointVersionV07, nil
+	case EntryPointV08:
+		return EntryPointVersionV08, nil
+	default:
+		return "", fmt.Errorf("unsupported entry point: %s", entryPoint.Hex())
+	}
+}
@@ -0,0 +1,278 @@
+// Package erc7579 encodes and decodes the calldata of ERC-7579 account executions,
+// i.e. execute(bytes32 mode, bytes executionCalldata).
+package erc7579
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// ExecuteSelector is the selector of execute(bytes32,bytes)
+var ExecuteSelector = []byte{0xe9, 0xae, 0x5c, 0x53}
+
+// CallType is the first byte of the execution mode
+type CallType byte
+
+const (
+	CallTypeSingle       CallType = 0x00
+	CallTypeBatch        CallType = 0x01
+	CallTypeDelegateCall CallType = 0xff
+)
+
+func (c CallType) String() string {
+	switch c {
+	case CallTypeSingle:
+		return "single"
+	case CallTypeBatch:
+		return "batch"
+	case CallTypeDelegateCall:
+		return "delegatecall"
+	default:
+		return fmt.Sprintf("unknown(0x%02x)", byte(c))
+	}
+}
+
+// ExecType is the second byte of the execution mode
+type ExecType byte
+
+const (
+	// ExecTypeDefault reverts the whole execution when a call reverts
+	ExecTypeDefault ExecType = 0x00
+	// ExecTypeTry continues with the next call when a call reverts
+	ExecTypeTry ExecType = 0x01
+)
+
+// Mode is the bytes32 execution mode:
+// callType (1 byte) | execType (1 byte) | unused (4 bytes) | modeSelector (4 bytes) | modePayload (22 bytes)
+type Mode [32]byte
+
+// NewMode creates an execution mode with an empty mode selector and payload
+func NewMode(callType CallType, execType ExecType) Mode {
+	var mode Mode
+	mode[0] = byte(callType)
+	mode[1] = byte(execType)
+	return mode
+}
+
+func (m Mode) CallType() CallType {
+	return CallType(m[0])
+}
+
+func (m Mode) ExecType() ExecType {
+	return ExecType(m[1])
+}
+
+func (m Mode) Selector() [4]byte {
+	var selector [4]byte
+	copy(selector[:], m[6:10])
+	return selector
+}
+
+func (m Mode) Payload() [22]byte {
+	var payload [22]byte
+	copy(payload[:], m[10:32])
+	return payload
+}
+
+// Call is a single call performed by the account
+type Call struct {
+	Target common.Address
+	Value  *big.Int
+	Data   []byte
+}
+
+// Execution is a decoded execute(bytes32,bytes) call
+type Execution struct {
+	Mode  Mode
+	Calls []Call
+}
+
+var (
+	bytes32Type, _ = abi.NewType("bytes32", "", nil)
+	bytesType, _   = abi.NewType("bytes", "", nil)
+	callsType, _   = abi.NewType("tuple[]", "", []abi.ArgumentMarshaling{
+		{Name: "target", Type: "address"},
+		{Name: "value", Type: "uint256"},
+		{Name: "callData", Type: "bytes"},
+	})
+
+	executeArgs = abi.Arguments{{Type: bytes32Type}, {Type: bytesType}}
+	batchArgs   = abi.Arguments{{Type: callsType}}
+)
+
+// batchCall mirrors the Execution struct of ERC-7579 for ABI encoding
+type batchCall struct {
+	Target   common.Address
+	Value    *big.Int
+	CallData []byte
+}
+
+// EncodeSingle encodes a single call as abi.encodePacked(target, value, callData)
+func EncodeSingle(call Call) []byte {
+	value := call.Value
+	if value == nil {
+		value = new(big.Int)
+	}
+
+	encoded := make([]byte, 0, 52+len(call.Data))
+	encoded = append(encoded, call.Target.Bytes()...)
+	encoded = append(encoded, common.BigToHash(value).Bytes()...)
+	encoded = append(encoded, call.Data...)
+	return encoded
+}
+
+// EncodeBatch encodes calls as abi.encode(Execution[])
+func EncodeBatch(calls []Call) ([]byte, error) {
+	batch := make([]batchCall, len(calls))
+	for i, call := range calls {
+		value := call.Value
+		if value == nil {
+			value = new(big.Int)
+		}
+		data := call.Data
+		if data == nil {
+			data = []byte{}
+		}
+		batch[i] = batchCall{Target: call.Target, Value: value, CallData: data}
+	}
+
+	encoded, err := batchArgs.Pack(batch)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode batch executions: %w", err)
+	}
+	return encoded, nil
+}
+
+// EncodeDelegateCall encodes a delegatecall as abi.encodePacked(target, callData)
+func EncodeDelegateCall(target common.Address, data []byte) []byte {
+	encoded := make([]byte, 0, 20+len(data))
+	encoded = append(encoded, target.Bytes()...)
+	encoded = append(encoded, data...)
+	return encoded
+}
+
+// EncodeExecutionCalldata encodes the calls for the call type of the mode
+func EncodeExecutionCalldata(mode Mode, calls []Call) ([]byte, error) {
+	switch mode.CallType() {
+	case CallTypeSingle:
+		if len(calls) != 1 {
+			return nil, fmt.Errorf("single call type requires exactly one call, got %d", len(calls))
+		}
+		return EncodeSingle(calls[0]), nil
+	case CallTypeBatch:
+		return EncodeBatch(calls)
+	case CallTypeDelegateCall:
+		if len(calls) != 1 {
+			return nil, fmt.Errorf("delegatecall call type requires exactly one call, got %d", len(calls))
+		}
+		if calls[0].Value != nil && calls[0].Value.Sign() != 0 {
+			return nil, fmt.Errorf("delegatecall cannot transfer value")
+		}
+		return EncodeDelegateCall(calls[0].Target, calls[0].Data), nil
+	default:
+		return nil, fmt.Errorf("unsupported call type: %s", mode.CallType())
+	}
+}
+
+// EncodeExecute encodes execute(bytes32 mode, bytes executionCalldata) for the given calls
+func EncodeExecute(mode Mode, calls []Call) ([]byte, error) {
+	executionCalldata, err := EncodeExecutionCalldata(mode, calls)
+	if err != nil {
+		return nil, err
+	}
+
+	encoded, err := executeArgs.Pack([32]byte(mode), executionCalldata)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode execute call: %w", err)
+	}
+	return append(append([]byte{}, ExecuteSelector...), encoded...), nil
+}
+
+// DecodeSingle decodes abi.encodePacked(target, value, callData)
+func DecodeSingle(executionCalldata []byte) (Call, error) {
+	if len(executionCalldata) < 52 {
+		return Call{}, fmt.Errorf("single execution calldata too short: %d bytes", len(executionCalldata))
+	}
+	return Call{
+		Target: common.BytesToAddress(executionCalldata[:20]),
+		Value:  new(big.Int).SetBytes(executionCalldata[20:52]),
+		Data:   common.CopyBytes(executionCalldata[52:]),
+	}, nil
+}
+
+// DecodeBatch decodes abi.encode(Execution[])
+func DecodeBatch(executionCalldata []byte) ([]Call, error) {
+	unpacked, err := batchArgs.Unpack(executionCalldata)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode batch executions: %w", err)
+	}
+
+	var batch []batchCall
+	if err := batchArgs.Copy(&batch, unpacked); err != nil {
+		return nil, fmt.Errorf("failed to decode batch executions: %w", err)
+	}
+
+	calls := make([]Call, len(batch))
+	for i, c := range batch {
+		calls[i] = Call{Target: c.Target, Value: c.Value, Data: c.CallData}
+	}
+	return calls, nil
+}
+
+// DecodeDelegateCall decodes abi.encodePacked(target, callData)
+func DecodeDelegateCall(executionCalldata []byte) (Call, error) {
+	if len(executionCalldata) < 20 {
+		return Call{}, fmt.Errorf("delegatecall execution calldata too short: %d bytes", len(executionCalldata))
+	}
+	return Call{
+		Target: common.BytesToAddress(executionCalldata[:20]),
+		Value:  new(big.Int),
+		Data:   common.CopyBytes(executionCalldata[20:]),
+	}, nil
+}
+
+// DecodeExecutionCalldata decodes the execution calldata for the call type of the mode
+func DecodeExecutionCalldata(mode Mode, executionCalldata []byte) ([]Call, error) {
+	switch mode.CallType() {
+	case CallTypeSingle:
+		call, err := DecodeSingle(executionCalldata)
+		if err != nil {
+			return nil, err
+		}
+		return []Call{call}, nil
+	case CallTypeBatch:
+		return DecodeBatch(executionCalldata)
+	case CallTypeDelegateCall:
+		call, err := DecodeDelegateCall(executionCalldata)
+		if err != nil {
+			return nil, err
+		}
+		return []Call{call}, nil
+	default:
+		return nil, fmt.Errorf("unsupported call type: %s", mode.CallType())
+	}
+}
+
+// DecodeExecute decodes the calldata of execute(bytes32 mode, bytes executionCalldata)
+func DecodeExecute(callData []byte) (*Execution, error) {
+	if len(callData) < 4 || !bytes.Equal(callData[:4], ExecuteSelector) {
+		return nil, fmt.Errorf("calldata is not an execute(bytes32,bytes) call")
+	}
+
+	unpacked, err := executeArgs.Unpack(callData[4:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode execute call: %w", err)
+	}
+
+	mode := Mode(unpacked[0].([32]byte))
+	calls, err := DecodeExecutionCalldata(mode, unpacked[1].([]byte))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Execution{Mode: mode, Calls: calls}, nil
+}
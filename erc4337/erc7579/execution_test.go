@@ -0,0 +1,130 @@
+package erc7579
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// callData of the sample user operation in cmd/erc4337: a single call to ScheduledTransfers.executeOrder(9)
+const sampleExecuteCallData = "0xe9ae5c53000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000400000000000000000000000000000000000000000000000000000000000000058a8e374779aee60413c974b484d6509c7e4ddb6ba000000000000000000000000000000000000000000000000000000000000000094f6113400000000000000000000000000000000000000000000000000000000000000090000000000000000"
+
+// assertCallsEqual compares calls field by field, since decoded big.Int values are not reflect.DeepEqual to constructed ones
+func assertCallsEqual(t *testing.T, expected, actual []Call) {
+	require.Len(t, actual, len(expected))
+	for i := range expected {
+		assert.Equal(t, expected[i].Target, actual[i].Target)
+		assert.Equal(t, 0, expected[i].Value.Cmp(actual[i].Value), "value of call %d", i)
+		assert.Equal(t, hexutil.Encode(expected[i].Data), hexutil.Encode(actual[i].Data))
+	}
+}
+
+func TestDecodeExecute_Single(t *testing.T) {
+	execution, err := DecodeExecute(hexutil.MustDecode(sampleExecuteCallData))
+	require.NoError(t, err)
+
+	assert.Equal(t, CallTypeSingle, execution.Mode.CallType())
+	assert.Equal(t, ExecTypeDefault, execution.Mode.ExecType())
+	require.Len(t, execution.Calls, 1)
+	assert.Equal(t, common.HexToAddress("0xA8E374779aeE60413c974b484d6509c7E4DDb6bA"), execution.Calls[0].Target)
+	assert.Equal(t, 0, execution.Calls[0].Value.Sign())
+	assert.Equal(t, "0x94f611340000000000000000000000000000000000000000000000000000000000000009", hexutil.Encode(execution.Calls[0].Data))
+
+	encoded, err := EncodeExecute(execution.Mode, execution.Calls)
+	require.NoError(t, err)
+	assert.Equal(t, sampleExecuteCallData, hexutil.Encode(encoded))
+}
+
+func TestEncodeDecodeExecute(t *testing.T) {
+	target := common.HexToAddress("0x1c7d4b196cb0c7b01d743fbc6116a902379c7238")
+	recipient := common.HexToAddress("0x1234567890123456789012345678901234567890")
+
+	tests := []struct {
+		name  string
+		mode  Mode
+		calls []Call
+	}{
+		{
+			name:  "single with value",
+			mode:  NewMode(CallTypeSingle, ExecTypeDefault),
+			calls: []Call{{Target: recipient, Value: big.NewInt(1e18), Data: []byte{}}},
+		},
+		{
+			name: "batch",
+			mode: NewMode(CallTypeBatch, ExecTypeTry),
+			calls: []Call{
+				{Target: target, Value: big.NewInt(0), Data: hexutil.MustDecode("0xa9059cbb0000000000000000000000001234567890123456789012345678901234567890000000000000000000000000000000000000000000000000000000000000000a")},
+				{Target: recipient, Value: big.NewInt(5), Data: []byte{}},
+			},
+		},
+		{
+			name:  "delegatecall",
+			mode:  NewMode(CallTypeDelegateCall, ExecTypeDefault),
+			calls: []Call{{Target: target, Value: big.NewInt(0), Data: []byte{0x12, 0x34}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encoded, err := EncodeExecute(tt.mode, tt.calls)
+			require.NoError(t, err)
+
+			execution, err := DecodeExecute(encoded)
+			require.NoError(t, err)
+			assert.Equal(t, tt.mode, execution.Mode)
+			assertCallsEqual(t, tt.calls, execution.Calls)
+		})
+	}
+}
+
+func TestDecodeExecute_Invalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		callData string
+	}{
+		{
+			name:     "wrong selector",
+			callData: "0xa9059cbb",
+		},
+		{
+			name:     "too short",
+			callData: "0xe9ae5c",
+		},
+		{
+			name: "unsupported call type",
+			callData: "0xe9ae5c53" +
+				"fe00000000000000000000000000000000000000000000000000000000000000" +
+				"0000000000000000000000000000000000000000000000000000000000000040" +
+				"0000000000000000000000000000000000000000000000000000000000000000",
+		},
+		{
+			name: "truncated single execution",
+			callData: "0xe9ae5c53" +
+				"0000000000000000000000000000000000000000000000000000000000000000" +
+				"0000000000000000000000000000000000000000000000000000000000000040" +
+				"0000000000000000000000000000000000000000000000000000000000000014" +
+				"1234567890123456789012345678901234567890000000000000000000000000",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := DecodeExecute(hexutil.MustDecode(tt.callData))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestEncodeExecutionCalldata_Invalid(t *testing.T) {
+	call := Call{Target: common.HexToAddress("0x01"), Value: big.NewInt(1)}
+
+	_, err := EncodeExecutionCalldata(NewMode(CallTypeSingle, ExecTypeDefault), []Call{call, call})
+	assert.Error(t, err)
+
+	_, err = EncodeExecutionCalldata(NewMode(CallTypeDelegateCall, ExecTypeDefault), []Call{call})
+	assert.Error(t, err)
+}
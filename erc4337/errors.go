@@ -0,0 +1,170 @@
+package erc4337
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// JSON-RPC error codes returned by bundlers (ERC-7769)
+const (
+	ErrCodeInvalidFields                  = -32602 // invalid user operation fields
+	ErrCodeSimulateValidation             = -32500 // rejected by the EntryPoint simulation of the account or factory
+	ErrCodeSimulatePaymasterValidation    = -32501 // rejected by the paymaster validation
+	ErrCodeOpcodeValidation               = -32502 // banned opcode or storage access during validation
+	ErrCodeExpiresShortly                 = -32503 // validity time range is expired or about to expire
+	ErrCodeThrottled                      = -32504 // account, factory or paymaster is throttled or banned
+	ErrCodeInsufficientStake              = -32505 // entity stake or unstake delay too low
+	ErrCodeUnsupportedSignatureAggregator = -32506
+	ErrCodeInvalidSignature               = -32507 // signature check failed
+)
+
+// AAError is an EntryPoint validation failure identified by its AAxx code, e.g. "AA21 didn't pay prefund".
+// The first digit of the code identifies the entity: 1 factory, 2 account, 3 paymaster, 4 verification gas,
+// 5 postOp and 9 the EntryPoint itself.
+type AAError struct {
+	Code   string // e.g. "AA21"
+	Reason string // the reason as defined by the EntryPoint, e.g. "didn't pay prefund"
+}
+
+func (e *AAError) Error() string {
+	if e.Reason == "" {
+		return e.Code
+	}
+	return e.Code + " " + e.Reason
+}
+
+// Transient reports whether the user operation may succeed if it is sent again later without changes,
+// e.g. once the account or paymaster is funded or the validity window is reached
+func (e *AAError) Transient() bool {
+	return transientAACodes[e.Code]
+}
+
+// aaReasons are the AAxx reasons of EntryPoint v0.6 to v0.8
+var aaReasons = map[string]string{
+	"AA10": "sender already constructed",
+	"AA13": "initCode failed or OOG",
+	"AA14": "initCode must return sender",
+	"AA15": "initCode must create sender",
+	"AA20": "account not deployed",
+	"AA21": "didn't pay prefund",
+	"AA22": "expired or not due",
+	"AA23": "reverted",
+	"AA24": "signature error",
+	"AA25": "invalid account nonce",
+	"AA26": "over verificationGasLimit",
+	"AA30": "paymaster not deployed",
+	"AA31": "paymaster deposit too low",
+	"AA32": "paymaster expired or not due",
+	"AA33": "reverted",
+	"AA34": "signature error",
+	"AA36": "over paymasterVerificationGasLimit",
+	"AA40": "over verificationGasLimit",
+	"AA41": "too little verificationGas",
+	"AA50": "postOp reverted",
+	"AA51": "prefund below actualGasCost",
+	"AA90": "invalid beneficiary",
+	"AA91": "failed send to beneficiary",
+	"AA92": "internal call only",
+	"AA93": "invalid paymasterAndData",
+	"AA94": "gas values overflow",
+	"AA95": "out of gas",
+	"AA96": "invalid aggregator",
+}
+
+// transientAACodes are the failures that depend on balances, time or the nonce rather than the user operation itself
+var transientAACodes = map[string]bool{
+	"AA21": true, // the account may be funded later
+	"AA22": true, // the validity window may not have started yet
+	"AA25": true, // another user operation with the same nonce may be pending
+	"AA31": true, // the paymaster deposit may be topped up
+	"AA32": true,
+	"AA95": true, // the bundle ran out of gas
+}
+
+var aaCodePattern = regexp.MustCompile(`\bAA\d{2}\b`)
+
+// ParseAAError extracts the AAxx code from a bundler error message, or returns nil if there is none
+func ParseAAError(message string) *AAError {
+	code := aaCodePattern.FindString(message)
+	if code == "" {
+		return nil
+	}
+	return &AAError{Code: code, Reason: aaReasons[code]}
+}
+
+// BundlerError is a JSON-RPC error returned by a bundler.
+// Use errors.As to get it from the errors returned by a Bundler, and errors.As with *AAError
+// to get the EntryPoint validation failure, if any.
+type BundlerError struct {
+	Operation string // the RPC method, e.g. eth_sendUserOperation
+	Code      int
+	Message   string
+	Data      interface{}
+	AA        *AAError
+
+	err rpc.Error
+}
+
+// NewBundlerError creates a BundlerError from a JSON-RPC error returned for the given RPC method
+func NewBundlerError(operation string, err rpc.Error) *BundlerError {
+	bundlerErr := &BundlerError{
+		Operation: operation,
+		Code:      err.ErrorCode(),
+		Message:   err.Error(),
+		AA:        ParseAAError(err.Error()),
+		err:       err,
+	}
+	if dataErr, ok := err.(rpc.DataError); ok {
+		bundlerErr.Data = dataErr.ErrorData()
+	}
+	return bundlerErr
+}
+
+func (e *BundlerError) Error() string {
+	if e.Data != nil {
+		return fmt.Sprintf("bundler RPC error in %s: %s, data: %v", e.Operation, e.Message, e.Data)
+	}
+	return fmt.Sprintf("bundler RPC error in %s: %s", e.Operation, e.Message)
+}
+
+// ErrorCode implements rpc.Error
+func (e *BundlerError) ErrorCode() int {
+	return e.Code
+}
+
+// ErrorData implements rpc.DataError
+func (e *BundlerError) ErrorData() interface{} {
+	return e.Data
+}
+
+// Unwrap returns the AA error if the message contains one, so errors.As works with *AAError
+func (e *BundlerError) Unwrap() []error {
+	if e.AA != nil {
+		return []error{e.err, e.AA}
+	}
+	return []error{e.err}
+}
+
+// Transient reports whether the request may succeed if it is sent again later without changes
+func (e *BundlerError) Transient() bool {
+	if e.AA != nil {
+		return e.AA.Transient()
+	}
+	switch e.Code {
+	case ErrCodeExpiresShortly, ErrCodeThrottled:
+		return true
+	case -32603, -32005: // internal error, limit exceeded
+		return true
+	}
+	return false
+}
+
+// IsTransientBundlerError reports whether an error returned by a Bundler is a bundler error that may not happen again,
+// such as an unfunded account or a throttled paymaster. Errors that are not a BundlerError return false.
+func IsTransientBundlerError(err error) bool {
+	var bundlerErr *BundlerError
+	return errors.As(err, &bundlerErr) && bundlerErr.Transient()
+}
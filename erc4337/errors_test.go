@@ -0,0 +1,102 @@
+package erc4337
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testDataRPCError is a JSON-RPC error response with data
+type testDataRPCError struct {
+	testRPCError
+	data interface{}
+}
+
+func (e *testDataRPCError) ErrorData() interface{} { return e.data }
+
+func TestParseAAError(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		want    *AAError
+	}{
+		{"plain", "AA21 didn't pay prefund", &AAError{Code: "AA21", Reason: "didn't pay prefund"}},
+		{"embedded", "UserOperation reverted during simulation with reason: AA23 reverted: 0x1234", &AAError{Code: "AA23", Reason: "reverted"}},
+		{"unknown code", "AA99 something new", &AAError{Code: "AA99"}},
+		{"no code", "invalid user operation", nil},
+		{"not a word", "AAA21 didn't pay prefund", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, ParseAAError(tt.message))
+		})
+	}
+}
+
+func TestHandleRPCError_BundlerError(t *testing.T) {
+	err := HandleRPCError(&testDataRPCError{
+		testRPCError: testRPCError{code: ErrCodeSimulateValidation, message: "AA25 invalid account nonce"},
+		data:         "0x",
+	}, "eth_sendUserOperation")
+	wrapped := fmt.Errorf("failed to send user operation: %w", err)
+
+	assert.Equal(t, "bundler RPC error in eth_sendUserOperation: AA25 invalid account nonce, data: 0x", err.Error())
+
+	var bundlerErr *BundlerError
+	require.True(t, errors.As(wrapped, &bundlerErr))
+	assert.Equal(t, "eth_sendUserOperation", bundlerErr.Operation)
+	assert.Equal(t, ErrCodeSimulateValidation, bundlerErr.Code)
+	assert.Equal(t, "0x", bundlerErr.Data)
+
+	var aaErr *AAError
+	require.True(t, errors.As(wrapped, &aaErr))
+	assert.Equal(t, "AA25", aaErr.Code)
+	assert.Equal(t, "invalid account nonce", aaErr.Reason)
+
+	var rpcErr *testDataRPCError
+	assert.True(t, errors.As(wrapped, &rpcErr))
+}
+
+func TestHandleRPCError_TransportError(t *testing.T) {
+	err := HandleRPCError(errors.New("connection refused"), "eth_chainId")
+	assert.Equal(t, "bundler call failed in eth_chainId: connection refused", err.Error())
+
+	var bundlerErr *BundlerError
+	assert.False(t, errors.As(err, &bundlerErr))
+	assert.False(t, IsTransientBundlerError(err))
+	assert.NoError(t, HandleRPCError(nil, "eth_chainId"))
+}
+
+func TestIsTransientBundlerError(t *testing.T) {
+	tests := []struct {
+		name      string
+		code      int
+		message   string
+		transient bool
+	}{
+		{"unfunded account", ErrCodeSimulateValidation, "AA21 didn't pay prefund", true},
+		{"not due yet", ErrCodeSimulateValidation, "AA22 expired or not due", true},
+		{"invalid nonce", ErrCodeSimulateValidation, "AA25 invalid account nonce", true},
+		{"paymaster deposit too low", ErrCodeSimulatePaymasterValidation, "AA31 paymaster deposit too low", true},
+		{"account reverted", ErrCodeSimulateValidation, "AA23 reverted", false},
+		{"signature error", ErrCodeSimulateValidation, "AA24 signature error", false},
+		{"paymaster reverted", ErrCodeSimulatePaymasterValidation, "AA33 reverted", false},
+		{"throttled", ErrCodeThrottled, "paymaster is throttled", true},
+		{"expires shortly", ErrCodeExpiresShortly, "expires too soon", true},
+		{"internal error", -32603, "internal error", true},
+		{"invalid fields", ErrCodeInvalidFields, "missing callGasLimit", false},
+		{"invalid signature", ErrCodeInvalidSignature, "invalid signature", false},
+		{"banned opcode", ErrCodeOpcodeValidation, "account uses banned opcode: GASPRICE", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := HandleRPCError(&testRPCError{code: tt.code, message: tt.message}, "eth_estimateUserOperationGas")
+			assert.Equal(t, tt.transient, IsTransientBundlerError(fmt.Errorf("failed to estimate user operation gas: %w", err)))
+		})
+	}
+}
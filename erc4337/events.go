@@ -0,0 +1,213 @@
+package erc4337
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// ABI of the EntryPoint events emitted for a user operation.
+// The signatures are identical in v0.6, v0.7 and v0.8 (PostOpRevertReason only exists since v0.7).
+const entryPointEventsABI = `[
+	{"anonymous":false,"inputs":[{"indexed":true,"name":"userOpHash","type":"bytes32"},{"indexed":true,"name":"sender","type":"address"},{"indexed":true,"name":"paymaster","type":"address"},{"indexed":false,"name":"nonce","type":"uint256"},{"indexed":false,"name":"success","type":"bool"},{"indexed":false,"name":"actualGasCost","type":"uint256"},{"indexed":false,"name":"actualGasUsed","type":"uint256"}],"name":"UserOperationEvent","type":"event"},
+	{"anonymous":false,"inputs":[{"indexed":true,"name":"userOpHash","type":"bytes32"},{"indexed":true,"name":"sender","type":"address"},{"indexed":false,"name":"nonce","type":"uint256"},{"indexed":false,"name":"revertReason","type":"bytes"}],"name":"UserOperationRevertReason","type":"event"},
+	{"anonymous":false,"inputs":[{"indexed":true,"name":"userOpHash","type":"bytes32"},{"indexed":true,"name":"sender","type":"address"},{"indexed":false,"name":"factory","type":"address"},{"indexed":false,"name":"paymaster","type":"address"}],"name":"AccountDeployed","type":"event"},
+	{"anonymous":false,"inputs":[{"indexed":true,"name":"userOpHash","type":"bytes32"},{"indexed":true,"name":"sender","type":"address"},{"indexed":false,"name":"nonce","type":"uint256"},{"indexed":false,"name":"revertReason","type":"bytes"}],"name":"PostOpRevertReason","type":"event"}
+]`
+
+var entryPointEvents = mustParseABI(entryPointEventsABI)
+
+// Topics of the EntryPoint events
+var (
+	UserOperationEventTopic        = entryPointEvents.Events["UserOperationEvent"].ID
+	UserOperationRevertReasonTopic = entryPointEvents.Events["UserOperationRevertReason"].ID
+	AccountDeployedTopic           = entryPointEvents.Events["AccountDeployed"].ID
+	PostOpRevertReasonTopic        = entryPointEvents.Events["PostOpRevertReason"].ID
+)
+
+func mustParseABI(abiJSON string) abi.ABI {
+	parsed, err := abi.JSON(strings.NewReader(abiJSON))
+	if err != nil {
+		panic(fmt.Sprintf("invalid ABI: %v", err))
+	}
+	return parsed
+}
+
+// UserOperationEvent is emitted by the EntryPoint after each user operation is executed
+type UserOperationEvent struct {
+	UserOpHash    common.Hash
+	Sender        common.Address
+	Paymaster     common.Address
+	Nonce         *big.Int
+	Success       bool
+	ActualGasCost *big.Int
+	ActualGasUsed *big.Int
+}
+
+// UserOperationRevertReason is emitted by the EntryPoint when the user operation's call reverts
+type UserOperationRevertReason struct {
+	UserOpHash   common.Hash
+	Sender       common.Address
+	Nonce        *big.Int
+	RevertReason []byte
+}
+
+// AccountDeployed is emitted by the EntryPoint when the user operation deploys the account through initCode
+type AccountDeployed struct {
+	UserOpHash common.Hash
+	Sender     common.Address
+	Factory    common.Address
+	Paymaster  common.Address
+}
+
+// PostOpRevertReason is emitted by the EntryPoint when the paymaster's postOp reverts
+type PostOpRevertReason struct {
+	UserOpHash   common.Hash
+	Sender       common.Address
+	Nonce        *big.Int
+	RevertReason []byte
+}
+
+// unpackEntryPointEvent checks the log topics and unpacks the non-indexed fields of the named event
+func unpackEntryPointEvent(name string, log *types.Log, indexed int) ([]interface{}, error) {
+	event := entryPointEvents.Events[name]
+	if log == nil || len(log.Topics) == 0 || log.Topics[0] != event.ID {
+		return nil, fmt.Errorf("log is not a %s event", name)
+	}
+	if len(log.Topics) != indexed+1 {
+		return nil, fmt.Errorf("invalid %s event: expected %d topics, got %d", name, indexed+1, len(log.Topics))
+	}
+
+	values, err := event.Inputs.NonIndexed().Unpack(log.Data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unpack %s event: %w", name, err)
+	}
+	return values, nil
+}
+
+// ParseUserOperationEvent decodes a UserOperationEvent log
+func ParseUserOperationEvent(log *types.Log) (*UserOperationEvent, error) {
+	values, err := unpackEntryPointEvent("UserOperationEvent", log, 3)
+	if err != nil {
+		return nil, err
+	}
+
+	return &UserOperationEvent{
+		UserOpHash:    log.Topics[1],
+		Sender:        common.BytesToAddress(log.Topics[2].Bytes()),
+		Paymaster:     common.BytesToAddress(log.Topics[3].Bytes()),
+		Nonce:         values[0].(*big.Int),
+		Success:       values[1].(bool),
+		ActualGasCost: values[2].(*big.Int),
+		ActualGasUsed: values[3].(*big.Int),
+	}, nil
+}
+
+// ParseUserOperationRevertReason decodes a UserOperationRevertReason log
+func ParseUserOperationRevertReason(log *types.Log) (*UserOperationRevertReason, error) {
+	values, err := unpackEntryPointEvent("UserOperationRevertReason", log, 2)
+	if err != nil {
+		return nil, err
+	}
+
+	return &UserOperationRevertReason{
+		UserOpHash:   log.Topics[1],
+		Sender:       common.BytesToAddress(log.Topics[2].Bytes()),
+		Nonce:        values[0].(*big.Int),
+		RevertReason: values[1].([]byte),
+	}, nil
+}
+
+// ParseAccountDeployed decodes an AccountDeployed log
+func ParseAccountDeployed(log *types.Log) (*AccountDeployed, error) {
+	values, err := unpackEntryPointEvent("AccountDeployed", log, 2)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AccountDeployed{
+		UserOpHash: log.Topics[1],
+		Sender:     common.BytesToAddress(log.Topics[2].Bytes()),
+		Factory:    values[0].(common.Address),
+		Paymaster:  values[1].(common.Address),
+	}, nil
+}
+
+// ParsePostOpRevertReason decodes a PostOpRevertReason log
+func ParsePostOpRevertReason(log *types.Log) (*PostOpRevertReason, error) {
+	values, err := unpackEntryPointEvent("PostOpRevertReason", log, 2)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PostOpRevertReason{
+		UserOpHash:   log.Topics[1],
+		Sender:       common.BytesToAddress(log.Topics[2].Bytes()),
+		Nonce:        values[0].(*big.Int),
+		RevertReason: values[1].([]byte),
+	}, nil
+}
+
+// UserOperationEvents holds the EntryPoint events of a single user operation.
+// Fields are nil when the corresponding event was not emitted.
+type UserOperationEvents struct {
+	UserOperationEvent        *UserOperationEvent
+	UserOperationRevertReason *UserOperationRevertReason
+	AccountDeployed           *AccountDeployed
+	PostOpRevertReason        *PostOpRevertReason
+}
+
+// DecodeEvents decodes the EntryPoint events that belong to the receipt's user operation.
+// Logs of other contracts and of other user operations in the same bundle are ignored.
+func (r *UserOperationReceipt) DecodeEvents() (*UserOperationEvents, error) {
+	events := &UserOperationEvents{}
+
+	for _, log := range r.Logs {
+		if log == nil || len(log.Topics) < 2 || log.Topics[1] != r.UserOpHash {
+			continue
+		}
+
+		var err error
+		switch log.Topics[0] {
+		case UserOperationEventTopic:
+			events.UserOperationEvent, err = ParseUserOperationEvent(log)
+		case UserOperationRevertReasonTopic:
+			events.UserOperationRevertReason, err = ParseUserOperationRevertReason(log)
+		case AccountDeployedTopic:
+			events.AccountDeployed, err = ParseAccountDeployed(log)
+		case PostOpRevertReasonTopic:
+			events.PostOpRevertReason, err = ParsePostOpRevertReason(log)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return events, nil
+}
+
+// FailureReason explains why the user operation of a failed receipt reverted.
+// Revert data is decoded with the given decoder, which may be nil to only decode Error(string) and Panic(uint256).
+func (r *UserOperationReceipt) FailureReason(decoder *RevertDecoder) string {
+	if r.Success {
+		return ""
+	}
+
+	events, err := r.DecodeEvents()
+	if err != nil {
+		return fmt.Sprintf("User operation failed on-chain (failed to decode logs: %v)", err)
+	}
+
+	switch {
+	case events.UserOperationRevertReason != nil:
+		return "User operation reverted: " + decoder.Decode(events.UserOperationRevertReason.RevertReason)
+	case events.PostOpRevertReason != nil:
+		return "Paymaster postOp reverted: " + decoder.Decode(events.PostOpRevertReason.RevertReason)
+	default:
+		return "User operation failed on-chain"
+	}
+}
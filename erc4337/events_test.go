@@ -0,0 +1,207 @@
+package erc4337
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newEntryPointLog builds an EntryPoint event log with the given indexed topics and non-indexed values
+func newEntryPointLog(t *testing.T, name string, topics []common.Hash, values ...interface{}) *types.Log {
+	event := entryPointEvents.Events[name]
+	data, err := event.Inputs.NonIndexed().Pack(values...)
+	require.NoError(t, err)
+
+	return &types.Log{
+		Address: EntryPointV07,
+		Topics:  append([]common.Hash{event.ID}, topics...),
+		Data:    data,
+	}
+}
+
+// encodeRevert builds revert data for a function-style error signature such as Error(string)
+func encodeRevert(t *testing.T, selector string, typ string, value interface{}) []byte {
+	abiType, err := abi.NewType(typ, "", nil)
+	require.NoError(t, err)
+	encoded, err := (abi.Arguments{{Type: abiType}}).Pack(value)
+	require.NoError(t, err)
+	return append(hexutil.MustDecode(selector), encoded...)
+}
+
+func TestEntryPointEventTopics(t *testing.T) {
+	assert.Equal(t, common.HexToHash("0x49628fd1471006c1482da88028e9ce4dbb080b815c9b0344d39e5a8e6ec1419f"), UserOperationEventTopic)
+	assert.Equal(t, common.HexToHash("0x1c4fada7374c0a9ee8841fc38afe82932dc0f8e69012e927f061a8bae611a201"), UserOperationRevertReasonTopic)
+	assert.Equal(t, common.HexToHash("0xd51a9c61267aa6196961883ecf5ff2da6619c37dac0fa92122513fb32c032d2d"), AccountDeployedTopic)
+	assert.Equal(t, common.HexToHash("0xf62676f440ff169a3a9afdbf812e89e7f95975ee8e5c31214ffdef631c5f4792"), PostOpRevertReasonTopic)
+}
+
+func TestUserOperationReceipt_DecodeEvents(t *testing.T) {
+	userOpHash := common.HexToHash("0x01")
+	otherUserOpHash := common.HexToHash("0x02")
+	sender := common.HexToAddress("0x1234567890123456789012345678901234567890")
+	paymaster := common.HexToAddress("0xabcdefabcdefabcdefabcdefabcdefabcdefabcd")
+	factory := common.HexToAddress("0x9999999999999999999999999999999999999999")
+	revertData := encodeRevert(t, "0x08c379a0", "string", "insufficient balance")
+
+	receipt := &UserOperationReceipt{
+		UserOpHash: userOpHash,
+		Success:    false,
+		Logs: []*types.Log{
+			newEntryPointLog(t, "AccountDeployed",
+				[]common.Hash{userOpHash, common.BytesToHash(sender.Bytes())},
+				factory, paymaster),
+			// Unrelated token transfer emitted during execution
+			{
+				Address: common.HexToAddress("0x1c7d4b196cb0c7b01d743fbc6116a902379c7238"),
+				Topics:  []common.Hash{common.HexToHash("0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef")},
+			},
+			// Revert of another user operation in the same bundle
+			newEntryPointLog(t, "UserOperationRevertReason",
+				[]common.Hash{otherUserOpHash, common.BytesToHash(sender.Bytes())},
+				big.NewInt(7), []byte{0xde, 0xad}),
+			newEntryPointLog(t, "UserOperationRevertReason",
+				[]common.Hash{userOpHash, common.BytesToHash(sender.Bytes())},
+				big.NewInt(1), revertData),
+			newEntryPointLog(t, "UserOperationEvent",
+				[]common.Hash{userOpHash, common.BytesToHash(sender.Bytes()), common.BytesToHash(paymaster.Bytes())},
+				big.NewInt(1), false, big.NewInt(21000000), big.NewInt(21000)),
+		},
+	}
+
+	events, err := receipt.DecodeEvents()
+	require.NoError(t, err)
+
+	require.NotNil(t, events.UserOperationEvent)
+	assert.Equal(t, userOpHash, events.UserOperationEvent.UserOpHash)
+	assert.Equal(t, sender, events.UserOperationEvent.Sender)
+	assert.Equal(t, paymaster, events.UserOperationEvent.Paymaster)
+	assert.Equal(t, big.NewInt(1), events.UserOperationEvent.Nonce)
+	assert.False(t, events.UserOperationEvent.Success)
+	assert.Equal(t, big.NewInt(21000000), events.UserOperationEvent.ActualGasCost)
+	assert.Equal(t, big.NewInt(21000), events.UserOperationEvent.ActualGasUsed)
+
+	require.NotNil(t, events.UserOperationRevertReason)
+	assert.Equal(t, revertData, events.UserOperationRevertReason.RevertReason)
+
+	require.NotNil(t, events.AccountDeployed)
+	assert.Equal(t, factory, events.AccountDeployed.Factory)
+	assert.Equal(t, paymaster, events.AccountDeployed.Paymaster)
+
+	assert.Nil(t, events.PostOpRevertReason)
+
+	assert.Equal(t, "User operation reverted: insufficient balance", receipt.FailureReason(nil))
+}
+
+func TestParseUserOperationEvent_InvalidLog(t *testing.T) {
+	_, err := ParseUserOperationEvent(&types.Log{Topics: []common.Hash{AccountDeployedTopic}})
+	assert.Error(t, err)
+
+	_, err = ParseUserOperationEvent(&types.Log{Topics: []common.Hash{UserOperationEventTopic}})
+	assert.Error(t, err)
+}
+
+func TestUserOperationReceipt_FailureReason(t *testing.T) {
+	userOpHash := common.HexToHash("0x01")
+	sender := common.BytesToHash(common.HexToAddress("0x1234567890123456789012345678901234567890").Bytes())
+
+	tests := []struct {
+		name     string
+		receipt  *UserOperationReceipt
+		expected string
+	}{
+		{
+			name:     "successful receipt",
+			receipt:  &UserOperationReceipt{UserOpHash: userOpHash, Success: true},
+			expected: "",
+		},
+		{
+			name:     "no revert reason log",
+			receipt:  &UserOperationReceipt{UserOpHash: userOpHash},
+			expected: "User operation failed on-chain",
+		},
+		{
+			name: "panic",
+			receipt: &UserOperationReceipt{
+				UserOpHash: userOpHash,
+				Logs: []*types.Log{
+					newEntryPointLog(t, "UserOperationRevertReason", []common.Hash{userOpHash, sender},
+						big.NewInt(1), encodeRevert(t, "0x4e487b71", "uint256", big.NewInt(0x11))),
+				},
+			},
+			expected: "User operation reverted: arithmetic underflow or overflow",
+		},
+		{
+			name: "paymaster postOp",
+			receipt: &UserOperationReceipt{
+				UserOpHash: userOpHash,
+				Logs: []*types.Log{
+					newEntryPointLog(t, "PostOpRevertReason", []common.Hash{userOpHash, sender},
+						big.NewInt(1), encodeRevert(t, "0x08c379a0", "string", "token transfer failed")),
+				},
+			},
+			expected: "Paymaster postOp reverted: token transfer failed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.receipt.FailureReason(nil))
+		})
+	}
+}
+
+func TestRevertDecoder_Decode(t *testing.T) {
+	customErrors, err := abi.JSON(strings.NewReader(`[{"inputs":[],"name":"InvalidExecution","type":"error"},{"inputs":[{"name":"smartAccount","type":"address"}],"name":"NotInitialized","type":"error"}]`))
+	require.NoError(t, err)
+	decoder := NewRevertDecoder(customErrors)
+
+	tests := []struct {
+		name     string
+		data     []byte
+		expected string
+	}{
+		{
+			name:     "empty",
+			data:     nil,
+			expected: "empty revert data",
+		},
+		{
+			name:     "error string",
+			data:     encodeRevert(t, "0x08c379a0", "string", "not allowed"),
+			expected: "not allowed",
+		},
+		{
+			name:     "panic",
+			data:     encodeRevert(t, "0x4e487b71", "uint256", big.NewInt(0x12)),
+			expected: "division or modulo by zero",
+		},
+		{
+			name:     "custom error without arguments",
+			data:     hexutil.MustDecode("0x29adbb29"),
+			expected: "InvalidExecution()",
+		},
+		{
+			name:     "custom error with arguments",
+			data:     encodeRevert(t, "0xf91bd6f1", "address", common.HexToAddress("0x1234567890123456789012345678901234567890")),
+			expected: "NotInitialized(0x1234567890123456789012345678901234567890)",
+		},
+		{
+			name:     "unknown selector",
+			data:     hexutil.MustDecode("0xdeadbeef"),
+			expected: "unknown revert data: 0xdeadbeef",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, decoder.Decode(tt.data))
+		})
+	}
+}
@@ -0,0 +1,285 @@
+package erc4337
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// handleOps of EntryPoint v0.7/v0.8 (PackedUserOperation[]) and v0.6 (UserOperation[])
+const handleOpsABI = `[
+	{"type":"function","name":"handleOps","stateMutability":"nonpayable","outputs":[],"inputs":[
+		{"name":"ops","type":"tuple[]","components":[
+			{"name":"sender","type":"address"},
+			{"name":"nonce","type":"uint256"},
+			{"name":"initCode","type":"bytes"},
+			{"name":"callData","type":"bytes"},
+			{"name":"accountGasLimits","type":"bytes32"},
+			{"name":"preVerificationGas","type":"uint256"},
+			{"name":"gasFees","type":"bytes32"},
+			{"name":"paymasterAndData","type":"bytes"},
+			{"name":"signature","type":"bytes"}]},
+		{"name":"beneficiary","type":"address"}]}
+]`
+
+const handleOpsV06ABI = `[
+	{"type":"function","name":"handleOps","stateMutability":"nonpayable","outputs":[],"inputs":[
+		{"name":"ops","type":"tuple[]","components":[
+			{"name":"sender","type":"address"},
+			{"name":"nonce","type":"uint256"},
+			{"name":"initCode","type":"bytes"},
+			{"name":"callData","type":"bytes"},
+			{"name":"callGasLimit","type":"uint256"},
+			{"name":"verificationGasLimit","type":"uint256"},
+			{"name":"preVerificationGas","type":"uint256"},
+			{"name":"maxFeePerGas","type":"uint256"},
+			{"name":"maxPriorityFeePerGas","type":"uint256"},
+			{"name":"paymasterAndData","type":"bytes"},
+			{"name":"signature","type":"bytes"}]},
+		{"name":"beneficiary","type":"address"}]}
+]`
+
+var (
+	handleOpsMethod    = mustParseABI(handleOpsABI).Methods["handleOps"]
+	handleOpsV06Method = mustParseABI(handleOpsV06ABI).Methods["handleOps"]
+)
+
+// Selectors of handleOps for each EntryPoint version
+var (
+	HandleOpsSelector    = handleOpsMethod.ID    // 0x765e827f, v0.7 and v0.8
+	HandleOpsV06Selector = handleOpsV06Method.ID // 0x1fad948c
+)
+
+// packedUserOperationABI mirrors the PackedUserOperation struct for ABI encoding
+type packedUserOperationABI struct {
+	Sender             common.Address
+	Nonce              *big.Int
+	InitCode           []byte
+	CallData           []byte
+	AccountGasLimits   [32]byte
+	PreVerificationGas *big.Int
+	GasFees            [32]byte
+	PaymasterAndData   []byte
+	Signature          []byte
+}
+
+// userOperationV06ABI mirrors the v0.6 UserOperation struct for ABI encoding
+type userOperationV06ABI struct {
+	Sender               common.Address
+	Nonce                *big.Int
+	InitCode             []byte
+	CallData             []byte
+	CallGasLimit         *big.Int
+	VerificationGasLimit *big.Int
+	PreVerificationGas   *big.Int
+	MaxFeePerGas         *big.Int
+	MaxPriorityFeePerGas *big.Int
+	PaymasterAndData     []byte
+	Signature            []byte
+}
+
+// newPackedUserOperationABI converts a packed user operation for ABI encoding
+func newPackedUserOperationABI(op *PackedUserOp) (packedUserOperationABI, error) {
+	if len(op.AccountGasLimits) != 32 || len(op.GasFees) != 32 {
+		return packedUserOperationABI{}, fmt.Errorf("accountGasLimits and gasFees must be 32 bytes")
+	}
+	return packedUserOperationABI{
+		Sender:             op.Sender,
+		Nonce:              bigOrZero(op.Nonce),
+		InitCode:           op.InitCode,
+		CallData:           op.CallData,
+		AccountGasLimits:   [32]byte(op.AccountGasLimits),
+		PreVerificationGas: bigOrZero(op.PreVerificationGas),
+		GasFees:            [32]byte(op.GasFees),
+		PaymasterAndData:   op.PaymasterAndData,
+		Signature:          op.Signature,
+	}, nil
+}
+
+// newUserOperationV06ABI converts a v0.6 user operation for ABI encoding
+func newUserOperationV06ABI(op *UserOperationV06) userOperationV06ABI {
+	return userOperationV06ABI{
+		Sender:               op.Sender,
+		Nonce:                bigOrZero(op.Nonce.ToInt()),
+		InitCode:             op.InitCode,
+		CallData:             op.CallData,
+		CallGasLimit:         bigOrZero(op.CallGasLimit.ToInt()),
+		VerificationGasLimit: bigOrZero(op.VerificationGasLimit.ToInt()),
+		PreVerificationGas:   bigOrZero(op.PreVerificationGas.ToInt()),
+		MaxFeePerGas:         bigOrZero(op.MaxFeePerGas.ToInt()),
+		MaxPriorityFeePerGas: bigOrZero(op.MaxPriorityFeePerGas.ToInt()),
+		PaymasterAndData:     op.PaymasterAndData,
+		Signature:            op.Signature,
+	}
+}
+
+// HandleOps is a decoded EntryPoint handleOps call, i.e. a bundle
+type HandleOps struct {
+	Version     EntryPointVersion
+	Ops         []*UserOperation
+	Beneficiary common.Address
+}
+
+// EncodeHandleOps encodes handleOps(PackedUserOperation[] ops, address beneficiary) of EntryPoint v0.7 and v0.8
+func EncodeHandleOps(ops []*PackedUserOp, beneficiary common.Address) ([]byte, error) {
+	abiOps := make([]packedUserOperationABI, len(ops))
+	for i, op := range ops {
+		abiOp, err := newPackedUserOperationABI(op)
+		if err != nil {
+			return nil, fmt.Errorf("invalid user operation %d: %w", i, err)
+		}
+		abiOps[i] = abiOp
+	}
+
+	args, err := handleOpsMethod.Inputs.Pack(abiOps, beneficiary)
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack handleOps: %w", err)
+	}
+	return append(bytes.Clone(HandleOpsSelector), args...), nil
+}
+
+// EncodeHandleOpsV06 encodes handleOps(UserOperation[] ops, address beneficiary) of EntryPoint v0.6
+func EncodeHandleOpsV06(ops []*UserOperationV06, beneficiary common.Address) ([]byte, error) {
+	abiOps := make([]userOperationV06ABI, len(ops))
+	for i, op := range ops {
+		abiOps[i] = newUserOperationV06ABI(op)
+	}
+
+	args, err := handleOpsV06Method.Inputs.Pack(abiOps, beneficiary)
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack handleOps: %w", err)
+	}
+	return append(bytes.Clone(HandleOpsV06Selector), args...), nil
+}
+
+// EncodeHandleOpsForEntryPoint encodes a bundle of user operations in the handleOps format of the given EntryPoint
+func EncodeHandleOpsForEntryPoint(entryPoint common.Address, ops []*UserOperation, beneficiary common.Address) ([]byte, error) {
+	version, err := GetEntryPointVersion(entryPoint)
+	if err != nil {
+		return nil, err
+	}
+
+	if version == EntryPointVersionV06 {
+		opsV06 := make([]*UserOperationV06, len(ops))
+		for i, op := range ops {
+			opsV06[i] = op.ToV06()
+		}
+		return EncodeHandleOpsV06(opsV06, beneficiary)
+	}
+
+	packed := make([]*PackedUserOp, len(ops))
+	for i, op := range ops {
+		packed[i] = op.PackUserOp()
+	}
+	return EncodeHandleOps(packed, beneficiary)
+}
+
+// DecodeHandleOps decodes the calldata of a handleOps call of any EntryPoint version.
+// The version is EntryPointVersionV07 for PackedUserOperation bundles, which v0.8 shares.
+func DecodeHandleOps(calldata []byte) (*HandleOps, error) {
+	if len(calldata) < 4 {
+		return nil, fmt.Errorf("calldata too short: %d bytes", len(calldata))
+	}
+
+	switch {
+	case bytes.Equal(calldata[:4], HandleOpsSelector):
+		return decodeHandleOpsPacked(calldata[4:])
+	case bytes.Equal(calldata[:4], HandleOpsV06Selector):
+		return decodeHandleOpsV06(calldata[4:])
+	default:
+		return nil, fmt.Errorf("not a handleOps call: selector %s", hexutil.Encode(calldata[:4]))
+	}
+}
+
+func decodeHandleOpsPacked(args []byte) (*HandleOps, error) {
+	unpacked, err := handleOpsMethod.Inputs.Unpack(args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unpack handleOps: %w", err)
+	}
+	abiOps := *abi.ConvertType(unpacked[0], new([]packedUserOperationABI)).(*[]packedUserOperationABI)
+
+	handleOps := &HandleOps{
+		Version:     EntryPointVersionV07,
+		Ops:         make([]*UserOperation, len(abiOps)),
+		Beneficiary: unpacked[1].(common.Address),
+	}
+	for i, op := range abiOps {
+		packed := &PackedUserOp{
+			Sender:             op.Sender,
+			Nonce:              op.Nonce,
+			InitCode:           op.InitCode,
+			CallData:           op.CallData,
+			AccountGasLimits:   op.AccountGasLimits[:],
+			PreVerificationGas: op.PreVerificationGas,
+			GasFees:            op.GasFees[:],
+			PaymasterAndData:   op.PaymasterAndData,
+			Signature:          op.Signature,
+		}
+		handleOps.Ops[i], err = packed.UnpackUserOp()
+		if err != nil {
+			return nil, fmt.Errorf("failed to unpack user operation %d: %w", i, err)
+		}
+	}
+	return handleOps, nil
+}
+
+func decodeHandleOpsV06(args []byte) (*HandleOps, error) {
+	unpacked, err := handleOpsV06Method.Inputs.Unpack(args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unpack handleOps: %w", err)
+	}
+	abiOps := *abi.ConvertType(unpacked[0], new([]userOperationV06ABI)).(*[]userOperationV06ABI)
+
+	handleOps := &HandleOps{
+		Version:     EntryPointVersionV06,
+		Ops:         make([]*UserOperation, len(abiOps)),
+		Beneficiary: unpacked[1].(common.Address),
+	}
+	for i, op := range abiOps {
+		if len(op.InitCode) > 0 && len(op.InitCode) < common.AddressLength {
+			return nil, fmt.Errorf("failed to unpack user operation %d: invalid initCode length %d", i, len(op.InitCode))
+		}
+		if len(op.PaymasterAndData) > 0 && len(op.PaymasterAndData) < common.AddressLength {
+			return nil, fmt.Errorf("failed to unpack user operation %d: invalid paymasterAndData length %d", i, len(op.PaymasterAndData))
+		}
+		handleOps.Ops[i] = (&UserOperationV06{
+			Sender:               op.Sender,
+			Nonce:                (*hexutil.Big)(op.Nonce),
+			InitCode:             op.InitCode,
+			CallData:             op.CallData,
+			CallGasLimit:         (*hexutil.Big)(op.CallGasLimit),
+			VerificationGasLimit: (*hexutil.Big)(op.VerificationGasLimit),
+			PreVerificationGas:   (*hexutil.Big)(op.PreVerificationGas),
+			MaxFeePerGas:         (*hexutil.Big)(op.MaxFeePerGas),
+			MaxPriorityFeePerGas: (*hexutil.Big)(op.MaxPriorityFeePerGas),
+			PaymasterAndData:     op.PaymasterAndData,
+			Signature:            op.Signature,
+		}).ToUserOperation()
+	}
+	return handleOps, nil
+}
+
+// UserOpHashes computes the hash of every user operation in the bundle, e.g. to match them to jobs.
+// The entry point must be the target of the handleOps call.
+func (h *HandleOps) UserOpHashes(entryPoint common.Address, chainId *big.Int) ([]common.Hash, error) {
+	hashes := make([]common.Hash, len(h.Ops))
+	for i, op := range h.Ops {
+		hash, err := op.GetUserOpHash(entryPoint, chainId)
+		if err != nil {
+			return nil, fmt.Errorf("failed to hash user operation %d: %w", i, err)
+		}
+		hashes[i] = hash
+	}
+	return hashes, nil
+}
+
+func bigOrZero(v *big.Int) *big.Int {
+	if v == nil {
+		return new(big.Int)
+	}
+	return v
+}
@@ -0,0 +1,140 @@
+package erc4337
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newHandleOpsTestUserOperation(withFactoryAndPaymaster bool) *UserOperation {
+	op := &UserOperation{
+		Sender:               common.HexToAddress("0x47d6a8a65cba9b61b194dac740aa192a7a1e91e1"),
+		Nonce:                (*hexutil.Big)(new(big.Int).Lsh(big.NewInt(1), 64)),
+		CallData:             hexutil.Bytes{0xe9, 0xae, 0x5c, 0x53, 0x01},
+		CallGasLimit:         (*hexutil.Big)(big.NewInt(100000)),
+		VerificationGasLimit: (*hexutil.Big)(big.NewInt(150000)),
+		PreVerificationGas:   (*hexutil.Big)(big.NewInt(50000)),
+		MaxFeePerGas:         (*hexutil.Big)(big.NewInt(1600000000)),
+		MaxPriorityFeePerGas: (*hexutil.Big)(big.NewInt(100000000)),
+		Signature:            hexutil.Bytes{0x01, 0x02, 0x03},
+	}
+	if withFactoryAndPaymaster {
+		factory := common.HexToAddress("0x1111111111111111111111111111111111111111")
+		paymaster := common.HexToAddress("0x2222222222222222222222222222222222222222")
+		op.Factory, op.FactoryData = &factory, hexutil.Bytes{0xaa, 0xbb}
+		op.Paymaster, op.PaymasterData = &paymaster, hexutil.Bytes{0xcc}
+		op.PaymasterVerificationGasLimit = (*hexutil.Big)(big.NewInt(30000))
+		op.PaymasterPostOpGasLimit = (*hexutil.Big)(big.NewInt(20000))
+	}
+	return op
+}
+
+// assertUserOperationsEqual compares user operations by their JSON form, which ignores big.Int internals
+func assertUserOperationsEqual(t *testing.T, expected, actual *UserOperation) {
+	expectedJSON, err := json.Marshal(expected)
+	require.NoError(t, err)
+	actualJSON, err := json.Marshal(actual)
+	require.NoError(t, err)
+	assert.JSONEq(t, string(expectedJSON), string(actualJSON))
+}
+
+func TestPackedUserOp_UnpackUserOp(t *testing.T) {
+	for _, withFactoryAndPaymaster := range []bool{false, true} {
+		op := newHandleOpsTestUserOperation(withFactoryAndPaymaster)
+
+		unpacked, err := op.PackUserOp().UnpackUserOp()
+		require.NoError(t, err)
+		if !withFactoryAndPaymaster {
+			assert.Nil(t, unpacked.Factory)
+			assert.Nil(t, unpacked.Paymaster)
+			assert.Nil(t, unpacked.PaymasterVerificationGasLimit)
+		}
+		assertUserOperationsEqual(t, op, unpacked)
+	}
+}
+
+func TestPackedUserOp_UnpackUserOp_Invalid(t *testing.T) {
+	valid := func() *PackedUserOp { return newHandleOpsTestUserOperation(false).PackUserOp() }
+
+	tests := []struct {
+		name   string
+		modify func(packed *PackedUserOp)
+	}{
+		{"short accountGasLimits", func(packed *PackedUserOp) { packed.AccountGasLimits = packed.AccountGasLimits[:31] }},
+		{"short gasFees", func(packed *PackedUserOp) { packed.GasFees = nil }},
+		{"short initCode", func(packed *PackedUserOp) { packed.InitCode = make([]byte, 19) }},
+		{"short paymasterAndData", func(packed *PackedUserOp) { packed.PaymasterAndData = make([]byte, 51) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			packed := valid()
+			tt.modify(packed)
+			_, err := packed.UnpackUserOp()
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestHandleOps_RoundTrip(t *testing.T) {
+	beneficiary := common.HexToAddress("0x3333333333333333333333333333333333333333")
+	ops := []*UserOperation{newHandleOpsTestUserOperation(false), newHandleOpsTestUserOperation(true)}
+	chainId := big.NewInt(11155111)
+
+	tests := []struct {
+		entryPoint common.Address
+		version    EntryPointVersion
+		selector   string
+	}{
+		{EntryPointV06, EntryPointVersionV06, "0x1fad948c"},
+		{EntryPointV07, EntryPointVersionV07, "0x765e827f"},
+		{EntryPointV08, EntryPointVersionV07, "0x765e827f"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.entryPoint.Hex(), func(t *testing.T) {
+			calldata, err := EncodeHandleOpsForEntryPoint(tt.entryPoint, ops, beneficiary)
+			require.NoError(t, err)
+			assert.Equal(t, tt.selector, hexutil.Encode(calldata[:4]))
+
+			decoded, err := DecodeHandleOps(calldata)
+			require.NoError(t, err)
+			assert.Equal(t, tt.version, decoded.Version)
+			assert.Equal(t, beneficiary, decoded.Beneficiary)
+			require.Len(t, decoded.Ops, len(ops))
+
+			hashes, err := decoded.UserOpHashes(tt.entryPoint, chainId)
+			require.NoError(t, err)
+			for i, op := range ops {
+				expectedHash, err := op.GetUserOpHash(tt.entryPoint, chainId)
+				require.NoError(t, err)
+				assert.Equal(t, expectedHash, hashes[i])
+			}
+		})
+	}
+
+	// v0.7 keeps every field, including the paymaster gas limits
+	calldata, err := EncodeHandleOpsForEntryPoint(EntryPointV07, ops, beneficiary)
+	require.NoError(t, err)
+	decoded, err := DecodeHandleOps(calldata)
+	require.NoError(t, err)
+	for i, op := range ops {
+		assertUserOperationsEqual(t, op, decoded.Ops[i])
+	}
+}
+
+func TestDecodeHandleOps_Invalid(t *testing.T) {
+	_, err := DecodeHandleOps([]byte{0x76})
+	assert.Error(t, err)
+
+	_, err = DecodeHandleOps(hexutil.MustDecode("0xe9ae5c53"))
+	assert.ErrorContains(t, err, "not a handleOps call")
+
+	_, err = DecodeHandleOps(hexutil.MustDecode("0x765e827f00"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,101 @@
+package erc4337
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// getNonce(address sender, uint192 key) of the EntryPoint, the same in every version
+const getNonceABI = `[
+	{"type":"function","name":"getNonce","stateMutability":"view",
+		"inputs":[{"name":"sender","type":"address"},{"name":"key","type":"uint192"}],
+		"outputs":[{"name":"nonce","type":"uint256"}]}
+]`
+
+var getNonceMethod = mustParseABI(getNonceABI).Methods["getNonce"]
+
+var (
+	// MaxNonceKey is the largest nonce key, which is 192 bits
+	MaxNonceKey = new(big.Int).Sub(new(big.Int).Lsh(common.Big1, 192), common.Big1)
+
+	maxUint64 = new(big.Int).SetUint64(^uint64(0))
+)
+
+// Nonce is the two-dimensional nonce of a user operation: the high 192 bits are a key selecting
+// an independent sequence, the low 64 bits the sequence number within it. User operations of one
+// account with different keys do not block each other.
+type Nonce struct {
+	Key      *big.Int
+	Sequence uint64
+}
+
+// ParseNonce splits the nonce of a user operation into its key and sequence
+func ParseNonce(nonce *big.Int) (Nonce, error) {
+	if nonce == nil {
+		return Nonce{}, errors.New("nonce is nil")
+	}
+	if nonce.Sign() < 0 || nonce.Cmp(maxUint256) > 0 {
+		return Nonce{}, fmt.Errorf("nonce out of uint256 range: %s", nonce)
+	}
+
+	return Nonce{
+		Key:      new(big.Int).Rsh(nonce, 64),
+		Sequence: new(big.Int).And(nonce, maxUint64).Uint64(),
+	}, nil
+}
+
+// BigInt returns the nonce as set in a user operation: key << 64 | sequence
+func (n Nonce) BigInt() *big.Int {
+	nonce := new(big.Int).Lsh(keyOrZero(n.Key), 64)
+	return nonce.Or(nonce, new(big.Int).SetUint64(n.Sequence))
+}
+
+func (n Nonce) String() string {
+	return fmt.Sprintf("key 0x%x sequence %d", keyOrZero(n.Key), n.Sequence)
+}
+
+func keyOrZero(key *big.Int) *big.Int {
+	if key == nil {
+		return new(big.Int)
+	}
+	return key
+}
+
+// EncodeGetNonce encodes the calldata of EntryPoint getNonce(sender, key)
+func EncodeGetNonce(sender common.Address, key *big.Int) ([]byte, error) {
+	key = keyOrZero(key)
+	if key.Sign() < 0 || key.Cmp(MaxNonceKey) > 0 {
+		return nil, fmt.Errorf("nonce key out of uint192 range: 0x%x", key)
+	}
+
+	args, err := getNonceMethod.Inputs.Pack(sender, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack getNonce: %w", err)
+	}
+	return append(bytes.Clone(getNonceMethod.ID), args...), nil
+}
+
+// GetNonce reads the next nonce of the sender for the given key from the EntryPoint
+func GetNonce(ctx context.Context, caller ethereum.ContractCaller, entryPoint common.Address, sender common.Address, key *big.Int) (Nonce, error) {
+	calldata, err := EncodeGetNonce(sender, key)
+	if err != nil {
+		return Nonce{}, err
+	}
+
+	result, err := caller.CallContract(ctx, ethereum.CallMsg{To: &entryPoint, Data: calldata}, nil)
+	if err != nil {
+		return Nonce{}, fmt.Errorf("failed to call getNonce: %w", err)
+	}
+
+	unpacked, err := getNonceMethod.Outputs.Unpack(result)
+	if err != nil {
+		return Nonce{}, fmt.Errorf("failed to decode getNonce result: %w", err)
+	}
+	return ParseNonce(unpacked[0].(*big.Int))
+}
@@ -0,0 +1,74 @@
+package erc4337
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseNonce(t *testing.T) {
+	tests := []struct {
+		name     string
+		nonce    string
+		key      string
+		sequence uint64
+	}{
+		{"zero key", "0x5", "0x0", 5},
+		{"key and sequence", "0x10000000000000007", "0x1", 7},
+		{"max key", "0xffffffffffffffffffffffffffffffffffffffffffffffff0000000000000002", "0xffffffffffffffffffffffffffffffffffffffffffffffff", 2},
+		{"session key from the frontend", "0x100000000002b0ecfbd0496ee71e01257da0e37de00000000000000000000", "0x100000000002b0ecfbd0496ee71e01257da0e37de0000", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nonce, err := ParseNonce(hexutil.MustDecodeBig(tt.nonce))
+			require.NoError(t, err)
+			assert.Equal(t, tt.key, hexutil.EncodeBig(nonce.Key))
+			assert.Equal(t, tt.sequence, nonce.Sequence)
+			assert.Equal(t, tt.nonce, hexutil.EncodeBig(nonce.BigInt()))
+		})
+	}
+
+	_, err := ParseNonce(nil)
+	assert.Error(t, err)
+	_, err = ParseNonce(new(big.Int).Lsh(common.Big1, 256))
+	assert.Error(t, err)
+}
+
+// fakeContractCaller returns a fixed result and records the last call
+type fakeContractCaller struct {
+	result []byte
+	msg    ethereum.CallMsg
+}
+
+func (c *fakeContractCaller) CallContract(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	c.msg = msg
+	return c.result, nil
+}
+
+func TestGetNonce(t *testing.T) {
+	sender := common.HexToAddress("0x47d6a8a65cba9b61b194dac740aa192a7a1e91e1")
+	key := big.NewInt(0x42)
+
+	calldata, err := EncodeGetNonce(sender, key)
+	require.NoError(t, err)
+	assert.Equal(t, "0x35567e1a000000000000000000000000"+"47d6a8a65cba9b61b194dac740aa192a7a1e91e1"+
+		"0000000000000000000000000000000000000000000000000000000000000042", hexutil.Encode(calldata))
+
+	caller := &fakeContractCaller{result: common.LeftPadBytes(hexutil.MustDecode("0x420000000000000009"), 32)}
+	nonce, err := GetNonce(context.Background(), caller, EntryPointV07, sender, key)
+	require.NoError(t, err)
+	assert.Equal(t, key, nonce.Key)
+	assert.Equal(t, uint64(9), nonce.Sequence)
+	assert.Equal(t, EntryPointV07, *caller.msg.To)
+	assert.Equal(t, calldata, caller.msg.Data)
+
+	_, err = EncodeGetNonce(sender, new(big.Int).Add(MaxNonceKey, common.Big1))
+	assert.Error(t, err)
+}
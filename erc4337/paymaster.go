@@ -0,0 +1,124 @@
+package erc4337
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// PaymasterSponsor describes the sponsor returned by pm_getPaymasterStubData
+type PaymasterSponsor struct {
+	Name string `json:"name"`
+	Icon string `json:"icon,omitempty"`
+}
+
+// PaymasterStubData is the result of pm_getPaymasterStubData (ERC-7677).
+// EntryPoint v0.6 paymasters return PaymasterAndData instead of Paymaster and PaymasterData.
+type PaymasterStubData struct {
+	Sponsor                       *PaymasterSponsor `json:"sponsor,omitempty"`
+	Paymaster                     *common.Address   `json:"paymaster,omitempty"`
+	PaymasterData                 hexutil.Bytes     `json:"paymasterData,omitempty"`
+	PaymasterVerificationGasLimit *hexutil.Big      `json:"paymasterVerificationGasLimit,omitempty"`
+	PaymasterPostOpGasLimit       *hexutil.Big      `json:"paymasterPostOpGasLimit,omitempty"`
+	PaymasterAndData              hexutil.Bytes     `json:"paymasterAndData,omitempty"`
+	// IsFinal is true when the stub data can be used as is and pm_getPaymasterData must not be called
+	IsFinal bool `json:"isFinal,omitempty"`
+}
+
+// PaymasterData is the result of pm_getPaymasterData (ERC-7677)
+type PaymasterData struct {
+	Paymaster        *common.Address `json:"paymaster,omitempty"`
+	PaymasterData    hexutil.Bytes   `json:"paymasterData,omitempty"`
+	PaymasterAndData hexutil.Bytes   `json:"paymasterAndData,omitempty"`
+}
+
+// Apply sets the paymaster fields of the user operation from the stub data.
+// Gas limits are only overwritten when the paymaster returns them.
+func (d *PaymasterStubData) Apply(op *UserOperation) {
+	applyPaymaster(op, d.Paymaster, d.PaymasterData, d.PaymasterAndData)
+	if d.PaymasterVerificationGasLimit != nil {
+		op.PaymasterVerificationGasLimit = d.PaymasterVerificationGasLimit
+	}
+	if d.PaymasterPostOpGasLimit != nil {
+		op.PaymasterPostOpGasLimit = d.PaymasterPostOpGasLimit
+	}
+}
+
+// Apply sets the paymaster and paymaster data of the user operation
+func (d *PaymasterData) Apply(op *UserOperation) {
+	applyPaymaster(op, d.Paymaster, d.PaymasterData, d.PaymasterAndData)
+}
+
+// applyPaymaster sets the paymaster fields, splitting v0.6 paymasterAndData into paymaster + paymasterData
+func applyPaymaster(op *UserOperation, paymaster *common.Address, paymasterData hexutil.Bytes, paymasterAndData hexutil.Bytes) {
+	if paymaster == nil && len(paymasterAndData) >= common.AddressLength {
+		address := common.BytesToAddress(paymasterAndData[:common.AddressLength])
+		paymaster = &address
+		paymasterData = paymasterAndData[common.AddressLength:]
+	}
+	if paymaster == nil {
+		return
+	}
+
+	op.Paymaster = paymaster
+	op.PaymasterData = paymasterData
+	if op.PaymasterData == nil {
+		op.PaymasterData = hexutil.Bytes{}
+	}
+}
+
+// Paymaster is an ERC-7677 paymaster web service
+type Paymaster interface {
+	GetPaymasterStubData(ctx context.Context, op *UserOperation, entryPoint common.Address, chainId *big.Int, pmContext map[string]interface{}) (*PaymasterStubData, error)
+	GetPaymasterData(ctx context.Context, op *UserOperation, entryPoint common.Address, chainId *big.Int, pmContext map[string]interface{}) (*PaymasterData, error)
+	Close()
+}
+
+type PaymasterClient struct {
+	client *rpc.Client
+}
+
+func DialPaymasterContext(ctx context.Context, rawurl string) (Paymaster, error) {
+	c, err := rpc.DialContext(ctx, rawurl)
+	if err != nil {
+		return nil, err
+	}
+	return NewPaymasterClient(c), nil
+}
+
+func NewPaymasterClient(c *rpc.Client) Paymaster {
+	return &PaymasterClient{c}
+}
+
+// paymasterContext returns the context parameter, which ERC-7677 requires to be an object
+func paymasterContext(pmContext map[string]interface{}) map[string]interface{} {
+	if pmContext == nil {
+		return map[string]interface{}{}
+	}
+	return pmContext
+}
+
+func (p *PaymasterClient) GetPaymasterStubData(ctx context.Context, op *UserOperation, entryPoint common.Address, chainId *big.Int, pmContext map[string]interface{}) (*PaymasterStubData, error) {
+	var result PaymasterStubData
+	err := p.client.CallContext(ctx, &result, "pm_getPaymasterStubData", rpcUserOperation(op, entryPoint), entryPoint, (*hexutil.Big)(chainId), paymasterContext(pmContext))
+	if err != nil {
+		return nil, HandleRPCError(err, "pm_getPaymasterStubData")
+	}
+	return &result, nil
+}
+
+func (p *PaymasterClient) GetPaymasterData(ctx context.Context, op *UserOperation, entryPoint common.Address, chainId *big.Int, pmContext map[string]interface{}) (*PaymasterData, error) {
+	var result PaymasterData
+	err := p.client.CallContext(ctx, &result, "pm_getPaymasterData", rpcUserOperation(op, entryPoint), entryPoint, (*hexutil.Big)(chainId), paymasterContext(pmContext))
+	if err != nil {
+		return nil, HandleRPCError(err, "pm_getPaymasterData")
+	}
+	return &result, nil
+}
+
+func (p *PaymasterClient) Close() {
+	p.client.Close()
+}
@@ -0,0 +1,104 @@
+package erc4337
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestPaymasterClient(t *testing.T, results map[string]string) Paymaster {
+	paymaster := NewPaymasterClient(newTestRPCClient(t, results))
+	t.Cleanup(paymaster.Close)
+	return paymaster
+}
+
+func TestPaymasterClient_GetPaymasterStubData(t *testing.T) {
+	op := &UserOperation{
+		Sender:   common.HexToAddress("0x1234567890123456789012345678901234567890"),
+		Nonce:    (*hexutil.Big)(big.NewInt(1)),
+		CallData: hexutil.Bytes{0x56, 0x78},
+	}
+	paymaster := common.HexToAddress("0xabcdefabcdefabcdefabcdefabcdefabcdefabcd")
+
+	t.Run("v0.7 stub data", func(t *testing.T) {
+		client := newTestPaymasterClient(t, map[string]string{
+			"pm_getPaymasterStubData": `{
+				"sponsor": {"name": "SAManager"},
+				"paymaster": "0xabcdefabcdefabcdefabcdefabcdefabcdefabcd",
+				"paymasterData": "0x1234",
+				"paymasterVerificationGasLimit": "0x100",
+				"paymasterPostOpGasLimit": "0x200"
+			}`,
+		})
+
+		stub, err := client.GetPaymasterStubData(context.Background(), op, EntryPointV07, big.NewInt(84532), nil)
+		require.NoError(t, err)
+		require.NotNil(t, stub.Sponsor)
+		assert.Equal(t, "SAManager", stub.Sponsor.Name)
+		assert.False(t, stub.IsFinal)
+
+		sponsored := *op
+		stub.Apply(&sponsored)
+		require.NotNil(t, sponsored.Paymaster)
+		assert.Equal(t, paymaster, *sponsored.Paymaster)
+		assert.Equal(t, hexutil.Bytes{0x12, 0x34}, sponsored.PaymasterData)
+		assert.Equal(t, big.NewInt(0x100), sponsored.PaymasterVerificationGasLimit.ToInt())
+		assert.Equal(t, big.NewInt(0x200), sponsored.PaymasterPostOpGasLimit.ToInt())
+	})
+
+	t.Run("v0.6 final stub data", func(t *testing.T) {
+		client := newTestPaymasterClient(t, map[string]string{
+			"pm_getPaymasterStubData": `{
+				"paymasterAndData": "0xabcdefabcdefabcdefabcdefabcdefabcdefabcd5678",
+				"isFinal": true
+			}`,
+		})
+
+		stub, err := client.GetPaymasterStubData(context.Background(), op, EntryPointV06, big.NewInt(84532), map[string]interface{}{"policy": "p"})
+		require.NoError(t, err)
+		assert.True(t, stub.IsFinal)
+
+		sponsored := *op
+		stub.Apply(&sponsored)
+		require.NotNil(t, sponsored.Paymaster)
+		assert.Equal(t, paymaster, *sponsored.Paymaster)
+		assert.Equal(t, hexutil.Bytes{0x56, 0x78}, sponsored.PaymasterData)
+		assert.Nil(t, sponsored.PaymasterVerificationGasLimit)
+	})
+}
+
+func TestPaymasterClient_GetPaymasterData(t *testing.T) {
+	client := newTestPaymasterClient(t, map[string]string{
+		"pm_getPaymasterData": `{"paymaster": "0xabcdefabcdefabcdefabcdefabcdefabcdefabcd", "paymasterData": "0x9999"}`,
+	})
+
+	oldPaymaster := common.HexToAddress("0x1111111111111111111111111111111111111111")
+	op := &UserOperation{
+		Sender:                  common.HexToAddress("0x1234567890123456789012345678901234567890"),
+		Nonce:                   (*hexutil.Big)(big.NewInt(1)),
+		Paymaster:               &oldPaymaster,
+		PaymasterData:           hexutil.Bytes{0x01},
+		PaymasterPostOpGasLimit: (*hexutil.Big)(big.NewInt(0x200)),
+	}
+
+	data, err := client.GetPaymasterData(context.Background(), op, EntryPointV07, big.NewInt(84532), nil)
+	require.NoError(t, err)
+
+	data.Apply(op)
+	assert.Equal(t, common.HexToAddress("0xabcdefabcdefabcdefabcdefabcdefabcdefabcd"), *op.Paymaster)
+	assert.Equal(t, hexutil.Bytes{0x99, 0x99}, op.PaymasterData)
+	assert.Equal(t, big.NewInt(0x200), op.PaymasterPostOpGasLimit.ToInt())
+}
+
+func TestPaymasterClient_Error(t *testing.T) {
+	client := newTestPaymasterClient(t, map[string]string{})
+
+	_, err := client.GetPaymasterData(context.Background(), &UserOperation{}, EntryPointV07, big.NewInt(1), nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "pm_getPaymasterData")
+}
@@ -0,0 +1,647 @@
+package erc4337
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const (
+	DefaultHealthCheckInterval = 30 * time.Second
+	DefaultHealthCheckTimeout  = 5 * time.Second
+	DefaultUnhealthyThreshold  = 3
+	DefaultUnhealthyCooldown   = time.Minute
+	DefaultEntryPointsTTL      = 10 * time.Minute
+
+	// latencyWeight is the weight of the latest sample in the latency and error rate moving averages
+	latencyWeight = 0.2
+	// errorRatePenalty scales the latency score of an endpoint by its error rate
+	errorRatePenalty = 10
+	// sentUserOpTTL is how long the pool remembers which endpoint accepted a user operation
+	sentUserOpTTL = 24 * time.Hour
+)
+
+// ErrAllEndpointsFailed is returned by the BundlerPool when no endpoint could serve a call
+var ErrAllEndpointsFailed = errors.New("all bundler endpoints failed")
+
+// ErrMethodNotSupported is returned by BundlerPool.CallContext when no endpoint accepts raw JSON-RPC calls
+var ErrMethodNotSupported = errors.New("method not supported by the bundler endpoints")
+
+// BundlerPoolConfig configures the health checks and failover of a BundlerPool.
+// Zero values use the defaults above.
+type BundlerPoolConfig struct {
+	// HealthCheckInterval is the interval of the background health checks; a negative value disables them
+	HealthCheckInterval time.Duration
+	HealthCheckTimeout  time.Duration
+	// UnhealthyThreshold is the number of consecutive failures after which an endpoint is only used as a last resort
+	UnhealthyThreshold int
+	// UnhealthyCooldown is how long an unhealthy endpoint is avoided before it is tried again
+	UnhealthyCooldown time.Duration
+	// EntryPointsTTL is how long the eth_supportedEntryPoints result of an endpoint is cached
+	EntryPointsTTL time.Duration
+	// OnEndpointFailure is called when a call to an endpoint fails and the pool fails over to the next one
+	OnEndpointFailure func(endpoint string, method string, err error)
+}
+
+func (c BundlerPoolConfig) withDefaults() BundlerPoolConfig {
+	if c.HealthCheckInterval == 0 {
+		c.HealthCheckInterval = DefaultHealthCheckInterval
+	}
+	if c.HealthCheckTimeout <= 0 {
+		c.HealthCheckTimeout = DefaultHealthCheckTimeout
+	}
+	if c.UnhealthyThreshold <= 0 {
+		c.UnhealthyThreshold = DefaultUnhealthyThreshold
+	}
+	if c.UnhealthyCooldown <= 0 {
+		c.UnhealthyCooldown = DefaultUnhealthyCooldown
+	}
+	if c.EntryPointsTTL <= 0 {
+		c.EntryPointsTTL = DefaultEntryPointsTTL
+	}
+	return c
+}
+
+// BundlerEndpoint is a named bundler in a BundlerPool. The name is used in errors and status,
+// so it should not contain API keys.
+type BundlerEndpoint struct {
+	Name    string
+	Bundler Bundler
+	// LastResort endpoints, such as a SelfBundler, are only used when every other endpoint failed.
+	// They are not asked about user operations sent to other endpoints, which they don't know while pending.
+	LastResort bool
+}
+
+// BundlerEndpointStatus is a snapshot of the health of a pool endpoint
+type BundlerEndpointStatus struct {
+	Name                string
+	Healthy             bool
+	Latency             time.Duration
+	ErrorRate           float64
+	ConsecutiveFailures int
+	LastError           error
+}
+
+type poolEndpoint struct {
+	index      int
+	name       string
+	bundler    Bundler
+	lastResort bool
+
+	// Guarded by BundlerPool.mu
+	latency             float64 // moving average in nanoseconds
+	errorRate           float64 // moving average of failures
+	consecutiveFailures int
+	lastFailure         time.Time
+	lastError           error
+	entryPoints         []common.Address
+	entryPointsAt       time.Time // zero until the entry points are known
+}
+
+type sentUserOp struct {
+	endpoint *poolEndpoint
+	sender   common.Address
+	nonce    *big.Int
+	sentAt   time.Time
+}
+
+// BundlerPool is a Bundler that spreads calls over several bundler endpoints of the same chain.
+// Endpoints are ordered by health, latency and error rate; a call fails over to the next endpoint
+// when an endpoint is unreachable or returns an internal error. Errors returned by the bundler
+// for the user operation itself, such as AA validation errors, are returned without failover.
+type BundlerPool struct {
+	chainId   *big.Int
+	config    BundlerPoolConfig
+	endpoints []*poolEndpoint
+
+	// sent remembers the endpoint that accepted a user operation, since only that bundler
+	// knows the operation while it is pending in its mempool
+	sent map[common.Hash]sentUserOp
+	mu   sync.Mutex
+
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewBundlerPool creates a pool of bundler endpoints in priority order and starts the background health checks.
+// If chainId is not nil, endpoints reporting another chain ID are marked as failed.
+func NewBundlerPool(chainId *big.Int, endpoints []BundlerEndpoint, config BundlerPoolConfig) (*BundlerPool, error) {
+	if len(endpoints) == 0 {
+		return nil, errors.New("bundler pool requires at least one endpoint")
+	}
+
+	p := &BundlerPool{
+		chainId: chainId,
+		config:  config.withDefaults(),
+		sent:    make(map[common.Hash]sentUserOp),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	for i, endpoint := range endpoints {
+		if endpoint.Bundler == nil {
+			return nil, fmt.Errorf("bundler pool endpoint %q has no bundler", endpoint.Name)
+		}
+		name := endpoint.Name
+		if name == "" {
+			name = fmt.Sprintf("bundler-%d", i)
+		}
+		p.endpoints = append(p.endpoints, &poolEndpoint{index: i, name: name, bundler: endpoint.Bundler, lastResort: endpoint.LastResort})
+	}
+
+	if p.config.HealthCheckInterval > 0 {
+		go p.healthCheckLoop()
+	} else {
+		close(p.done)
+	}
+
+	return p, nil
+}
+
+func (p *BundlerPool) healthCheckLoop() {
+	defer close(p.done)
+
+	ticker := time.NewTicker(p.config.HealthCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+			p.CheckHealth(context.Background())
+		}
+	}
+}
+
+// CheckHealth calls eth_chainId on every endpoint and records the result
+func (p *BundlerPool) CheckHealth(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, endpoint := range p.endpoints {
+		wg.Add(1)
+		go func(endpoint *poolEndpoint) {
+			defer wg.Done()
+
+			checkCtx, cancel := context.WithTimeout(ctx, p.config.HealthCheckTimeout)
+			defer cancel()
+
+			start := time.Now()
+			chainId, err := endpoint.bundler.ChainId(checkCtx)
+			if err == nil && p.chainId != nil && chainId.Cmp(p.chainId) != 0 {
+				err = fmt.Errorf("chain id mismatch: expected %s, got %s", p.chainId, chainId)
+			}
+			if err != nil && ctx.Err() != nil {
+				// The caller gave up, which says nothing about the endpoint
+				return
+			}
+			p.record(endpoint, time.Since(start), err)
+		}(endpoint)
+	}
+	wg.Wait()
+}
+
+// Status returns the health of every endpoint in priority order
+func (p *BundlerPool) Status() []BundlerEndpointStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	status := make([]BundlerEndpointStatus, 0, len(p.endpoints))
+	for _, endpoint := range p.orderedLocked(nil, now) {
+		status = append(status, BundlerEndpointStatus{
+			Name:                endpoint.name,
+			Healthy:             p.healthyLocked(endpoint, now),
+			Latency:             time.Duration(endpoint.latency),
+			ErrorRate:           endpoint.errorRate,
+			ConsecutiveFailures: endpoint.consecutiveFailures,
+			LastError:           endpoint.lastError,
+		})
+	}
+	return status
+}
+
+// record updates the scores of an endpoint after a call
+func (p *BundlerPool) record(endpoint *poolEndpoint, latency time.Duration, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	failure := 0.0
+	if err != nil {
+		failure = 1
+		endpoint.consecutiveFailures++
+		endpoint.lastFailure = time.Now()
+		endpoint.lastError = err
+	} else {
+		endpoint.consecutiveFailures = 0
+		if endpoint.latency == 0 {
+			endpoint.latency = float64(latency)
+		} else {
+			endpoint.latency = (1-latencyWeight)*endpoint.latency + latencyWeight*float64(latency)
+		}
+	}
+	endpoint.errorRate = (1-latencyWeight)*endpoint.errorRate + latencyWeight*failure
+}
+
+func (p *BundlerPool) healthyLocked(endpoint *poolEndpoint, now time.Time) bool {
+	return endpoint.consecutiveFailures < p.config.UnhealthyThreshold ||
+		now.Sub(endpoint.lastFailure) >= p.config.UnhealthyCooldown
+}
+
+// orderedLocked returns the endpoints to try in order: the preferred endpoint first, then healthy endpoints
+// by score, then unhealthy endpoints and finally the endpoints configured as a last resort
+func (p *BundlerPool) orderedLocked(preferred *poolEndpoint, now time.Time) []*poolEndpoint {
+	ordered := make([]*poolEndpoint, len(p.endpoints))
+	copy(ordered, p.endpoints)
+
+	score := func(endpoint *poolEndpoint) float64 {
+		return endpoint.latency * (1 + errorRatePenalty*endpoint.errorRate)
+	}
+	sort.SliceStable(ordered, func(i, j int) bool {
+		a, b := ordered[i], ordered[j]
+		if (a == preferred) != (b == preferred) {
+			return a == preferred
+		}
+		if a.lastResort != b.lastResort {
+			return b.lastResort
+		}
+		aHealthy, bHealthy := p.healthyLocked(a, now), p.healthyLocked(b, now)
+		if aHealthy != bHealthy {
+			return aHealthy
+		}
+		if score(a) != score(b) {
+			return score(a) < score(b)
+		}
+		return a.index < b.index
+	})
+	return ordered
+}
+
+func (p *BundlerPool) ordered() []*poolEndpoint {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.orderedLocked(nil, time.Now())
+}
+
+// orderedForSend returns the endpoints to send a user operation to, starting with the endpoint that accepted
+// a user operation with the same sender and nonce
+func (p *BundlerPool) orderedForSend(op *UserOperation) []*poolEndpoint {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var replaced *poolEndpoint
+	if op.Nonce != nil {
+		for _, sent := range p.sent {
+			if sent.sender == op.Sender && sent.nonce != nil && sent.nonce.Cmp(op.Nonce.ToInt()) == 0 {
+				replaced = sent.endpoint
+				break
+			}
+		}
+	}
+	return p.orderedLocked(replaced, time.Now())
+}
+
+// orderedForUserOp returns the endpoints to ask about a user operation: the endpoint that accepted it first,
+// then the other endpoints except the last resort ones
+func (p *BundlerPool) orderedForUserOp(userOpHash common.Hash) []*poolEndpoint {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	sentTo := p.sent[userOpHash].endpoint
+	var ordered []*poolEndpoint
+	for _, endpoint := range p.orderedLocked(sentTo, time.Now()) {
+		if !endpoint.lastResort || endpoint == sentTo {
+			ordered = append(ordered, endpoint)
+		}
+	}
+	return ordered
+}
+
+// sentEndpoint returns the endpoint that accepted a user operation, or nil if the pool does not remember it
+func (p *BundlerPool) sentEndpoint(userOpHash common.Hash) *poolEndpoint {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.sent[userOpHash].endpoint
+}
+
+func (p *BundlerPool) rememberSent(userOpHash common.Hash, op *UserOperation, endpoint *poolEndpoint) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	for hash, sent := range p.sent {
+		if now.Sub(sent.sentAt) > sentUserOpTTL {
+			delete(p.sent, hash)
+		}
+	}
+	sent := sentUserOp{endpoint: endpoint, sender: op.Sender, sentAt: now}
+	if op.Nonce != nil {
+		sent.nonce = new(big.Int).Set(op.Nonce.ToInt())
+	}
+	p.sent[userOpHash] = sent
+}
+
+func (p *BundlerPool) forgetSent(userOpHash common.Hash) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.sent, userOpHash)
+}
+
+// isFailoverError reports whether an error is caused by the endpoint rather than the request,
+// in which case the call is retried on the next endpoint
+func isFailoverError(err error) bool {
+	if errors.Is(err, ErrUserOperationNotTracked) {
+		return false
+	}
+	var rpcErr rpc.Error
+	if errors.As(err, &rpcErr) {
+		switch rpcErr.ErrorCode() {
+		case -32601, // method not found
+			-32603, // internal error
+			-32005: // limit exceeded
+			return true
+		}
+		return false
+	}
+	// Transport errors such as connection failures, timeouts and HTTP errors
+	return true
+}
+
+// isSendFailoverError reports whether a user operation surely did not reach the bundler, so that it can be sent to
+// the next endpoint: the connection could not be opened, or the bundler rate limited or did not implement the call.
+// A timeout or a dropped connection may come after the bundler accepted the user operation, and sending it elsewhere,
+// in particular to the self bundler, would include it twice.
+func isSendFailoverError(err error) bool {
+	var rpcErr rpc.Error
+	if errors.As(err, &rpcErr) {
+		switch rpcErr.ErrorCode() {
+		case -32601, // method not found
+			-32005: // limit exceeded
+			return true
+		}
+		return false
+	}
+	var httpErr rpc.HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode == http.StatusTooManyRequests
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// poolCall calls fn on the endpoints in order until one succeeds or returns an error for which failover is false
+func poolCall[T any](ctx context.Context, p *BundlerPool, endpoints []*poolEndpoint, method string, failover func(error) bool, fn func(Bundler) (T, error)) (T, *poolEndpoint, error) {
+	var zero T
+	var errs []error
+
+	for _, endpoint := range endpoints {
+		start := time.Now()
+		result, err := fn(endpoint.bundler)
+		if err == nil || !failover(err) {
+			// Errors of the request itself do not count against the endpoint, unlike a send that timed out
+			var failure error
+			if err != nil && isFailoverError(err) {
+				failure = err
+			}
+			p.record(endpoint, time.Since(start), failure)
+			return result, endpoint, err
+		}
+		if ctx.Err() != nil {
+			return zero, nil, err
+		}
+
+		p.record(endpoint, time.Since(start), err)
+		if p.config.OnEndpointFailure != nil {
+			p.config.OnEndpointFailure(endpoint.name, method, err)
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", endpoint.name, err))
+	}
+
+	return zero, nil, fmt.Errorf("%w in %s: %w", ErrAllEndpointsFailed, method, errors.Join(errs...))
+}
+
+// IsMethodNotFound reports whether an RPC call failed because the endpoint does not implement the method
+func IsMethodNotFound(err error) bool {
+	if errors.Is(err, ErrMethodNotSupported) {
+		return true
+	}
+	var rpcErr rpc.Error
+	return errors.As(err, &rpcErr) && rpcErr.ErrorCode() == -32601
+}
+
+// CallContext sends a raw JSON-RPC call to the endpoints that accept raw calls, failing over like the Bundler
+// methods. Endpoints that do not implement the method are skipped without counting as a failure, so a bundler
+// without a vendor gas method stays healthy for user operations.
+func (p *BundlerPool) CallContext(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+	var errs []error
+
+	for _, endpoint := range p.ordered() {
+		caller, ok := endpoint.bundler.(RPCCaller)
+		if !ok {
+			continue
+		}
+
+		start := time.Now()
+		err := caller.CallContext(ctx, result, method, args...)
+		if err == nil || !isFailoverError(err) {
+			p.record(endpoint, time.Since(start), nil)
+			return err
+		}
+		if ctx.Err() != nil {
+			return err
+		}
+
+		errs = append(errs, fmt.Errorf("%s: %w", endpoint.name, err))
+		if IsMethodNotFound(err) {
+			continue
+		}
+		p.record(endpoint, time.Since(start), err)
+		if p.config.OnEndpointFailure != nil {
+			p.config.OnEndpointFailure(endpoint.name, method, err)
+		}
+	}
+
+	if len(errs) == 0 {
+		return fmt.Errorf("%w: %s", ErrMethodNotSupported, method)
+	}
+	return fmt.Errorf("%w in %s: %w", ErrAllEndpointsFailed, method, errors.Join(errs...))
+}
+
+func (p *BundlerPool) ChainId(ctx context.Context) (*big.Int, error) {
+	result, _, err := poolCall(ctx, p, p.ordered(), "eth_chainId", isFailoverError, func(b Bundler) (*big.Int, error) {
+		return b.ChainId(ctx)
+	})
+	return result, err
+}
+
+// SupportedEntryPoints returns the entry points supported by any endpoint. The entry points of each endpoint are
+// cached for EntryPointsTTL, and unhealthy endpoints whose entry points are not cached are skipped.
+func (p *BundlerPool) SupportedEntryPoints(ctx context.Context) ([]common.Address, error) {
+	var result []common.Address
+	var errs []error
+	answered := false
+
+	for _, endpoint := range p.ordered() {
+		entryPoints, err := p.endpointEntryPoints(ctx, endpoint)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, err
+			}
+			errs = append(errs, fmt.Errorf("%s: %w", endpoint.name, err))
+			continue
+		}
+		answered = true
+		for _, entryPoint := range entryPoints {
+			if !slices.Contains(result, entryPoint) {
+				result = append(result, entryPoint)
+			}
+		}
+	}
+
+	if !answered {
+		return nil, fmt.Errorf("%w in eth_supportedEntryPoints: %w", ErrAllEndpointsFailed, errors.Join(errs...))
+	}
+	return result, nil
+}
+
+// endpointEntryPoints returns the cached entry points of an endpoint, asking the endpoint once they expired
+func (p *BundlerPool) endpointEntryPoints(ctx context.Context, endpoint *poolEndpoint) ([]common.Address, error) {
+	p.mu.Lock()
+	now := time.Now()
+	if !endpoint.entryPointsAt.IsZero() && now.Sub(endpoint.entryPointsAt) < p.config.EntryPointsTTL {
+		entryPoints := endpoint.entryPoints
+		p.mu.Unlock()
+		return entryPoints, nil
+	}
+	healthy := p.healthyLocked(endpoint, now)
+	p.mu.Unlock()
+
+	if !healthy {
+		return nil, errors.New("endpoint unhealthy")
+	}
+
+	start := time.Now()
+	entryPoints, err := endpoint.bundler.SupportedEntryPoints(ctx)
+	if err != nil {
+		if ctx.Err() == nil && isFailoverError(err) {
+			p.record(endpoint, time.Since(start), err)
+		}
+		return nil, err
+	}
+	p.record(endpoint, time.Since(start), nil)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	endpoint.entryPoints = entryPoints
+	endpoint.entryPointsAt = time.Now()
+	return entryPoints, nil
+}
+
+// supportingEntryPoint filters out the endpoints known not to support an entry point, using the cached entry points
+// only. Endpoints whose entry points are not known are kept, and so are all endpoints if none is known to support it.
+func (p *BundlerPool) supportingEntryPoint(endpoints []*poolEndpoint, entryPoint common.Address) []*poolEndpoint {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var supporting []*poolEndpoint
+	for _, endpoint := range endpoints {
+		if endpoint.entryPointsAt.IsZero() || slices.Contains(endpoint.entryPoints, entryPoint) {
+			supporting = append(supporting, endpoint)
+		}
+	}
+	if len(supporting) == 0 {
+		return endpoints
+	}
+	return supporting
+}
+
+func (p *BundlerPool) EstimateUserOperationGas(ctx context.Context, op *UserOperation, entryPoint common.Address) (*GasEstimates, error) {
+	result, _, err := poolCall(ctx, p, p.supportingEntryPoint(p.ordered(), entryPoint), "eth_estimateUserOperationGas", isFailoverError, func(b Bundler) (*GasEstimates, error) {
+		return b.EstimateUserOperationGas(ctx, op, entryPoint)
+	})
+	return result, err
+}
+
+// SendUserOperation sends a replacement of a user operation, with the same sender and nonce, to the endpoint that
+// accepted the original first: another bundler would not evict the original from its mempool.
+// It only fails over when the user operation surely did not reach the endpoint, see isSendFailoverError.
+func (p *BundlerPool) SendUserOperation(ctx context.Context, op *UserOperation, entryPoint common.Address) (common.Hash, error) {
+	result, endpoint, err := poolCall(ctx, p, p.supportingEntryPoint(p.orderedForSend(op), entryPoint), "eth_sendUserOperation", isSendFailoverError, func(b Bundler) (common.Hash, error) {
+		return b.SendUserOperation(ctx, op, entryPoint)
+	})
+	if err == nil {
+		p.rememberSent(result, op, endpoint)
+	}
+	return result, err
+}
+
+// GetUserOperationReceipt asks the endpoint that accepted the user operation first
+func (p *BundlerPool) GetUserOperationReceipt(ctx context.Context, userOpHash common.Hash) (*UserOperationReceipt, error) {
+	result, _, err := poolCall(ctx, p, p.orderedForUserOp(userOpHash), "eth_getUserOperationReceipt", isFailoverError, func(b Bundler) (*UserOperationReceipt, error) {
+		return b.GetUserOperationReceipt(ctx, userOpHash)
+	})
+	if err == nil && result != nil {
+		p.forgetSent(userOpHash)
+	}
+	return result, err
+}
+
+// GetUserOperationByHash asks the endpoint that accepted the user operation first,
+// since other bundlers don't know operations pending in its mempool.
+// Only that endpoint can tell that the user operation was dropped: when it is unknown, e.g. after a restart,
+// or did not answer, a user operation no endpoint knows returns ErrUserOperationNotTracked.
+func (p *BundlerPool) GetUserOperationByHash(ctx context.Context, userOpHash common.Hash) (*UserOperationByHash, error) {
+	const method = "eth_getUserOperationByHash"
+	getUserOperation := func(b Bundler) (*UserOperationByHash, error) {
+		return b.GetUserOperationByHash(ctx, userOpHash)
+	}
+
+	sentTo := p.sentEndpoint(userOpHash)
+	endpoints := p.orderedForUserOp(userOpHash)
+	if sentTo != nil {
+		result, endpoint, err := poolCall(ctx, p, endpoints, method, isFailoverError, getUserOperation)
+		if err != nil || result != nil || endpoint == sentTo {
+			return result, err
+		}
+		return nil, fmt.Errorf("%w: %s did not answer for %s", ErrUserOperationNotTracked, sentTo.name, userOpHash.Hex())
+	}
+
+	// Any endpoint may have accepted the user operation, so a single null answer does not mean it was dropped
+	var errs []error
+	for i := range endpoints {
+		result, _, err := poolCall(ctx, p, endpoints[i:i+1], method, isFailoverError, getUserOperation)
+		if err == nil && result != nil {
+			return result, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) == len(endpoints) {
+		return nil, fmt.Errorf("%w in %s: %w", ErrAllEndpointsFailed, method, errors.Join(errs...))
+	}
+	return nil, fmt.Errorf("%w: no bundler endpoint knows %s", ErrUserOperationNotTracked, userOpHash.Hex())
+}
+
+// Close stops the health checks and closes every endpoint
+func (p *BundlerPool) Close() {
+	p.closeOnce.Do(func() {
+		close(p.stop)
+		<-p.done
+		for _, endpoint := range p.endpoints {
+			endpoint.bundler.Close()
+		}
+	})
+}
@@ -0,0 +1,447 @@
+package erc4337
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testRPCError is a JSON-RPC error response
+type testRPCError struct {
+	code    int
+	message string
+}
+
+func (e *testRPCError) Error() string  { return e.message }
+func (e *testRPCError) ErrorCode() int { return e.code }
+
+// errConnectionRefused is the error of a bundler that could not be reached, which surely did not read the request
+var errConnectionRefused = &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+
+// fakePoolBundler answers the calls used by the pool tests and counts them
+type fakePoolBundler struct {
+	Bundler
+
+	mu          sync.Mutex
+	chainId     *big.Int
+	entryPoints []common.Address
+	err         error
+	calls       int
+	known       map[common.Hash]bool
+	closed      bool
+}
+
+func newFakePoolBundler(chainId int64) *fakePoolBundler {
+	return &fakePoolBundler{chainId: big.NewInt(chainId), entryPoints: []common.Address{EntryPointV07}, known: make(map[common.Hash]bool)}
+}
+
+func (b *fakePoolBundler) setError(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.err = err
+}
+
+func (b *fakePoolBundler) callCount() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.calls
+}
+
+func (b *fakePoolBundler) call(method string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.calls++
+	if b.err != nil {
+		return HandleRPCError(b.err, method)
+	}
+	return nil
+}
+
+func (b *fakePoolBundler) ChainId(ctx context.Context) (*big.Int, error) {
+	if err := b.call("eth_chainId"); err != nil {
+		return nil, err
+	}
+	return b.chainId, nil
+}
+
+func (b *fakePoolBundler) SupportedEntryPoints(ctx context.Context) ([]common.Address, error) {
+	if err := b.call("eth_supportedEntryPoints"); err != nil {
+		return nil, err
+	}
+	return b.entryPoints, nil
+}
+
+func (b *fakePoolBundler) EstimateUserOperationGas(ctx context.Context, op *UserOperation, entryPoint common.Address) (*GasEstimates, error) {
+	if err := b.call("eth_estimateUserOperationGas"); err != nil {
+		return nil, err
+	}
+	return &GasEstimates{}, nil
+}
+
+func (b *fakePoolBundler) SendUserOperation(ctx context.Context, op *UserOperation, entryPoint common.Address) (common.Hash, error) {
+	if err := b.call("eth_sendUserOperation"); err != nil {
+		return common.Hash{}, err
+	}
+	hash := common.BytesToHash(op.CallData)
+	b.mu.Lock()
+	b.known[hash] = true
+	b.mu.Unlock()
+	return hash, nil
+}
+
+func (b *fakePoolBundler) GetUserOperationByHash(ctx context.Context, userOpHash common.Hash) (*UserOperationByHash, error) {
+	if err := b.call("eth_getUserOperationByHash"); err != nil {
+		return nil, err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.known[userOpHash] {
+		return nil, nil
+	}
+	return &UserOperationByHash{}, nil
+}
+
+func (b *fakePoolBundler) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+}
+
+func newTestBundlerPool(t *testing.T, config BundlerPoolConfig, bundlers ...*fakePoolBundler) *BundlerPool {
+	config.HealthCheckInterval = -1
+	var endpoints []BundlerEndpoint
+	for i, bundler := range bundlers {
+		endpoints = append(endpoints, BundlerEndpoint{Name: string(rune('a' + i)), Bundler: bundler})
+	}
+	pool, err := NewBundlerPool(big.NewInt(1), endpoints, config)
+	require.NoError(t, err)
+	t.Cleanup(pool.Close)
+	return pool
+}
+
+func TestBundlerPool_Failover(t *testing.T) {
+	primary, fallback := newFakePoolBundler(1), newFakePoolBundler(1)
+	primary.setError(errConnectionRefused)
+
+	var failures []string
+	pool := newTestBundlerPool(t, BundlerPoolConfig{
+		UnhealthyThreshold: 2,
+		OnEndpointFailure: func(endpoint string, method string, err error) {
+			failures = append(failures, endpoint+" "+method)
+		},
+	}, primary, fallback)
+
+	for i := 0; i < 3; i++ {
+		_, err := pool.EstimateUserOperationGas(context.Background(), &UserOperation{}, EntryPointV07)
+		require.NoError(t, err)
+	}
+
+	// The primary is skipped once it is unhealthy
+	assert.Equal(t, 2, primary.callCount())
+	assert.Equal(t, 3, fallback.callCount())
+	assert.Equal(t, []string{"a eth_estimateUserOperationGas", "a eth_estimateUserOperationGas"}, failures)
+
+	status := pool.Status()
+	require.Len(t, status, 2)
+	assert.Equal(t, "b", status[0].Name)
+	assert.True(t, status[0].Healthy)
+	assert.Equal(t, "a", status[1].Name)
+	assert.False(t, status[1].Healthy)
+	assert.Equal(t, 2, status[1].ConsecutiveFailures)
+	assert.Greater(t, status[1].ErrorRate, 0.0)
+	assert.Error(t, status[1].LastError)
+}
+
+func TestBundlerPool_NoFailoverOnBundlerError(t *testing.T) {
+	primary, fallback := newFakePoolBundler(1), newFakePoolBundler(1)
+	primary.setError(&testRPCError{code: -32500, message: "AA23 reverted"})
+
+	pool := newTestBundlerPool(t, BundlerPoolConfig{}, primary, fallback)
+
+	_, err := pool.EstimateUserOperationGas(context.Background(), &UserOperation{}, EntryPointV07)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "AA23 reverted")
+	assert.Equal(t, 0, fallback.callCount())
+
+	// The endpoint answered, so it stays healthy
+	assert.True(t, pool.Status()[0].Healthy)
+	assert.Equal(t, 0, pool.Status()[0].ConsecutiveFailures)
+}
+
+func TestBundlerPool_AllEndpointsFail(t *testing.T) {
+	primary, fallback := newFakePoolBundler(1), newFakePoolBundler(1)
+	primary.setError(errConnectionRefused)
+	fallback.setError(&testRPCError{code: -32005, message: "limit exceeded"})
+
+	pool := newTestBundlerPool(t, BundlerPoolConfig{}, primary, fallback)
+
+	_, err := pool.SendUserOperation(context.Background(), &UserOperation{}, EntryPointV07)
+	require.Error(t, err)
+	assert.ErrorIs(t, err, ErrAllEndpointsFailed)
+	assert.Contains(t, err.Error(), "all bundler endpoints failed in eth_sendUserOperation")
+	assert.Contains(t, err.Error(), "connection refused")
+	assert.Contains(t, err.Error(), "limit exceeded")
+}
+
+func TestBundlerPool_SendFailover(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		failover bool
+	}{
+		{"connection refused", errConnectionRefused, true},
+		{"rate limited", rpc.HTTPError{StatusCode: http.StatusTooManyRequests, Status: "429 Too Many Requests"}, true},
+		{"limit exceeded", &testRPCError{code: -32005, message: "limit exceeded"}, true},
+		{"timeout", &net.OpError{Op: "read", Net: "tcp", Err: os.ErrDeadlineExceeded}, false},
+		{"deadline exceeded", context.DeadlineExceeded, false},
+		{"bad gateway", rpc.HTTPError{StatusCode: http.StatusBadGateway, Status: "502 Bad Gateway"}, false},
+		{"internal error", &testRPCError{code: -32603, message: "internal error"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			primary, lastResort := newFakePoolBundler(1), newFakePoolBundler(1)
+			primary.setError(tt.err)
+
+			pool, err := NewBundlerPool(big.NewInt(1), []BundlerEndpoint{
+				{Name: "primary", Bundler: primary},
+				{Name: "self", Bundler: lastResort, LastResort: true},
+			}, BundlerPoolConfig{HealthCheckInterval: -1})
+			require.NoError(t, err)
+			t.Cleanup(pool.Close)
+
+			// The user operation may have reached the primary unless it was refused unread,
+			// and the self bundler must not include it a second time
+			_, err = pool.SendUserOperation(context.Background(), &UserOperation{CallData: []byte{0x01}}, EntryPointV07)
+			if tt.failover {
+				require.NoError(t, err)
+				assert.Equal(t, 1, lastResort.callCount())
+			} else {
+				require.Error(t, err)
+				assert.Equal(t, 0, lastResort.callCount())
+			}
+			assert.Equal(t, 1, pool.Status()[0].ConsecutiveFailures)
+
+			// Other calls fail over on any endpoint failure
+			_, err = pool.EstimateUserOperationGas(context.Background(), &UserOperation{}, EntryPointV07)
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestBundlerPool_PendingUserOperationFromAcceptingBundler(t *testing.T) {
+	primary, fallback := newFakePoolBundler(1), newFakePoolBundler(1)
+	primary.setError(errConnectionRefused)
+
+	pool := newTestBundlerPool(t, BundlerPoolConfig{}, primary, fallback)
+
+	userOpHash, err := pool.SendUserOperation(context.Background(), &UserOperation{CallData: []byte{0x01}}, EntryPointV07)
+	require.NoError(t, err)
+
+	// The primary recovers, but only the fallback knows the pending user operation
+	primary.setError(nil)
+	userOp, err := pool.GetUserOperationByHash(context.Background(), userOpHash)
+	require.NoError(t, err)
+	assert.NotNil(t, userOp)
+}
+
+func TestBundlerPool_UntrackedUserOperation(t *testing.T) {
+	primary, fallback := newFakePoolBundler(1), newFakePoolBundler(1)
+	primary.setError(errConnectionRefused)
+
+	pool := newTestBundlerPool(t, BundlerPoolConfig{}, primary, fallback)
+	userOpHash, err := pool.SendUserOperation(context.Background(), &UserOperation{CallData: []byte{0x01}}, EntryPointV07)
+	require.NoError(t, err)
+	primary.setError(nil)
+
+	// A new pool, e.g. after a restart, does not know which endpoint accepted the user operation
+	restarted := newTestBundlerPool(t, BundlerPoolConfig{}, primary, fallback)
+	userOp, err := restarted.GetUserOperationByHash(context.Background(), userOpHash)
+	require.NoError(t, err)
+	assert.NotNil(t, userOp)
+
+	// No endpoint knowing it does not mean it was dropped
+	_, err = restarted.GetUserOperationByHash(context.Background(), common.HexToHash("0x02"))
+	assert.ErrorIs(t, err, ErrUserOperationNotTracked)
+
+	// The endpoint that accepted it can tell
+	fallback.mu.Lock()
+	delete(fallback.known, userOpHash)
+	fallback.mu.Unlock()
+	userOp, err = pool.GetUserOperationByHash(context.Background(), userOpHash)
+	require.NoError(t, err)
+	assert.Nil(t, userOp)
+
+	// Unless another endpoint answered for it
+	fallback.setError(errConnectionRefused)
+	_, err = pool.GetUserOperationByHash(context.Background(), userOpHash)
+	assert.ErrorIs(t, err, ErrUserOperationNotTracked)
+}
+
+func TestBundlerPool_ReplacementToAcceptingBundler(t *testing.T) {
+	primary, fallback := newFakePoolBundler(1), newFakePoolBundler(1)
+	primary.setError(errConnectionRefused)
+
+	pool := newTestBundlerPool(t, BundlerPoolConfig{}, primary, fallback)
+	nonce := (*hexutil.Big)(big.NewInt(1))
+	_, err := pool.SendUserOperation(context.Background(), &UserOperation{Nonce: nonce, CallData: []byte{0x01}}, EntryPointV07)
+	require.NoError(t, err)
+	primary.setError(nil)
+
+	// The replacement goes to the fallback holding the original, other user operations to the primary
+	_, err = pool.SendUserOperation(context.Background(), &UserOperation{Nonce: nonce, CallData: []byte{0x02}}, EntryPointV07)
+	require.NoError(t, err)
+	assert.Equal(t, 2, fallback.callCount())
+	assert.Equal(t, 1, primary.callCount())
+
+	_, err = pool.SendUserOperation(context.Background(), &UserOperation{Nonce: (*hexutil.Big)(big.NewInt(2)), CallData: []byte{0x03}}, EntryPointV07)
+	require.NoError(t, err)
+	assert.Equal(t, 2, fallback.callCount())
+	assert.Equal(t, 2, primary.callCount())
+}
+
+func TestBundlerPool_LastResort(t *testing.T) {
+	primary, lastResort := newFakePoolBundler(1), newFakePoolBundler(1)
+
+	pool, err := NewBundlerPool(big.NewInt(1), []BundlerEndpoint{
+		{Name: "self", Bundler: lastResort, LastResort: true},
+		{Name: "primary", Bundler: primary},
+	}, BundlerPoolConfig{HealthCheckInterval: -1, UnhealthyThreshold: 1})
+	require.NoError(t, err)
+	t.Cleanup(pool.Close)
+
+	// Used after the primary even though it is listed first
+	primaryOpHash, err := pool.SendUserOperation(context.Background(), &UserOperation{CallData: []byte{0x01}}, EntryPointV07)
+	require.NoError(t, err)
+	assert.Equal(t, 1, primary.callCount())
+	assert.Equal(t, 0, lastResort.callCount())
+
+	// Used once the primary fails, even when the primary is unhealthy
+	primary.setError(errConnectionRefused)
+	lastResortOpHash, err := pool.SendUserOperation(context.Background(), &UserOperation{CallData: []byte{0x02}}, EntryPointV07)
+	require.NoError(t, err)
+	assert.Equal(t, 1, lastResort.callCount())
+	assert.Equal(t, "primary", pool.Status()[0].Name)
+	assert.Equal(t, "self", pool.Status()[1].Name)
+
+	// Only asked about the user operations it accepted
+	userOp, err := pool.GetUserOperationByHash(context.Background(), lastResortOpHash)
+	require.NoError(t, err)
+	assert.NotNil(t, userOp)
+	assert.Equal(t, 2, lastResort.callCount())
+
+	_, err = pool.GetUserOperationByHash(context.Background(), primaryOpHash)
+	require.Error(t, err)
+	assert.Equal(t, 2, lastResort.callCount())
+}
+
+func TestBundlerPool_SupportedEntryPoints(t *testing.T) {
+	v07, v08, down := newFakePoolBundler(1), newFakePoolBundler(1), newFakePoolBundler(1)
+	v08.entryPoints = []common.Address{EntryPointV08}
+	down.setError(errConnectionRefused)
+
+	pool := newTestBundlerPool(t, BundlerPoolConfig{UnhealthyThreshold: 1}, v07, v08, down)
+	ctx := context.Background()
+
+	// The entry points of the reachable endpoints are merged and cached
+	entryPoints, err := pool.SupportedEntryPoints(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []common.Address{EntryPointV07, EntryPointV08}, entryPoints)
+	_, err = pool.SupportedEntryPoints(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, v07.callCount())
+	assert.Equal(t, 1, v08.callCount())
+	assert.Equal(t, 1, down.callCount())
+
+	// User operations only go to the endpoints supporting their entry point
+	_, err = pool.SendUserOperation(ctx, &UserOperation{CallData: []byte{0x01}}, EntryPointV08)
+	require.NoError(t, err)
+	assert.Equal(t, 1, v07.callCount())
+	assert.Equal(t, 2, v08.callCount())
+
+	_, err = pool.EstimateUserOperationGas(ctx, &UserOperation{}, EntryPointV07)
+	require.NoError(t, err)
+	assert.Equal(t, 2, v07.callCount())
+	assert.Equal(t, 2, v08.callCount())
+
+	// Without a reachable endpoint the entry points are unknown
+	v07.setError(errConnectionRefused)
+	v08.setError(errConnectionRefused)
+	unreachable := newTestBundlerPool(t, BundlerPoolConfig{}, v07, v08)
+	_, err = unreachable.SupportedEntryPoints(ctx)
+	assert.ErrorIs(t, err, ErrAllEndpointsFailed)
+}
+
+func TestBundlerPool_CheckHealth(t *testing.T) {
+	primary, wrongChain := newFakePoolBundler(1), newFakePoolBundler(2)
+
+	pool := newTestBundlerPool(t, BundlerPoolConfig{UnhealthyThreshold: 1, UnhealthyCooldown: time.Hour}, wrongChain, primary)
+	pool.CheckHealth(context.Background())
+
+	status := pool.Status()
+	require.Len(t, status, 2)
+	assert.Equal(t, "b", status[0].Name)
+	assert.True(t, status[0].Healthy)
+	assert.Equal(t, "a", status[1].Name)
+	assert.False(t, status[1].Healthy)
+	assert.ErrorContains(t, status[1].LastError, "chain id mismatch")
+}
+
+func TestBundlerPool_Close(t *testing.T) {
+	primary, fallback := newFakePoolBundler(1), newFakePoolBundler(1)
+
+	pool, err := NewBundlerPool(nil, []BundlerEndpoint{{Name: "a", Bundler: primary}, {Name: "b", Bundler: fallback}}, BundlerPoolConfig{HealthCheckInterval: time.Millisecond})
+	require.NoError(t, err)
+	pool.Close()
+	pool.Close()
+
+	assert.True(t, primary.closed)
+	assert.True(t, fallback.closed)
+
+	_, err = NewBundlerPool(nil, nil, BundlerPoolConfig{})
+	assert.Error(t, err)
+}
+
+func TestBundlerPool_CallContext(t *testing.T) {
+	withoutMethod := newTestBundlerClient(t, map[string]string{})
+	withMethod := newTestBundlerClient(t, map[string]string{"rundler_maxPriorityFeePerGas": `"0x3b9aca00"`})
+	selfBundler := newFakePoolBundler(1)
+
+	pool, err := NewBundlerPool(big.NewInt(1), []BundlerEndpoint{
+		{Name: "a", Bundler: withoutMethod},
+		{Name: "b", Bundler: withMethod},
+		{Name: "self", Bundler: selfBundler, LastResort: true},
+	}, BundlerPoolConfig{HealthCheckInterval: -1, UnhealthyThreshold: 1})
+	require.NoError(t, err)
+	t.Cleanup(pool.Close)
+
+	// Served by the endpoint implementing the method, without marking the other endpoint as failed
+	var fee hexutil.Big
+	require.NoError(t, pool.CallContext(context.Background(), &fee, "rundler_maxPriorityFeePerGas"))
+	assert.Equal(t, int64(1_000_000_000), fee.ToInt().Int64())
+	for _, status := range pool.Status() {
+		assert.True(t, status.Healthy, status.Name)
+	}
+
+	// No endpoint implements the method; endpoints without raw calls are skipped
+	err = pool.CallContext(context.Background(), &fee, "pimlico_getUserOperationGasPrice")
+	require.Error(t, err)
+	assert.True(t, IsMethodNotFound(err))
+	assert.Equal(t, 0, selfBundler.callCount())
+
+	selfOnly := newTestBundlerPool(t, BundlerPoolConfig{}, newFakePoolBundler(1))
+	err = selfOnly.CallContext(context.Background(), &fee, "rundler_maxPriorityFeePerGas")
+	assert.ErrorIs(t, err, ErrMethodNotSupported)
+	assert.True(t, IsMethodNotFound(err))
+}
@@ -0,0 +1,458 @@
+package erc4337
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+const (
+	DefaultReceiptPollInterval    = time.Second
+	DefaultReceiptMaxPollInterval = 30 * time.Second
+	DefaultReceiptPollMultiplier  = 2
+	DefaultReceiptPollConcurrency = 8
+	DefaultUntrackedDropAfter     = 30 * time.Minute
+
+	// untrackedDropPolls is the number of polls a user operation must be untracked for before it is reported dropped
+	untrackedDropPolls = 3
+)
+
+var (
+	// ErrReceiptTimeout is the error of a ReceiptResult when the user operation is not included before the watch timeout
+	ErrReceiptTimeout = errors.New("timed out waiting for user operation receipt")
+	// ErrReceiptWatcherClosed is the error of a ReceiptResult when the watcher is closed before the user operation is included
+	ErrReceiptWatcherClosed = errors.New("receipt watcher closed")
+	// ErrUserOperationDropped is returned by WaitForReceipt when the bundler no longer knows the user operation
+	ErrUserOperationDropped = errors.New("user operation dropped by the bundler")
+	// ErrUserOperationNotTracked is returned by bundlers that cannot tell whether a user operation they do not know
+	// is still pending elsewhere, e.g. a BundlerPool after a restart when the bundler that accepted it is unknown
+	ErrUserOperationNotTracked = errors.New("user operation not tracked by the bundler")
+)
+
+// LogFilterer reads logs from a node; *ethclient.Client implements it
+type LogFilterer interface {
+	FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]types.Log, error)
+}
+
+// ReceiptWatcherConfig configures a ReceiptWatcher. Zero values use the defaults above.
+type ReceiptWatcherConfig struct {
+	// PollInterval is the delay before the second poll of a user operation, which grows by Multiplier
+	// after each poll without a result, up to MaxPollInterval
+	PollInterval    time.Duration
+	MaxPollInterval time.Duration
+	Multiplier      float64
+	// Concurrency bounds the number of user operations polled at the same time
+	Concurrency int
+	// Timeout is how long a user operation is watched; zero watches it until it is included or dropped
+	Timeout time.Duration
+	// UntrackedDropAfter is how long the bundler must answer ErrUserOperationNotTracked for a user operation
+	// before it is reported dropped, since it may still be pending on a bundler that is not asked about it
+	UntrackedDropAfter time.Duration
+	// LogConfirmer, if set, confirms each receipt with the UserOperationEvent log of the EntryPoint on the node,
+	// so a receipt is only delivered once the node has the block and its success comes from the chain
+	LogConfirmer LogFilterer
+	// OnResult, if set, is called with every result, in addition to the channels returned by Watch
+	OnResult func(ReceiptResult)
+}
+
+func (c ReceiptWatcherConfig) withDefaults() ReceiptWatcherConfig {
+	if c.PollInterval <= 0 {
+		c.PollInterval = DefaultReceiptPollInterval
+	}
+	if c.MaxPollInterval <= 0 {
+		c.MaxPollInterval = DefaultReceiptMaxPollInterval
+	}
+	if c.MaxPollInterval < c.PollInterval {
+		c.MaxPollInterval = c.PollInterval
+	}
+	if c.Multiplier < 1 {
+		c.Multiplier = DefaultReceiptPollMultiplier
+	}
+	if c.Concurrency <= 0 {
+		c.Concurrency = DefaultReceiptPollConcurrency
+	}
+	if c.UntrackedDropAfter <= 0 {
+		c.UntrackedDropAfter = DefaultUntrackedDropAfter
+	}
+	return c
+}
+
+// ReceiptResult is the outcome of watching a user operation. Exactly one of Receipt, Dropped and Err is set.
+type ReceiptResult struct {
+	UserOpHash common.Hash
+	// Receipt is set once the user operation is included
+	Receipt *UserOperationReceipt
+	// Dropped is set when the bundler no longer knows the user operation, e.g. after it was evicted from the mempool,
+	// or when it could not be tracked for UntrackedDropAfter
+	Dropped bool
+	// Err is ErrReceiptTimeout, wrapping the last poll error if any, or ErrReceiptWatcherClosed
+	Err error
+}
+
+type watchedUserOp struct {
+	interval    time.Duration
+	nextPoll    time.Time
+	deadline    time.Time
+	lastErr     error
+	subscribers []chan ReceiptResult
+
+	// untrackedSince and untrackedPolls count the consecutive polls answered with ErrUserOperationNotTracked
+	untrackedSince time.Time
+	untrackedPolls int
+}
+
+// ReceiptWatcher polls the receipts of pending user operations of a chain until they are included or dropped.
+// All due user operations are polled together, each with its own exponential backoff, and results are
+// delivered on the channels returned by Watch and to the OnResult callback.
+type ReceiptWatcher struct {
+	bundler Bundler
+	config  ReceiptWatcherConfig
+
+	watched map[common.Hash]*watchedUserOp
+	closed  bool
+	mu      sync.Mutex
+
+	wake      chan struct{}
+	ctx       context.Context
+	cancel    context.CancelFunc
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewReceiptWatcher creates a watcher polling the given bundler and starts its polling loop
+func NewReceiptWatcher(bundler Bundler, config ReceiptWatcherConfig) *ReceiptWatcher {
+	ctx, cancel := context.WithCancel(context.Background())
+	w := &ReceiptWatcher{
+		bundler: bundler,
+		config:  config.withDefaults(),
+		watched: make(map[common.Hash]*watchedUserOp),
+		wake:    make(chan struct{}, 1),
+		ctx:     ctx,
+		cancel:  cancel,
+		done:    make(chan struct{}),
+	}
+	go w.loop()
+	return w
+}
+
+// Watch starts watching a user operation and returns a channel that receives its result and is then closed.
+// Watching a user operation that is already watched subscribes to the same watch.
+func (w *ReceiptWatcher) Watch(userOpHash common.Hash) <-chan ReceiptResult {
+	ch := make(chan ReceiptResult, 1)
+
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		ch <- ReceiptResult{UserOpHash: userOpHash, Err: ErrReceiptWatcherClosed}
+		close(ch)
+		return ch
+	}
+
+	watched, exists := w.watched[userOpHash]
+	if !exists {
+		now := time.Now()
+		watched = &watchedUserOp{interval: w.config.PollInterval, nextPoll: now}
+		if w.config.Timeout > 0 {
+			watched.deadline = now.Add(w.config.Timeout)
+		}
+		w.watched[userOpHash] = watched
+	}
+	watched.subscribers = append(watched.subscribers, ch)
+	w.mu.Unlock()
+
+	// Poll the new user operation right away
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+	return ch
+}
+
+// IsWatching reports whether a user operation is being watched
+func (w *ReceiptWatcher) IsWatching(userOpHash common.Hash) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	_, exists := w.watched[userOpHash]
+	return exists
+}
+
+// Pending returns the number of watched user operations
+func (w *ReceiptWatcher) Pending() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return len(w.watched)
+}
+
+// Unwatch stops watching a user operation and closes its channels without a result
+func (w *ReceiptWatcher) Unwatch(userOpHash common.Hash) {
+	w.mu.Lock()
+	watched, exists := w.watched[userOpHash]
+	delete(w.watched, userOpHash)
+	w.mu.Unlock()
+
+	if exists {
+		for _, ch := range watched.subscribers {
+			close(ch)
+		}
+	}
+}
+
+// WaitForReceipt watches a user operation and blocks until it is included, dropped, timed out or ctx is done
+func (w *ReceiptWatcher) WaitForReceipt(ctx context.Context, userOpHash common.Hash) (*UserOperationReceipt, error) {
+	ch := w.Watch(userOpHash)
+
+	select {
+	case <-ctx.Done():
+		w.unsubscribe(userOpHash, ch)
+		return nil, ctx.Err()
+	case result, ok := <-ch:
+		switch {
+		case !ok:
+			return nil, fmt.Errorf("stopped watching user operation %s", userOpHash.Hex())
+		case result.Err != nil:
+			return nil, result.Err
+		case result.Dropped:
+			return nil, fmt.Errorf("%w: %s", ErrUserOperationDropped, userOpHash.Hex())
+		default:
+			return result.Receipt, nil
+		}
+	}
+}
+
+// unsubscribe removes a subscriber, and the watch itself when nothing else waits for its result
+func (w *ReceiptWatcher) unsubscribe(userOpHash common.Hash, ch <-chan ReceiptResult) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	watched, exists := w.watched[userOpHash]
+	if !exists {
+		return
+	}
+	for i, subscriber := range watched.subscribers {
+		if subscriber == ch {
+			watched.subscribers = append(watched.subscribers[:i], watched.subscribers[i+1:]...)
+			break
+		}
+	}
+	if len(watched.subscribers) == 0 && w.config.OnResult == nil {
+		delete(w.watched, userOpHash)
+	}
+}
+
+// Close stops polling. Subscribers of the user operations still watched receive ErrReceiptWatcherClosed.
+// The bundler is not closed.
+func (w *ReceiptWatcher) Close() {
+	w.closeOnce.Do(func() {
+		w.cancel()
+		<-w.done
+
+		w.mu.Lock()
+		w.closed = true
+		watched := w.watched
+		w.watched = make(map[common.Hash]*watchedUserOp)
+		w.mu.Unlock()
+
+		for userOpHash, op := range watched {
+			for _, ch := range op.subscribers {
+				ch <- ReceiptResult{UserOpHash: userOpHash, Err: ErrReceiptWatcherClosed}
+				close(ch)
+			}
+		}
+	})
+}
+
+func (w *ReceiptWatcher) loop() {
+	defer close(w.done)
+
+	timer := time.NewTimer(w.config.MaxPollInterval)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-w.ctx.Done():
+			return
+		case <-w.wake:
+		case <-timer.C:
+		}
+
+		w.pollDue(w.ctx)
+		timer.Reset(w.untilNextPoll())
+	}
+}
+
+// untilNextPoll returns the delay until the next user operation is due
+func (w *ReceiptWatcher) untilNextPoll() time.Duration {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	delay := w.config.MaxPollInterval
+	now := time.Now()
+	for _, watched := range w.watched {
+		if until := watched.nextPoll.Sub(now); until < delay {
+			delay = until
+		}
+	}
+	if delay < 0 {
+		delay = 0
+	}
+	return delay
+}
+
+// pollDue polls every user operation whose next poll is due
+func (w *ReceiptWatcher) pollDue(ctx context.Context) {
+	now := time.Now()
+
+	w.mu.Lock()
+	var due []common.Hash
+	for userOpHash, watched := range w.watched {
+		if !watched.nextPoll.After(now) {
+			due = append(due, userOpHash)
+		}
+	}
+	w.mu.Unlock()
+
+	sem := make(chan struct{}, w.config.Concurrency)
+	var wg sync.WaitGroup
+	for _, userOpHash := range due {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(userOpHash common.Hash) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			w.check(ctx, userOpHash)
+		}(userOpHash)
+	}
+	wg.Wait()
+}
+
+// check polls a user operation once and either delivers its result or schedules the next poll
+func (w *ReceiptWatcher) check(ctx context.Context, userOpHash common.Hash) {
+	result, err := w.lookup(ctx, userOpHash)
+	if ctx.Err() != nil {
+		return
+	}
+
+	w.mu.Lock()
+	watched, exists := w.watched[userOpHash]
+	if !exists {
+		w.mu.Unlock()
+		return
+	}
+
+	now := time.Now()
+	if errors.Is(err, ErrUserOperationNotTracked) {
+		if watched.untrackedPolls == 0 {
+			watched.untrackedSince = now
+		}
+		watched.untrackedPolls++
+		if watched.untrackedPolls >= untrackedDropPolls && now.Sub(watched.untrackedSince) >= w.config.UntrackedDropAfter {
+			result, err = &ReceiptResult{UserOpHash: userOpHash, Dropped: true}, nil
+		}
+	} else {
+		watched.untrackedPolls = 0
+	}
+
+	if result == nil && !watched.deadline.IsZero() && !now.Before(watched.deadline) {
+		timeoutErr := ErrReceiptTimeout
+		if err != nil {
+			timeoutErr = fmt.Errorf("%w: %w", ErrReceiptTimeout, err)
+		} else if watched.lastErr != nil {
+			timeoutErr = fmt.Errorf("%w: %w", ErrReceiptTimeout, watched.lastErr)
+		}
+		result = &ReceiptResult{UserOpHash: userOpHash, Err: timeoutErr}
+	}
+
+	if result == nil {
+		if err != nil {
+			watched.lastErr = err
+		}
+		watched.nextPoll = now.Add(watched.interval)
+		watched.interval = time.Duration(float64(watched.interval) * w.config.Multiplier)
+		if watched.interval > w.config.MaxPollInterval {
+			watched.interval = w.config.MaxPollInterval
+		}
+		w.mu.Unlock()
+		return
+	}
+
+	delete(w.watched, userOpHash)
+	w.mu.Unlock()
+
+	for _, ch := range watched.subscribers {
+		ch <- *result
+		close(ch)
+	}
+	if w.config.OnResult != nil {
+		w.config.OnResult(*result)
+	}
+}
+
+// lookup returns the result of a user operation, or nil if it is still pending
+func (w *ReceiptWatcher) lookup(ctx context.Context, userOpHash common.Hash) (*ReceiptResult, error) {
+	receipt, err := w.bundler.GetUserOperationReceipt(ctx, userOpHash)
+	if err != nil {
+		return nil, err
+	}
+
+	if receipt != nil {
+		if w.config.LogConfirmer != nil {
+			confirmed, err := w.confirm(ctx, receipt)
+			if err != nil || !confirmed {
+				return nil, err
+			}
+		}
+		return &ReceiptResult{UserOpHash: userOpHash, Receipt: receipt}, nil
+	}
+
+	// Receipt not found yet, check whether the bundler still knows the user operation
+	userOp, err := w.bundler.GetUserOperationByHash(ctx, userOpHash)
+	if err != nil {
+		return nil, err
+	}
+	if userOp == nil {
+		return &ReceiptResult{UserOpHash: userOpHash, Dropped: true}, nil
+	}
+	return nil, nil
+}
+
+// confirm looks for the UserOperationEvent of the receipt in its block and takes the success from the chain.
+// It returns false if the node does not have the event (yet), e.g. because it lags behind the bundler.
+func (w *ReceiptWatcher) confirm(ctx context.Context, receipt *UserOperationReceipt) (bool, error) {
+	if receipt.Receipt == nil {
+		return false, fmt.Errorf("receipt of user operation %s has no transaction receipt", receipt.UserOpHash.Hex())
+	}
+	blockNumber, err := hexutil.DecodeBig(receipt.Receipt.BlockNumber)
+	if err != nil {
+		return false, fmt.Errorf("invalid block number in receipt of user operation %s: %w", receipt.UserOpHash.Hex(), err)
+	}
+
+	logs, err := w.config.LogConfirmer.FilterLogs(ctx, ethereum.FilterQuery{
+		FromBlock: blockNumber,
+		ToBlock:   blockNumber,
+		Addresses: []common.Address{EntryPointV06, EntryPointV07, EntryPointV08},
+		Topics:    [][]common.Hash{{UserOperationEventTopic}, {receipt.UserOpHash}},
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to get user operation logs: %w", err)
+	}
+
+	for i := range logs {
+		if logs[i].Removed || logs[i].TxHash != receipt.Receipt.TransactionHash {
+			continue
+		}
+		event, err := ParseUserOperationEvent(&logs[i])
+		if err != nil {
+			return false, err
+		}
+		receipt.Success = event.Success
+		return true, nil
+	}
+	return false, nil
+}
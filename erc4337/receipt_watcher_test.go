@@ -0,0 +1,263 @@
+package erc4337
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeReceiptBundler knows pending user operations and returns the receipts of included ones
+type fakeReceiptBundler struct {
+	Bundler
+
+	mu       sync.Mutex
+	pending  map[common.Hash]bool
+	receipts map[common.Hash]*UserOperationReceipt
+	err      error
+	polls    int
+	// untracked answers ErrUserOperationNotTracked instead of null for user operations that are not pending
+	untracked bool
+}
+
+func newFakeReceiptBundler() *fakeReceiptBundler {
+	return &fakeReceiptBundler{pending: make(map[common.Hash]bool), receipts: make(map[common.Hash]*UserOperationReceipt)}
+}
+
+func (b *fakeReceiptBundler) include(userOpHash common.Hash, success bool) *UserOperationReceipt {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	receipt := &UserOperationReceipt{
+		UserOpHash: userOpHash,
+		Success:    success,
+		Receipt:    &UserOperationTransactionReceipt{BlockNumber: "0x10", TransactionHash: common.HexToHash("0xaa")},
+	}
+	delete(b.pending, userOpHash)
+	b.receipts[userOpHash] = receipt
+	return receipt
+}
+
+func (b *fakeReceiptBundler) pollCount() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.polls
+}
+
+func (b *fakeReceiptBundler) GetUserOperationReceipt(ctx context.Context, userOpHash common.Hash) (*UserOperationReceipt, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.polls++
+	if b.err != nil {
+		return nil, b.err
+	}
+	return b.receipts[userOpHash], nil
+}
+
+func (b *fakeReceiptBundler) GetUserOperationByHash(ctx context.Context, userOpHash common.Hash) (*UserOperationByHash, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.pending[userOpHash] {
+		if b.untracked {
+			return nil, ErrUserOperationNotTracked
+		}
+		return nil, nil
+	}
+	return &UserOperationByHash{}, nil
+}
+
+// fakeLogFilterer returns the logs added to it
+type fakeLogFilterer struct {
+	mu   sync.Mutex
+	logs []types.Log
+}
+
+func (f *fakeLogFilterer) FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]types.Log, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.logs, nil
+}
+
+func newTestReceiptWatcher(t *testing.T, bundler Bundler, config ReceiptWatcherConfig) *ReceiptWatcher {
+	if config.PollInterval == 0 {
+		config.PollInterval = 5 * time.Millisecond
+	}
+	watcher := NewReceiptWatcher(bundler, config)
+	t.Cleanup(watcher.Close)
+	return watcher
+}
+
+func receiveResult(t *testing.T, ch <-chan ReceiptResult) ReceiptResult {
+	select {
+	case result, ok := <-ch:
+		require.True(t, ok, "channel closed without a result")
+		return result
+	case <-time.After(5 * time.Second):
+		t.Fatal("no receipt result")
+		return ReceiptResult{}
+	}
+}
+
+func TestReceiptWatcher_Included(t *testing.T) {
+	bundler := newFakeReceiptBundler()
+	userOpHash := common.HexToHash("0x01")
+	bundler.pending[userOpHash] = true
+
+	var callbackResults []ReceiptResult
+	var callbackMu sync.Mutex
+	watcher := newTestReceiptWatcher(t, bundler, ReceiptWatcherConfig{
+		OnResult: func(result ReceiptResult) {
+			callbackMu.Lock()
+			defer callbackMu.Unlock()
+			callbackResults = append(callbackResults, result)
+		},
+	})
+
+	ch := watcher.Watch(userOpHash)
+	second := watcher.Watch(userOpHash)
+	assert.True(t, watcher.IsWatching(userOpHash))
+	assert.Equal(t, 1, watcher.Pending())
+
+	require.Eventually(t, func() bool { return bundler.pollCount() >= 2 }, 5*time.Second, time.Millisecond)
+	receipt := bundler.include(userOpHash, true)
+
+	result := receiveResult(t, ch)
+	assert.Equal(t, receipt, result.Receipt)
+	assert.False(t, result.Dropped)
+	assert.NoError(t, result.Err)
+	assert.Equal(t, receipt, receiveResult(t, second).Receipt)
+
+	assert.False(t, watcher.IsWatching(userOpHash))
+	callbackMu.Lock()
+	defer callbackMu.Unlock()
+	require.Len(t, callbackResults, 1)
+	assert.Equal(t, userOpHash, callbackResults[0].UserOpHash)
+}
+
+func TestReceiptWatcher_Dropped(t *testing.T) {
+	watcher := newTestReceiptWatcher(t, newFakeReceiptBundler(), ReceiptWatcherConfig{})
+
+	result := receiveResult(t, watcher.Watch(common.HexToHash("0x01")))
+	assert.True(t, result.Dropped)
+	assert.Nil(t, result.Receipt)
+
+	_, err := watcher.WaitForReceipt(context.Background(), common.HexToHash("0x02"))
+	assert.ErrorIs(t, err, ErrUserOperationDropped)
+}
+
+func TestReceiptWatcher_Untracked(t *testing.T) {
+	bundler := newFakeReceiptBundler()
+	bundler.untracked = true
+	userOpHash := common.HexToHash("0x01")
+
+	watcher := newTestReceiptWatcher(t, bundler, ReceiptWatcherConfig{MaxPollInterval: 5 * time.Millisecond, UntrackedDropAfter: 50 * time.Millisecond})
+	start := time.Now()
+	ch := watcher.Watch(userOpHash)
+
+	// Only reported dropped once the bundler could not vouch for it for a while
+	result := receiveResult(t, ch)
+	assert.True(t, result.Dropped)
+	assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+	assert.GreaterOrEqual(t, bundler.pollCount(), untrackedDropPolls)
+
+	// Seeing the user operation pending again resets the count
+	other := common.HexToHash("0x02")
+	watcher = newTestReceiptWatcher(t, bundler, ReceiptWatcherConfig{PollInterval: time.Hour, UntrackedDropAfter: time.Nanosecond})
+	watcher.mu.Lock()
+	watcher.watched[other] = &watchedUserOp{interval: time.Hour, nextPoll: time.Now().Add(time.Hour)}
+	watcher.mu.Unlock()
+	for range untrackedDropPolls - 1 {
+		watcher.check(context.Background(), other)
+	}
+	bundler.mu.Lock()
+	bundler.pending[other] = true
+	bundler.mu.Unlock()
+	watcher.check(context.Background(), other)
+	bundler.mu.Lock()
+	delete(bundler.pending, other)
+	bundler.mu.Unlock()
+	watcher.check(context.Background(), other)
+	assert.True(t, watcher.IsWatching(other))
+}
+
+func TestReceiptWatcher_Backoff(t *testing.T) {
+	bundler := newFakeReceiptBundler()
+	userOpHash := common.HexToHash("0x01")
+	bundler.pending[userOpHash] = true
+
+	// Long intervals so that only the first poll happens on its own
+	watcher := newTestReceiptWatcher(t, bundler, ReceiptWatcherConfig{PollInterval: time.Hour, MaxPollInterval: 3 * time.Hour})
+	watcher.Watch(userOpHash)
+	require.Eventually(t, func() bool { return bundler.pollCount() == 1 }, 5*time.Second, time.Millisecond)
+
+	interval := func() time.Duration {
+		watcher.mu.Lock()
+		defer watcher.mu.Unlock()
+		return watcher.watched[userOpHash].interval
+	}
+	require.Eventually(t, func() bool { return interval() == 2*time.Hour }, 5*time.Second, time.Millisecond)
+
+	watcher.check(context.Background(), userOpHash)
+	assert.Equal(t, 3*time.Hour, interval())
+	watcher.check(context.Background(), userOpHash)
+	assert.Equal(t, 3*time.Hour, interval())
+}
+
+func TestReceiptWatcher_Timeout(t *testing.T) {
+	bundler := newFakeReceiptBundler()
+	bundler.err = errors.New("connection refused")
+
+	watcher := newTestReceiptWatcher(t, bundler, ReceiptWatcherConfig{Timeout: 20 * time.Millisecond})
+
+	result := receiveResult(t, watcher.Watch(common.HexToHash("0x01")))
+	assert.ErrorIs(t, result.Err, ErrReceiptTimeout)
+	assert.ErrorContains(t, result.Err, "connection refused")
+}
+
+func TestReceiptWatcher_LogConfirmation(t *testing.T) {
+	bundler := newFakeReceiptBundler()
+	userOpHash := common.HexToHash("0x01")
+	receipt := bundler.include(userOpHash, true)
+
+	logs := &fakeLogFilterer{}
+	watcher := newTestReceiptWatcher(t, bundler, ReceiptWatcherConfig{LogConfirmer: logs})
+	ch := watcher.Watch(userOpHash)
+
+	// Not delivered while the node does not have the event
+	require.Eventually(t, func() bool { return bundler.pollCount() >= 2 }, 5*time.Second, time.Millisecond)
+	assert.True(t, watcher.IsWatching(userOpHash))
+
+	sender := common.HexToAddress("0x1234567890123456789012345678901234567890")
+	log := newEntryPointLog(t, "UserOperationEvent",
+		[]common.Hash{userOpHash, common.BytesToHash(sender.Bytes()), {}},
+		common.Big1, false, common.Big0, common.Big0)
+	log.TxHash = receipt.Receipt.TransactionHash
+	logs.mu.Lock()
+	logs.logs = []types.Log{*log}
+	logs.mu.Unlock()
+
+	// The success comes from the chain rather than the bundler
+	result := receiveResult(t, ch)
+	require.NotNil(t, result.Receipt)
+	assert.False(t, result.Receipt.Success)
+}
+
+func TestReceiptWatcher_Close(t *testing.T) {
+	bundler := newFakeReceiptBundler()
+	userOpHash := common.HexToHash("0x01")
+	bundler.pending[userOpHash] = true
+
+	watcher := NewReceiptWatcher(bundler, ReceiptWatcherConfig{PollInterval: time.Hour})
+	ch := watcher.Watch(userOpHash)
+	watcher.Close()
+	watcher.Close()
+
+	assert.ErrorIs(t, receiveResult(t, ch).Err, ErrReceiptWatcherClosed)
+	assert.ErrorIs(t, receiveResult(t, watcher.Watch(userOpHash)).Err, ErrReceiptWatcherClosed)
+}
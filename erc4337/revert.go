@@ -0,0 +1,74 @@
+package erc4337
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// RevertDecoder turns revert data into a readable reason.
+// Error(string) and Panic(uint256) are always decoded; custom errors are decoded when their ABI is registered.
+type RevertDecoder struct {
+	errors map[[4]byte]abi.Error
+}
+
+// NewRevertDecoder creates a decoder for the custom errors declared in the given contract ABIs
+func NewRevertDecoder(contractABIs ...abi.ABI) *RevertDecoder {
+	d := &RevertDecoder{errors: make(map[[4]byte]abi.Error)}
+	for _, contractABI := range contractABIs {
+		for _, abiErr := range contractABI.Errors {
+			var selector [4]byte
+			copy(selector[:], abiErr.ID[:4])
+			d.errors[selector] = abiErr
+		}
+	}
+	return d
+}
+
+// Decode returns a readable reason for the revert data.
+// Unknown revert data is returned as hex so that no information is lost.
+func (d *RevertDecoder) Decode(data []byte) string {
+	if len(data) == 0 {
+		return "empty revert data"
+	}
+
+	// Error(string) and Panic(uint256)
+	if reason, err := abi.UnpackRevert(data); err == nil {
+		return reason
+	}
+
+	if d != nil && len(data) >= 4 {
+		var selector [4]byte
+		copy(selector[:], data[:4])
+		if abiErr, ok := d.errors[selector]; ok {
+			return formatCustomError(abiErr, data)
+		}
+	}
+
+	return "unknown revert data: " + hexutil.Encode(data)
+}
+
+// formatCustomError formats a custom error as Name(arg1, arg2, ...)
+func formatCustomError(abiErr abi.Error, data []byte) string {
+	unpacked, err := abiErr.Unpack(data)
+	if err != nil {
+		return fmt.Sprintf("%s (failed to decode arguments: %s)", abiErr.Name, hexutil.Encode(data))
+	}
+
+	values, _ := unpacked.([]interface{})
+	args := make([]string, len(values))
+	for i, value := range values {
+		switch v := value.(type) {
+		case []byte:
+			args[i] = hexutil.Encode(v)
+		case fmt.Stringer:
+			args[i] = v.String()
+		default:
+			args[i] = fmt.Sprintf("%v", v)
+		}
+	}
+
+	return fmt.Sprintf("%s(%s)", abiErr.Name, strings.Join(args, ", "))
+}
@@ -0,0 +1,583 @@
+package erc4337
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const (
+	DefaultSelfBundlerGasLimitBufferPercent = 20
+	DefaultSelfBundlerLookbackBlocks        = 10_000
+
+	// Gas limits used by SelfBundler.EstimateUserOperationGas for the limits missing in the user operation
+	DefaultSelfBundlerPreVerificationGas            = 100_000
+	DefaultSelfBundlerVerificationGasLimit          = 500_000
+	DefaultSelfBundlerCallGasLimit                  = 500_000
+	DefaultSelfBundlerPaymasterVerificationGasLimit = 200_000
+
+	// Nodes only replace a pending transaction when both of its fees are raised by at least this much
+	selfBundlerReplacementBumpPercent = 10
+)
+
+// Errors of the EntryPoint reverting handleOps when a user operation fails validation
+const entryPointErrorsABI = `[
+	{"type":"error","name":"FailedOp","inputs":[{"name":"opIndex","type":"uint256"},{"name":"reason","type":"string"}]},
+	{"type":"error","name":"FailedOpWithRevert","inputs":[{"name":"opIndex","type":"uint256"},{"name":"reason","type":"string"},{"name":"inner","type":"bytes"}]}
+]`
+
+var entryPointErrors = mustParseABI(entryPointErrorsABI)
+
+// SelfBundlerBackend is the part of an Ethereum client used by a SelfBundler; *ethclient.Client implements it
+type SelfBundlerBackend interface {
+	BlockNumber(ctx context.Context) (uint64, error)
+	PendingNonceAt(ctx context.Context, account common.Address) (uint64, error)
+	EstimateGas(ctx context.Context, msg ethereum.CallMsg) (uint64, error)
+	SendTransaction(ctx context.Context, tx *types.Transaction) error
+	TransactionByHash(ctx context.Context, hash common.Hash) (tx *types.Transaction, isPending bool, err error)
+	TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error)
+	FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]types.Log, error)
+}
+
+// TransactionSigner signs the handleOps transactions of a SelfBundler with the executor key,
+// which may be held by a keystore or a remote signer
+type TransactionSigner interface {
+	// Address returns the executor address
+	Address() common.Address
+	// SignTransaction returns the transaction signed for the chain
+	SignTransaction(ctx context.Context, tx *types.Transaction, chainId *big.Int) (*types.Transaction, error)
+}
+
+// SelfBundlerConfig configures a SelfBundler. Zero values use the defaults above.
+type SelfBundlerConfig struct {
+	// Beneficiary receives the gas refund of the bundles; nil refunds the executor
+	Beneficiary *common.Address
+	// GasLimitBufferPercent is added to the estimated gas of the handleOps transaction
+	GasLimitBufferPercent uint64
+	// LookbackBlocks bounds the log search for user operations this bundler did not send itself,
+	// e.g. after a restart
+	LookbackBlocks uint64
+}
+
+type selfBundledOp struct {
+	op         UserOperation
+	entryPoint common.Address
+	txHash     common.Hash
+	sentAt     time.Time
+}
+
+// SelfBundler is a Bundler that sends each user operation to the EntryPoint itself, in a handleOps
+// transaction signed by a funded executor EOA. It is meant as a fallback when no bundler is available:
+// there is no mempool, no simulation beyond eth_estimateGas and no bundling of several user operations.
+//
+// Receipts are read from the UserOperationEvent logs of the EntryPoint, so user operations included
+// by any bundler can be looked up. Pending user operations are only known until the process restarts.
+type SelfBundler struct {
+	backend     SelfBundlerBackend
+	chainId     *big.Int
+	signer      TransactionSigner
+	address     common.Address
+	beneficiary common.Address
+	config      SelfBundlerConfig
+
+	// sendMu serializes sends so that concurrent transactions get consecutive nonces
+	sendMu sync.Mutex
+
+	sent map[common.Hash]selfBundledOp
+	mu   sync.Mutex
+}
+
+// NewSelfBundler creates a self bundler sending handleOps transactions signed by the executor on the given chain
+func NewSelfBundler(backend SelfBundlerBackend, chainId *big.Int, executor TransactionSigner, config SelfBundlerConfig) *SelfBundler {
+	if config.GasLimitBufferPercent == 0 {
+		config.GasLimitBufferPercent = DefaultSelfBundlerGasLimitBufferPercent
+	}
+	if config.LookbackBlocks == 0 {
+		config.LookbackBlocks = DefaultSelfBundlerLookbackBlocks
+	}
+
+	address := executor.Address()
+	beneficiary := address
+	if config.Beneficiary != nil {
+		beneficiary = *config.Beneficiary
+	}
+
+	return &SelfBundler{
+		backend:     backend,
+		chainId:     chainId,
+		signer:      executor,
+		address:     address,
+		beneficiary: beneficiary,
+		config:      config,
+		sent:        make(map[common.Hash]selfBundledOp),
+	}
+}
+
+// Address returns the executor address sending the handleOps transactions
+func (s *SelfBundler) Address() common.Address {
+	return s.address
+}
+
+func (s *SelfBundler) ChainId(ctx context.Context) (*big.Int, error) {
+	// The chain ID is fixed, but the node is still called so that pool health checks reflect its availability
+	if _, err := s.backend.BlockNumber(ctx); err != nil {
+		return nil, fmt.Errorf("failed to reach node: %w", err)
+	}
+	return new(big.Int).Set(s.chainId), nil
+}
+
+func (s *SelfBundler) SupportedEntryPoints(ctx context.Context) ([]common.Address, error) {
+	return []common.Address{EntryPointV08, EntryPointV07, EntryPointV06}, nil
+}
+
+// EstimateUserOperationGas returns the gas limits of the user operation, using the defaults above for missing ones.
+// Without a bundler there is no simulation to estimate them; handleOps is checked with eth_estimateGas when sending.
+func (s *SelfBundler) EstimateUserOperationGas(ctx context.Context, op *UserOperation, entryPoint common.Address) (*GasEstimates, error) {
+	version, err := GetEntryPointVersion(entryPoint)
+	if err != nil {
+		return nil, err
+	}
+
+	orDefault := func(value *hexutil.Big, fallback int64) *hexutil.Big {
+		if value == nil || value.ToInt().Sign() == 0 {
+			return (*hexutil.Big)(big.NewInt(fallback))
+		}
+		return (*hexutil.Big)(new(big.Int).Set(value.ToInt()))
+	}
+
+	estimates := &GasEstimates{
+		PreVerificationGas:   orDefault(op.PreVerificationGas, DefaultSelfBundlerPreVerificationGas),
+		VerificationGasLimit: orDefault(op.VerificationGasLimit, DefaultSelfBundlerVerificationGasLimit),
+		CallGasLimit:         orDefault(op.CallGasLimit, DefaultSelfBundlerCallGasLimit),
+	}
+	if op.Paymaster != nil && version != EntryPointVersionV06 {
+		estimates.PaymasterVerificationGasLimit = orDefault(op.PaymasterVerificationGasLimit, DefaultSelfBundlerPaymasterVerificationGasLimit)
+	}
+	return estimates, nil
+}
+
+// SendUserOperation signs and sends an EIP-1559 handleOps transaction for the user operation.
+// The transaction pays the user operation's gas fees, which the EntryPoint refunds to the beneficiary.
+// Validation failures are returned as a *BundlerError with the AAxx code, like a bundler would.
+//
+// A user operation with the sender and nonce of one whose transaction is still pending replaces it:
+// the transaction is sent again with the same executor nonce and fees raised enough for the node to accept it,
+// so that the replacement does not queue behind the stuck transaction.
+func (s *SelfBundler) SendUserOperation(ctx context.Context, op *UserOperation, entryPoint common.Address) (common.Hash, error) {
+	if op.MaxFeePerGas == nil || op.MaxPriorityFeePerGas == nil {
+		return common.Hash{}, errors.New("user operation has no gas fees")
+	}
+
+	userOpHash, err := op.GetUserOpHash(entryPoint, s.chainId)
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("failed to calculate user operation hash: %w", err)
+	}
+
+	calldata, err := EncodeHandleOpsForEntryPoint(entryPoint, []*UserOperation{op}, s.beneficiary)
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("failed to encode handleOps: %w", err)
+	}
+
+	gasFeeCap := new(big.Int).Set(op.MaxFeePerGas.ToInt())
+	gasTipCap := new(big.Int).Set(op.MaxPriorityFeePerGas.ToInt())
+
+	s.sendMu.Lock()
+	defer s.sendMu.Unlock()
+
+	var nonce uint64
+	replacedHash, replaced := s.pendingSameNonce(ctx, op, entryPoint)
+	if replaced != nil {
+		nonce = replaced.Nonce()
+		gasFeeCap = bigMax(gasFeeCap, bumpReplacementFee(replaced.GasFeeCap()))
+		gasTipCap = bigMax(gasTipCap, bumpReplacementFee(replaced.GasTipCap()))
+	} else {
+		nonce, err = s.backend.PendingNonceAt(ctx, s.address)
+		if err != nil {
+			return common.Hash{}, fmt.Errorf("failed to get executor nonce: %w", err)
+		}
+	}
+
+	gas, err := s.backend.EstimateGas(ctx, ethereum.CallMsg{
+		From:      s.address,
+		To:        &entryPoint,
+		GasFeeCap: gasFeeCap,
+		GasTipCap: gasTipCap,
+		Data:      calldata,
+	})
+	if err != nil {
+		return common.Hash{}, handleOpsError(err)
+	}
+	gas += gas * s.config.GasLimitBufferPercent / 100
+
+	tx, err := s.signer.SignTransaction(ctx, types.NewTx(&types.DynamicFeeTx{
+		ChainID:   s.chainId,
+		Nonce:     nonce,
+		GasTipCap: gasTipCap,
+		GasFeeCap: gasFeeCap,
+		Gas:       gas,
+		To:        &entryPoint,
+		Data:      calldata,
+	}), s.chainId)
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("failed to sign handleOps transaction: %w", err)
+	}
+
+	if err := s.backend.SendTransaction(ctx, tx); err != nil {
+		return common.Hash{}, fmt.Errorf("failed to send handleOps transaction: %w", err)
+	}
+
+	if replaced != nil && replacedHash != userOpHash {
+		s.forgetSent(replacedHash)
+	}
+	s.rememberSent(userOpHash, selfBundledOp{op: *op, entryPoint: entryPoint, txHash: tx.Hash(), sentAt: time.Now()})
+	return userOpHash, nil
+}
+
+// GetUserOperationReceipt returns the receipt of a user operation once its handleOps transaction is mined,
+// or nil if it is not included yet
+func (s *SelfBundler) GetUserOperationReceipt(ctx context.Context, userOpHash common.Hash) (*UserOperationReceipt, error) {
+	included, err := s.findIncluded(ctx, userOpHash)
+	if err != nil || included == nil {
+		return nil, err
+	}
+	return included.userOperationReceipt(userOpHash)
+}
+
+// GetUserOperationByHash returns a pending user operation while its handleOps transaction is known to the node.
+// It returns nil once the transaction is dropped or mined without including the user operation, and
+// ErrUserOperationNotTracked for user operations it did not send, e.g. before a restart.
+func (s *SelfBundler) GetUserOperationByHash(ctx context.Context, userOpHash common.Hash) (*UserOperationByHash, error) {
+	included, err := s.findIncluded(ctx, userOpHash)
+	if err != nil {
+		return nil, err
+	}
+	if included != nil {
+		return included.userOperationByHash(userOpHash, s.chainId), nil
+	}
+
+	// The handleOps transaction of a user operation sent before a restart may still be pending
+	sent, tracked := s.sentOp(userOpHash)
+	if !tracked {
+		return nil, fmt.Errorf("%w: %s", ErrUserOperationNotTracked, userOpHash.Hex())
+	}
+
+	_, isPending, err := s.backend.TransactionByHash(ctx, sent.txHash)
+	if errors.Is(err, ethereum.NotFound) || (err == nil && !isPending) {
+		s.forgetSent(userOpHash)
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get handleOps transaction: %w", err)
+	}
+
+	op := sent.op
+	return &UserOperationByHash{
+		UserOperation:   &op,
+		EntryPoint:      sent.entryPoint,
+		TransactionHash: &sent.txHash,
+	}, nil
+}
+
+// Close does nothing: the backend is usually a shared node client and is closed by its owner
+func (s *SelfBundler) Close() {}
+
+func (s *SelfBundler) rememberSent(userOpHash common.Hash, sent selfBundledOp) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for hash, old := range s.sent {
+		if sent.sentAt.Sub(old.sentAt) > sentUserOpTTL {
+			delete(s.sent, hash)
+		}
+	}
+	s.sent[userOpHash] = sent
+}
+
+func (s *SelfBundler) sentOp(userOpHash common.Hash) (selfBundledOp, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sent, ok := s.sent[userOpHash]
+	return sent, ok
+}
+
+func (s *SelfBundler) forgetSent(userOpHash common.Hash) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sent, userOpHash)
+}
+
+// pendingSameNonce returns the pending handleOps transaction sent for a user operation with the sender and nonce of op,
+// or nil if there is none
+func (s *SelfBundler) pendingSameNonce(ctx context.Context, op *UserOperation, entryPoint common.Address) (common.Hash, *types.Transaction) {
+	if op.Nonce == nil {
+		return common.Hash{}, nil
+	}
+
+	s.mu.Lock()
+	candidates := make(map[common.Hash]common.Hash)
+	for userOpHash, sent := range s.sent {
+		if sent.entryPoint == entryPoint && sent.op.Sender == op.Sender &&
+			sent.op.Nonce != nil && sent.op.Nonce.ToInt().Cmp(op.Nonce.ToInt()) == 0 {
+			candidates[userOpHash] = sent.txHash
+		}
+	}
+	s.mu.Unlock()
+
+	for userOpHash, txHash := range candidates {
+		tx, isPending, err := s.backend.TransactionByHash(ctx, txHash)
+		if err == nil && isPending {
+			return userOpHash, tx
+		}
+	}
+	return common.Hash{}, nil
+}
+
+// bumpReplacementFee returns the lowest fee a node accepts to replace a transaction paying fee
+func bumpReplacementFee(fee *big.Int) *big.Int {
+	bumped := new(big.Int).Mul(fee, big.NewInt(100+selfBundlerReplacementBumpPercent))
+	return bumped.Div(bumped, big.NewInt(100))
+}
+
+func bigMax(a, b *big.Int) *big.Int {
+	if a.Cmp(b) >= 0 {
+		return a
+	}
+	return b
+}
+
+// includedUserOp is a user operation found in a mined bundle transaction
+type includedUserOp struct {
+	tx      *types.Transaction
+	from    common.Address
+	receipt *types.Receipt
+	event   int // index of the UserOperationEvent in receipt.Logs
+}
+
+// findIncluded returns the mined transaction that included a user operation, or nil if there is none.
+// Transactions sent by this bundler are looked up directly, others through the EntryPoint logs.
+func (s *SelfBundler) findIncluded(ctx context.Context, userOpHash common.Hash) (*includedUserOp, error) {
+	sent, tracked := s.sentOp(userOpHash)
+	txHash := sent.txHash
+	if !tracked {
+		var err error
+		txHash, err = s.findUserOperationEvent(ctx, userOpHash)
+		if err != nil || txHash == (common.Hash{}) {
+			return nil, err
+		}
+	}
+
+	receipt, err := s.backend.TransactionReceipt(ctx, txHash)
+	if errors.Is(err, ethereum.NotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bundle transaction receipt: %w", err)
+	}
+
+	event := -1
+	for i, log := range receipt.Logs {
+		if len(log.Topics) > 1 && log.Topics[0] == UserOperationEventTopic && log.Topics[1] == userOpHash {
+			event = i
+			break
+		}
+	}
+	if event < 0 {
+		// The bundle was mined without the user operation, e.g. because handleOps reverted
+		return nil, nil
+	}
+
+	tx, _, err := s.backend.TransactionByHash(ctx, txHash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bundle transaction: %w", err)
+	}
+	from, err := types.Sender(types.LatestSignerForChainID(s.chainId), tx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to recover bundle transaction sender: %w", err)
+	}
+
+	return &includedUserOp{tx: tx, from: from, receipt: receipt, event: event}, nil
+}
+
+// findUserOperationEvent returns the transaction hash of the UserOperationEvent of a user operation
+// in the recent blocks, or the zero hash if there is none
+func (s *SelfBundler) findUserOperationEvent(ctx context.Context, userOpHash common.Hash) (common.Hash, error) {
+	head, err := s.backend.BlockNumber(ctx)
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("failed to get block number: %w", err)
+	}
+	fromBlock := uint64(0)
+	if head > s.config.LookbackBlocks {
+		fromBlock = head - s.config.LookbackBlocks
+	}
+
+	logs, err := s.backend.FilterLogs(ctx, ethereum.FilterQuery{
+		FromBlock: new(big.Int).SetUint64(fromBlock),
+		Addresses: []common.Address{EntryPointV06, EntryPointV07, EntryPointV08},
+		Topics:    [][]common.Hash{{UserOperationEventTopic}, {userOpHash}},
+	})
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("failed to get user operation logs: %w", err)
+	}
+
+	for i := len(logs) - 1; i >= 0; i-- {
+		if !logs[i].Removed {
+			return logs[i].TxHash, nil
+		}
+	}
+	return common.Hash{}, nil
+}
+
+// userOperationReceipt builds the receipt of the user operation. Its logs are the ones emitted by the
+// user operation, i.e. after the previous UserOperationEvent of the bundle, up to and including its own.
+func (i *includedUserOp) userOperationReceipt(userOpHash common.Hash) (*UserOperationReceipt, error) {
+	event, err := ParseUserOperationEvent(i.receipt.Logs[i.event])
+	if err != nil {
+		return nil, err
+	}
+
+	start := 0
+	for j := i.event - 1; j >= 0; j-- {
+		if len(i.receipt.Logs[j].Topics) > 0 && i.receipt.Logs[j].Topics[0] == UserOperationEventTopic {
+			start = j + 1
+			break
+		}
+	}
+
+	effectiveGasPrice := "0x0"
+	if i.receipt.EffectiveGasPrice != nil {
+		effectiveGasPrice = hexutil.EncodeBig(i.receipt.EffectiveGasPrice)
+	}
+
+	return &UserOperationReceipt{
+		UserOpHash:    userOpHash,
+		Sender:        event.Sender,
+		Paymaster:     event.Paymaster,
+		Nonce:         hexutil.EncodeBig(event.Nonce),
+		Success:       event.Success,
+		ActualGasCost: hexutil.EncodeBig(event.ActualGasCost),
+		ActualGasUsed: hexutil.EncodeBig(event.ActualGasUsed),
+		From:          event.Sender,
+		Receipt: &UserOperationTransactionReceipt{
+			BlockHash:         i.receipt.BlockHash,
+			BlockNumber:       hexutil.EncodeBig(i.receipt.BlockNumber),
+			From:              i.from,
+			CumulativeGasUsed: hexutil.EncodeUint64(i.receipt.CumulativeGasUsed),
+			GasUsed:           hexutil.EncodeUint64(i.receipt.GasUsed),
+			Logs:              i.receipt.Logs,
+			LogsBloom:         i.receipt.Bloom,
+			TransactionHash:   i.receipt.TxHash,
+			TransactionIndex:  hexutil.EncodeUint64(uint64(i.receipt.TransactionIndex)),
+			EffectiveGasPrice: effectiveGasPrice,
+		},
+		Logs: i.receipt.Logs[start : i.event+1],
+	}, nil
+}
+
+// userOperationByHash returns the included user operation, decoded from the handleOps calldata.
+// UserOperation is nil if the bundle was not a direct handleOps call to the EntryPoint.
+func (i *includedUserOp) userOperationByHash(userOpHash common.Hash, chainId *big.Int) *UserOperationByHash {
+	blockHash := i.receipt.BlockHash
+	txHash := i.receipt.TxHash
+	result := &UserOperationByHash{
+		EntryPoint:      i.receipt.Logs[i.event].Address,
+		BlockNumber:     (*hexutil.Big)(i.receipt.BlockNumber),
+		BlockHash:       &blockHash,
+		TransactionHash: &txHash,
+	}
+
+	if handleOps, err := DecodeHandleOps(i.tx.Data()); err == nil {
+		if hashes, err := handleOps.UserOpHashes(result.EntryPoint, chainId); err == nil {
+			for j, hash := range hashes {
+				if hash == userOpHash {
+					result.UserOperation = handleOps.Ops[j]
+					break
+				}
+			}
+		}
+	}
+	return result
+}
+
+// selfBundlerRPCError is the JSON-RPC error a bundler would return for a user operation rejected by the EntryPoint
+type selfBundlerRPCError struct {
+	code    int
+	message string
+}
+
+func (e *selfBundlerRPCError) Error() string  { return e.message }
+func (e *selfBundlerRPCError) ErrorCode() int { return e.code }
+
+// handleOpsError converts a FailedOp revert of handleOps into a *BundlerError carrying the AAxx reason
+func handleOpsError(err error) error {
+	if reason, ok := failedOpReason(err); ok {
+		code := ErrCodeSimulateValidation
+		if strings.HasPrefix(reason, "AA3") {
+			code = ErrCodeSimulatePaymasterValidation
+		}
+		return NewBundlerError("eth_sendUserOperation", &selfBundlerRPCError{code: code, message: reason})
+	}
+	return fmt.Errorf("failed to estimate handleOps gas: %w", err)
+}
+
+// failedOpReason returns the reason of a FailedOp or FailedOpWithRevert error in the revert data of an RPC error
+func failedOpReason(err error) (string, bool) {
+	revert, ok := revertData(err)
+	if !ok {
+		return "", false
+	}
+	reason, _, ok := decodeFailedOp(revert)
+	return reason, ok
+}
+
+// revertData returns the revert data carried by the RPC error of a reverted eth_call or eth_estimateGas
+func revertData(err error) ([]byte, bool) {
+	var dataErr rpc.DataError
+	if !errors.As(err, &dataErr) {
+		return nil, false
+	}
+	data, ok := dataErr.ErrorData().(string)
+	if !ok {
+		return nil, false
+	}
+	revert, err := hexutil.Decode(data)
+	if err != nil {
+		return nil, false
+	}
+	return revert, true
+}
+
+// decodeFailedOp decodes a FailedOp or FailedOpWithRevert error.
+// inner is the revert data of the account or paymaster for FailedOpWithRevert, nil for FailedOp.
+func decodeFailedOp(revert []byte) (reason string, inner []byte, ok bool) {
+	if len(revert) < 4 {
+		return "", nil, false
+	}
+
+	for _, name := range []string{"FailedOp", "FailedOpWithRevert"} {
+		abiErr := entryPointErrors.Errors[name]
+		if !bytes.Equal(revert[:4], abiErr.ID[:4]) {
+			continue
+		}
+		unpacked, err := abiErr.Unpack(revert)
+		if err != nil {
+			return "", nil, false
+		}
+		values := unpacked.([]interface{})
+		if len(values) > 2 {
+			inner = values[2].([]byte)
+		}
+		return values[1].(string), inner, true
+	}
+	return "", nil, false
+}
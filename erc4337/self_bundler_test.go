@@ -0,0 +1,391 @@
+package erc4337
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"math/big"
+	"sync"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSelfBundlerBackend is an in-memory node that keeps sent transactions pending until they are mined
+type fakeSelfBundlerBackend struct {
+	mu          sync.Mutex
+	head        uint64
+	nonce       uint64
+	gas         uint64
+	estimateErr error
+	txs         map[common.Hash]*types.Transaction
+	receipts    map[common.Hash]*types.Receipt
+	logs        []types.Log
+}
+
+func newFakeSelfBundlerBackend() *fakeSelfBundlerBackend {
+	return &fakeSelfBundlerBackend{
+		head:     100,
+		gas:      200000,
+		txs:      make(map[common.Hash]*types.Transaction),
+		receipts: make(map[common.Hash]*types.Receipt),
+	}
+}
+
+func (b *fakeSelfBundlerBackend) BlockNumber(ctx context.Context) (uint64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.head, nil
+}
+
+func (b *fakeSelfBundlerBackend) PendingNonceAt(ctx context.Context, account common.Address) (uint64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.nonce, nil
+}
+
+func (b *fakeSelfBundlerBackend) EstimateGas(ctx context.Context, msg ethereum.CallMsg) (uint64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.gas, b.estimateErr
+}
+
+// SendTransaction replaces a pending transaction with the same nonce, like the node's transaction pool
+func (b *fakeSelfBundlerBackend) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for hash, pending := range b.txs {
+		if _, mined := b.receipts[hash]; !mined && pending.Nonce() == tx.Nonce() {
+			delete(b.txs, hash)
+		}
+	}
+	b.txs[tx.Hash()] = tx
+	if tx.Nonce() == b.nonce {
+		b.nonce++
+	}
+	return nil
+}
+
+func (b *fakeSelfBundlerBackend) TransactionByHash(ctx context.Context, hash common.Hash) (*types.Transaction, bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	tx, ok := b.txs[hash]
+	if !ok {
+		return nil, false, ethereum.NotFound
+	}
+	_, mined := b.receipts[hash]
+	return tx, !mined, nil
+}
+
+func (b *fakeSelfBundlerBackend) TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	receipt, ok := b.receipts[txHash]
+	if !ok {
+		return nil, ethereum.NotFound
+	}
+	return receipt, nil
+}
+
+func (b *fakeSelfBundlerBackend) FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]types.Log, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var logs []types.Log
+	for _, log := range b.logs {
+		if log.Topics[0] == q.Topics[0][0] && log.Topics[1] == q.Topics[1][0] && log.BlockNumber >= q.FromBlock.Uint64() {
+			logs = append(logs, log)
+		}
+	}
+	return logs, nil
+}
+
+// lastTx returns the only transaction sent so far
+func (b *fakeSelfBundlerBackend) lastTx(t *testing.T) *types.Transaction {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	require.Len(t, b.txs, 1)
+	for _, tx := range b.txs {
+		return tx
+	}
+	return nil
+}
+
+// mine includes a transaction in the next block with the given logs
+func (b *fakeSelfBundlerBackend) mine(tx *types.Transaction, logs ...*types.Log) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.head++
+	blockHash := crypto.Keccak256Hash(new(big.Int).SetUint64(b.head).Bytes())
+	for i, log := range logs {
+		log.BlockNumber, log.BlockHash, log.TxHash, log.Index = b.head, blockHash, tx.Hash(), uint(i)
+		b.logs = append(b.logs, *log)
+	}
+	b.receipts[tx.Hash()] = &types.Receipt{
+		Type:              types.DynamicFeeTxType,
+		Status:            types.ReceiptStatusSuccessful,
+		CumulativeGasUsed: 150000,
+		GasUsed:           150000,
+		EffectiveGasPrice: big.NewInt(1000000000),
+		Logs:              logs,
+		TxHash:            tx.Hash(),
+		BlockHash:         blockHash,
+		BlockNumber:       new(big.Int).SetUint64(b.head),
+	}
+}
+
+func newUserOperationEventLog(t *testing.T, userOpHash common.Hash, op *UserOperation, success bool) *types.Log {
+	return newEntryPointLog(t, "UserOperationEvent",
+		[]common.Hash{userOpHash, common.BytesToHash(op.Sender.Bytes()), {}},
+		op.Nonce.ToInt(), success, big.NewInt(21000), big.NewInt(150000))
+}
+
+// testTransactionSigner signs with a key held in memory
+type testTransactionSigner struct {
+	key *ecdsa.PrivateKey
+}
+
+func (s *testTransactionSigner) Address() common.Address {
+	return crypto.PubkeyToAddress(s.key.PublicKey)
+}
+
+func (s *testTransactionSigner) SignTransaction(ctx context.Context, tx *types.Transaction, chainId *big.Int) (*types.Transaction, error) {
+	return types.SignTx(tx, types.LatestSignerForChainID(chainId), s.key)
+}
+
+func newTestSelfBundler(t *testing.T, backend SelfBundlerBackend) (*SelfBundler, common.Address) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	return NewSelfBundler(backend, big.NewInt(1), &testTransactionSigner{key: key}, SelfBundlerConfig{}), crypto.PubkeyToAddress(key.PublicKey)
+}
+
+func TestSelfBundler_SendUserOperation(t *testing.T) {
+	ctx := context.Background()
+	backend := newFakeSelfBundlerBackend()
+	bundler, executor := newTestSelfBundler(t, backend)
+	op := newHandleOpsTestUserOperation(false)
+
+	userOpHash, err := bundler.SendUserOperation(ctx, op, EntryPointV07)
+	require.NoError(t, err)
+	expectedHash, err := op.GetUserOpHash(EntryPointV07, big.NewInt(1))
+	require.NoError(t, err)
+	assert.Equal(t, expectedHash, userOpHash)
+
+	// An EIP-1559 handleOps transaction from the executor paying the user operation's fees
+	tx := backend.lastTx(t)
+	assert.Equal(t, uint8(types.DynamicFeeTxType), tx.Type())
+	assert.Equal(t, EntryPointV07, *tx.To())
+	assert.Equal(t, op.MaxFeePerGas.ToInt(), tx.GasFeeCap())
+	assert.Equal(t, op.MaxPriorityFeePerGas.ToInt(), tx.GasTipCap())
+	assert.Equal(t, uint64(240000), tx.Gas())
+	from, err := types.Sender(types.LatestSignerForChainID(big.NewInt(1)), tx)
+	require.NoError(t, err)
+	assert.Equal(t, executor, from)
+
+	handleOps, err := DecodeHandleOps(tx.Data())
+	require.NoError(t, err)
+	assert.Equal(t, executor, handleOps.Beneficiary)
+	require.Len(t, handleOps.Ops, 1)
+	assertUserOperationsEqual(t, op, handleOps.Ops[0])
+
+	// Pending until the transaction is mined
+	receipt, err := bundler.GetUserOperationReceipt(ctx, userOpHash)
+	require.NoError(t, err)
+	assert.Nil(t, receipt)
+	pending, err := bundler.GetUserOperationByHash(ctx, userOpHash)
+	require.NoError(t, err)
+	require.NotNil(t, pending)
+	assert.True(t, pending.IsPending())
+	assert.Equal(t, tx.Hash(), *pending.TransactionHash)
+
+	backend.mine(tx, newUserOperationEventLog(t, userOpHash, op, true))
+
+	receipt, err = bundler.GetUserOperationReceipt(ctx, userOpHash)
+	require.NoError(t, err)
+	require.NotNil(t, receipt)
+	assert.True(t, receipt.Success)
+	assert.Equal(t, op.Sender, receipt.Sender)
+	assert.Equal(t, hexutil.EncodeBig(op.Nonce.ToInt()), receipt.Nonce)
+	assert.Equal(t, "0x5208", receipt.ActualGasCost)
+	assert.Equal(t, executor, receipt.Receipt.From)
+	assert.Equal(t, tx.Hash(), receipt.Receipt.TransactionHash)
+	assert.Equal(t, "0x65", receipt.Receipt.BlockNumber)
+	assert.Len(t, receipt.Logs, 1)
+
+	included, err := bundler.GetUserOperationByHash(ctx, userOpHash)
+	require.NoError(t, err)
+	require.NotNil(t, included)
+	assert.False(t, included.IsPending())
+	assert.Equal(t, EntryPointV07, included.EntryPoint)
+	assertUserOperationsEqual(t, op, included.UserOperation)
+}
+
+func TestSelfBundler_FailedUserOperation(t *testing.T) {
+	ctx := context.Background()
+	backend := newFakeSelfBundlerBackend()
+	bundler, _ := newTestSelfBundler(t, backend)
+	op := newHandleOpsTestUserOperation(false)
+
+	userOpHash, err := bundler.SendUserOperation(ctx, op, EntryPointV07)
+	require.NoError(t, err)
+	backend.mine(backend.lastTx(t), newUserOperationEventLog(t, userOpHash, op, false))
+
+	receipt, err := bundler.GetUserOperationReceipt(ctx, userOpHash)
+	require.NoError(t, err)
+	require.NotNil(t, receipt)
+	assert.False(t, receipt.Success)
+}
+
+func TestSelfBundler_DroppedUserOperation(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("transaction dropped by the node", func(t *testing.T) {
+		backend := newFakeSelfBundlerBackend()
+		bundler, _ := newTestSelfBundler(t, backend)
+
+		userOpHash, err := bundler.SendUserOperation(ctx, newHandleOpsTestUserOperation(false), EntryPointV07)
+		require.NoError(t, err)
+		backend.mu.Lock()
+		backend.txs = make(map[common.Hash]*types.Transaction)
+		backend.mu.Unlock()
+
+		userOp, err := bundler.GetUserOperationByHash(ctx, userOpHash)
+		require.NoError(t, err)
+		assert.Nil(t, userOp)
+	})
+
+	t.Run("user operation sent before a restart", func(t *testing.T) {
+		bundler, _ := newTestSelfBundler(t, newFakeSelfBundlerBackend())
+
+		_, err := bundler.GetUserOperationByHash(ctx, common.HexToHash("0x01"))
+		assert.ErrorIs(t, err, ErrUserOperationNotTracked)
+	})
+
+	t.Run("transaction mined without the user operation", func(t *testing.T) {
+		backend := newFakeSelfBundlerBackend()
+		bundler, _ := newTestSelfBundler(t, backend)
+
+		userOpHash, err := bundler.SendUserOperation(ctx, newHandleOpsTestUserOperation(false), EntryPointV07)
+		require.NoError(t, err)
+		backend.mine(backend.lastTx(t))
+
+		receipt, err := bundler.GetUserOperationReceipt(ctx, userOpHash)
+		require.NoError(t, err)
+		assert.Nil(t, receipt)
+		userOp, err := bundler.GetUserOperationByHash(ctx, userOpHash)
+		require.NoError(t, err)
+		assert.Nil(t, userOp)
+	})
+}
+
+func TestSelfBundler_ReplaceUserOperation(t *testing.T) {
+	ctx := context.Background()
+	backend := newFakeSelfBundlerBackend()
+	bundler, _ := newTestSelfBundler(t, backend)
+
+	op := newHandleOpsTestUserOperation(false)
+	userOpHash, err := bundler.SendUserOperation(ctx, op, EntryPointV07)
+	require.NoError(t, err)
+	stuck := backend.lastTx(t)
+
+	// A replacement raising the fees by less than the node requires is sent with the fees the node accepts
+	replacement := newHandleOpsTestUserOperation(false)
+	replacement.MaxFeePerGas = (*hexutil.Big)(big.NewInt(1700000000))
+	replacement.MaxPriorityFeePerGas = (*hexutil.Big)(big.NewInt(200000000))
+	replacementHash, err := bundler.SendUserOperation(ctx, replacement, EntryPointV07)
+	require.NoError(t, err)
+	assert.NotEqual(t, userOpHash, replacementHash)
+
+	tx := backend.lastTx(t)
+	assert.Equal(t, stuck.Nonce(), tx.Nonce())
+	assert.Equal(t, big.NewInt(1760000000), tx.GasFeeCap())
+	assert.Equal(t, big.NewInt(200000000), tx.GasTipCap())
+	handleOps, err := DecodeHandleOps(tx.Data())
+	require.NoError(t, err)
+	assertUserOperationsEqual(t, replacement, handleOps.Ops[0])
+
+	pending, err := bundler.GetUserOperationByHash(ctx, replacementHash)
+	require.NoError(t, err)
+	require.NotNil(t, pending)
+	assert.Equal(t, tx.Hash(), *pending.TransactionHash)
+	_, err = bundler.GetUserOperationByHash(ctx, userOpHash)
+	assert.ErrorIs(t, err, ErrUserOperationNotTracked)
+
+	// Once the transaction is mined, the next user operation of the sender gets a new transaction
+	backend.mine(tx, newUserOperationEventLog(t, replacementHash, replacement, true))
+	next := newHandleOpsTestUserOperation(false)
+	next.Nonce = (*hexutil.Big)(new(big.Int).Add(op.Nonce.ToInt(), big.NewInt(1)))
+	_, err = bundler.SendUserOperation(ctx, next, EntryPointV07)
+	require.NoError(t, err)
+	backend.mu.Lock()
+	assert.Equal(t, uint64(2), backend.nonce)
+	backend.mu.Unlock()
+}
+
+func TestSelfBundler_ReceiptFromLogs(t *testing.T) {
+	ctx := context.Background()
+	backend := newFakeSelfBundlerBackend()
+	bundler, _ := newTestSelfBundler(t, backend)
+	op := newHandleOpsTestUserOperation(true)
+
+	userOpHash, err := bundler.SendUserOperation(ctx, op, EntryPointV07)
+	require.NoError(t, err)
+	backend.mine(backend.lastTx(t), newUserOperationEventLog(t, userOpHash, op, true))
+
+	// A restarted bundler no longer tracks the transaction and finds it through the EntryPoint logs
+	restarted, _ := newTestSelfBundler(t, backend)
+	receipt, err := restarted.GetUserOperationReceipt(ctx, userOpHash)
+	require.NoError(t, err)
+	require.NotNil(t, receipt)
+	assert.True(t, receipt.Success)
+
+	unknown, err := restarted.GetUserOperationReceipt(ctx, common.HexToHash("0x01"))
+	require.NoError(t, err)
+	assert.Nil(t, unknown)
+}
+
+func TestSelfBundler_ValidationFailure(t *testing.T) {
+	failedOp := entryPointErrors.Errors["FailedOp"]
+	args, err := failedOp.Inputs.Pack(big.NewInt(0), "AA21 didn't pay prefund")
+	require.NoError(t, err)
+
+	backend := newFakeSelfBundlerBackend()
+	backend.estimateErr = &testDataRPCError{
+		testRPCError: testRPCError{code: 3, message: "execution reverted"},
+		data:         hexutil.Encode(append(bytes.Clone(failedOp.ID[:4]), args...)),
+	}
+	bundler, _ := newTestSelfBundler(t, backend)
+
+	_, err = bundler.SendUserOperation(context.Background(), newHandleOpsTestUserOperation(false), EntryPointV07)
+	require.Error(t, err)
+
+	var bundlerErr *BundlerError
+	require.ErrorAs(t, err, &bundlerErr)
+	assert.Equal(t, ErrCodeSimulateValidation, bundlerErr.Code)
+	var aaErr *AAError
+	require.ErrorAs(t, err, &aaErr)
+	assert.Equal(t, "AA21", aaErr.Code)
+	assert.True(t, IsTransientBundlerError(err))
+	assert.Empty(t, backend.txs)
+}
+
+func TestSelfBundler_EstimateUserOperationGas(t *testing.T) {
+	bundler, _ := newTestSelfBundler(t, newFakeSelfBundlerBackend())
+
+	op := newHandleOpsTestUserOperation(true)
+	op.CallGasLimit = nil
+	estimates, err := bundler.EstimateUserOperationGas(context.Background(), op, EntryPointV07)
+	require.NoError(t, err)
+	assert.Equal(t, big.NewInt(DefaultSelfBundlerCallGasLimit), estimates.CallGasLimit.ToInt())
+	assert.Equal(t, op.VerificationGasLimit.ToInt(), estimates.VerificationGasLimit.ToInt())
+	assert.Equal(t, op.PaymasterVerificationGasLimit.ToInt(), estimates.PaymasterVerificationGasLimit.ToInt())
+
+	_, ok := Bundler(bundler).(DebugBundler)
+	assert.False(t, ok, "the self bundler has no debug namespace")
+}
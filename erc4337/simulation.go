@@ -0,0 +1,524 @@
+package erc4337
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// DefaultSimulationSender sends the simulated handleOps calls and receives their gas refund.
+// It has no code, which the EntryPoint requires of the handleOps caller.
+var DefaultSimulationSender = common.HexToAddress("0x000000000000000000000000000000000000dEaD")
+
+// errCodeMethodNotFound is the JSON-RPC error code of nodes that do not implement a method, e.g. eth_simulateV1
+const errCodeMethodNotFound = -32601
+
+// simulateValidation of EntryPoint v0.6, which always reverts with ValidationResult, ValidationResultWithAggregation or FailedOp
+const simulateValidationV06ABI = `[
+	{"type":"function","name":"simulateValidation","stateMutability":"nonpayable","outputs":[],"inputs":[
+		{"name":"userOp","type":"tuple","components":[
+			{"name":"sender","type":"address"},
+			{"name":"nonce","type":"uint256"},
+			{"name":"initCode","type":"bytes"},
+			{"name":"callData","type":"bytes"},
+			{"name":"callGasLimit","type":"uint256"},
+			{"name":"verificationGasLimit","type":"uint256"},
+			{"name":"preVerificationGas","type":"uint256"},
+			{"name":"maxFeePerGas","type":"uint256"},
+			{"name":"maxPriorityFeePerGas","type":"uint256"},
+			{"name":"paymasterAndData","type":"bytes"},
+			{"name":"signature","type":"bytes"}]}]},
+	{"type":"error","name":"ValidationResult","inputs":[
+		{"name":"returnInfo","type":"tuple","components":[
+			{"name":"preOpGas","type":"uint256"},
+			{"name":"prefund","type":"uint256"},
+			{"name":"sigFailed","type":"bool"},
+			{"name":"validAfter","type":"uint48"},
+			{"name":"validUntil","type":"uint48"},
+			{"name":"paymasterContext","type":"bytes"}]},
+		{"name":"senderInfo","type":"tuple","components":[{"name":"stake","type":"uint256"},{"name":"unstakeDelaySec","type":"uint256"}]},
+		{"name":"factoryInfo","type":"tuple","components":[{"name":"stake","type":"uint256"},{"name":"unstakeDelaySec","type":"uint256"}]},
+		{"name":"paymasterInfo","type":"tuple","components":[{"name":"stake","type":"uint256"},{"name":"unstakeDelaySec","type":"uint256"}]}]},
+	{"type":"error","name":"ValidationResultWithAggregation","inputs":[
+		{"name":"returnInfo","type":"tuple","components":[
+			{"name":"preOpGas","type":"uint256"},
+			{"name":"prefund","type":"uint256"},
+			{"name":"sigFailed","type":"bool"},
+			{"name":"validAfter","type":"uint48"},
+			{"name":"validUntil","type":"uint48"},
+			{"name":"paymasterContext","type":"bytes"}]},
+		{"name":"senderInfo","type":"tuple","components":[{"name":"stake","type":"uint256"},{"name":"unstakeDelaySec","type":"uint256"}]},
+		{"name":"factoryInfo","type":"tuple","components":[{"name":"stake","type":"uint256"},{"name":"unstakeDelaySec","type":"uint256"}]},
+		{"name":"paymasterInfo","type":"tuple","components":[{"name":"stake","type":"uint256"},{"name":"unstakeDelaySec","type":"uint256"}]},
+		{"name":"aggregatorInfo","type":"tuple","components":[
+			{"name":"aggregator","type":"address"},
+			{"name":"stakeInfo","type":"tuple","components":[{"name":"stake","type":"uint256"},{"name":"unstakeDelaySec","type":"uint256"}]}]}]}
+]`
+
+// simulateValidation of EntryPointSimulations v0.7 and v0.8, which returns the ValidationResult
+const simulateValidationABI = `[
+	{"type":"function","name":"simulateValidation","stateMutability":"nonpayable","inputs":[
+		{"name":"userOp","type":"tuple","components":[
+			{"name":"sender","type":"address"},
+			{"name":"nonce","type":"uint256"},
+			{"name":"initCode","type":"bytes"},
+			{"name":"callData","type":"bytes"},
+			{"name":"accountGasLimits","type":"bytes32"},
+			{"name":"preVerificationGas","type":"uint256"},
+			{"name":"gasFees","type":"bytes32"},
+			{"name":"paymasterAndData","type":"bytes"},
+			{"name":"signature","type":"bytes"}]}],
+	"outputs":[
+		{"name":"","type":"tuple","components":[
+			{"name":"returnInfo","type":"tuple","components":[
+				{"name":"preOpGas","type":"uint256"},
+				{"name":"prefund","type":"uint256"},
+				{"name":"accountValidationData","type":"uint256"},
+				{"name":"paymasterValidationData","type":"uint256"},
+				{"name":"paymasterContext","type":"bytes"}]},
+			{"name":"senderInfo","type":"tuple","components":[{"name":"stake","type":"uint256"},{"name":"unstakeDelaySec","type":"uint256"}]},
+			{"name":"factoryInfo","type":"tuple","components":[{"name":"stake","type":"uint256"},{"name":"unstakeDelaySec","type":"uint256"}]},
+			{"name":"paymasterInfo","type":"tuple","components":[{"name":"stake","type":"uint256"},{"name":"unstakeDelaySec","type":"uint256"}]},
+			{"name":"aggregatorInfo","type":"tuple","components":[
+				{"name":"aggregator","type":"address"},
+				{"name":"stakeInfo","type":"tuple","components":[{"name":"stake","type":"uint256"},{"name":"unstakeDelaySec","type":"uint256"}]}]}]}]}
+]`
+
+var (
+	simulateValidationV06 = mustParseABI(simulateValidationV06ABI)
+	simulateValidation    = mustParseABI(simulateValidationABI)
+)
+
+// stakeInfoABI mirrors the StakeInfo struct of the EntryPoint
+type stakeInfoABI struct {
+	Stake           *big.Int
+	UnstakeDelaySec *big.Int
+}
+
+// aggregatorStakeInfoABI mirrors the AggregatorStakeInfo struct of the EntryPoint
+type aggregatorStakeInfoABI struct {
+	Aggregator common.Address
+	StakeInfo  stakeInfoABI
+}
+
+// returnInfoV06ABI mirrors the ReturnInfo struct of EntryPoint v0.6
+type returnInfoV06ABI struct {
+	PreOpGas         *big.Int
+	Prefund          *big.Int
+	SigFailed        bool
+	ValidAfter       *big.Int
+	ValidUntil       *big.Int
+	PaymasterContext []byte
+}
+
+// validationResultABI mirrors the ValidationResult struct of EntryPointSimulations v0.7 and v0.8
+type validationResultABI struct {
+	ReturnInfo struct {
+		PreOpGas                *big.Int
+		Prefund                 *big.Int
+		AccountValidationData   *big.Int
+		PaymasterValidationData *big.Int
+		PaymasterContext        []byte
+	}
+	SenderInfo     stakeInfoABI
+	FactoryInfo    stakeInfoABI
+	PaymasterInfo  stakeInfoABI
+	AggregatorInfo aggregatorStakeInfoABI
+}
+
+// SimulationBackend is the JSON-RPC client of the node used to simulate user operations; *rpc.Client implements it
+type SimulationBackend interface {
+	CallContext(ctx context.Context, result interface{}, method string, args ...interface{}) error
+}
+
+// SimulatorConfig configures a Simulator
+type SimulatorConfig struct {
+	// SimulationsCode is the deployed bytecode of EntryPointSimulations by EntryPoint version. v0.7 and v0.8 have no
+	// simulation methods on-chain, so simulateValidation runs with this code overriding the EntryPoint code.
+	// Versions without code skip the validation simulation and only simulate handleOps.
+	SimulationsCode map[EntryPointVersion][]byte
+	// Sender calls the simulated handleOps; zero uses DefaultSimulationSender
+	Sender common.Address
+	// RevertDecoder decodes the revert reasons of the user operation execution; nil only decodes Error and Panic
+	RevertDecoder *RevertDecoder
+}
+
+// SimulatedValidation is the validation data returned by the EntryPoint simulateValidation
+type SimulatedValidation struct {
+	PreOpGas *big.Int
+	Prefund  *big.Int
+	// Packed validation data of the account and the paymaster, nil on v0.6 which returns it unpacked
+	AccountValidationData   *big.Int
+	PaymasterValidationData *big.Int
+	// SigFailed reports a signature failure of the account or the paymaster
+	SigFailed bool
+	// Aggregator is the signature aggregator of the account, zero without one
+	Aggregator common.Address
+	// ValidAfter and ValidUntil bound the time range of the user operation in unix seconds; ValidUntil 0 never expires
+	ValidAfter uint64
+	ValidUntil uint64
+	// PaymasterContext is passed by the EntryPoint to the paymaster postOp
+	PaymasterContext []byte
+}
+
+// SimulationResult is the outcome of simulating a signed user operation before sending it
+type SimulationResult struct {
+	// Validation is nil when the validation was not simulated, see SimulatorConfig.SimulationsCode
+	Validation *SimulatedValidation
+	// ExecutionSimulated reports whether the execution was simulated with eth_simulateV1.
+	// Nodes without eth_simulateV1 only catch validation failures of handleOps.
+	ExecutionSimulated bool
+	// Success reports whether the user operation would pass validation and, when simulated, execute successfully
+	Success bool
+	// RevertReason explains why the user operation would fail, empty on success
+	RevertReason string
+}
+
+// Err returns a *SimulationError when the user operation would fail, nil otherwise
+func (r *SimulationResult) Err() error {
+	if r.Success {
+		return nil
+	}
+	return &SimulationError{Reason: r.RevertReason, AA: ParseAAError(r.RevertReason)}
+}
+
+// SimulationError reports a user operation that would fail if it was sent.
+// Use errors.As with *AAError to get the EntryPoint validation failure, if any.
+type SimulationError struct {
+	Reason string
+	AA     *AAError
+}
+
+func (e *SimulationError) Error() string {
+	return "user operation simulation failed: " + e.Reason
+}
+
+// Unwrap returns the AA error if the reason contains one, so errors.As works with *AAError
+func (e *SimulationError) Unwrap() error {
+	if e.AA != nil {
+		return e.AA
+	}
+	return nil
+}
+
+// Transient reports whether the user operation may succeed if it is simulated again later, see AAError.Transient
+func (e *SimulationError) Transient() bool {
+	return e.AA != nil && e.AA.Transient()
+}
+
+// IsTransientSimulationError reports whether an error is a simulation failure that may not happen again,
+// such as an unfunded account. Errors that are not a SimulationError return false.
+func IsTransientSimulationError(err error) bool {
+	var simulationErr *SimulationError
+	return errors.As(err, &simulationErr) && simulationErr.Transient()
+}
+
+// Simulator checks that a signed user operation would succeed before it is sent to a bundler.
+// It runs the EntryPoint simulateValidation with eth_call, then handleOps with eth_simulateV1 to also catch
+// execution reverts that a bundler would only report in the receipt.
+type Simulator struct {
+	backend SimulationBackend
+	chainId *big.Int
+	config  SimulatorConfig
+}
+
+// NewSimulator creates a simulator using the node of the given chain
+func NewSimulator(backend SimulationBackend, chainId *big.Int, config SimulatorConfig) *Simulator {
+	if config.Sender == (common.Address{}) {
+		config.Sender = DefaultSimulationSender
+	}
+	return &Simulator{
+		backend: backend,
+		chainId: chainId,
+		config:  config,
+	}
+}
+
+// Simulate simulates the signed user operation against the given EntryPoint.
+// A user operation that would fail is reported in the result; errors are only returned when the simulation could not run.
+func (s *Simulator) Simulate(ctx context.Context, op *UserOperation, entryPoint common.Address) (*SimulationResult, error) {
+	version, err := GetEntryPointVersion(entryPoint)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &SimulationResult{}
+
+	result.Validation, result.RevertReason, err = s.simulateValidation(ctx, op, entryPoint, version)
+	if err != nil {
+		return nil, fmt.Errorf("failed to simulate validation: %w", err)
+	}
+	if result.Validation != nil && result.Validation.SigFailed {
+		result.RevertReason = "Validation failed: signature check failed"
+	}
+	if result.RevertReason != "" {
+		return result, nil
+	}
+
+	result.ExecutionSimulated, result.RevertReason, err = s.simulateHandleOps(ctx, op, entryPoint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to simulate handleOps: %w", err)
+	}
+	result.Success = result.RevertReason == ""
+
+	return result, nil
+}
+
+// simulateValidation runs simulateValidation and returns its validation data, or the reason of a validation failure
+func (s *Simulator) simulateValidation(ctx context.Context, op *UserOperation, entryPoint common.Address, version EntryPointVersion) (*SimulatedValidation, string, error) {
+	if version == EntryPointVersionV06 {
+		calldata, err := simulateValidationV06.Pack("simulateValidation", newUserOperationV06ABI(op.ToV06()))
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to pack simulateValidation: %w", err)
+		}
+
+		// simulateValidation of v0.6 reverts even when the validation succeeds
+		err = s.call(ctx, entryPoint, calldata, nil, new(hexutil.Bytes))
+		revert, ok := revertData(err)
+		if !ok {
+			if err == nil {
+				err = errors.New("simulateValidation did not revert")
+			}
+			return nil, "", err
+		}
+		return decodeValidationResultV06(revert, s.config.RevertDecoder)
+	}
+
+	code := s.config.SimulationsCode[version]
+	if len(code) == 0 {
+		return nil, "", nil
+	}
+
+	packed, err := newPackedUserOperationABI(op.PackUserOp())
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid user operation: %w", err)
+	}
+	calldata, err := simulateValidation.Pack("simulateValidation", packed)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to pack simulateValidation: %w", err)
+	}
+
+	var out hexutil.Bytes
+	overrides := map[common.Address]map[string]interface{}{
+		entryPoint: {"code": hexutil.Bytes(code)},
+	}
+	if err := s.call(ctx, entryPoint, calldata, overrides, &out); err != nil {
+		if reason, ok := simulationRevertReason(err, s.config.RevertDecoder); ok {
+			return nil, reason, nil
+		}
+		return nil, "", err
+	}
+
+	unpacked, err := simulateValidation.Unpack("simulateValidation", out)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to decode simulateValidation result: %w", err)
+	}
+	validationResult := abi.ConvertType(unpacked[0], new(validationResultABI)).(*validationResultABI)
+	returnInfo := validationResult.ReturnInfo
+
+	validation := &SimulatedValidation{
+		PreOpGas:                returnInfo.PreOpGas,
+		Prefund:                 returnInfo.Prefund,
+		AccountValidationData:   returnInfo.AccountValidationData,
+		PaymasterValidationData: returnInfo.PaymasterValidationData,
+		PaymasterContext:        returnInfo.PaymasterContext,
+	}
+
+	// The time range of the user operation is the intersection of the account and paymaster time ranges
+	for i, data := range []*big.Int{returnInfo.AccountValidationData, returnInfo.PaymasterValidationData} {
+		aggregator, validAfter, validUntil := parseValidationData(data)
+		switch {
+		case aggregator == sigFailedAggregator:
+			validation.SigFailed = true
+		case i == 0:
+			validation.Aggregator = aggregator
+		}
+		validation.ValidAfter = max(validation.ValidAfter, validAfter)
+		if validUntil != 0 && (validation.ValidUntil == 0 || validUntil < validation.ValidUntil) {
+			validation.ValidUntil = validUntil
+		}
+	}
+
+	return validation, "", nil
+}
+
+// decodeValidationResultV06 decodes the revert of simulateValidation of EntryPoint v0.6
+func decodeValidationResultV06(revert []byte, decoder *RevertDecoder) (*SimulatedValidation, string, error) {
+	if reason, inner, ok := decodeFailedOp(revert); ok {
+		return nil, failedOpMessage(reason, inner, decoder), nil
+	}
+
+	for _, name := range []string{"ValidationResult", "ValidationResultWithAggregation"} {
+		abiErr := simulateValidationV06.Errors[name]
+		if len(revert) < 4 || [4]byte(revert[:4]) != [4]byte(abiErr.ID[:4]) {
+			continue
+		}
+		unpacked, err := abiErr.Unpack(revert)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to decode %s: %w", name, err)
+		}
+		values := unpacked.([]interface{})
+		returnInfo := abi.ConvertType(values[0], new(returnInfoV06ABI)).(*returnInfoV06ABI)
+
+		validation := &SimulatedValidation{
+			PreOpGas:         returnInfo.PreOpGas,
+			Prefund:          returnInfo.Prefund,
+			SigFailed:        returnInfo.SigFailed,
+			ValidAfter:       returnInfo.ValidAfter.Uint64(),
+			ValidUntil:       returnInfo.ValidUntil.Uint64(),
+			PaymasterContext: returnInfo.PaymasterContext,
+		}
+		if len(values) > 4 {
+			validation.Aggregator = abi.ConvertType(values[4], new(aggregatorStakeInfoABI)).(*aggregatorStakeInfoABI).Aggregator
+		}
+		return validation, "", nil
+	}
+
+	return nil, "", fmt.Errorf("unexpected simulateValidation revert: %s", hexutil.Encode(revert))
+}
+
+// sigFailedAggregator is the aggregator value of validation data marking a signature failure
+var sigFailedAggregator = common.BigToAddress(common.Big1)
+
+// parseValidationData unpacks validation data: the aggregator in the low 160 bits, then validUntil and validAfter in 48 bits each
+func parseValidationData(data *big.Int) (aggregator common.Address, validAfter uint64, validUntil uint64) {
+	if data == nil {
+		return common.Address{}, 0, 0
+	}
+	const mask48 = 1<<48 - 1
+	aggregator = common.BigToAddress(data)
+	validUntil = new(big.Int).Rsh(data, 160).Uint64() & mask48
+	validAfter = new(big.Int).Rsh(data, 208).Uint64() & mask48
+	return aggregator, validAfter, validUntil
+}
+
+// simulatedBlock is a block in the result of eth_simulateV1
+type simulatedBlock struct {
+	Calls []simulatedCall `json:"calls"`
+}
+
+// simulatedCall is the result of a call simulated by eth_simulateV1
+type simulatedCall struct {
+	ReturnData hexutil.Bytes       `json:"returnData"`
+	Logs       []*types.Log        `json:"logs"`
+	Status     hexutil.Uint64      `json:"status"`
+	Error      *simulatedCallError `json:"error"`
+}
+
+// simulatedCallError is the error of a call reverted in eth_simulateV1, with the revert data as hex
+type simulatedCallError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    string `json:"data"`
+}
+
+// simulateHandleOps simulates handleOps with the user operation and returns the reason it would fail.
+// executed is false when the node has no eth_simulateV1 and only the validation of handleOps could be checked.
+func (s *Simulator) simulateHandleOps(ctx context.Context, op *UserOperation, entryPoint common.Address) (executed bool, reason string, err error) {
+	userOpHash, err := op.GetUserOpHash(entryPoint, s.chainId)
+	if err != nil {
+		return false, "", fmt.Errorf("failed to calculate user operation hash: %w", err)
+	}
+	calldata, err := EncodeHandleOpsForEntryPoint(entryPoint, []*UserOperation{op}, s.config.Sender)
+	if err != nil {
+		return false, "", err
+	}
+
+	var blocks []simulatedBlock
+	err = s.backend.CallContext(ctx, &blocks, "eth_simulateV1", map[string]interface{}{
+		"blockStateCalls": []interface{}{
+			map[string]interface{}{"calls": []interface{}{s.callArgs(entryPoint, calldata)}},
+		},
+	}, "latest")
+
+	var rpcErr rpc.Error
+	if errors.As(err, &rpcErr) && rpcErr.ErrorCode() == errCodeMethodNotFound {
+		// Without eth_simulateV1 the logs are not available, so only a reverting validation can be detected
+		if err := s.call(ctx, entryPoint, calldata, nil, new(hexutil.Bytes)); err != nil {
+			if reason, ok := simulationRevertReason(err, s.config.RevertDecoder); ok {
+				return false, reason, nil
+			}
+			return false, "", err
+		}
+		return false, "", nil
+	}
+	if err != nil {
+		return false, "", err
+	}
+	if len(blocks) != 1 || len(blocks[0].Calls) != 1 {
+		return false, "", fmt.Errorf("unexpected eth_simulateV1 result")
+	}
+
+	call := blocks[0].Calls[0]
+	if call.Status == 0 {
+		if call.Error == nil {
+			return true, "handleOps reverted", nil
+		}
+		revert, err := hexutil.Decode(call.Error.Data)
+		if err != nil || len(revert) == 0 {
+			return true, "handleOps reverted: " + call.Error.Message, nil
+		}
+		return true, revertMessage(revert, s.config.RevertDecoder), nil
+	}
+
+	// handleOps succeeds even when the execution of the user operation reverts, which only shows in its events
+	receipt := &UserOperationReceipt{UserOpHash: userOpHash, Logs: call.Logs}
+	events, err := receipt.DecodeEvents()
+	if err != nil {
+		return true, "", fmt.Errorf("failed to decode simulated events: %w", err)
+	}
+	if events.UserOperationEvent == nil {
+		return true, "User operation was not executed by handleOps", nil
+	}
+	receipt.Success = events.UserOperationEvent.Success
+	return true, receipt.FailureReason(s.config.RevertDecoder), nil
+}
+
+// call runs eth_call from the simulation sender on the latest block with optional state overrides
+func (s *Simulator) call(ctx context.Context, to common.Address, calldata []byte, overrides map[common.Address]map[string]interface{}, result *hexutil.Bytes) error {
+	if overrides != nil {
+		return s.backend.CallContext(ctx, result, "eth_call", s.callArgs(to, calldata), "latest", overrides)
+	}
+	return s.backend.CallContext(ctx, result, "eth_call", s.callArgs(to, calldata), "latest")
+}
+
+func (s *Simulator) callArgs(to common.Address, calldata []byte) map[string]interface{} {
+	return map[string]interface{}{
+		"from": s.config.Sender,
+		"to":   to,
+		"data": hexutil.Bytes(calldata),
+	}
+}
+
+// simulationRevertReason returns the reason of a reverted call, false when the error is not a revert
+func simulationRevertReason(err error, decoder *RevertDecoder) (string, bool) {
+	revert, ok := revertData(err)
+	if !ok || len(revert) == 0 {
+		return "", false
+	}
+	return revertMessage(revert, decoder), true
+}
+
+// revertMessage explains the revert data of a simulated EntryPoint call
+func revertMessage(revert []byte, decoder *RevertDecoder) string {
+	if reason, inner, ok := decodeFailedOp(revert); ok {
+		return failedOpMessage(reason, inner, decoder)
+	}
+	return "EntryPoint reverted: " + decoder.Decode(revert)
+}
+
+// failedOpMessage formats the reason of a FailedOp, with the inner revert reason of a FailedOpWithRevert
+func failedOpMessage(reason string, inner []byte, decoder *RevertDecoder) string {
+	message := "Validation failed: " + reason
+	if len(inner) > 0 {
+		message += ": " + decoder.Decode(inner)
+	}
+	return message
+}
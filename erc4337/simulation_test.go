@@ -0,0 +1,271 @@
+package erc4337
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSimulationBackend answers simulateValidation and handleOps calls with canned results
+type fakeSimulationBackend struct {
+	validation    []byte
+	validationErr error
+	handleOpsErr  error
+	blocks        []simulatedBlock
+	simulateErr   error
+
+	overrides []map[common.Address]map[string]interface{}
+	methods   []string
+}
+
+func (b *fakeSimulationBackend) CallContext(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+	b.methods = append(b.methods, method)
+
+	switch method {
+	case "eth_simulateV1":
+		if b.simulateErr != nil {
+			return b.simulateErr
+		}
+		*result.(*[]simulatedBlock) = b.blocks
+	case "eth_call":
+		if len(args) > 2 {
+			b.overrides = append(b.overrides, args[2].(map[common.Address]map[string]interface{}))
+		}
+		data := args[0].(map[string]interface{})["data"].(hexutil.Bytes)
+		if bytes.HasPrefix(data, HandleOpsSelector) || bytes.HasPrefix(data, HandleOpsV06Selector) {
+			return b.handleOpsErr
+		}
+		if b.validationErr != nil {
+			return b.validationErr
+		}
+		*result.(*hexutil.Bytes) = b.validation
+	}
+	return nil
+}
+
+func revertError(data []byte) error {
+	return &testDataRPCError{
+		testRPCError: testRPCError{code: 3, message: "execution reverted"},
+		data:         hexutil.Encode(data),
+	}
+}
+
+func packFailedOp(t *testing.T, reason string) []byte {
+	abiErr := entryPointErrors.Errors["FailedOp"]
+	args, err := abiErr.Inputs.Pack(common.Big0, reason)
+	require.NoError(t, err)
+	return append(bytes.Clone(abiErr.ID[:4]), args...)
+}
+
+// packValidationData packs an aggregator and a time range like the EntryPoint _packValidationData
+func packValidationData(aggregator common.Address, validAfter, validUntil uint64) *big.Int {
+	data := new(big.Int).SetBytes(aggregator.Bytes())
+	data.Or(data, new(big.Int).Lsh(new(big.Int).SetUint64(validUntil), 160))
+	return data.Or(data, new(big.Int).Lsh(new(big.Int).SetUint64(validAfter), 208))
+}
+
+func packValidationResult(t *testing.T, accountValidationData, paymasterValidationData *big.Int) []byte {
+	result := validationResultABI{
+		SenderInfo:     stakeInfoABI{Stake: common.Big0, UnstakeDelaySec: common.Big0},
+		FactoryInfo:    stakeInfoABI{Stake: common.Big0, UnstakeDelaySec: common.Big0},
+		PaymasterInfo:  stakeInfoABI{Stake: common.Big0, UnstakeDelaySec: common.Big0},
+		AggregatorInfo: aggregatorStakeInfoABI{StakeInfo: stakeInfoABI{Stake: common.Big0, UnstakeDelaySec: common.Big0}},
+	}
+	result.ReturnInfo.PreOpGas = big.NewInt(80000)
+	result.ReturnInfo.Prefund = big.NewInt(1000)
+	result.ReturnInfo.AccountValidationData = accountValidationData
+	result.ReturnInfo.PaymasterValidationData = paymasterValidationData
+	result.ReturnInfo.PaymasterContext = []byte{0xcc}
+
+	data, err := simulateValidation.Methods["simulateValidation"].Outputs.Pack(result)
+	require.NoError(t, err)
+	return data
+}
+
+// simulatedUserOperationLogs returns the logs of handleOps executing the user operation
+func simulatedUserOperationLogs(t *testing.T, op *UserOperation, entryPoint common.Address, success bool, revertReason []byte) []*types.Log {
+	userOpHash, err := op.GetUserOpHash(entryPoint, big.NewInt(1))
+	require.NoError(t, err)
+
+	var logs []*types.Log
+	if revertReason != nil {
+		logs = append(logs, newEntryPointLog(t, "UserOperationRevertReason",
+			[]common.Hash{userOpHash, common.BytesToHash(op.Sender.Bytes())},
+			op.Nonce.ToInt(), revertReason))
+	}
+	return append(logs, newEntryPointLog(t, "UserOperationEvent",
+		[]common.Hash{userOpHash, common.BytesToHash(op.Sender.Bytes()), {}},
+		op.Nonce.ToInt(), success, common.Big0, common.Big0))
+}
+
+func TestSimulator_Success(t *testing.T) {
+	op := newHandleOpsTestUserOperation(true)
+	aggregator := common.HexToAddress("0x3333333333333333333333333333333333333333")
+	backend := &fakeSimulationBackend{
+		validation: packValidationResult(t,
+			packValidationData(aggregator, 100, 2000),
+			packValidationData(common.Address{}, 200, 1000)),
+		blocks: []simulatedBlock{{Calls: []simulatedCall{{
+			Status: 1,
+			Logs:   simulatedUserOperationLogs(t, op, EntryPointV07, true, nil),
+		}}}},
+	}
+	code := []byte{0x60, 0x80}
+	simulator := NewSimulator(backend, big.NewInt(1), SimulatorConfig{
+		SimulationsCode: map[EntryPointVersion][]byte{EntryPointVersionV07: code},
+	})
+
+	result, err := simulator.Simulate(context.Background(), op, EntryPointV07)
+	require.NoError(t, err)
+
+	assert.True(t, result.Success)
+	assert.True(t, result.ExecutionSimulated)
+	assert.Empty(t, result.RevertReason)
+	require.NotNil(t, result.Validation)
+	assert.Equal(t, big.NewInt(80000), result.Validation.PreOpGas)
+	assert.Equal(t, aggregator, result.Validation.Aggregator)
+	assert.False(t, result.Validation.SigFailed)
+	assert.Equal(t, uint64(200), result.Validation.ValidAfter)
+	assert.Equal(t, uint64(1000), result.Validation.ValidUntil)
+	assert.Equal(t, []byte{0xcc}, result.Validation.PaymasterContext)
+
+	// The EntryPoint code is replaced by the simulation code
+	require.Len(t, backend.overrides, 1)
+	assert.Equal(t, hexutil.Bytes(code), backend.overrides[0][EntryPointV07]["code"])
+}
+
+func TestSimulator_ValidationFailure(t *testing.T) {
+	backend := &fakeSimulationBackend{validationErr: revertError(packFailedOp(t, "AA25 invalid account nonce"))}
+	simulator := NewSimulator(backend, big.NewInt(1), SimulatorConfig{
+		SimulationsCode: map[EntryPointVersion][]byte{EntryPointVersionV07: {0x60, 0x80}},
+	})
+
+	result, err := simulator.Simulate(context.Background(), newHandleOpsTestUserOperation(false), EntryPointV07)
+	require.NoError(t, err)
+
+	assert.False(t, result.Success)
+	assert.Equal(t, "Validation failed: AA25 invalid account nonce", result.RevertReason)
+	assert.Equal(t, []string{"eth_call"}, backend.methods)
+}
+
+func TestSimulator_ExecutionRevert(t *testing.T) {
+	op := newHandleOpsTestUserOperation(false)
+	backend := &fakeSimulationBackend{
+		blocks: []simulatedBlock{{Calls: []simulatedCall{{
+			Status: 1,
+			Logs:   simulatedUserOperationLogs(t, op, EntryPointV08, false, encodeRevert(t, "0x08c379a0", "string", "insufficient balance")),
+		}}}},
+	}
+	simulator := NewSimulator(backend, big.NewInt(1), SimulatorConfig{})
+
+	result, err := simulator.Simulate(context.Background(), op, EntryPointV08)
+	require.NoError(t, err)
+
+	// Without simulation code the validation is only checked by handleOps
+	assert.Nil(t, result.Validation)
+	assert.True(t, result.ExecutionSimulated)
+	assert.False(t, result.Success)
+	assert.Equal(t, "User operation reverted: insufficient balance", result.RevertReason)
+	assert.False(t, IsTransientSimulationError(result.Err()))
+}
+
+func TestSimulator_HandleOpsRevert(t *testing.T) {
+	backend := &fakeSimulationBackend{
+		blocks: []simulatedBlock{{Calls: []simulatedCall{{
+			Status: 0,
+			Error: &simulatedCallError{
+				Code:    3,
+				Message: "execution reverted",
+				Data:    hexutil.Encode(packFailedOp(t, "AA22 expired or not due")),
+			},
+		}}}},
+	}
+	simulator := NewSimulator(backend, big.NewInt(1), SimulatorConfig{})
+
+	result, err := simulator.Simulate(context.Background(), newHandleOpsTestUserOperation(false), EntryPointV07)
+	require.NoError(t, err)
+
+	assert.False(t, result.Success)
+	assert.Equal(t, "Validation failed: AA22 expired or not due", result.RevertReason)
+}
+
+func TestSimulator_WithoutSimulateV1(t *testing.T) {
+	backend := &fakeSimulationBackend{
+		simulateErr:  &testRPCError{code: errCodeMethodNotFound, message: "the method eth_simulateV1 does not exist/is not available"},
+		handleOpsErr: revertError(packFailedOp(t, "AA21 didn't pay prefund")),
+	}
+	simulator := NewSimulator(backend, big.NewInt(1), SimulatorConfig{})
+
+	result, err := simulator.Simulate(context.Background(), newHandleOpsTestUserOperation(false), EntryPointV07)
+	require.NoError(t, err)
+	assert.False(t, result.ExecutionSimulated)
+	assert.Equal(t, "Validation failed: AA21 didn't pay prefund", result.RevertReason)
+
+	// An unfunded account may be funded later
+	err = fmt.Errorf("failed to execute job: %w", result.Err())
+	assert.True(t, IsTransientSimulationError(err))
+	var aaErr *AAError
+	require.ErrorAs(t, err, &aaErr)
+	assert.Equal(t, "AA21", aaErr.Code)
+
+	// A handleOps call that does not revert passes, since its execution cannot be checked
+	backend.handleOpsErr = nil
+	result, err = simulator.Simulate(context.Background(), newHandleOpsTestUserOperation(false), EntryPointV07)
+	require.NoError(t, err)
+	assert.True(t, result.Success)
+	assert.False(t, result.ExecutionSimulated)
+	assert.NoError(t, result.Err())
+}
+
+func TestSimulator_V06(t *testing.T) {
+	abiErr := simulateValidationV06.Errors["ValidationResult"]
+	stake := stakeInfoABI{Stake: common.Big0, UnstakeDelaySec: common.Big0}
+	args, err := abiErr.Inputs.Pack(returnInfoV06ABI{
+		PreOpGas:         big.NewInt(60000),
+		Prefund:          big.NewInt(500),
+		SigFailed:        true,
+		ValidAfter:       big.NewInt(10),
+		ValidUntil:       big.NewInt(20),
+		PaymasterContext: []byte{},
+	}, stake, stake, stake)
+	require.NoError(t, err)
+
+	backend := &fakeSimulationBackend{validationErr: revertError(append(bytes.Clone(abiErr.ID[:4]), args...))}
+	simulator := NewSimulator(backend, big.NewInt(1), SimulatorConfig{})
+
+	result, err := simulator.Simulate(context.Background(), newHandleOpsTestUserOperation(false), EntryPointV06)
+	require.NoError(t, err)
+
+	// A signature failure is reported without simulating handleOps
+	assert.False(t, result.Success)
+	assert.Equal(t, "Validation failed: signature check failed", result.RevertReason)
+	assert.Equal(t, []string{"eth_call"}, backend.methods)
+
+	require.NotNil(t, result.Validation)
+	assert.Equal(t, big.NewInt(60000), result.Validation.PreOpGas)
+	assert.True(t, result.Validation.SigFailed)
+	assert.Equal(t, uint64(10), result.Validation.ValidAfter)
+	assert.Equal(t, uint64(20), result.Validation.ValidUntil)
+	assert.Nil(t, result.Validation.AccountValidationData)
+	assert.Empty(t, backend.overrides)
+}
+
+func TestParseValidationData(t *testing.T) {
+	aggregator, validAfter, validUntil := parseValidationData(packValidationData(sigFailedAggregator, 1700000000, 1800000000))
+	assert.Equal(t, sigFailedAggregator, aggregator)
+	assert.Equal(t, uint64(1700000000), validAfter)
+	assert.Equal(t, uint64(1800000000), validUntil)
+
+	aggregator, validAfter, validUntil = parseValidationData(common.Big0)
+	assert.Equal(t, common.Address{}, aggregator)
+	assert.Zero(t, validAfter)
+	assert.Zero(t, validUntil)
+}
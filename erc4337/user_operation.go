@@ -11,9 +11,6 @@ import (
 	"github.com/ethereum/go-ethereum/crypto"
 )
 
-// EntryPointV07 address constant
-var EntryPointV07 = common.HexToAddress("0x0000000071727De22E5E9d8BAf0edAc6f37da032")
-
 // UserOperation represents the ERC-4337 user operation structure
 type UserOperation struct {
 	Sender                        common.Address  `json:"sender"`
@@ -389,9 +386,90 @@ func (uo *UserOperation) PackUserOp() *PackedUserOp {
 	return packed
 }
 
-// getUserOpHashV07 computes the user operation hash for ERC-4337 v0.7
+// UnpackUserOp unpacks a PackedUserOp into a UserOperation, the reverse of PackUserOp
+func (puo *PackedUserOp) UnpackUserOp() (*UserOperation, error) {
+	if len(puo.AccountGasLimits) != 32 {
+		return nil, fmt.Errorf("invalid accountGasLimits length: expected 32 bytes, got %d", len(puo.AccountGasLimits))
+	}
+	if len(puo.GasFees) != 32 {
+		return nil, fmt.Errorf("invalid gasFees length: expected 32 bytes, got %d", len(puo.GasFees))
+	}
+	if len(puo.InitCode) > 0 && len(puo.InitCode) < common.AddressLength {
+		return nil, fmt.Errorf("invalid initCode length: expected at least 20 bytes, got %d", len(puo.InitCode))
+	}
+	if len(puo.PaymasterAndData) > 0 && len(puo.PaymasterAndData) < 52 {
+		return nil, fmt.Errorf("invalid paymasterAndData length: expected at least 52 bytes, got %d", len(puo.PaymasterAndData))
+	}
+
+	// uint128 at the given offset of a packed field
+	unpackUint128 := func(packed []byte, offset int) *hexutil.Big {
+		return (*hexutil.Big)(new(big.Int).SetBytes(packed[offset : offset+16]))
+	}
+
+	uo := &UserOperation{
+		Sender:               puo.Sender,
+		Nonce:                (*hexutil.Big)(new(big.Int)),
+		CallData:             puo.CallData,
+		VerificationGasLimit: unpackUint128(puo.AccountGasLimits, 0),
+		CallGasLimit:         unpackUint128(puo.AccountGasLimits, 16),
+		PreVerificationGas:   (*hexutil.Big)(new(big.Int)),
+		MaxPriorityFeePerGas: unpackUint128(puo.GasFees, 0),
+		MaxFeePerGas:         unpackUint128(puo.GasFees, 16),
+		Signature:            puo.Signature,
+	}
+	if puo.Nonce != nil {
+		uo.Nonce = (*hexutil.Big)(new(big.Int).Set(puo.Nonce))
+	}
+	if puo.PreVerificationGas != nil {
+		uo.PreVerificationGas = (*hexutil.Big)(new(big.Int).Set(puo.PreVerificationGas))
+	}
+
+	// Unpack initCode (factory + factoryData)
+	if len(puo.InitCode) > 0 {
+		factory := common.BytesToAddress(puo.InitCode[:common.AddressLength])
+		uo.Factory = &factory
+		uo.FactoryData = puo.InitCode[common.AddressLength:]
+	}
+
+	// Unpack paymasterAndData (paymaster + paymasterVerificationGasLimit + paymasterPostOpGasLimit + paymasterData)
+	if len(puo.PaymasterAndData) > 0 {
+		paymaster := common.BytesToAddress(puo.PaymasterAndData[:common.AddressLength])
+		uo.Paymaster = &paymaster
+		uo.PaymasterVerificationGasLimit = unpackUint128(puo.PaymasterAndData, 20)
+		uo.PaymasterPostOpGasLimit = unpackUint128(puo.PaymasterAndData, 36)
+		uo.PaymasterData = puo.PaymasterAndData[52:]
+	}
+
+	return uo, nil
+}
+
+// GetUserOpHash computes the user operation hash for the version of the given EntryPoint
+func (uo *UserOperation) GetUserOpHash(entryPoint common.Address, chainId *big.Int) (common.Hash, error) {
+	version, err := GetEntryPointVersion(entryPoint)
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	switch version {
+	case EntryPointVersionV06:
+		return uo.ToV06().GetUserOpHash(entryPoint, chainId)
+	case EntryPointVersionV07:
+		return uo.getUserOpHashV07(entryPoint, chainId)
+	case EntryPointVersionV08:
+		return uo.GetUserOpHashV08(entryPoint, chainId)
+	default:
+		return common.Hash{}, fmt.Errorf("unsupported entry point version: %s", version)
+	}
+}
+
+// GetUserOpHashV07 computes the user operation hash for ERC-4337 v0.7
 func (uo *UserOperation) GetUserOpHashV07(chainId *big.Int) (common.Hash, error) {
-	packed := uo.PackUserOp()
+	return uo.getUserOpHashV07(EntryPointV07, chainId)
+}
+
+// encodePackedUserOp ABI-encodes the packed user operation fields that are covered by the hash,
+// with the dynamic fields (initCode, callData, paymasterAndData) replaced by their keccak256 hashes
+func encodePackedUserOp(packed *PackedUserOp) ([]byte, error) {
 	// Hash the initCode, callData, and paymasterAndData
 	hashedInitCode := crypto.Keccak256Hash(packed.InitCode)
 	hashedCallData := crypto.Keccak256Hash(packed.CallData)
@@ -408,7 +486,6 @@ func (uo *UserOperation) GetUserOpHashV07(chainId *big.Int) (common.Hash, error)
 	uint256Type, _ := abi.NewType("uint256", "", nil)
 	bytes32Type, _ := abi.NewType("bytes32", "", nil)
 
-	// First level encoding: encode the user operation data
 	userOpArgs := abi.Arguments{
 		{Type: addressType}, // sender
 		{Type: uint256Type}, // nonce
@@ -444,22 +521,37 @@ func (uo *UserOperation) GetUserOpHashV07(chainId *big.Int) (common.Hash, error)
 		hashedPaymasterAndData,
 	)
 	if err != nil {
-		return common.Hash{}, fmt.Errorf("failed to encode user operation: %v", err)
+		return nil, fmt.Errorf("failed to encode user operation: %v", err)
+	}
+
+	return userOpEncoded, nil
+}
+
+// getUserOpHashV07 computes the user operation hash for ERC-4337 v0.7
+func (uo *UserOperation) getUserOpHashV07(entryPoint common.Address, chainId *big.Int) (common.Hash, error) {
+	// First level encoding: encode the user operation data
+	userOpEncoded, err := encodePackedUserOp(uo.PackUserOp())
+	if err != nil {
+		return common.Hash{}, err
 	}
 
 	// Hash the encoded user operation
 	userOpHash := crypto.Keccak256Hash(userOpEncoded)
 
 	// Second level encoding: encode the hash with EntryPoint and chainId
+	addressType, _ := abi.NewType("address", "", nil)
+	uint256Type, _ := abi.NewType("uint256", "", nil)
+	bytes32Type, _ := abi.NewType("bytes32", "", nil)
+
 	finalArgs := abi.Arguments{
 		{Type: bytes32Type}, // userOpHash
-		{Type: addressType}, // EntryPointV07
+		{Type: addressType}, // entryPoint
 		{Type: uint256Type}, // chainId
 	}
 
 	finalEncoded, err := finalArgs.Pack(
 		userOpHash,
-		EntryPointV07,
+		entryPoint,
 		chainId,
 	)
 	if err != nil {
@@ -469,3 +561,47 @@ func (uo *UserOperation) GetUserOpHashV07(chainId *big.Int) (common.Hash, error)
 	// Return the final keccak256 hash
 	return crypto.Keccak256Hash(finalEncoded), nil
 }
+
+// packedUserOpTypeHash is keccak256 of the EIP-712 PackedUserOperation type used by EntryPoint v0.8
+var packedUserOpTypeHash = crypto.Keccak256Hash([]byte("PackedUserOperation(address sender,uint256 nonce,bytes initCode,bytes callData,bytes32 accountGasLimits,uint256 preVerificationGas,bytes32 gasFees,bytes paymasterAndData)"))
+
+// eip712DomainTypeHash is keccak256 of the EIP-712 domain type used by EntryPoint v0.8
+var eip712DomainTypeHash = crypto.Keccak256Hash([]byte("EIP712Domain(string name,string version,uint256 chainId,address verifyingContract)"))
+
+// GetUserOpHashV08 computes the user operation hash for ERC-4337 v0.8, which is the EIP-712
+// typed data hash of the packed user operation under the ("ERC4337", "1") domain of the EntryPoint
+func (uo *UserOperation) GetUserOpHashV08(entryPoint common.Address, chainId *big.Int) (common.Hash, error) {
+	userOpEncoded, err := encodePackedUserOp(uo.PackUserOp())
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	// All encoded fields are static, so abi.encode(typeHash, ...fields) is a plain concatenation
+	structHash := crypto.Keccak256Hash(packedUserOpTypeHash.Bytes(), userOpEncoded)
+
+	addressType, _ := abi.NewType("address", "", nil)
+	uint256Type, _ := abi.NewType("uint256", "", nil)
+	bytes32Type, _ := abi.NewType("bytes32", "", nil)
+
+	domainArgs := abi.Arguments{
+		{Type: bytes32Type}, // typeHash
+		{Type: bytes32Type}, // hashedName
+		{Type: bytes32Type}, // hashedVersion
+		{Type: uint256Type}, // chainId
+		{Type: addressType}, // verifyingContract
+	}
+
+	domainEncoded, err := domainArgs.Pack(
+		eip712DomainTypeHash,
+		crypto.Keccak256Hash([]byte("ERC4337")),
+		crypto.Keccak256Hash([]byte("1")),
+		chainId,
+		entryPoint,
+	)
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("failed to encode domain separator: %v", err)
+	}
+	domainSeparator := crypto.Keccak256Hash(domainEncoded)
+
+	return crypto.Keccak256Hash([]byte{0x19, 0x01}, domainSeparator.Bytes(), structHash.Bytes()), nil
+}
@@ -758,3 +758,97 @@ func TestGetUserOpHashV07_DifferentInputs(t *testing.T) {
 	require.NoError(t, err)
 	assert.NotEqual(t, baseHash, diffCallDataHash, "Different call data should produce different hash")
 }
+
+func TestGetUserOpHash_EntryPointVersions(t *testing.T) {
+	userOp := &UserOperation{
+		Sender:                        common.HexToAddress("0x1234567890123456789012345678901234567890"),
+		Nonce:                         (*hexutil.Big)(big.NewInt(123)),
+		Factory:                       addressPtr("0xabcdefabcdefabcdefabcdefabcdefabcdefabcd"),
+		FactoryData:                   hexutil.MustDecode("0x1234"),
+		CallData:                      hexutil.MustDecode("0x5678"),
+		CallGasLimit:                  (*hexutil.Big)(big.NewInt(1000000)),
+		VerificationGasLimit:          (*hexutil.Big)(big.NewInt(2000000)),
+		PreVerificationGas:            (*hexutil.Big)(big.NewInt(3000000)),
+		MaxPriorityFeePerGas:          (*hexutil.Big)(big.NewInt(1000000000)),
+		MaxFeePerGas:                  (*hexutil.Big)(big.NewInt(2000000000)),
+		Paymaster:                     addressPtr("0xfedcbafedcbafedcbafedcbafedcbafedcbafeda"),
+		PaymasterVerificationGasLimit: (*hexutil.Big)(big.NewInt(500000)),
+		PaymasterPostOpGasLimit:       (*hexutil.Big)(big.NewInt(100000)),
+		PaymasterData:                 hexutil.MustDecode("0x9abc"),
+		Signature:                     hexutil.MustDecode("0xdef0"),
+	}
+	chainId := big.NewInt(1)
+
+	tests := []struct {
+		name       string
+		entryPoint common.Address
+		expected   string
+	}{
+		{
+			name:       "v0.6",
+			entryPoint: EntryPointV06,
+			expected:   "0x6243c769fcd4e2d04593d4ff019d18408fae1b9e73389cf218fc8615671c11ad",
+		},
+		{
+			name:       "v0.7",
+			entryPoint: EntryPointV07,
+			expected:   "0xb66e9e4bba62aabfa3156c94be52aef13ba5396c582a28d27cd282c3fb4c80d3",
+		},
+		{
+			name:       "v0.8",
+			entryPoint: EntryPointV08,
+			expected:   "0xcea75ea1aeb0637df059741a30609c432d8d182c599a1798eb1da8fa64106de5",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hash, err := userOp.GetUserOpHash(tt.entryPoint, chainId)
+			require.NoError(t, err)
+			assert.Equal(t, common.HexToHash(tt.expected), hash)
+		})
+	}
+
+	// GetUserOpHashV07 must stay equivalent to the version-aware hash
+	v07Hash, err := userOp.GetUserOpHashV07(chainId)
+	require.NoError(t, err)
+	versionedHash, err := userOp.GetUserOpHash(EntryPointV07, chainId)
+	require.NoError(t, err)
+	assert.Equal(t, v07Hash, versionedHash)
+
+	// Unknown entry points are rejected
+	_, err = userOp.GetUserOpHash(common.HexToAddress("0x1111111111111111111111111111111111111111"), chainId)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unsupported entry point")
+}
+
+func TestUserOperation_ToV06(t *testing.T) {
+	userOp := &UserOperation{
+		Sender:                        common.HexToAddress("0x1234567890123456789012345678901234567890"),
+		Nonce:                         (*hexutil.Big)(big.NewInt(7)),
+		Factory:                       addressPtr("0xabcdefabcdefabcdefabcdefabcdefabcdefabcd"),
+		FactoryData:                   hexutil.MustDecode("0x1234"),
+		CallData:                      hexutil.MustDecode("0x5678"),
+		Paymaster:                     addressPtr("0xfedcbafedcbafedcbafedcbafedcbafedcbafeda"),
+		PaymasterVerificationGasLimit: (*hexutil.Big)(big.NewInt(500000)),
+		PaymasterData:                 hexutil.MustDecode("0x9abc"),
+		Signature:                     hexutil.MustDecode("0xdef0"),
+	}
+
+	op := userOp.ToV06()
+
+	assert.Equal(t, userOp.Sender, op.Sender)
+	assert.Equal(t, hexutil.MustDecode("0xabcdefabcdefabcdefabcdefabcdefabcdefabcd1234"), []byte(op.InitCode))
+	assert.Equal(t, hexutil.MustDecode("0xfedcbafedcbafedcbafedcbafedcbafedcbafeda9abc"), []byte(op.PaymasterAndData))
+	assert.Equal(t, big.NewInt(0), op.CallGasLimit.ToInt())
+	assert.Equal(t, big.NewInt(7), op.Nonce.ToInt())
+
+	data, err := json.Marshal(op)
+	require.NoError(t, err)
+
+	var result map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &result))
+	assert.Equal(t, "0x0", result["maxFeePerGas"])
+	assert.NotContains(t, result, "factory")
+	assert.NotContains(t, result, "paymasterVerificationGasLimit")
+}
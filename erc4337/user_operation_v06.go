@@ -0,0 +1,161 @@
+package erc4337
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// UserOperationV06 represents the ERC-4337 v0.6 user operation structure
+type UserOperationV06 struct {
+	Sender               common.Address `json:"sender"`
+	Nonce                *hexutil.Big   `json:"nonce"`
+	InitCode             hexutil.Bytes  `json:"initCode"`
+	CallData             hexutil.Bytes  `json:"callData"`
+	CallGasLimit         *hexutil.Big   `json:"callGasLimit"`
+	VerificationGasLimit *hexutil.Big   `json:"verificationGasLimit"`
+	PreVerificationGas   *hexutil.Big   `json:"preVerificationGas"`
+	MaxFeePerGas         *hexutil.Big   `json:"maxFeePerGas"`
+	MaxPriorityFeePerGas *hexutil.Big   `json:"maxPriorityFeePerGas"`
+	PaymasterAndData     hexutil.Bytes  `json:"paymasterAndData"`
+	Signature            hexutil.Bytes  `json:"signature"`
+}
+
+// ToV06 converts a UserOperation into the v0.6 format.
+// v0.6 has no separate paymaster gas limits, so paymasterAndData is paymaster + paymasterData.
+func (uo *UserOperation) ToV06() *UserOperationV06 {
+	// Helper function to default nil numeric fields to zero
+	orZero := func(v *hexutil.Big) *hexutil.Big {
+		if v == nil {
+			return (*hexutil.Big)(big.NewInt(0))
+		}
+		return v
+	}
+
+	op := &UserOperationV06{
+		Sender:               uo.Sender,
+		Nonce:                orZero(uo.Nonce),
+		InitCode:             uo.PackUserOp().InitCode,
+		CallData:             uo.CallData,
+		CallGasLimit:         orZero(uo.CallGasLimit),
+		VerificationGasLimit: orZero(uo.VerificationGasLimit),
+		PreVerificationGas:   orZero(uo.PreVerificationGas),
+		MaxFeePerGas:         orZero(uo.MaxFeePerGas),
+		MaxPriorityFeePerGas: orZero(uo.MaxPriorityFeePerGas),
+		PaymasterAndData:     hexutil.Bytes{},
+		Signature:            uo.Signature,
+	}
+
+	if op.CallData == nil {
+		op.CallData = hexutil.Bytes{}
+	}
+	if op.Signature == nil {
+		op.Signature = hexutil.Bytes{}
+	}
+
+	if uo.Paymaster != nil {
+		paymasterAndData := make([]byte, 0, 20+len(uo.PaymasterData))
+		paymasterAndData = append(paymasterAndData, uo.Paymaster.Bytes()...)
+		paymasterAndData = append(paymasterAndData, uo.PaymasterData...)
+		op.PaymasterAndData = paymasterAndData
+	}
+
+	return op
+}
+
+// ToUserOperation converts a v0.6 user operation back into the unpacked UserOperation format.
+// initCode is split into factory + factoryData and paymasterAndData into paymaster + paymasterData.
+func (uo *UserOperationV06) ToUserOperation() *UserOperation {
+	op := &UserOperation{
+		Sender:               uo.Sender,
+		Nonce:                uo.Nonce,
+		CallData:             uo.CallData,
+		CallGasLimit:         uo.CallGasLimit,
+		VerificationGasLimit: uo.VerificationGasLimit,
+		PreVerificationGas:   uo.PreVerificationGas,
+		MaxFeePerGas:         uo.MaxFeePerGas,
+		MaxPriorityFeePerGas: uo.MaxPriorityFeePerGas,
+		Signature:            uo.Signature,
+	}
+
+	if len(uo.InitCode) >= common.AddressLength {
+		factory := common.BytesToAddress(uo.InitCode[:common.AddressLength])
+		op.Factory = &factory
+		op.FactoryData = uo.InitCode[common.AddressLength:]
+	}
+
+	if len(uo.PaymasterAndData) >= common.AddressLength {
+		paymaster := common.BytesToAddress(uo.PaymasterAndData[:common.AddressLength])
+		op.Paymaster = &paymaster
+		op.PaymasterData = uo.PaymasterAndData[common.AddressLength:]
+	}
+
+	return op
+}
+
+// GetUserOpHash computes the user operation hash for ERC-4337 v0.6
+func (uo *UserOperationV06) GetUserOpHash(entryPoint common.Address, chainId *big.Int) (common.Hash, error) {
+	// Create ABI types for encoding
+	addressType, _ := abi.NewType("address", "", nil)
+	uint256Type, _ := abi.NewType("uint256", "", nil)
+	bytes32Type, _ := abi.NewType("bytes32", "", nil)
+
+	toBig := func(v *hexutil.Big) *big.Int {
+		if v == nil {
+			return big.NewInt(0)
+		}
+		return (*big.Int)(v)
+	}
+
+	// First level encoding: encode the user operation data
+	userOpArgs := abi.Arguments{
+		{Type: addressType}, // sender
+		{Type: uint256Type}, // nonce
+		{Type: bytes32Type}, // hashedInitCode
+		{Type: bytes32Type}, // hashedCallData
+		{Type: uint256Type}, // callGasLimit
+		{Type: uint256Type}, // verificationGasLimit
+		{Type: uint256Type}, // preVerificationGas
+		{Type: uint256Type}, // maxFeePerGas
+		{Type: uint256Type}, // maxPriorityFeePerGas
+		{Type: bytes32Type}, // hashedPaymasterAndData
+	}
+
+	userOpEncoded, err := userOpArgs.Pack(
+		uo.Sender,
+		toBig(uo.Nonce),
+		crypto.Keccak256Hash(uo.InitCode),
+		crypto.Keccak256Hash(uo.CallData),
+		toBig(uo.CallGasLimit),
+		toBig(uo.VerificationGasLimit),
+		toBig(uo.PreVerificationGas),
+		toBig(uo.MaxFeePerGas),
+		toBig(uo.MaxPriorityFeePerGas),
+		crypto.Keccak256Hash(uo.PaymasterAndData),
+	)
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("failed to encode user operation: %v", err)
+	}
+
+	// Second level encoding: encode the hash with EntryPoint and chainId
+	finalArgs := abi.Arguments{
+		{Type: bytes32Type}, // userOpHash
+		{Type: addressType}, // entryPoint
+		{Type: uint256Type}, // chainId
+	}
+
+	finalEncoded, err := finalArgs.Pack(
+		crypto.Keccak256Hash(userOpEncoded),
+		entryPoint,
+		chainId,
+	)
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("failed to encode final hash: %v", err)
+	}
+
+	return crypto.Keccak256Hash(finalEncoded), nil
+}
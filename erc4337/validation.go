@@ -0,0 +1,185 @@
+package erc4337
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// DefaultMaxUserOperationGas bounds the total gas of a user operation, which must fit in a bundle
+const DefaultMaxUserOperationGas = 30_000_000
+
+var (
+	maxUint128 = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 128), big.NewInt(1))
+	maxUint256 = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1))
+)
+
+// FieldError is a problem with one field of a user operation. Field is the JSON name of the field,
+// or empty if the problem concerns the user operation as a whole.
+type FieldError struct {
+	Field  string
+	Reason string
+}
+
+func (e FieldError) Error() string {
+	if e.Field == "" {
+		return e.Reason
+	}
+	return e.Field + ": " + e.Reason
+}
+
+// ValidationError lists every problem found by ValidateUserOperation
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	reasons := make([]string, len(e.Fields))
+	for i, field := range e.Fields {
+		reasons[i] = field.Error()
+	}
+	return "invalid user operation: " + strings.Join(reasons, "; ")
+}
+
+// ValidationOptions configures the checks of ValidateUserOperation that depend on the caller
+type ValidationOptions struct {
+	// Sender, if set, is the account the user operation must be sent from
+	Sender *common.Address
+	// SignatureSuffix, if set, must terminate the signature, e.g. a dummy signature replaced at execution time
+	SignatureSuffix []byte
+	// RequireNonceKey requires a non-zero 192-bit nonce key, e.g. one that selects a validator module
+	RequireNonceKey bool
+	// MaxGas bounds the sum of the gas limits; zero uses DefaultMaxUserOperationGas
+	MaxGas uint64
+}
+
+// ValidateUserOperation checks the structure, field sizes and gas bounds of a user operation without any RPC call.
+// It returns a *ValidationError listing every invalid field, or an error if the entry point is not supported.
+// Gas limits and fees may be zero since they are usually estimated right before sending.
+func ValidateUserOperation(op *UserOperation, entryPoint common.Address, opts ValidationOptions) error {
+	version, err := GetEntryPointVersion(entryPoint)
+	if err != nil {
+		return err
+	}
+
+	var fields []FieldError
+	invalid := func(field, format string, args ...interface{}) {
+		fields = append(fields, FieldError{Field: field, Reason: fmt.Sprintf(format, args...)})
+	}
+
+	// Sender
+	if op.Sender == (common.Address{}) {
+		invalid("sender", "must not be the zero address")
+	} else if opts.Sender != nil && op.Sender != *opts.Sender {
+		invalid("sender", "must be the account address %s", opts.Sender.Hex())
+	}
+
+	// Nonce: 192-bit key followed by a 64-bit sequence
+	switch {
+	case op.Nonce == nil:
+		invalid("nonce", "is required")
+	case op.Nonce.ToInt().Cmp(maxUint256) > 0:
+		invalid("nonce", "does not fit in uint256")
+	case opts.RequireNonceKey && new(big.Int).Rsh(op.Nonce.ToInt(), 64).Sign() == 0:
+		invalid("nonce", "must have a non-zero key in its upper 192 bits")
+	}
+
+	// Factory and factory data are set together
+	if op.Factory != nil && *op.Factory == (common.Address{}) {
+		invalid("factory", "must not be the zero address")
+	}
+	if op.Factory != nil && len(op.FactoryData) == 0 {
+		invalid("factoryData", "is required when factory is set")
+	}
+	if op.Factory == nil && len(op.FactoryData) > 0 {
+		invalid("factory", "is required when factoryData is set")
+	}
+
+	if len(op.CallData) < 4 {
+		invalid("callData", "must contain at least a function selector")
+	}
+
+	// Paymaster fields require a paymaster
+	if op.Paymaster != nil && *op.Paymaster == (common.Address{}) {
+		invalid("paymaster", "must not be the zero address")
+	}
+	if op.Paymaster == nil {
+		if len(op.PaymasterData) > 0 {
+			invalid("paymasterData", "requires a paymaster")
+		}
+		if op.PaymasterVerificationGasLimit != nil && op.PaymasterVerificationGasLimit.ToInt().Sign() != 0 {
+			invalid("paymasterVerificationGasLimit", "requires a paymaster")
+		}
+		if op.PaymasterPostOpGasLimit != nil && op.PaymasterPostOpGasLimit.ToInt().Sign() != 0 {
+			invalid("paymasterPostOpGasLimit", "requires a paymaster")
+		}
+	}
+
+	// Signature
+	if len(op.Signature) == 0 {
+		invalid("signature", "is required")
+	} else if len(opts.SignatureSuffix) > 0 && !bytes.HasSuffix(op.Signature, opts.SignatureSuffix) {
+		invalid("signature", "must end with the dummy signature %s", hexutil.Encode(opts.SignatureSuffix))
+	}
+
+	// Gas limits are packed as uint128 pairs since v0.7 and must fit in a bundle together
+	gasLimits := []struct {
+		field string
+		value *hexutil.Big
+	}{
+		{"callGasLimit", op.CallGasLimit},
+		{"verificationGasLimit", op.VerificationGasLimit},
+		{"preVerificationGas", op.PreVerificationGas},
+		{"paymasterVerificationGasLimit", op.PaymasterVerificationGasLimit},
+		{"paymasterPostOpGasLimit", op.PaymasterPostOpGasLimit},
+	}
+	maxGas := opts.MaxGas
+	if maxGas == 0 {
+		maxGas = DefaultMaxUserOperationGas
+	}
+	totalGas := new(big.Int)
+	for _, limit := range gasLimits {
+		if limit.value == nil {
+			continue
+		}
+		value := limit.value.ToInt()
+		if value.Sign() < 0 {
+			invalid(limit.field, "must not be negative")
+			continue
+		}
+		if value.Cmp(maxUint128) > 0 && version != EntryPointVersionV06 {
+			invalid(limit.field, "does not fit in uint128")
+			continue
+		}
+		totalGas.Add(totalGas, value)
+	}
+	if totalGas.Cmp(new(big.Int).SetUint64(maxGas)) > 0 {
+		invalid("", "total gas %s exceeds the maximum of %d", totalGas, maxGas)
+	}
+
+	// Fees
+	for _, fee := range []struct {
+		field string
+		value *hexutil.Big
+	}{
+		{"maxFeePerGas", op.MaxFeePerGas},
+		{"maxPriorityFeePerGas", op.MaxPriorityFeePerGas},
+	} {
+		if fee.value != nil && (fee.value.ToInt().Sign() < 0 || fee.value.ToInt().Cmp(maxUint128) > 0) {
+			invalid(fee.field, "must fit in uint128")
+		}
+	}
+	if op.MaxFeePerGas != nil && op.MaxPriorityFeePerGas != nil &&
+		op.MaxFeePerGas.ToInt().Sign() > 0 && op.MaxPriorityFeePerGas.ToInt().Cmp(op.MaxFeePerGas.ToInt()) > 0 {
+		invalid("maxPriorityFeePerGas", "must not exceed maxFeePerGas")
+	}
+
+	if len(fields) > 0 {
+		return &ValidationError{Fields: fields}
+	}
+	return nil
+}
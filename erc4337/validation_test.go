@@ -0,0 +1,101 @@
+package erc4337
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateUserOperation(t *testing.T) {
+	sender := common.HexToAddress("0x47d6a8a65cba9b61b194dac740aa192a7a1e91e1")
+	factory := common.HexToAddress("0x1111111111111111111111111111111111111111")
+	paymaster := common.HexToAddress("0x2222222222222222222222222222222222222222")
+	dummySignature := hexutil.Bytes{0xff, 0xff, 0x1c}
+	nonceWithKey, _ := new(big.Int).SetString("10000000000000000", 16)
+
+	validOp := func() *UserOperation {
+		return &UserOperation{
+			Sender:               sender,
+			Nonce:                (*hexutil.Big)(nonceWithKey),
+			CallData:             hexutil.Bytes{0xe9, 0xae, 0x5c, 0x53},
+			CallGasLimit:         (*hexutil.Big)(big.NewInt(100000)),
+			VerificationGasLimit: (*hexutil.Big)(big.NewInt(150000)),
+			PreVerificationGas:   (*hexutil.Big)(big.NewInt(50000)),
+			MaxFeePerGas:         (*hexutil.Big)(big.NewInt(2000000000)),
+			MaxPriorityFeePerGas: (*hexutil.Big)(big.NewInt(100000000)),
+			Signature:            hexutil.Bytes{0x00, 0x11, 0xff, 0xff, 0x1c},
+		}
+	}
+	opts := ValidationOptions{Sender: &sender, SignatureSuffix: dummySignature, RequireNonceKey: true}
+
+	tests := []struct {
+		name   string
+		modify func(op *UserOperation)
+		fields []string
+	}{
+		{"valid", func(op *UserOperation) {}, nil},
+		{"valid without gas values", func(op *UserOperation) {
+			op.CallGasLimit, op.VerificationGasLimit, op.PreVerificationGas = nil, nil, nil
+			op.MaxFeePerGas, op.MaxPriorityFeePerGas = nil, nil
+		}, nil},
+		{"valid with factory and paymaster", func(op *UserOperation) {
+			op.Factory, op.FactoryData = &factory, hexutil.Bytes{0x01}
+			op.Paymaster, op.PaymasterData = &paymaster, hexutil.Bytes{0x02}
+		}, nil},
+		{"zero sender", func(op *UserOperation) { op.Sender = common.Address{} }, []string{"sender"}},
+		{"sender is not the account", func(op *UserOperation) { op.Sender = factory }, []string{"sender"}},
+		{"missing nonce", func(op *UserOperation) { op.Nonce = nil }, []string{"nonce"}},
+		{"nonce without key", func(op *UserOperation) { op.Nonce = (*hexutil.Big)(big.NewInt(5)) }, []string{"nonce"}},
+		{"factory without factoryData", func(op *UserOperation) { op.Factory = &factory }, []string{"factoryData"}},
+		{"factoryData without factory", func(op *UserOperation) { op.FactoryData = hexutil.Bytes{0x01} }, []string{"factory"}},
+		{"empty callData", func(op *UserOperation) { op.CallData = nil }, []string{"callData"}},
+		{"paymaster fields without paymaster", func(op *UserOperation) {
+			op.PaymasterData = hexutil.Bytes{0x02}
+			op.PaymasterPostOpGasLimit = (*hexutil.Big)(big.NewInt(1))
+		}, []string{"paymasterData", "paymasterPostOpGasLimit"}},
+		{"missing signature", func(op *UserOperation) { op.Signature = nil }, []string{"signature"}},
+		{"signature without dummy suffix", func(op *UserOperation) { op.Signature = hexutil.Bytes{0x00, 0x11} }, []string{"signature"}},
+		{"gas limit over uint128", func(op *UserOperation) {
+			op.CallGasLimit = (*hexutil.Big)(new(big.Int).Lsh(big.NewInt(1), 128))
+		}, []string{"callGasLimit"}},
+		{"total gas over maximum", func(op *UserOperation) {
+			op.CallGasLimit = (*hexutil.Big)(big.NewInt(DefaultMaxUserOperationGas))
+		}, []string{""}},
+		{"priority fee over max fee", func(op *UserOperation) {
+			op.MaxPriorityFeePerGas = (*hexutil.Big)(big.NewInt(3000000000))
+		}, []string{"maxPriorityFeePerGas"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			op := validOp()
+			tt.modify(op)
+
+			err := ValidateUserOperation(op, EntryPointV07, opts)
+			if tt.fields == nil {
+				assert.NoError(t, err)
+				return
+			}
+
+			var validationErr *ValidationError
+			require.ErrorAs(t, err, &validationErr)
+			var fields []string
+			for _, fieldErr := range validationErr.Fields {
+				fields = append(fields, fieldErr.Field)
+			}
+			assert.Equal(t, tt.fields, fields)
+		})
+	}
+}
+
+func TestValidateUserOperation_UnsupportedEntryPoint(t *testing.T) {
+	err := ValidateUserOperation(&UserOperation{}, common.HexToAddress("0x1234"), ValidationOptions{})
+	require.Error(t, err)
+
+	var validationErr *ValidationError
+	assert.NotErrorAs(t, err, &validationErr)
+}
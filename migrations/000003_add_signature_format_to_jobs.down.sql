@@ -0,0 +1,2 @@
+-- Drop the signature_format column
+ALTER TABLE jobs DROP COLUMN signature_format;
@@ -0,0 +1,2 @@
+-- Add signature_format column to the jobs table; existing jobs use the personal_sign format
+ALTER TABLE jobs ADD COLUMN signature_format VARCHAR(32) NOT NULL DEFAULT 'personal_sign';
@@ -0,0 +1,8 @@
+-- Restore the unique constraint without the module version
+ALTER TABLE jobs DROP CONSTRAINT jobs_account_address_chain_id_on_chain_job_id_job_type_module_version_key;
+
+ALTER TABLE jobs ADD CONSTRAINT jobs_account_address_chain_id_on_chain_job_id_job_type_key
+    UNIQUE(account_address, chain_id, on_chain_job_id, job_type);
+
+-- Drop the module_version column
+ALTER TABLE jobs DROP COLUMN module_version;
@@ -0,0 +1,8 @@
+-- Add module_version column to the jobs table; existing jobs were registered against the v1 module deployments
+ALTER TABLE jobs ADD COLUMN module_version VARCHAR(32) NOT NULL DEFAULT 'v1';
+
+-- On-chain job IDs restart at each module deployment, so the module version is part of the job identity
+ALTER TABLE jobs DROP CONSTRAINT jobs_account_address_chain_id_on_chain_job_id_job_type_key;
+
+ALTER TABLE jobs ADD CONSTRAINT jobs_account_address_chain_id_on_chain_job_id_job_type_module_version_key
+    UNIQUE(account_address, chain_id, on_chain_job_id, job_type, module_version);
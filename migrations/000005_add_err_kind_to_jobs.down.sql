@@ -0,0 +1,2 @@
+-- Drop the err_kind column
+ALTER TABLE jobs DROP COLUMN err_kind;
@@ -0,0 +1,6 @@
+-- Add err_kind column to the jobs table, recording which step of a queuing job its error message comes from
+ALTER TABLE jobs ADD COLUMN err_kind VARCHAR(20) CHECK (err_kind IN ('execution_config', 'execution'));
+
+-- Existing error messages of queuing jobs tell their kind by their prefix
+UPDATE jobs SET err_kind = 'execution' WHERE status = 'queuing' AND err_msg LIKE 'execution failed: %';
+UPDATE jobs SET err_kind = 'execution_config' WHERE status = 'queuing' AND err_msg LIKE 'failed to read execution config: %';
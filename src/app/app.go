@@ -8,6 +8,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/ethaccount/backend/erc4337"
 	"github.com/ethaccount/backend/src/handler"
 	"github.com/ethaccount/backend/src/repository"
 	"github.com/ethaccount/backend/src/service"
@@ -32,6 +33,7 @@ type Application struct {
 	redis             *redis.Client
 	PasskeyService    *service.PasskeyService
 	JobService        *service.JobService
+	ExecutionService  *service.ExecutionService
 	BlockchainService *service.BlockchainService
 	Scheduler         *service.JobScheduler
 }
@@ -105,22 +107,31 @@ func NewApplication(ctx context.Context, config AppConfig) (*Application, error)
 	jobRepo := repository.NewJobRepository(database)
 	jobService := service.NewJobService(jobRepo)
 
-	blockchainService := service.NewBlockchainService(service.BlockchainConfig{
-		SepoliaRPCURL:         *config.SepoliaRPCURL,
-		ArbitrumSepoliaRPCURL: *config.ArbitrumSepoliaRPCURL,
-		BaseSepoliaRPCURL:     *config.BaseSepoliaRPCURL,
-		OptimismSepoliaRPCURL: *config.OptimismSepoliaRPCURL,
-		PolygonAmoyRPCURL:     *config.PolygonAmoyRPCURL,
+	// The executor signs the handleOps transactions of self-bundled chains
+	var executor erc4337.TransactionSigner
+	if config.ExecutorSigner != nil {
+		executor, err = service.NewExecutorSigner(ctx, *config.ExecutorSigner)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create executor signer: %w", err)
+		}
+		logger.Info().
+			Str("signer_type", string(config.ExecutorSigner.Type)).
+			Str("executor_address", executor.Address().Hex()).
+			Msg("Executor signer ready")
+	}
 
-		// Mainnet URLs
-		ArbitrumRPCURL: *config.ArbitrumRPCURL,
-		BaseRPCURL:     *config.BaseRPCURL,
-	})
+	blockchainService := service.NewBlockchainService(config.BlockchainConfig(executor))
 
-	executionService, err := service.NewExecutionService(blockchainService, *config.PrivateKey)
+	signer, err := service.NewSigner(ctx, config.SignerConfig())
 	if err != nil {
-		return nil, fmt.Errorf("failed to create execution service: %w", err)
+		return nil, fmt.Errorf("failed to create signer: %w", err)
 	}
+	logger.Info().
+		Str("signer_type", *config.SignerType).
+		Str("session_signer_address", signer.Address().Hex()).
+		Msg("Session signer ready")
+
+	executionService := service.NewExecutionService(blockchainService, signer)
 
 	jobCache := repository.NewJobCacheRepository(rdb, "job_queue")
 	scheduler := service.NewJobScheduler(ctx, jobCache, *config.PollingInterval, jobService, executionService, blockchainService)
@@ -131,6 +142,7 @@ func NewApplication(ctx context.Context, config AppConfig) (*Application, error)
 		redis:             rdb,
 		PasskeyService:    passkeyService,
 		JobService:        jobService,
+		ExecutionService:  executionService,
 		BlockchainService: blockchainService,
 		Scheduler:         scheduler,
 	}, nil
@@ -256,7 +268,7 @@ func (app *Application) registerRoutes(ctx context.Context, router *gin.Engine)
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
 	// passkeyHandler := handler.NewPasskeyHandler(app.PasskeyService)
-	jobHandler := handler.NewJobHandler(app.JobService)
+	jobHandler := handler.NewJobHandler(app.JobService, app.BlockchainService, app.ExecutionService)
 
 	v1 := router.Group("/api/v1")
 	{
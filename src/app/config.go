@@ -1,13 +1,20 @@
 package app
 
 import (
+	"encoding/json"
+	"fmt"
 	"log"
+	"math/big"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/ethaccount/backend/erc4337"
+	"github.com/ethaccount/backend/src/service"
 	"github.com/ethaccount/backend/src/utils"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 )
 
 type AppConfig struct {
@@ -19,8 +26,15 @@ type AppConfig struct {
 	DSN *string
 	// Redis configuration (required)
 	RedisURL *string
-	// Private key for signing user operations (required)
+	// Signer for user operations (required): a local private key, a keystore file or a remote signer
+	SignerType *string
 	PrivateKey *string
+	// Keystore signer
+	KeystorePath     *string
+	KeystorePassword *string
+	// Remote signer
+	RemoteSignerURL     *string
+	RemoteSignerAddress *string
 	// API secret for validating requests from frontend (required)
 	APISecret *string
 	// CORS configuration (required)
@@ -46,16 +60,36 @@ type AppConfig struct {
 	RPID          *string
 	RPOrigins     *[]string
 
-	// Blockchain RPC URLs (all have defaults)
-	SepoliaRPCURL         *string
-	ArbitrumSepoliaRPCURL *string
-	BaseSepoliaRPCURL     *string
-	OptimismSepoliaRPCURL *string
-	PolygonAmoyRPCURL     *string
+	// Supported chains (defaults to the built-in chains)
+	Chains *service.ChainRegistry
 
-	// Mainnet RPC URLs
-	ArbitrumRPCURL *string
-	BaseRPCURL     *string
+	// Gas price oracle configuration (default and per chain overrides)
+	GasPrice      *service.GasPriceConfig
+	ChainGasPrice *map[int64]service.GasPriceConfig
+
+	// ERC-7677 paymaster services by chain ID
+	Paymasters *map[int64]service.PaymasterConfig
+
+	// Fallback bundler URLs by chain ID and the interval of the bundler health checks
+	BundlerFallbackURLs        *map[int64][]string
+	BundlerHealthCheckInterval *time.Duration
+
+	// Self-bundling mode by chain ID and the executor EOA sending handleOps
+	SelfBundling   *map[int64]service.SelfBundlingMode
+	ExecutorSigner *service.SignerConfig
+
+	// Pre-flight simulation of user operations and the EntryPointSimulations code it uses
+	Simulation *service.SimulationConfig
+
+	// Replacement of stuck user operations (default and per chain overrides)
+	FeeBump      *service.FeeBumpConfig
+	ChainFeeBump *map[int64]service.FeeBumpConfig
+
+	// Number of executionLog reads per Multicall3 call or JSON-RPC batch
+	MulticallChunkSize *int
+
+	// Rate limits, retries and circuit breaking of the node RPC endpoints
+	RPCTransport *service.RPCTransportConfig
 }
 
 func NewAppConfig() *AppConfig {
@@ -86,14 +120,8 @@ func loadRequiredConfig(config *AppConfig) {
 	}
 	config.RedisURL = &redisURL
 
-	// Private key for signing operations (required)
-	privateKey := os.Getenv("PRIVATE_KEY")
-	if privateKey == "" {
-		log.Fatalf("REQUIRED: PRIVATE_KEY not set in environment")
-	}
-	// Remove 0x prefix if it exists
-	privateKey = strings.TrimPrefix(privateKey, "0x")
-	config.PrivateKey = &privateKey
+	// Signer for user operations (required)
+	loadSignerConfig(config)
 
 	// API secret for validating requests from frontend (required)
 	apiSecret := os.Getenv("API_SECRET")
@@ -142,8 +170,33 @@ func loadOptionalConfig(config *AppConfig) {
 	// Load WebAuthn configuration
 	loadWebAuthnConfig(config)
 
-	// Load blockchain RPC URLs with defaults
-	loadRPCConfig(config)
+	// Load the supported chains
+	loadChainConfig(config)
+
+	// Load gas price oracle configuration
+	loadGasPriceConfig(config)
+
+	// Load paymaster services
+	loadPaymasterConfig(config)
+
+	// Load bundler failover configuration
+	loadBundlerConfig(config)
+
+	// Load self-bundling configuration
+	loadSelfBundlingConfig(config)
+
+	// Load user operation simulation configuration
+	loadSimulationConfig(config)
+
+	// Load fee bumping configuration
+	loadFeeBumpConfig(config)
+
+	// Multicall chunk size (default: 500)
+	multicallChunkSize := int(parsePositiveInt("MULTICALL_CHUNK_SIZE", getEnvWithDefault("MULTICALL_CHUNK_SIZE", strconv.Itoa(service.DefaultMulticallChunkSize))))
+	config.MulticallChunkSize = &multicallChunkSize
+
+	// Load node RPC transport configuration
+	loadRPCTransportConfig(config)
 }
 
 // loadCORSConfig handles CORS origins configuration
@@ -166,6 +219,121 @@ func loadCORSConfig(config *AppConfig) {
 	config.AllowOrigins = &allowOrigins
 }
 
+// loadSignerConfig loads the signer configuration and fails fast if the selected signer is not configured
+func loadSignerConfig(config *AppConfig) {
+	// Signer type (default: local)
+	signerType := getEnvWithDefault("SIGNER_TYPE", "local")
+	config.SignerType = &signerType
+
+	switch signerType {
+	case "local":
+		// Private key for signing operations
+		privateKey := os.Getenv("PRIVATE_KEY")
+		if privateKey == "" {
+			log.Fatalf("REQUIRED: PRIVATE_KEY not set in environment")
+		}
+		// Remove 0x prefix if it exists
+		privateKey = strings.TrimPrefix(privateKey, "0x")
+		config.PrivateKey = &privateKey
+
+	case "keystore":
+		keystorePath := os.Getenv("SIGNER_KEYSTORE_PATH")
+		if keystorePath == "" {
+			log.Fatalf("REQUIRED: SIGNER_KEYSTORE_PATH not set in environment")
+		}
+		config.KeystorePath = &keystorePath
+
+		keystorePassword := loadKeystorePassword("SIGNER_KEYSTORE_PASSWORD")
+		config.KeystorePassword = &keystorePassword
+
+	case "remote":
+		remoteSignerURL := os.Getenv("SIGNER_URL")
+		if remoteSignerURL == "" {
+			log.Fatalf("REQUIRED: SIGNER_URL not set in environment")
+		}
+		config.RemoteSignerURL = &remoteSignerURL
+
+		remoteSignerAddress := os.Getenv("SIGNER_ADDRESS")
+		if remoteSignerAddress == "" {
+			log.Fatalf("REQUIRED: SIGNER_ADDRESS not set in environment")
+		}
+		config.RemoteSignerAddress = &remoteSignerAddress
+
+	default:
+		log.Fatalf("REQUIRED: SIGNER_TYPE must be one of: local, keystore, remote (got: %s)", signerType)
+	}
+}
+
+// loadKeystorePassword reads a keystore password from the given variable, or from the file named by its _FILE variant
+func loadKeystorePassword(key string) string {
+	// Prefer a password file (e.g. a mounted secret) over a plain environment variable
+	if passwordFile := os.Getenv(key + "_FILE"); passwordFile != "" {
+		password, err := os.ReadFile(passwordFile)
+		if err != nil {
+			log.Fatalf("REQUIRED: failed to read %s_FILE: %v", key, err)
+		}
+		return strings.TrimRight(string(password), "\r\n")
+	}
+	return os.Getenv(key)
+}
+
+// SignerConfig returns the service signer configuration
+func (config *AppConfig) SignerConfig() service.SignerConfig {
+	// Helper function to dereference optional values
+	value := func(v *string) string {
+		if v == nil {
+			return ""
+		}
+		return *v
+	}
+
+	return service.SignerConfig{
+		Type:             service.SignerType(value(config.SignerType)),
+		PrivateKey:       value(config.PrivateKey),
+		KeystorePath:     value(config.KeystorePath),
+		KeystorePassword: value(config.KeystorePassword),
+		RemoteURL:        value(config.RemoteSignerURL),
+		RemoteAddress:    value(config.RemoteSignerAddress),
+	}
+}
+
+// BlockchainConfig returns the blockchain service configuration, with the executor signing the handleOps
+// transactions of self-bundled chains
+func (config *AppConfig) BlockchainConfig(executor erc4337.TransactionSigner) service.BlockchainConfig {
+	return service.BlockchainConfig{
+		// Supported chains
+		Chains: config.Chains,
+
+		// Gas price oracles
+		DefaultGasPrice: *config.GasPrice,
+		GasPrice:        *config.ChainGasPrice,
+
+		// Paymaster services
+		Paymasters: *config.Paymasters,
+
+		// Bundler failover
+		BundlerFallbackURLs: *config.BundlerFallbackURLs,
+		BundlerPool:         erc4337.BundlerPoolConfig{HealthCheckInterval: *config.BundlerHealthCheckInterval},
+
+		// Self-bundling
+		SelfBundling: *config.SelfBundling,
+		Executor:     executor,
+
+		// Pre-flight simulation
+		Simulation: *config.Simulation,
+
+		// Replacement of stuck user operations
+		DefaultFeeBump: *config.FeeBump,
+		FeeBump:        *config.ChainFeeBump,
+
+		// Execution config reads
+		MulticallChunkSize: *config.MulticallChunkSize,
+
+		// Node RPC endpoints
+		RPCTransport: *config.RPCTransport,
+	}
+}
+
 // loadWebAuthnConfig loads WebAuthn configuration with sensible defaults
 func loadWebAuthnConfig(config *AppConfig) {
 	// WebAuthn RP Display Name
@@ -202,29 +370,375 @@ func loadWebAuthnOrigins(config *AppConfig) {
 	config.RPOrigins = &origins
 }
 
-// loadRPCConfig loads blockchain RPC URLs with public node defaults
-func loadRPCConfig(config *AppConfig) {
-	sepoliaRPCURL := getEnvWithDefault("SEPOLIA_RPC_URL", "https://ethereum-sepolia-rpc.publicnode.com")
-	config.SepoliaRPCURL = &sepoliaRPCURL
+// defaultChainRPCURLEnvKeys are the variables overriding the public node RPC URLs of the default chains,
+// comma-separated in failover order
+var defaultChainRPCURLEnvKeys = map[int64]string{
+	11155111: "SEPOLIA_RPC_URL",
+	421614:   "ARBITRUM_SEPOLIA_RPC_URL",
+	84532:    "BASE_SEPOLIA_RPC_URL",
+	11155420: "OPTIMISM_SEPOLIA_RPC_URL",
+	80002:    "POLYGON_AMOY_RPC_URL",
+	42161:    "ARBITRUM_RPC_URL",
+	8453:     "BASE_RPC_URL",
+}
+
+// loadChainConfig loads the supported chains from the JSON file at CHAINS_FILE. Without it, the default chains
+// are used with the RPC URLs of defaultChainRPCURLEnvKeys when set. BUNDLER_URLS_<CHAIN_ID> (comma-separated)
+// overrides the bundler URLs of a chain; chains without bundler URLs send user operations to their node.
+func loadChainConfig(config *AppConfig) {
+	var chains []service.ChainConfig
+
+	if chainsFile := os.Getenv("CHAINS_FILE"); chainsFile != "" {
+		data, err := os.ReadFile(chainsFile)
+		if err != nil {
+			log.Fatalf("REQUIRED: failed to read CHAINS_FILE %s: %v", chainsFile, err)
+		}
+		chains, err = service.ParseChainConfigs(data)
+		if err != nil {
+			log.Fatalf("REQUIRED: CHAINS_FILE %s is invalid: %v", chainsFile, err)
+		}
+	} else {
+		chains = service.DefaultChains()
+		for i := range chains {
+			if rpcURLs := splitURLs(os.Getenv(defaultChainRPCURLEnvKeys[chains[i].ChainID])); len(rpcURLs) > 0 {
+				chains[i].RPCURLs = rpcURLs
+			}
+		}
+	}
+
+	// Bundler endpoints configured apart from the node, e.g. BUNDLER_URLS_8453=https://api.pimlico.io/v2/8453/rpc?apikey=...
+	for i := range chains {
+		if bundlerURLs := splitURLs(os.Getenv(fmt.Sprintf("BUNDLER_URLS_%d", chains[i].ChainID))); len(bundlerURLs) > 0 {
+			chains[i].BundlerURLs = bundlerURLs
+		}
+	}
+
+	registry, err := service.NewChainRegistry(chains)
+	if err != nil {
+		log.Fatalf("REQUIRED: invalid chain configuration: %v", err)
+	}
+	config.Chains = registry
+}
 
-	arbitrumSepoliaRPCURL := getEnvWithDefault("ARBITRUM_SEPOLIA_RPC_URL", "https://arbitrum-sepolia-rpc.publicnode.com")
-	config.ArbitrumSepoliaRPCURL = &arbitrumSepoliaRPCURL
+// Gas price environment variables. Each can be overridden per chain with a _<CHAIN_ID> suffix,
+// e.g. GAS_PRICE_ORACLE_8453=pimlico
+var gasPriceEnvKeys = []string{
+	"GAS_PRICE_ORACLE",
+	"GAS_BASE_FEE_MULTIPLIER_PERCENT",
+	"GAS_PRIORITY_FEE_MULTIPLIER_PERCENT",
+	"GAS_MAX_FEE_PER_GAS_CAP",
+	"GAS_MAX_PRIORITY_FEE_PER_GAS_CAP",
+	"GAS_FEE_HISTORY_PERCENTILE",
+	"GAS_PIMLICO_SPEED",
+}
 
-	baseSepoliaRPCURL := getEnvWithDefault("BASE_SEPOLIA_RPC_URL", "https://base-sepolia-rpc.publicnode.com")
-	config.BaseSepoliaRPCURL = &baseSepoliaRPCURL
+// loadGasPriceConfig loads the default gas price configuration and the per chain overrides
+func loadGasPriceConfig(config *AppConfig) {
+	defaultConfig := parseGasPriceConfig(service.GasPriceConfig{}, "")
+	config.GasPrice = &defaultConfig
+
+	// Collect the chain IDs that have at least one override
+	chainGasPrice := make(map[int64]service.GasPriceConfig)
+	for _, env := range os.Environ() {
+		key := strings.SplitN(env, "=", 2)[0]
+		for _, prefix := range gasPriceEnvKeys {
+			suffix, found := strings.CutPrefix(key, prefix+"_")
+			if !found {
+				continue
+			}
+			chainID, err := strconv.ParseInt(suffix, 10, 64)
+			if err != nil {
+				continue
+			}
+			if _, exists := chainGasPrice[chainID]; !exists {
+				chainGasPrice[chainID] = parseGasPriceConfig(defaultConfig, "_"+suffix)
+			}
+		}
+	}
+	config.ChainGasPrice = &chainGasPrice
+}
 
-	optimismSepoliaRPCURL := getEnvWithDefault("OPTIMISM_SEPOLIA_RPC_URL", "https://optimism-sepolia-rpc.publicnode.com")
-	config.OptimismSepoliaRPCURL = &optimismSepoliaRPCURL
+// parseGasPriceConfig reads the gas price variables with the given suffix on top of a base config
+func parseGasPriceConfig(base service.GasPriceConfig, suffix string) service.GasPriceConfig {
+	gasPrice := base
 
-	polygonAmoyRPCURL := getEnvWithDefault("POLYGON_AMOY_RPC_URL", "https://polygon-amoy-rpc.publicnode.com")
-	config.PolygonAmoyRPCURL = &polygonAmoyRPCURL
+	if oracle := os.Getenv("GAS_PRICE_ORACLE" + suffix); oracle != "" {
+		gasPrice.Oracle = service.GasPriceOracleType(oracle)
+	}
+	if v := os.Getenv("GAS_BASE_FEE_MULTIPLIER_PERCENT" + suffix); v != "" {
+		gasPrice.BaseFeeMultiplierPercent = parsePositiveInt("GAS_BASE_FEE_MULTIPLIER_PERCENT"+suffix, v)
+	}
+	if v := os.Getenv("GAS_PRIORITY_FEE_MULTIPLIER_PERCENT" + suffix); v != "" {
+		gasPrice.PriorityFeeMultiplierPercent = parsePositiveInt("GAS_PRIORITY_FEE_MULTIPLIER_PERCENT"+suffix, v)
+	}
+	if v := os.Getenv("GAS_MAX_FEE_PER_GAS_CAP" + suffix); v != "" {
+		gasPrice.MaxFeePerGasCap = parseWei("GAS_MAX_FEE_PER_GAS_CAP"+suffix, v)
+	}
+	if v := os.Getenv("GAS_MAX_PRIORITY_FEE_PER_GAS_CAP" + suffix); v != "" {
+		gasPrice.MaxPriorityFeePerGasCap = parseWei("GAS_MAX_PRIORITY_FEE_PER_GAS_CAP"+suffix, v)
+	}
+	if v := os.Getenv("GAS_FEE_HISTORY_PERCENTILE" + suffix); v != "" {
+		percentile, err := strconv.ParseFloat(v, 64)
+		if err != nil || percentile <= 0 || percentile > 100 {
+			log.Fatalf("REQUIRED: GAS_FEE_HISTORY_PERCENTILE%s must be a number in (0, 100] (got: %s)", suffix, v)
+		}
+		gasPrice.FeeHistoryPercentile = percentile
+	}
+	if v := os.Getenv("GAS_PIMLICO_SPEED" + suffix); v != "" {
+		gasPrice.PimlicoSpeed = v
+	}
 
-	// Mainnet RPC URLs
-	arbitrumRPCURL := getEnvWithDefault("ARBITRUM_RPC_URL", "https://arbitrum-one-rpc.publicnode.com")
-	config.ArbitrumRPCURL = &arbitrumRPCURL
+	return gasPrice
+}
 
-	baseRPCURL := getEnvWithDefault("BASE_RPC_URL", "https://base-rpc.publicnode.com")
-	config.BaseRPCURL = &baseRPCURL
+// parsePositiveInt parses a positive integer and fails fast on invalid values
+func parsePositiveInt(key, value string) int64 {
+	parsed, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || parsed <= 0 {
+		log.Fatalf("REQUIRED: %s must be a positive integer (got: %s)", key, value)
+	}
+	return parsed
+}
+
+// parseWei parses an amount in wei (decimal or 0x-prefixed hex) and fails fast on invalid values
+func parseWei(key, value string) *big.Int {
+	parsed, ok := new(big.Int).SetString(value, 0)
+	if !ok || parsed.Sign() <= 0 {
+		log.Fatalf("REQUIRED: %s must be a positive amount in wei (got: %s)", key, value)
+	}
+	return parsed
+}
+
+// loadPaymasterConfig loads the ERC-7677 paymaster services from PAYMASTER_URL_<CHAIN_ID>
+// and their optional JSON context from PAYMASTER_CONTEXT_<CHAIN_ID>
+func loadPaymasterConfig(config *AppConfig) {
+	paymasters := make(map[int64]service.PaymasterConfig)
+
+	for _, env := range os.Environ() {
+		parts := strings.SplitN(env, "=", 2)
+		suffix, found := strings.CutPrefix(parts[0], "PAYMASTER_URL_")
+		if !found || len(parts) != 2 || parts[1] == "" {
+			continue
+		}
+		chainID, err := strconv.ParseInt(suffix, 10, 64)
+		if err != nil {
+			log.Fatalf("REQUIRED: %s must end with a chain ID", parts[0])
+		}
+
+		paymaster := service.PaymasterConfig{URL: parts[1]}
+		if contextJSON := os.Getenv("PAYMASTER_CONTEXT_" + suffix); contextJSON != "" {
+			if err := json.Unmarshal([]byte(contextJSON), &paymaster.Context); err != nil {
+				log.Fatalf("REQUIRED: PAYMASTER_CONTEXT_%s must be a JSON object: %v", suffix, err)
+			}
+		}
+		paymasters[chainID] = paymaster
+	}
+
+	config.Paymasters = &paymasters
+}
+
+// loadBundlerConfig loads the fallback bundlers from BUNDLER_FALLBACK_URLS_<CHAIN_ID> (comma-separated)
+// and the bundler health check interval in seconds from BUNDLER_HEALTH_CHECK_INTERVAL
+func loadBundlerConfig(config *AppConfig) {
+	fallbackURLs := make(map[int64][]string)
+
+	for _, env := range os.Environ() {
+		parts := strings.SplitN(env, "=", 2)
+		suffix, found := strings.CutPrefix(parts[0], "BUNDLER_FALLBACK_URLS_")
+		if !found || len(parts) != 2 || parts[1] == "" {
+			continue
+		}
+		chainID, err := strconv.ParseInt(suffix, 10, 64)
+		if err != nil {
+			log.Fatalf("REQUIRED: %s must end with a chain ID", parts[0])
+		}
+
+		fallbackURLs[chainID] = append(fallbackURLs[chainID], splitURLs(parts[1])...)
+	}
+	config.BundlerFallbackURLs = &fallbackURLs
+
+	// Health check interval (default: 30 seconds)
+	healthCheckInterval := time.Duration(parsePositiveInt("BUNDLER_HEALTH_CHECK_INTERVAL", getEnvWithDefault("BUNDLER_HEALTH_CHECK_INTERVAL", "30"))) * time.Second
+	config.BundlerHealthCheckInterval = &healthCheckInterval
+}
+
+// loadRPCTransportConfig loads the per endpoint rate limit in requests per second from RPC_RATE_LIMIT,
+// the number of retries from RPC_MAX_RETRIES and the circuit breaker from RPC_BREAKER_THRESHOLD
+// and RPC_BREAKER_COOLDOWN (seconds)
+func loadRPCTransportConfig(config *AppConfig) {
+	rateLimit, err := strconv.ParseFloat(getEnvWithDefault("RPC_RATE_LIMIT", "0"), 64)
+	if err != nil || rateLimit < 0 {
+		log.Fatalf("REQUIRED: RPC_RATE_LIMIT must be a non-negative number (got: %s)", os.Getenv("RPC_RATE_LIMIT"))
+	}
+
+	maxRetriesValue := getEnvWithDefault("RPC_MAX_RETRIES", strconv.Itoa(service.DefaultRPCMaxRetries))
+	maxRetries, err := strconv.Atoi(maxRetriesValue)
+	if err != nil || maxRetries < 0 {
+		log.Fatalf("REQUIRED: RPC_MAX_RETRIES must be a non-negative integer (got: %s)", maxRetriesValue)
+	}
+	if maxRetries == 0 {
+		// A zero MaxRetries uses the default
+		maxRetries = -1
+	}
+
+	rpcTransport := service.RPCTransportConfig{
+		RateLimit:        rateLimit,
+		MaxRetries:       maxRetries,
+		BreakerThreshold: int(parsePositiveInt("RPC_BREAKER_THRESHOLD", getEnvWithDefault("RPC_BREAKER_THRESHOLD", strconv.Itoa(service.DefaultRPCBreakerThreshold)))),
+		BreakerCooldown:  time.Duration(parsePositiveInt("RPC_BREAKER_COOLDOWN", getEnvWithDefault("RPC_BREAKER_COOLDOWN", "30"))) * time.Second,
+	}
+	config.RPCTransport = &rpcTransport
+}
+
+// loadSelfBundlingConfig loads the self-bundling mode of each chain from SELF_BUNDLING_<CHAIN_ID>
+// (off, fallback or always) and the executor signer, which is required when any chain self-bundles
+func loadSelfBundlingConfig(config *AppConfig) {
+	selfBundling := make(map[int64]service.SelfBundlingMode)
+
+	for _, env := range os.Environ() {
+		parts := strings.SplitN(env, "=", 2)
+		suffix, found := strings.CutPrefix(parts[0], "SELF_BUNDLING_")
+		if !found || len(parts) != 2 || parts[1] == "" {
+			continue
+		}
+		chainID, err := strconv.ParseInt(suffix, 10, 64)
+		if err != nil {
+			log.Fatalf("REQUIRED: %s must end with a chain ID", parts[0])
+		}
+
+		mode := service.SelfBundlingMode(parts[1])
+		switch mode {
+		case service.SelfBundlingOff:
+			continue
+		case service.SelfBundlingFallback, service.SelfBundlingAlways:
+			selfBundling[chainID] = mode
+		default:
+			log.Fatalf("REQUIRED: %s must be one of: off, fallback, always (got: %s)", parts[0], parts[1])
+		}
+	}
+	config.SelfBundling = &selfBundling
+
+	if len(selfBundling) > 0 {
+		loadExecutorSignerConfig(config)
+	}
+}
+
+// loadExecutorSignerConfig loads the signer of the self-bundling executor: a keystore file
+// (EXECUTOR_KEYSTORE_PATH) or a remote signer (EXECUTOR_SIGNER_URL and EXECUTOR_SIGNER_ADDRESS).
+// The executor key is funded, so it is never read from a plain environment variable.
+func loadExecutorSignerConfig(config *AppConfig) {
+	signerType := os.Getenv("EXECUTOR_SIGNER_TYPE")
+	signerConfig := service.SignerConfig{Type: service.SignerType(signerType)}
+
+	switch signerConfig.Type {
+	case service.SignerTypeKeystore:
+		signerConfig.KeystorePath = os.Getenv("EXECUTOR_KEYSTORE_PATH")
+		if signerConfig.KeystorePath == "" {
+			log.Fatalf("REQUIRED: EXECUTOR_KEYSTORE_PATH not set in environment")
+		}
+		signerConfig.KeystorePassword = loadKeystorePassword("EXECUTOR_KEYSTORE_PASSWORD")
+
+	case service.SignerTypeRemote:
+		signerConfig.RemoteURL = os.Getenv("EXECUTOR_SIGNER_URL")
+		if signerConfig.RemoteURL == "" {
+			log.Fatalf("REQUIRED: EXECUTOR_SIGNER_URL not set in environment")
+		}
+		signerConfig.RemoteAddress = os.Getenv("EXECUTOR_SIGNER_ADDRESS")
+		if signerConfig.RemoteAddress == "" {
+			log.Fatalf("REQUIRED: EXECUTOR_SIGNER_ADDRESS not set in environment")
+		}
+
+	default:
+		log.Fatalf("REQUIRED: EXECUTOR_SIGNER_TYPE must be one of: keystore, remote (got: %q, required by SELF_BUNDLING_<CHAIN_ID>)", signerType)
+	}
+
+	config.ExecutorSigner = &signerConfig
+}
+
+// loadSimulationConfig loads whether user operations are simulated before being sent (SIMULATE_USER_OPERATIONS,
+// default: true) and the EntryPointSimulations bytecode of v0.7 and v0.8 from ENTRY_POINT_SIMULATIONS_CODE_V07/_V08
+func loadSimulationConfig(config *AppConfig) {
+	enabled, err := strconv.ParseBool(getEnvWithDefault("SIMULATE_USER_OPERATIONS", "true"))
+	if err != nil {
+		log.Fatalf("REQUIRED: SIMULATE_USER_OPERATIONS must be true or false (got: %s)", os.Getenv("SIMULATE_USER_OPERATIONS"))
+	}
+
+	simulationsCode := make(map[erc4337.EntryPointVersion][]byte)
+	for version, key := range map[erc4337.EntryPointVersion]string{
+		erc4337.EntryPointVersionV07: "ENTRY_POINT_SIMULATIONS_CODE_V07",
+		erc4337.EntryPointVersionV08: "ENTRY_POINT_SIMULATIONS_CODE_V08",
+	} {
+		value := os.Getenv(key)
+		if value == "" {
+			continue
+		}
+		code, err := hexutil.Decode(value)
+		if err != nil {
+			log.Fatalf("REQUIRED: %s must be 0x-prefixed hex bytecode: %v", key, err)
+		}
+		simulationsCode[version] = code
+	}
+
+	config.Simulation = &service.SimulationConfig{
+		Enabled:                   enabled,
+		EntryPointSimulationsCode: simulationsCode,
+	}
+}
+
+// Fee bump environment variables. Each can be overridden per chain with a _<CHAIN_ID> suffix,
+// e.g. FEE_BUMP_AFTER_42161=60
+var feeBumpEnvKeys = []string{
+	"FEE_BUMP_AFTER",
+	"FEE_BUMP_PERCENT",
+	"FEE_BUMP_MAX_REPLACEMENTS",
+}
+
+// loadFeeBumpConfig loads the default fee bumping configuration and the per chain overrides
+func loadFeeBumpConfig(config *AppConfig) {
+	defaultConfig := parseFeeBumpConfig(service.FeeBumpConfig{}, "")
+	config.FeeBump = &defaultConfig
+
+	chainFeeBump := make(map[int64]service.FeeBumpConfig)
+	for _, env := range os.Environ() {
+		key := strings.SplitN(env, "=", 2)[0]
+		for _, prefix := range feeBumpEnvKeys {
+			suffix, found := strings.CutPrefix(key, prefix+"_")
+			if !found {
+				continue
+			}
+			chainID, err := strconv.ParseInt(suffix, 10, 64)
+			if err != nil {
+				continue
+			}
+			if _, exists := chainFeeBump[chainID]; !exists {
+				chainFeeBump[chainID] = parseFeeBumpConfig(defaultConfig, "_"+suffix)
+			}
+		}
+	}
+	config.ChainFeeBump = &chainFeeBump
+}
+
+// parseFeeBumpConfig reads the fee bump variables with the given suffix on top of a base config.
+// FEE_BUMP_AFTER is in seconds, 0 disables fee bumping.
+func parseFeeBumpConfig(base service.FeeBumpConfig, suffix string) service.FeeBumpConfig {
+	feeBump := base
+
+	if v := os.Getenv("FEE_BUMP_AFTER" + suffix); v != "" {
+		seconds, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || seconds < 0 {
+			log.Fatalf("REQUIRED: FEE_BUMP_AFTER%s must be a number of seconds (got: %s)", suffix, v)
+		}
+		feeBump.Disabled = seconds == 0
+		feeBump.After = time.Duration(seconds) * time.Second
+	}
+	if v := os.Getenv("FEE_BUMP_PERCENT" + suffix); v != "" {
+		feeBump.Percent = parsePositiveInt("FEE_BUMP_PERCENT"+suffix, v)
+	}
+	if v := os.Getenv("FEE_BUMP_MAX_REPLACEMENTS" + suffix); v != "" {
+		feeBump.MaxReplacements = int(parsePositiveInt("FEE_BUMP_MAX_REPLACEMENTS"+suffix, v))
+	}
+
+	return feeBump
 }
 
 // getPollingInterval parses polling interval from environment with default fallback
@@ -242,6 +756,18 @@ func getPollingInterval() int {
 	return 60
 }
 
+// splitURLs splits a comma-separated list of URLs, skipping empty entries
+func splitURLs(value string) []string {
+	var urls []string
+	for _, url := range strings.Split(value, ",") {
+		url = strings.TrimSpace(url)
+		if url != "" {
+			urls = append(urls, url)
+		}
+	}
+	return urls
+}
+
 // getEnvWithDefault returns environment variable value or default if not set
 func getEnvWithDefault(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
@@ -0,0 +1,5 @@
+// Package scheduledorders contains the Go bindings of the ScheduledOrders module, which makes recurring
+// Uniswap V3 swaps from smart accounts, and the decoder of its execution data.
+package scheduledorders
+
+//go:generate go run github.com/ethereum/go-ethereum/cmd/abigen --v2 --abi ScheduledOrders.abi.json --pkg scheduledorders --type ScheduledOrders --out scheduled_orders.go
@@ -0,0 +1,45 @@
+package scheduledorders
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// executionDataArgs is abi.encode(address tokenIn, address tokenOut, uint256 amountIn, uint160 sqrtPriceLimitX96)
+var executionDataArgs = func() abi.Arguments {
+	addressType, _ := abi.NewType("address", "", nil)
+	uint256Type, _ := abi.NewType("uint256", "", nil)
+	uint160Type, _ := abi.NewType("uint160", "", nil)
+	return abi.Arguments{{Name: "tokenIn", Type: addressType}, {Name: "tokenOut", Type: addressType}, {Name: "amountIn", Type: uint256Type}, {Name: "sqrtPriceLimitX96", Type: uint160Type}}
+}()
+
+// ExecutionData is the swap made by each execution of a job
+type ExecutionData struct {
+	TokenIn  common.Address
+	TokenOut common.Address
+	AmountIn *big.Int
+	// SqrtPriceLimitX96 is the Uniswap V3 price limit of the swap, or zero for no limit
+	SqrtPriceLimitX96 *big.Int
+}
+
+// DecodeExecutionData decodes the execution data of a ScheduledOrders job
+func DecodeExecutionData(data []byte) (*ExecutionData, error) {
+	unpacked, err := executionDataArgs.Unpack(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode swap execution data: %w", err)
+	}
+	return &ExecutionData{
+		TokenIn:           unpacked[0].(common.Address),
+		TokenOut:          unpacked[1].(common.Address),
+		AmountIn:          unpacked[2].(*big.Int),
+		SqrtPriceLimitX96: unpacked[3].(*big.Int),
+	}, nil
+}
+
+// Encode encodes the execution data as passed to addOrder and returned by executionLog
+func (d *ExecutionData) Encode() ([]byte, error) {
+	return executionDataArgs.Pack(d.TokenIn, d.TokenOut, d.AmountIn, d.SqrtPriceLimitX96)
+}
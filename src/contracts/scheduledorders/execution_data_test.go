@@ -0,0 +1,45 @@
+package scheduledorders
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecutionData(t *testing.T) {
+	weth := common.HexToAddress("0xfff9976782d46cc05630d1f6ebab18b2324d6b14")
+	usdc := common.HexToAddress("0x1c7d4b196cb0c7b01d743fbc6116a902379c7238")
+	sqrtPriceLimit, _ := new(big.Int).SetString("1461446703485210103287273052203988822378723970341", 10)
+
+	encoded, err := (&ExecutionData{TokenIn: weth, TokenOut: usdc, AmountIn: big.NewInt(1e15), SqrtPriceLimitX96: sqrtPriceLimit}).Encode()
+	require.NoError(t, err)
+	assert.Len(t, encoded, 4*32)
+
+	data, err := DecodeExecutionData(encoded)
+	require.NoError(t, err)
+	assert.Equal(t, weth, data.TokenIn)
+	assert.Equal(t, usdc, data.TokenOut)
+	assert.Equal(t, int64(1e15), data.AmountIn.Int64())
+	assert.Equal(t, 0, sqrtPriceLimit.Cmp(data.SqrtPriceLimitX96))
+
+	_, err = DecodeExecutionData(encoded[:96])
+	assert.ErrorContains(t, err, "failed to decode swap execution data")
+}
+
+func TestScheduledOrders(t *testing.T) {
+	scheduledOrders := NewScheduledOrders()
+
+	calldata := scheduledOrders.PackExecuteOrder(big.NewInt(9), big.NewInt(0), big.NewInt(1), 500)
+	assert.Equal(t, "0x88c102e7", hexutil.Encode(calldata[:4]))
+	assert.Len(t, calldata, 4+4*32)
+
+	account := common.HexToAddress("0x47d6a8a65cba9b61b194dac740aa192a7a1e91e1")
+	revert := append(ScheduledOrdersAlreadyInitializedErrorID().Bytes()[:4], common.LeftPadBytes(account.Bytes(), 32)...)
+	decoded, err := scheduledOrders.UnpackError(revert)
+	require.NoError(t, err)
+	assert.Equal(t, account, decoded.(*ScheduledOrdersAlreadyInitialized).SmartAccount)
+}
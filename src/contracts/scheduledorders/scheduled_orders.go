@@ -0,0 +1,534 @@
+// Code generated via abigen V2 - DO NOT EDIT.
+// This file is a generated binding and any manual changes will be lost.
+
+package scheduledorders
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind/v2"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var (
+	_ = bytes.Equal
+	_ = errors.New
+	_ = big.NewInt
+	_ = common.Big1
+	_ = types.BloomLookup
+	_ = abi.ConvertType
+)
+
+// ScheduledOrdersMetaData contains all meta data concerning the ScheduledOrders contract.
+var ScheduledOrdersMetaData = bind.MetaData{
+	ABI: "[{\"type\":\"function\",\"name\":\"accountJobCount\",\"inputs\":[{\"name\":\"smartAccount\",\"type\":\"address\",\"internalType\":\"address\"}],\"outputs\":[{\"name\":\"jobCount\",\"type\":\"uint256\",\"internalType\":\"uint256\"}],\"stateMutability\":\"view\"},{\"type\":\"function\",\"name\":\"addOrder\",\"inputs\":[{\"name\":\"orderData\",\"type\":\"bytes\",\"internalType\":\"bytes\"}],\"outputs\":[],\"stateMutability\":\"nonpayable\"},{\"type\":\"function\",\"name\":\"executeOrder\",\"inputs\":[{\"name\":\"jobId\",\"type\":\"uint256\",\"internalType\":\"uint256\"},{\"name\":\"sqrtPriceLimitX96\",\"type\":\"uint160\",\"internalType\":\"uint160\"},{\"name\":\"amountOutMinimum\",\"type\":\"uint256\",\"internalType\":\"uint256\"},{\"name\":\"fee\",\"type\":\"uint16\",\"internalType\":\"uint16\"}],\"outputs\":[],\"stateMutability\":\"nonpayable\"},{\"type\":\"function\",\"name\":\"executionLog\",\"inputs\":[{\"name\":\"smartAccount\",\"type\":\"address\",\"internalType\":\"address\"},{\"name\":\"jobId\",\"type\":\"uint256\",\"internalType\":\"uint256\"}],\"outputs\":[{\"name\":\"executeInterval\",\"type\":\"uint48\",\"internalType\":\"uint48\"},{\"name\":\"numberOfExecutions\",\"type\":\"uint16\",\"internalType\":\"uint16\"},{\"name\":\"numberOfExecutionsCompleted\",\"type\":\"uint16\",\"internalType\":\"uint16\"},{\"name\":\"startDate\",\"type\":\"uint48\",\"internalType\":\"uint48\"},{\"name\":\"isEnabled\",\"type\":\"bool\",\"internalType\":\"bool\"},{\"name\":\"lastExecutionTime\",\"type\":\"uint48\",\"internalType\":\"uint48\"},{\"name\":\"executionData\",\"type\":\"bytes\",\"internalType\":\"bytes\"}],\"stateMutability\":\"view\"},{\"type\":\"function\",\"name\":\"isInitialized\",\"inputs\":[{\"name\":\"smartAccount\",\"type\":\"address\",\"internalType\":\"address\"}],\"outputs\":[{\"name\":\"\",\"type\":\"bool\",\"internalType\":\"bool\"}],\"stateMutability\":\"view\"},{\"type\":\"function\",\"name\":\"isModuleType\",\"inputs\":[{\"name\":\"typeID\",\"type\":\"uint256\",\"internalType\":\"uint256\"}],\"outputs\":[{\"name\":\"\",\"type\":\"bool\",\"internalType\":\"bool\"}],\"stateMutability\":\"pure\"},{\"type\":\"function\",\"name\":\"name\",\"inputs\":[],\"outputs\":[{\"name\":\"\",\"type\":\"string\",\"internalType\":\"string\"}],\"stateMutability\":\"pure\"},{\"type\":\"function\",\"name\":\"onInstall\",\"inputs\":[{\"name\":\"data\",\"type\":\"bytes\",\"internalType\":\"bytes\"}],\"outputs\":[],\"stateMutability\":\"nonpayable\"},{\"type\":\"function\",\"name\":\"onUninstall\",\"inputs\":[{\"name\":\"\",\"type\":\"bytes\",\"internalType\":\"bytes\"}],\"outputs\":[],\"stateMutability\":\"nonpayable\"},{\"type\":\"function\",\"name\":\"toggleOrder\",\"inputs\":[{\"name\":\"jobId\",\"type\":\"uint256\",\"internalType\":\"uint256\"}],\"outputs\":[],\"stateMutability\":\"nonpayable\"},{\"type\":\"function\",\"name\":\"version\",\"inputs\":[],\"outputs\":[{\"name\":\"\",\"type\":\"string\",\"internalType\":\"string\"}],\"stateMutability\":\"pure\"},{\"type\":\"event\",\"name\":\"ExecutionAdded\",\"inputs\":[{\"name\":\"smartAccount\",\"type\":\"address\",\"indexed\":true,\"internalType\":\"address\"},{\"name\":\"jobId\",\"type\":\"uint256\",\"indexed\":true,\"internalType\":\"uint256\"}],\"anonymous\":false},{\"type\":\"event\",\"name\":\"ExecutionStatusUpdated\",\"inputs\":[{\"name\":\"smartAccount\",\"type\":\"address\",\"indexed\":true,\"internalType\":\"address\"},{\"name\":\"jobId\",\"type\":\"uint256\",\"indexed\":true,\"internalType\":\"uint256\"}],\"anonymous\":false},{\"type\":\"event\",\"name\":\"ExecutionTriggered\",\"inputs\":[{\"name\":\"smartAccount\",\"type\":\"address\",\"indexed\":true,\"internalType\":\"address\"},{\"name\":\"jobId\",\"type\":\"uint256\",\"indexed\":true,\"internalType\":\"uint256\"}],\"anonymous\":false},{\"type\":\"event\",\"name\":\"ExecutionsCancelled\",\"inputs\":[{\"name\":\"smartAccount\",\"type\":\"address\",\"indexed\":true,\"internalType\":\"address\"}],\"anonymous\":false},{\"type\":\"error\",\"name\":\"AlreadyInitialized\",\"inputs\":[{\"name\":\"smartAccount\",\"type\":\"address\",\"internalType\":\"address\"}]},{\"type\":\"error\",\"name\":\"InvalidExecution\",\"inputs\":[]},{\"type\":\"error\",\"name\":\"NotInitialized\",\"inputs\":[{\"name\":\"smartAccount\",\"type\":\"address\",\"internalType\":\"address\"}]}]",
+	ID:  "ScheduledOrders",
+}
+
+// ScheduledOrders is an auto generated Go binding around an Ethereum contract.
+type ScheduledOrders struct {
+	abi abi.ABI
+}
+
+// NewScheduledOrders creates a new instance of ScheduledOrders.
+func NewScheduledOrders() *ScheduledOrders {
+	parsed, err := ScheduledOrdersMetaData.ParseABI()
+	if err != nil {
+		panic(errors.New("invalid ABI: " + err.Error()))
+	}
+	return &ScheduledOrders{abi: *parsed}
+}
+
+// Instance creates a wrapper for a deployed contract instance at the given address.
+// Use this to create the instance object passed to abigen v2 library functions Call, Transact, etc.
+func (c *ScheduledOrders) Instance(backend bind.ContractBackend, addr common.Address) *bind.BoundContract {
+	return bind.NewBoundContract(addr, c.abi, backend, backend, backend)
+}
+
+// PackAccountJobCount is the Go binding used to pack the parameters required for calling
+// the contract method with ID 0x8eda33d3.
+//
+// Solidity: function accountJobCount(address smartAccount) view returns(uint256 jobCount)
+func (scheduledOrders *ScheduledOrders) PackAccountJobCount(smartAccount common.Address) []byte {
+	enc, err := scheduledOrders.abi.Pack("accountJobCount", smartAccount)
+	if err != nil {
+		panic(err)
+	}
+	return enc
+}
+
+// UnpackAccountJobCount is the Go binding that unpacks the parameters returned
+// from invoking the contract method with ID 0x8eda33d3.
+//
+// Solidity: function accountJobCount(address smartAccount) view returns(uint256 jobCount)
+func (scheduledOrders *ScheduledOrders) UnpackAccountJobCount(data []byte) (*big.Int, error) {
+	out, err := scheduledOrders.abi.Unpack("accountJobCount", data)
+	if err != nil {
+		return new(big.Int), err
+	}
+	out0 := abi.ConvertType(out[0], new(big.Int)).(*big.Int)
+	return out0, err
+}
+
+// PackAddOrder is the Go binding used to pack the parameters required for calling
+// the contract method with ID 0xa3d6df42.
+//
+// Solidity: function addOrder(bytes orderData) returns()
+func (scheduledOrders *ScheduledOrders) PackAddOrder(orderData []byte) []byte {
+	enc, err := scheduledOrders.abi.Pack("addOrder", orderData)
+	if err != nil {
+		panic(err)
+	}
+	return enc
+}
+
+// PackExecuteOrder is the Go binding used to pack the parameters required for calling
+// the contract method with ID 0x88c102e7.
+//
+// Solidity: function executeOrder(uint256 jobId, uint160 sqrtPriceLimitX96, uint256 amountOutMinimum, uint16 fee) returns()
+func (scheduledOrders *ScheduledOrders) PackExecuteOrder(jobId *big.Int, sqrtPriceLimitX96 *big.Int, amountOutMinimum *big.Int, fee uint16) []byte {
+	enc, err := scheduledOrders.abi.Pack("executeOrder", jobId, sqrtPriceLimitX96, amountOutMinimum, fee)
+	if err != nil {
+		panic(err)
+	}
+	return enc
+}
+
+// PackExecutionLog is the Go binding used to pack the parameters required for calling
+// the contract method with ID 0x548dbd71.
+//
+// Solidity: function executionLog(address smartAccount, uint256 jobId) view returns(uint48 executeInterval, uint16 numberOfExecutions, uint16 numberOfExecutionsCompleted, uint48 startDate, bool isEnabled, uint48 lastExecutionTime, bytes executionData)
+func (scheduledOrders *ScheduledOrders) PackExecutionLog(smartAccount common.Address, jobId *big.Int) []byte {
+	enc, err := scheduledOrders.abi.Pack("executionLog", smartAccount, jobId)
+	if err != nil {
+		panic(err)
+	}
+	return enc
+}
+
+// ExecutionLogOutput serves as a container for the return parameters of contract
+// method ExecutionLog.
+type ExecutionLogOutput struct {
+	ExecuteInterval             *big.Int
+	NumberOfExecutions          uint16
+	NumberOfExecutionsCompleted uint16
+	StartDate                   *big.Int
+	IsEnabled                   bool
+	LastExecutionTime           *big.Int
+	ExecutionData               []byte
+}
+
+// UnpackExecutionLog is the Go binding that unpacks the parameters returned
+// from invoking the contract method with ID 0x548dbd71.
+//
+// Solidity: function executionLog(address smartAccount, uint256 jobId) view returns(uint48 executeInterval, uint16 numberOfExecutions, uint16 numberOfExecutionsCompleted, uint48 startDate, bool isEnabled, uint48 lastExecutionTime, bytes executionData)
+func (scheduledOrders *ScheduledOrders) UnpackExecutionLog(data []byte) (ExecutionLogOutput, error) {
+	out, err := scheduledOrders.abi.Unpack("executionLog", data)
+	outstruct := new(ExecutionLogOutput)
+	if err != nil {
+		return *outstruct, err
+	}
+	outstruct.ExecuteInterval = abi.ConvertType(out[0], new(big.Int)).(*big.Int)
+	outstruct.NumberOfExecutions = *abi.ConvertType(out[1], new(uint16)).(*uint16)
+	outstruct.NumberOfExecutionsCompleted = *abi.ConvertType(out[2], new(uint16)).(*uint16)
+	outstruct.StartDate = abi.ConvertType(out[3], new(big.Int)).(*big.Int)
+	outstruct.IsEnabled = *abi.ConvertType(out[4], new(bool)).(*bool)
+	outstruct.LastExecutionTime = abi.ConvertType(out[5], new(big.Int)).(*big.Int)
+	outstruct.ExecutionData = *abi.ConvertType(out[6], new([]byte)).(*[]byte)
+	return *outstruct, err
+
+}
+
+// PackIsInitialized is the Go binding used to pack the parameters required for calling
+// the contract method with ID 0xd60b347f.
+//
+// Solidity: function isInitialized(address smartAccount) view returns(bool)
+func (scheduledOrders *ScheduledOrders) PackIsInitialized(smartAccount common.Address) []byte {
+	enc, err := scheduledOrders.abi.Pack("isInitialized", smartAccount)
+	if err != nil {
+		panic(err)
+	}
+	return enc
+}
+
+// UnpackIsInitialized is the Go binding that unpacks the parameters returned
+// from invoking the contract method with ID 0xd60b347f.
+//
+// Solidity: function isInitialized(address smartAccount) view returns(bool)
+func (scheduledOrders *ScheduledOrders) UnpackIsInitialized(data []byte) (bool, error) {
+	out, err := scheduledOrders.abi.Unpack("isInitialized", data)
+	if err != nil {
+		return *new(bool), err
+	}
+	out0 := *abi.ConvertType(out[0], new(bool)).(*bool)
+	return out0, err
+}
+
+// PackIsModuleType is the Go binding used to pack the parameters required for calling
+// the contract method with ID 0xecd05961.
+//
+// Solidity: function isModuleType(uint256 typeID) pure returns(bool)
+func (scheduledOrders *ScheduledOrders) PackIsModuleType(typeID *big.Int) []byte {
+	enc, err := scheduledOrders.abi.Pack("isModuleType", typeID)
+	if err != nil {
+		panic(err)
+	}
+	return enc
+}
+
+// UnpackIsModuleType is the Go binding that unpacks the parameters returned
+// from invoking the contract method with ID 0xecd05961.
+//
+// Solidity: function isModuleType(uint256 typeID) pure returns(bool)
+func (scheduledOrders *ScheduledOrders) UnpackIsModuleType(data []byte) (bool, error) {
+	out, err := scheduledOrders.abi.Unpack("isModuleType", data)
+	if err != nil {
+		return *new(bool), err
+	}
+	out0 := *abi.ConvertType(out[0], new(bool)).(*bool)
+	return out0, err
+}
+
+// PackName is the Go binding used to pack the parameters required for calling
+// the contract method with ID 0x06fdde03.
+//
+// Solidity: function name() pure returns(string)
+func (scheduledOrders *ScheduledOrders) PackName() []byte {
+	enc, err := scheduledOrders.abi.Pack("name")
+	if err != nil {
+		panic(err)
+	}
+	return enc
+}
+
+// UnpackName is the Go binding that unpacks the parameters returned
+// from invoking the contract method with ID 0x06fdde03.
+//
+// Solidity: function name() pure returns(string)
+func (scheduledOrders *ScheduledOrders) UnpackName(data []byte) (string, error) {
+	out, err := scheduledOrders.abi.Unpack("name", data)
+	if err != nil {
+		return *new(string), err
+	}
+	out0 := *abi.ConvertType(out[0], new(string)).(*string)
+	return out0, err
+}
+
+// PackOnInstall is the Go binding used to pack the parameters required for calling
+// the contract method with ID 0x6d61fe70.
+//
+// Solidity: function onInstall(bytes data) returns()
+func (scheduledOrders *ScheduledOrders) PackOnInstall(data []byte) []byte {
+	enc, err := scheduledOrders.abi.Pack("onInstall", data)
+	if err != nil {
+		panic(err)
+	}
+	return enc
+}
+
+// PackOnUninstall is the Go binding used to pack the parameters required for calling
+// the contract method with ID 0x8a91b0e3.
+//
+// Solidity: function onUninstall(bytes ) returns()
+func (scheduledOrders *ScheduledOrders) PackOnUninstall(arg0 []byte) []byte {
+	enc, err := scheduledOrders.abi.Pack("onUninstall", arg0)
+	if err != nil {
+		panic(err)
+	}
+	return enc
+}
+
+// PackToggleOrder is the Go binding used to pack the parameters required for calling
+// the contract method with ID 0x8805cbd2.
+//
+// Solidity: function toggleOrder(uint256 jobId) returns()
+func (scheduledOrders *ScheduledOrders) PackToggleOrder(jobId *big.Int) []byte {
+	enc, err := scheduledOrders.abi.Pack("toggleOrder", jobId)
+	if err != nil {
+		panic(err)
+	}
+	return enc
+}
+
+// PackVersion is the Go binding used to pack the parameters required for calling
+// the contract method with ID 0x54fd4d50.
+//
+// Solidity: function version() pure returns(string)
+func (scheduledOrders *ScheduledOrders) PackVersion() []byte {
+	enc, err := scheduledOrders.abi.Pack("version")
+	if err != nil {
+		panic(err)
+	}
+	return enc
+}
+
+// UnpackVersion is the Go binding that unpacks the parameters returned
+// from invoking the contract method with ID 0x54fd4d50.
+//
+// Solidity: function version() pure returns(string)
+func (scheduledOrders *ScheduledOrders) UnpackVersion(data []byte) (string, error) {
+	out, err := scheduledOrders.abi.Unpack("version", data)
+	if err != nil {
+		return *new(string), err
+	}
+	out0 := *abi.ConvertType(out[0], new(string)).(*string)
+	return out0, err
+}
+
+// ScheduledOrdersExecutionAdded represents a ExecutionAdded event raised by the ScheduledOrders contract.
+type ScheduledOrdersExecutionAdded struct {
+	SmartAccount common.Address
+	JobId        *big.Int
+	Raw          *types.Log // Blockchain specific contextual infos
+}
+
+const ScheduledOrdersExecutionAddedEventName = "ExecutionAdded"
+
+// ContractEventName returns the user-defined event name.
+func (ScheduledOrdersExecutionAdded) ContractEventName() string {
+	return ScheduledOrdersExecutionAddedEventName
+}
+
+// UnpackExecutionAddedEvent is the Go binding that unpacks the event data emitted
+// by contract.
+//
+// Solidity: event ExecutionAdded(address indexed smartAccount, uint256 indexed jobId)
+func (scheduledOrders *ScheduledOrders) UnpackExecutionAddedEvent(log *types.Log) (*ScheduledOrdersExecutionAdded, error) {
+	event := "ExecutionAdded"
+	if log.Topics[0] != scheduledOrders.abi.Events[event].ID {
+		return nil, errors.New("event signature mismatch")
+	}
+	out := new(ScheduledOrdersExecutionAdded)
+	if len(log.Data) > 0 {
+		if err := scheduledOrders.abi.UnpackIntoInterface(out, event, log.Data); err != nil {
+			return nil, err
+		}
+	}
+	var indexed abi.Arguments
+	for _, arg := range scheduledOrders.abi.Events[event].Inputs {
+		if arg.Indexed {
+			indexed = append(indexed, arg)
+		}
+	}
+	if err := abi.ParseTopics(out, indexed, log.Topics[1:]); err != nil {
+		return nil, err
+	}
+	out.Raw = log
+	return out, nil
+}
+
+// ScheduledOrdersExecutionStatusUpdated represents a ExecutionStatusUpdated event raised by the ScheduledOrders contract.
+type ScheduledOrdersExecutionStatusUpdated struct {
+	SmartAccount common.Address
+	JobId        *big.Int
+	Raw          *types.Log // Blockchain specific contextual infos
+}
+
+const ScheduledOrdersExecutionStatusUpdatedEventName = "ExecutionStatusUpdated"
+
+// ContractEventName returns the user-defined event name.
+func (ScheduledOrdersExecutionStatusUpdated) ContractEventName() string {
+	return ScheduledOrdersExecutionStatusUpdatedEventName
+}
+
+// UnpackExecutionStatusUpdatedEvent is the Go binding that unpacks the event data emitted
+// by contract.
+//
+// Solidity: event ExecutionStatusUpdated(address indexed smartAccount, uint256 indexed jobId)
+func (scheduledOrders *ScheduledOrders) UnpackExecutionStatusUpdatedEvent(log *types.Log) (*ScheduledOrdersExecutionStatusUpdated, error) {
+	event := "ExecutionStatusUpdated"
+	if log.Topics[0] != scheduledOrders.abi.Events[event].ID {
+		return nil, errors.New("event signature mismatch")
+	}
+	out := new(ScheduledOrdersExecutionStatusUpdated)
+	if len(log.Data) > 0 {
+		if err := scheduledOrders.abi.UnpackIntoInterface(out, event, log.Data); err != nil {
+			return nil, err
+		}
+	}
+	var indexed abi.Arguments
+	for _, arg := range scheduledOrders.abi.Events[event].Inputs {
+		if arg.Indexed {
+			indexed = append(indexed, arg)
+		}
+	}
+	if err := abi.ParseTopics(out, indexed, log.Topics[1:]); err != nil {
+		return nil, err
+	}
+	out.Raw = log
+	return out, nil
+}
+
+// ScheduledOrdersExecutionTriggered represents a ExecutionTriggered event raised by the ScheduledOrders contract.
+type ScheduledOrdersExecutionTriggered struct {
+	SmartAccount common.Address
+	JobId        *big.Int
+	Raw          *types.Log // Blockchain specific contextual infos
+}
+
+const ScheduledOrdersExecutionTriggeredEventName = "ExecutionTriggered"
+
+// ContractEventName returns the user-defined event name.
+func (ScheduledOrdersExecutionTriggered) ContractEventName() string {
+	return ScheduledOrdersExecutionTriggeredEventName
+}
+
+// UnpackExecutionTriggeredEvent is the Go binding that unpacks the event data emitted
+// by contract.
+//
+// Solidity: event ExecutionTriggered(address indexed smartAccount, uint256 indexed jobId)
+func (scheduledOrders *ScheduledOrders) UnpackExecutionTriggeredEvent(log *types.Log) (*ScheduledOrdersExecutionTriggered, error) {
+	event := "ExecutionTriggered"
+	if log.Topics[0] != scheduledOrders.abi.Events[event].ID {
+		return nil, errors.New("event signature mismatch")
+	}
+	out := new(ScheduledOrdersExecutionTriggered)
+	if len(log.Data) > 0 {
+		if err := scheduledOrders.abi.UnpackIntoInterface(out, event, log.Data); err != nil {
+			return nil, err
+		}
+	}
+	var indexed abi.Arguments
+	for _, arg := range scheduledOrders.abi.Events[event].Inputs {
+		if arg.Indexed {
+			indexed = append(indexed, arg)
+		}
+	}
+	if err := abi.ParseTopics(out, indexed, log.Topics[1:]); err != nil {
+		return nil, err
+	}
+	out.Raw = log
+	return out, nil
+}
+
+// ScheduledOrdersExecutionsCancelled represents a ExecutionsCancelled event raised by the ScheduledOrders contract.
+type ScheduledOrdersExecutionsCancelled struct {
+	SmartAccount common.Address
+	Raw          *types.Log // Blockchain specific contextual infos
+}
+
+const ScheduledOrdersExecutionsCancelledEventName = "ExecutionsCancelled"
+
+// ContractEventName returns the user-defined event name.
+func (ScheduledOrdersExecutionsCancelled) ContractEventName() string {
+	return ScheduledOrdersExecutionsCancelledEventName
+}
+
+// UnpackExecutionsCancelledEvent is the Go binding that unpacks the event data emitted
+// by contract.
+//
+// Solidity: event ExecutionsCancelled(address indexed smartAccount)
+func (scheduledOrders *ScheduledOrders) UnpackExecutionsCancelledEvent(log *types.Log) (*ScheduledOrdersExecutionsCancelled, error) {
+	event := "ExecutionsCancelled"
+	if log.Topics[0] != scheduledOrders.abi.Events[event].ID {
+		return nil, errors.New("event signature mismatch")
+	}
+	out := new(ScheduledOrdersExecutionsCancelled)
+	if len(log.Data) > 0 {
+		if err := scheduledOrders.abi.UnpackIntoInterface(out, event, log.Data); err != nil {
+			return nil, err
+		}
+	}
+	var indexed abi.Arguments
+	for _, arg := range scheduledOrders.abi.Events[event].Inputs {
+		if arg.Indexed {
+			indexed = append(indexed, arg)
+		}
+	}
+	if err := abi.ParseTopics(out, indexed, log.Topics[1:]); err != nil {
+		return nil, err
+	}
+	out.Raw = log
+	return out, nil
+}
+
+// UnpackError attempts to decode the provided error data using user-defined
+// error definitions.
+func (scheduledOrders *ScheduledOrders) UnpackError(raw []byte) (any, error) {
+	if bytes.Equal(raw[:4], scheduledOrders.abi.Errors["AlreadyInitialized"].ID.Bytes()[:4]) {
+		return scheduledOrders.UnpackAlreadyInitializedError(raw[4:])
+	}
+	if bytes.Equal(raw[:4], scheduledOrders.abi.Errors["InvalidExecution"].ID.Bytes()[:4]) {
+		return scheduledOrders.UnpackInvalidExecutionError(raw[4:])
+	}
+	if bytes.Equal(raw[:4], scheduledOrders.abi.Errors["NotInitialized"].ID.Bytes()[:4]) {
+		return scheduledOrders.UnpackNotInitializedError(raw[4:])
+	}
+	return nil, errors.New("Unknown error")
+}
+
+// ScheduledOrdersAlreadyInitialized represents a AlreadyInitialized error raised by the ScheduledOrders contract.
+type ScheduledOrdersAlreadyInitialized struct {
+	SmartAccount common.Address
+}
+
+// ErrorID returns the hash of canonical representation of the error's signature.
+//
+// Solidity: error AlreadyInitialized(address smartAccount)
+func ScheduledOrdersAlreadyInitializedErrorID() common.Hash {
+	return common.HexToHash("0x93360fbf8a5bb1666656771229b5f96751e7c99ebfe95e80a8416cbefd7fd9da")
+}
+
+// UnpackAlreadyInitializedError is the Go binding used to decode the provided
+// error data into the corresponding Go error struct.
+//
+// Solidity: error AlreadyInitialized(address smartAccount)
+func (scheduledOrders *ScheduledOrders) UnpackAlreadyInitializedError(raw []byte) (*ScheduledOrdersAlreadyInitialized, error) {
+	out := new(ScheduledOrdersAlreadyInitialized)
+	if err := scheduledOrders.abi.UnpackIntoInterface(out, "AlreadyInitialized", raw); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ScheduledOrdersInvalidExecution represents a InvalidExecution error raised by the ScheduledOrders contract.
+type ScheduledOrdersInvalidExecution struct {
+}
+
+// ErrorID returns the hash of canonical representation of the error's signature.
+//
+// Solidity: error InvalidExecution()
+func ScheduledOrdersInvalidExecutionErrorID() common.Hash {
+	return common.HexToHash("0x29adbb292fb39b5ec72dd2cf15f2b75e75c3f77323231f0332f644ee8539ceef")
+}
+
+// UnpackInvalidExecutionError is the Go binding used to decode the provided
+// error data into the corresponding Go error struct.
+//
+// Solidity: error InvalidExecution()
+func (scheduledOrders *ScheduledOrders) UnpackInvalidExecutionError(raw []byte) (*ScheduledOrdersInvalidExecution, error) {
+	out := new(ScheduledOrdersInvalidExecution)
+	if err := scheduledOrders.abi.UnpackIntoInterface(out, "InvalidExecution", raw); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ScheduledOrdersNotInitialized represents a NotInitialized error raised by the ScheduledOrders contract.
+type ScheduledOrdersNotInitialized struct {
+	SmartAccount common.Address
+}
+
+// ErrorID returns the hash of canonical representation of the error's signature.
+//
+// Solidity: error NotInitialized(address smartAccount)
+func ScheduledOrdersNotInitializedErrorID() common.Hash {
+	return common.HexToHash("0xf91bd6f1b02fa64d24fb54dea79a60eda0fd046154dcb147e76ca498113f5311")
+}
+
+// UnpackNotInitializedError is the Go binding used to decode the provided
+// error data into the corresponding Go error struct.
+//
+// Solidity: error NotInitialized(address smartAccount)
+func (scheduledOrders *ScheduledOrders) UnpackNotInitializedError(raw []byte) (*ScheduledOrdersNotInitialized, error) {
+	out := new(ScheduledOrdersNotInitialized)
+	if err := scheduledOrders.abi.UnpackIntoInterface(out, "NotInitialized", raw); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
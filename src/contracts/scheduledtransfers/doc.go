@@ -0,0 +1,5 @@
+// Package scheduledtransfers contains the Go bindings of the ScheduledTransfers module, which makes recurring
+// native or ERC-20 token transfers from smart accounts, and the decoder of its execution data.
+package scheduledtransfers
+
+//go:generate go run github.com/ethereum/go-ethereum/cmd/abigen --v2 --abi ScheduledTransfers.abi.json --pkg scheduledtransfers --type ScheduledTransfers --out scheduled_transfers.go
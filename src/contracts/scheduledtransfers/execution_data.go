@@ -0,0 +1,47 @@
+package scheduledtransfers
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// executionDataArgs is abi.encode(address recipient, address token, uint256 amount)
+var executionDataArgs = func() abi.Arguments {
+	addressType, _ := abi.NewType("address", "", nil)
+	uint256Type, _ := abi.NewType("uint256", "", nil)
+	return abi.Arguments{{Name: "recipient", Type: addressType}, {Name: "token", Type: addressType}, {Name: "amount", Type: uint256Type}}
+}()
+
+// ExecutionData is the transfer made by each execution of a job
+type ExecutionData struct {
+	Recipient common.Address
+	// Token is the ERC-20 token transferred, or the zero address for the native token
+	Token  common.Address
+	Amount *big.Int
+}
+
+// IsNative reports whether the transfer is of the native token
+func (d *ExecutionData) IsNative() bool {
+	return d.Token == (common.Address{})
+}
+
+// DecodeExecutionData decodes the execution data of a ScheduledTransfers job
+func DecodeExecutionData(data []byte) (*ExecutionData, error) {
+	unpacked, err := executionDataArgs.Unpack(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode transfer execution data: %w", err)
+	}
+	return &ExecutionData{
+		Recipient: unpacked[0].(common.Address),
+		Token:     unpacked[1].(common.Address),
+		Amount:    unpacked[2].(*big.Int),
+	}, nil
+}
+
+// Encode encodes the execution data as passed to addOrder and returned by executionLog
+func (d *ExecutionData) Encode() ([]byte, error) {
+	return executionDataArgs.Pack(d.Recipient, d.Token, d.Amount)
+}
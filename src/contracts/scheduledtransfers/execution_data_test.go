@@ -0,0 +1,63 @@
+package scheduledtransfers
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecutionData(t *testing.T) {
+	usdc := common.HexToAddress("0x1c7d4b196cb0c7b01d743fbc6116a902379c7238")
+	recipient := common.HexToAddress("0x1234567890123456789012345678901234567890")
+
+	encoded, err := (&ExecutionData{Recipient: recipient, Token: usdc, Amount: big.NewInt(1_000_000)}).Encode()
+	require.NoError(t, err)
+	assert.Len(t, encoded, 3*32)
+
+	data, err := DecodeExecutionData(encoded)
+	require.NoError(t, err)
+	assert.Equal(t, recipient, data.Recipient)
+	assert.Equal(t, usdc, data.Token)
+	assert.Equal(t, int64(1_000_000), data.Amount.Int64())
+	assert.False(t, data.IsNative())
+
+	_, err = DecodeExecutionData(encoded[:64])
+	assert.ErrorContains(t, err, "failed to decode transfer execution data")
+}
+
+func TestScheduledTransfers(t *testing.T) {
+	scheduledTransfers := NewScheduledTransfers()
+
+	// The call of the module in the sample user operation of cmd/erc4337
+	assert.Equal(t, "0x94f611340000000000000000000000000000000000000000000000000000000000000009", hexutil.Encode(scheduledTransfers.PackExecuteOrder(big.NewInt(9))))
+
+	executionData, err := (&ExecutionData{Recipient: common.HexToAddress("0x01"), Amount: big.NewInt(1)}).Encode()
+	require.NoError(t, err)
+	output, err := scheduledTransfers.abi.Methods["executionLog"].Outputs.Pack(big.NewInt(60), uint16(10), uint16(3), big.NewInt(1), true, big.NewInt(120), executionData)
+	require.NoError(t, err)
+	log, err := scheduledTransfers.UnpackExecutionLog(output)
+	require.NoError(t, err)
+	assert.Equal(t, uint16(3), log.NumberOfExecutionsCompleted)
+	assert.True(t, log.IsEnabled)
+	assert.Equal(t, executionData, log.ExecutionData)
+
+	account := common.HexToAddress("0x47d6a8a65cba9b61b194dac740aa192a7a1e91e1")
+	event, err := scheduledTransfers.UnpackExecutionTriggeredEvent(&types.Log{Topics: []common.Hash{
+		scheduledTransfers.abi.Events[ScheduledTransfersExecutionTriggeredEventName].ID,
+		common.BytesToHash(account.Bytes()),
+		common.BigToHash(big.NewInt(9)),
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, account, event.SmartAccount)
+	assert.Equal(t, int64(9), event.JobId.Int64())
+
+	revert := append(ScheduledTransfersNotInitializedErrorID().Bytes()[:4], common.LeftPadBytes(account.Bytes(), 32)...)
+	decoded, err := scheduledTransfers.UnpackError(revert)
+	require.NoError(t, err)
+	assert.Equal(t, account, decoded.(*ScheduledTransfersNotInitialized).SmartAccount)
+}
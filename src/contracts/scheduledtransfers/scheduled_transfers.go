@@ -0,0 +1,534 @@
+// Code generated via abigen V2 - DO NOT EDIT.
+// This file is a generated binding and any manual changes will be lost.
+
+package scheduledtransfers
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind/v2"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var (
+	_ = bytes.Equal
+	_ = errors.New
+	_ = big.NewInt
+	_ = common.Big1
+	_ = types.BloomLookup
+	_ = abi.ConvertType
+)
+
+// ScheduledTransfersMetaData contains all meta data concerning the ScheduledTransfers contract.
+var ScheduledTransfersMetaData = bind.MetaData{
+	ABI: "[{\"type\":\"function\",\"name\":\"accountJobCount\",\"inputs\":[{\"name\":\"smartAccount\",\"type\":\"address\",\"internalType\":\"address\"}],\"outputs\":[{\"name\":\"jobCount\",\"type\":\"uint256\",\"internalType\":\"uint256\"}],\"stateMutability\":\"view\"},{\"type\":\"function\",\"name\":\"addOrder\",\"inputs\":[{\"name\":\"orderData\",\"type\":\"bytes\",\"internalType\":\"bytes\"}],\"outputs\":[],\"stateMutability\":\"nonpayable\"},{\"type\":\"function\",\"name\":\"executeOrder\",\"inputs\":[{\"name\":\"jobId\",\"type\":\"uint256\",\"internalType\":\"uint256\"}],\"outputs\":[],\"stateMutability\":\"nonpayable\"},{\"type\":\"function\",\"name\":\"executionLog\",\"inputs\":[{\"name\":\"smartAccount\",\"type\":\"address\",\"internalType\":\"address\"},{\"name\":\"jobId\",\"type\":\"uint256\",\"internalType\":\"uint256\"}],\"outputs\":[{\"name\":\"executeInterval\",\"type\":\"uint48\",\"internalType\":\"uint48\"},{\"name\":\"numberOfExecutions\",\"type\":\"uint16\",\"internalType\":\"uint16\"},{\"name\":\"numberOfExecutionsCompleted\",\"type\":\"uint16\",\"internalType\":\"uint16\"},{\"name\":\"startDate\",\"type\":\"uint48\",\"internalType\":\"uint48\"},{\"name\":\"isEnabled\",\"type\":\"bool\",\"internalType\":\"bool\"},{\"name\":\"lastExecutionTime\",\"type\":\"uint48\",\"internalType\":\"uint48\"},{\"name\":\"executionData\",\"type\":\"bytes\",\"internalType\":\"bytes\"}],\"stateMutability\":\"view\"},{\"type\":\"function\",\"name\":\"isInitialized\",\"inputs\":[{\"name\":\"smartAccount\",\"type\":\"address\",\"internalType\":\"address\"}],\"outputs\":[{\"name\":\"\",\"type\":\"bool\",\"internalType\":\"bool\"}],\"stateMutability\":\"view\"},{\"type\":\"function\",\"name\":\"isModuleType\",\"inputs\":[{\"name\":\"typeID\",\"type\":\"uint256\",\"internalType\":\"uint256\"}],\"outputs\":[{\"name\":\"\",\"type\":\"bool\",\"internalType\":\"bool\"}],\"stateMutability\":\"pure\"},{\"type\":\"function\",\"name\":\"name\",\"inputs\":[],\"outputs\":[{\"name\":\"\",\"type\":\"string\",\"internalType\":\"string\"}],\"stateMutability\":\"pure\"},{\"type\":\"function\",\"name\":\"onInstall\",\"inputs\":[{\"name\":\"data\",\"type\":\"bytes\",\"internalType\":\"bytes\"}],\"outputs\":[],\"stateMutability\":\"nonpayable\"},{\"type\":\"function\",\"name\":\"onUninstall\",\"inputs\":[{\"name\":\"\",\"type\":\"bytes\",\"internalType\":\"bytes\"}],\"outputs\":[],\"stateMutability\":\"nonpayable\"},{\"type\":\"function\",\"name\":\"toggleOrder\",\"inputs\":[{\"name\":\"jobId\",\"type\":\"uint256\",\"internalType\":\"uint256\"}],\"outputs\":[],\"stateMutability\":\"nonpayable\"},{\"type\":\"function\",\"name\":\"version\",\"inputs\":[],\"outputs\":[{\"name\":\"\",\"type\":\"string\",\"internalType\":\"string\"}],\"stateMutability\":\"pure\"},{\"type\":\"event\",\"name\":\"ExecutionAdded\",\"inputs\":[{\"name\":\"smartAccount\",\"type\":\"address\",\"indexed\":true,\"internalType\":\"address\"},{\"name\":\"jobId\",\"type\":\"uint256\",\"indexed\":true,\"internalType\":\"uint256\"}],\"anonymous\":false},{\"type\":\"event\",\"name\":\"ExecutionStatusUpdated\",\"inputs\":[{\"name\":\"smartAccount\",\"type\":\"address\",\"indexed\":true,\"internalType\":\"address\"},{\"name\":\"jobId\",\"type\":\"uint256\",\"indexed\":true,\"internalType\":\"uint256\"}],\"anonymous\":false},{\"type\":\"event\",\"name\":\"ExecutionTriggered\",\"inputs\":[{\"name\":\"smartAccount\",\"type\":\"address\",\"indexed\":true,\"internalType\":\"address\"},{\"name\":\"jobId\",\"type\":\"uint256\",\"indexed\":true,\"internalType\":\"uint256\"}],\"anonymous\":false},{\"type\":\"event\",\"name\":\"ExecutionsCancelled\",\"inputs\":[{\"name\":\"smartAccount\",\"type\":\"address\",\"indexed\":true,\"internalType\":\"address\"}],\"anonymous\":false},{\"type\":\"error\",\"name\":\"AlreadyInitialized\",\"inputs\":[{\"name\":\"smartAccount\",\"type\":\"address\",\"internalType\":\"address\"}]},{\"type\":\"error\",\"name\":\"InvalidExecution\",\"inputs\":[]},{\"type\":\"error\",\"name\":\"NotInitialized\",\"inputs\":[{\"name\":\"smartAccount\",\"type\":\"address\",\"internalType\":\"address\"}]}]",
+	ID:  "ScheduledTransfers",
+}
+
+// ScheduledTransfers is an auto generated Go binding around an Ethereum contract.
+type ScheduledTransfers struct {
+	abi abi.ABI
+}
+
+// NewScheduledTransfers creates a new instance of ScheduledTransfers.
+func NewScheduledTransfers() *ScheduledTransfers {
+	parsed, err := ScheduledTransfersMetaData.ParseABI()
+	if err != nil {
+		panic(errors.New("invalid ABI: " + err.Error()))
+	}
+	return &ScheduledTransfers{abi: *parsed}
+}
+
+// Instance creates a wrapper for a deployed contract instance at the given address.
+// Use this to create the instance object passed to abigen v2 library functions Call, Transact, etc.
+func (c *ScheduledTransfers) Instance(backend bind.ContractBackend, addr common.Address) *bind.BoundContract {
+	return bind.NewBoundContract(addr, c.abi, backend, backend, backend)
+}
+
+// PackAccountJobCount is the Go binding used to pack the parameters required for calling
+// the contract method with ID 0x8eda33d3.
+//
+// Solidity: function accountJobCount(address smartAccount) view returns(uint256 jobCount)
+func (scheduledTransfers *ScheduledTransfers) PackAccountJobCount(smartAccount common.Address) []byte {
+	enc, err := scheduledTransfers.abi.Pack("accountJobCount", smartAccount)
+	if err != nil {
+		panic(err)
+	}
+	return enc
+}
+
+// UnpackAccountJobCount is the Go binding that unpacks the parameters returned
+// from invoking the contract method with ID 0x8eda33d3.
+//
+// Solidity: function accountJobCount(address smartAccount) view returns(uint256 jobCount)
+func (scheduledTransfers *ScheduledTransfers) UnpackAccountJobCount(data []byte) (*big.Int, error) {
+	out, err := scheduledTransfers.abi.Unpack("accountJobCount", data)
+	if err != nil {
+		return new(big.Int), err
+	}
+	out0 := abi.ConvertType(out[0], new(big.Int)).(*big.Int)
+	return out0, err
+}
+
+// PackAddOrder is the Go binding used to pack the parameters required for calling
+// the contract method with ID 0xa3d6df42.
+//
+// Solidity: function addOrder(bytes orderData) returns()
+func (scheduledTransfers *ScheduledTransfers) PackAddOrder(orderData []byte) []byte {
+	enc, err := scheduledTransfers.abi.Pack("addOrder", orderData)
+	if err != nil {
+		panic(err)
+	}
+	return enc
+}
+
+// PackExecuteOrder is the Go binding used to pack the parameters required for calling
+// the contract method with ID 0x94f61134.
+//
+// Solidity: function executeOrder(uint256 jobId) returns()
+func (scheduledTransfers *ScheduledTransfers) PackExecuteOrder(jobId *big.Int) []byte {
+	enc, err := scheduledTransfers.abi.Pack("executeOrder", jobId)
+	if err != nil {
+		panic(err)
+	}
+	return enc
+}
+
+// PackExecutionLog is the Go binding used to pack the parameters required for calling
+// the contract method with ID 0x548dbd71.
+//
+// Solidity: function executionLog(address smartAccount, uint256 jobId) view returns(uint48 executeInterval, uint16 numberOfExecutions, uint16 numberOfExecutionsCompleted, uint48 startDate, bool isEnabled, uint48 lastExecutionTime, bytes executionData)
+func (scheduledTransfers *ScheduledTransfers) PackExecutionLog(smartAccount common.Address, jobId *big.Int) []byte {
+	enc, err := scheduledTransfers.abi.Pack("executionLog", smartAccount, jobId)
+	if err != nil {
+		panic(err)
+	}
+	return enc
+}
+
+// ExecutionLogOutput serves as a container for the return parameters of contract
+// method ExecutionLog.
+type ExecutionLogOutput struct {
+	ExecuteInterval             *big.Int
+	NumberOfExecutions          uint16
+	NumberOfExecutionsCompleted uint16
+	StartDate                   *big.Int
+	IsEnabled                   bool
+	LastExecutionTime           *big.Int
+	ExecutionData               []byte
+}
+
+// UnpackExecutionLog is the Go binding that unpacks the parameters returned
+// from invoking the contract method with ID 0x548dbd71.
+//
+// Solidity: function executionLog(address smartAccount, uint256 jobId) view returns(uint48 executeInterval, uint16 numberOfExecutions, uint16 numberOfExecutionsCompleted, uint48 startDate, bool isEnabled, uint48 lastExecutionTime, bytes executionData)
+func (scheduledTransfers *ScheduledTransfers) UnpackExecutionLog(data []byte) (ExecutionLogOutput, error) {
+	out, err := scheduledTransfers.abi.Unpack("executionLog", data)
+	outstruct := new(ExecutionLogOutput)
+	if err != nil {
+		return *outstruct, err
+	}
+	outstruct.ExecuteInterval = abi.ConvertType(out[0], new(big.Int)).(*big.Int)
+	outstruct.NumberOfExecutions = *abi.ConvertType(out[1], new(uint16)).(*uint16)
+	outstruct.NumberOfExecutionsCompleted = *abi.ConvertType(out[2], new(uint16)).(*uint16)
+	outstruct.StartDate = abi.ConvertType(out[3], new(big.Int)).(*big.Int)
+	outstruct.IsEnabled = *abi.ConvertType(out[4], new(bool)).(*bool)
+	outstruct.LastExecutionTime = abi.ConvertType(out[5], new(big.Int)).(*big.Int)
+	outstruct.ExecutionData = *abi.ConvertType(out[6], new([]byte)).(*[]byte)
+	return *outstruct, err
+
+}
+
+// PackIsInitialized is the Go binding used to pack the parameters required for calling
+// the contract method with ID 0xd60b347f.
+//
+// Solidity: function isInitialized(address smartAccount) view returns(bool)
+func (scheduledTransfers *ScheduledTransfers) PackIsInitialized(smartAccount common.Address) []byte {
+	enc, err := scheduledTransfers.abi.Pack("isInitialized", smartAccount)
+	if err != nil {
+		panic(err)
+	}
+	return enc
+}
+
+// UnpackIsInitialized is the Go binding that unpacks the parameters returned
+// from invoking the contract method with ID 0xd60b347f.
+//
+// Solidity: function isInitialized(address smartAccount) view returns(bool)
+func (scheduledTransfers *ScheduledTransfers) UnpackIsInitialized(data []byte) (bool, error) {
+	out, err := scheduledTransfers.abi.Unpack("isInitialized", data)
+	if err != nil {
+		return *new(bool), err
+	}
+	out0 := *abi.ConvertType(out[0], new(bool)).(*bool)
+	return out0, err
+}
+
+// PackIsModuleType is the Go binding used to pack the parameters required for calling
+// the contract method with ID 0xecd05961.
+//
+// Solidity: function isModuleType(uint256 typeID) pure returns(bool)
+func (scheduledTransfers *ScheduledTransfers) PackIsModuleType(typeID *big.Int) []byte {
+	enc, err := scheduledTransfers.abi.Pack("isModuleType", typeID)
+	if err != nil {
+		panic(err)
+	}
+	return enc
+}
+
+// UnpackIsModuleType is the Go binding that unpacks the parameters returned
+// from invoking the contract method with ID 0xecd05961.
+//
+// Solidity: function isModuleType(uint256 typeID) pure returns(bool)
+func (scheduledTransfers *ScheduledTransfers) UnpackIsModuleType(data []byte) (bool, error) {
+	out, err := scheduledTransfers.abi.Unpack("isModuleType", data)
+	if err != nil {
+		return *new(bool), err
+	}
+	out0 := *abi.ConvertType(out[0], new(bool)).(*bool)
+	return out0, err
+}
+
+// PackName is the Go binding used to pack the parameters required for calling
+// the contract method with ID 0x06fdde03.
+//
+// Solidity: function name() pure returns(string)
+func (scheduledTransfers *ScheduledTransfers) PackName() []byte {
+	enc, err := scheduledTransfers.abi.Pack("name")
+	if err != nil {
+		panic(err)
+	}
+	return enc
+}
+
+// UnpackName is the Go binding that unpacks the parameters returned
+// from invoking the contract method with ID 0x06fdde03.
+//
+// Solidity: function name() pure returns(string)
+func (scheduledTransfers *ScheduledTransfers) UnpackName(data []byte) (string, error) {
+	out, err := scheduledTransfers.abi.Unpack("name", data)
+	if err != nil {
+		return *new(string), err
+	}
+	out0 := *abi.ConvertType(out[0], new(string)).(*string)
+	return out0, err
+}
+
+// PackOnInstall is the Go binding used to pack the parameters required for calling
+// the contract method with ID 0x6d61fe70.
+//
+// Solidity: function onInstall(bytes data) returns()
+func (scheduledTransfers *ScheduledTransfers) PackOnInstall(data []byte) []byte {
+	enc, err := scheduledTransfers.abi.Pack("onInstall", data)
+	if err != nil {
+		panic(err)
+	}
+	return enc
+}
+
+// PackOnUninstall is the Go binding used to pack the parameters required for calling
+// the contract method with ID 0x8a91b0e3.
+//
+// Solidity: function onUninstall(bytes ) returns()
+func (scheduledTransfers *ScheduledTransfers) PackOnUninstall(arg0 []byte) []byte {
+	enc, err := scheduledTransfers.abi.Pack("onUninstall", arg0)
+	if err != nil {
+		panic(err)
+	}
+	return enc
+}
+
+// PackToggleOrder is the Go binding used to pack the parameters required for calling
+// the contract method with ID 0x8805cbd2.
+//
+// Solidity: function toggleOrder(uint256 jobId) returns()
+func (scheduledTransfers *ScheduledTransfers) PackToggleOrder(jobId *big.Int) []byte {
+	enc, err := scheduledTransfers.abi.Pack("toggleOrder", jobId)
+	if err != nil {
+		panic(err)
+	}
+	return enc
+}
+
+// PackVersion is the Go binding used to pack the parameters required for calling
+// the contract method with ID 0x54fd4d50.
+//
+// Solidity: function version() pure returns(string)
+func (scheduledTransfers *ScheduledTransfers) PackVersion() []byte {
+	enc, err := scheduledTransfers.abi.Pack("version")
+	if err != nil {
+		panic(err)
+	}
+	return enc
+}
+
+// UnpackVersion is the Go binding that unpacks the parameters returned
+// from invoking the contract method with ID 0x54fd4d50.
+//
+// Solidity: function version() pure returns(string)
+func (scheduledTransfers *ScheduledTransfers) UnpackVersion(data []byte) (string, error) {
+	out, err := scheduledTransfers.abi.Unpack("version", data)
+	if err != nil {
+		return *new(string), err
+	}
+	out0 := *abi.ConvertType(out[0], new(string)).(*string)
+	return out0, err
+}
+
+// ScheduledTransfersExecutionAdded represents a ExecutionAdded event raised by the ScheduledTransfers contract.
+type ScheduledTransfersExecutionAdded struct {
+	SmartAccount common.Address
+	JobId        *big.Int
+	Raw          *types.Log // Blockchain specific contextual infos
+}
+
+const ScheduledTransfersExecutionAddedEventName = "ExecutionAdded"
+
+// ContractEventName returns the user-defined event name.
+func (ScheduledTransfersExecutionAdded) ContractEventName() string {
+	return ScheduledTransfersExecutionAddedEventName
+}
+
+// UnpackExecutionAddedEvent is the Go binding that unpacks the event data emitted
+// by contract.
+//
+// Solidity: event ExecutionAdded(address indexed smartAccount, uint256 indexed jobId)
+func (scheduledTransfers *ScheduledTransfers) UnpackExecutionAddedEvent(log *types.Log) (*ScheduledTransfersExecutionAdded, error) {
+	event := "ExecutionAdded"
+	if log.Topics[0] != scheduledTransfers.abi.Events[event].ID {
+		return nil, errors.New("event signature mismatch")
+	}
+	out := new(ScheduledTransfersExecutionAdded)
+	if len(log.Data) > 0 {
+		if err := scheduledTransfers.abi.UnpackIntoInterface(out, event, log.Data); err != nil {
+			return nil, err
+		}
+	}
+	var indexed abi.Arguments
+	for _, arg := range scheduledTransfers.abi.Events[event].Inputs {
+		if arg.Indexed {
+			indexed = append(indexed, arg)
+		}
+	}
+	if err := abi.ParseTopics(out, indexed, log.Topics[1:]); err != nil {
+		return nil, err
+	}
+	out.Raw = log
+	return out, nil
+}
+
+// ScheduledTransfersExecutionStatusUpdated represents a ExecutionStatusUpdated event raised by the ScheduledTransfers contract.
+type ScheduledTransfersExecutionStatusUpdated struct {
+	SmartAccount common.Address
+	JobId        *big.Int
+	Raw          *types.Log // Blockchain specific contextual infos
+}
+
+const ScheduledTransfersExecutionStatusUpdatedEventName = "ExecutionStatusUpdated"
+
+// ContractEventName returns the user-defined event name.
+func (ScheduledTransfersExecutionStatusUpdated) ContractEventName() string {
+	return ScheduledTransfersExecutionStatusUpdatedEventName
+}
+
+// UnpackExecutionStatusUpdatedEvent is the Go binding that unpacks the event data emitted
+// by contract.
+//
+// Solidity: event ExecutionStatusUpdated(address indexed smartAccount, uint256 indexed jobId)
+func (scheduledTransfers *ScheduledTransfers) UnpackExecutionStatusUpdatedEvent(log *types.Log) (*ScheduledTransfersExecutionStatusUpdated, error) {
+	event := "ExecutionStatusUpdated"
+	if log.Topics[0] != scheduledTransfers.abi.Events[event].ID {
+		return nil, errors.New("event signature mismatch")
+	}
+	out := new(ScheduledTransfersExecutionStatusUpdated)
+	if len(log.Data) > 0 {
+		if err := scheduledTransfers.abi.UnpackIntoInterface(out, event, log.Data); err != nil {
+			return nil, err
+		}
+	}
+	var indexed abi.Arguments
+	for _, arg := range scheduledTransfers.abi.Events[event].Inputs {
+		if arg.Indexed {
+			indexed = append(indexed, arg)
+		}
+	}
+	if err := abi.ParseTopics(out, indexed, log.Topics[1:]); err != nil {
+		return nil, err
+	}
+	out.Raw = log
+	return out, nil
+}
+
+// ScheduledTransfersExecutionTriggered represents a ExecutionTriggered event raised by the ScheduledTransfers contract.
+type ScheduledTransfersExecutionTriggered struct {
+	SmartAccount common.Address
+	JobId        *big.Int
+	Raw          *types.Log // Blockchain specific contextual infos
+}
+
+const ScheduledTransfersExecutionTriggeredEventName = "ExecutionTriggered"
+
+// ContractEventName returns the user-defined event name.
+func (ScheduledTransfersExecutionTriggered) ContractEventName() string {
+	return ScheduledTransfersExecutionTriggeredEventName
+}
+
+// UnpackExecutionTriggeredEvent is the Go binding that unpacks the event data emitted
+// by contract.
+//
+// Solidity: event ExecutionTriggered(address indexed smartAccount, uint256 indexed jobId)
+func (scheduledTransfers *ScheduledTransfers) UnpackExecutionTriggeredEvent(log *types.Log) (*ScheduledTransfersExecutionTriggered, error) {
+	event := "ExecutionTriggered"
+	if log.Topics[0] != scheduledTransfers.abi.Events[event].ID {
+		return nil, errors.New("event signature mismatch")
+	}
+	out := new(ScheduledTransfersExecutionTriggered)
+	if len(log.Data) > 0 {
+		if err := scheduledTransfers.abi.UnpackIntoInterface(out, event, log.Data); err != nil {
+			return nil, err
+		}
+	}
+	var indexed abi.Arguments
+	for _, arg := range scheduledTransfers.abi.Events[event].Inputs {
+		if arg.Indexed {
+			indexed = append(indexed, arg)
+		}
+	}
+	if err := abi.ParseTopics(out, indexed, log.Topics[1:]); err != nil {
+		return nil, err
+	}
+	out.Raw = log
+	return out, nil
+}
+
+// ScheduledTransfersExecutionsCancelled represents a ExecutionsCancelled event raised by the ScheduledTransfers contract.
+type ScheduledTransfersExecutionsCancelled struct {
+	SmartAccount common.Address
+	Raw          *types.Log // Blockchain specific contextual infos
+}
+
+const ScheduledTransfersExecutionsCancelledEventName = "ExecutionsCancelled"
+
+// ContractEventName returns the user-defined event name.
+func (ScheduledTransfersExecutionsCancelled) ContractEventName() string {
+	return ScheduledTransfersExecutionsCancelledEventName
+}
+
+// UnpackExecutionsCancelledEvent is the Go binding that unpacks the event data emitted
+// by contract.
+//
+// Solidity: event ExecutionsCancelled(address indexed smartAccount)
+func (scheduledTransfers *ScheduledTransfers) UnpackExecutionsCancelledEvent(log *types.Log) (*ScheduledTransfersExecutionsCancelled, error) {
+	event := "ExecutionsCancelled"
+	if log.Topics[0] != scheduledTransfers.abi.Events[event].ID {
+		return nil, errors.New("event signature mismatch")
+	}
+	out := new(ScheduledTransfersExecutionsCancelled)
+	if len(log.Data) > 0 {
+		if err := scheduledTransfers.abi.UnpackIntoInterface(out, event, log.Data); err != nil {
+			return nil, err
+		}
+	}
+	var indexed abi.Arguments
+	for _, arg := range scheduledTransfers.abi.Events[event].Inputs {
+		if arg.Indexed {
+			indexed = append(indexed, arg)
+		}
+	}
+	if err := abi.ParseTopics(out, indexed, log.Topics[1:]); err != nil {
+		return nil, err
+	}
+	out.Raw = log
+	return out, nil
+}
+
+// UnpackError attempts to decode the provided error data using user-defined
+// error definitions.
+func (scheduledTransfers *ScheduledTransfers) UnpackError(raw []byte) (any, error) {
+	if bytes.Equal(raw[:4], scheduledTransfers.abi.Errors["AlreadyInitialized"].ID.Bytes()[:4]) {
+		return scheduledTransfers.UnpackAlreadyInitializedError(raw[4:])
+	}
+	if bytes.Equal(raw[:4], scheduledTransfers.abi.Errors["InvalidExecution"].ID.Bytes()[:4]) {
+		return scheduledTransfers.UnpackInvalidExecutionError(raw[4:])
+	}
+	if bytes.Equal(raw[:4], scheduledTransfers.abi.Errors["NotInitialized"].ID.Bytes()[:4]) {
+		return scheduledTransfers.UnpackNotInitializedError(raw[4:])
+	}
+	return nil, errors.New("Unknown error")
+}
+
+// ScheduledTransfersAlreadyInitialized represents a AlreadyInitialized error raised by the ScheduledTransfers contract.
+type ScheduledTransfersAlreadyInitialized struct {
+	SmartAccount common.Address
+}
+
+// ErrorID returns the hash of canonical representation of the error's signature.
+//
+// Solidity: error AlreadyInitialized(address smartAccount)
+func ScheduledTransfersAlreadyInitializedErrorID() common.Hash {
+	return common.HexToHash("0x93360fbf8a5bb1666656771229b5f96751e7c99ebfe95e80a8416cbefd7fd9da")
+}
+
+// UnpackAlreadyInitializedError is the Go binding used to decode the provided
+// error data into the corresponding Go error struct.
+//
+// Solidity: error AlreadyInitialized(address smartAccount)
+func (scheduledTransfers *ScheduledTransfers) UnpackAlreadyInitializedError(raw []byte) (*ScheduledTransfersAlreadyInitialized, error) {
+	out := new(ScheduledTransfersAlreadyInitialized)
+	if err := scheduledTransfers.abi.UnpackIntoInterface(out, "AlreadyInitialized", raw); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ScheduledTransfersInvalidExecution represents a InvalidExecution error raised by the ScheduledTransfers contract.
+type ScheduledTransfersInvalidExecution struct {
+}
+
+// ErrorID returns the hash of canonical representation of the error's signature.
+//
+// Solidity: error InvalidExecution()
+func ScheduledTransfersInvalidExecutionErrorID() common.Hash {
+	return common.HexToHash("0x29adbb292fb39b5ec72dd2cf15f2b75e75c3f77323231f0332f644ee8539ceef")
+}
+
+// UnpackInvalidExecutionError is the Go binding used to decode the provided
+// error data into the corresponding Go error struct.
+//
+// Solidity: error InvalidExecution()
+func (scheduledTransfers *ScheduledTransfers) UnpackInvalidExecutionError(raw []byte) (*ScheduledTransfersInvalidExecution, error) {
+	out := new(ScheduledTransfersInvalidExecution)
+	if err := scheduledTransfers.abi.UnpackIntoInterface(out, "InvalidExecution", raw); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ScheduledTransfersNotInitialized represents a NotInitialized error raised by the ScheduledTransfers contract.
+type ScheduledTransfersNotInitialized struct {
+	SmartAccount common.Address
+}
+
+// ErrorID returns the hash of canonical representation of the error's signature.
+//
+// Solidity: error NotInitialized(address smartAccount)
+func ScheduledTransfersNotInitializedErrorID() common.Hash {
+	return common.HexToHash("0xf91bd6f1b02fa64d24fb54dea79a60eda0fd046154dcb147e76ca498113f5311")
+}
+
+// UnpackNotInitializedError is the Go binding used to decode the provided
+// error data into the corresponding Go error struct.
+//
+// Solidity: error NotInitialized(address smartAccount)
+func (scheduledTransfers *ScheduledTransfers) UnpackNotInitializedError(raw []byte) (*ScheduledTransfersNotInitialized, error) {
+	out := new(ScheduledTransfersNotInitialized)
+	if err := scheduledTransfers.abi.UnpackIntoInterface(out, "NotInitialized", raw); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
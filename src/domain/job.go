@@ -28,6 +28,16 @@ const (
 	DBJobTypeSwap     DBJobType = "swap"
 )
 
+// DBJobErrKind tells which step of a queuing job its error message was recorded by
+type DBJobErrKind string
+
+const (
+	// DBJobErrKindExecutionConfig is recorded when the execution config of the job cannot be read
+	DBJobErrKindExecutionConfig DBJobErrKind = "execution_config"
+	// DBJobErrKindExecution is recorded when the execution of the job failed with a transient error
+	DBJobErrKindExecution DBJobErrKind = "execution"
+)
+
 // DBJob represents a job in the database (persistence layer)
 type DBJob struct {
 	ID                uuid.UUID       `gorm:"primaryKey;type:uuid;default:gen_random_uuid()" json:"id"`
@@ -37,8 +47,11 @@ type DBJob struct {
 	UserOperation     json.RawMessage `gorm:"type:jsonb;not null" json:"userOperation"`
 	EntryPointAddress string          `gorm:"type:varchar(42);not null" json:"entryPointAddress"`
 	JobType           DBJobType       `gorm:"type:varchar(20);not null;default:transfer;check:job_type IN ('transfer', 'swap')" json:"jobType"`
+	SignatureFormat   string          `gorm:"type:varchar(32);not null;default:personal_sign" json:"signatureFormat"`
+	ModuleVersion     string          `gorm:"type:varchar(32);not null;default:v1" json:"moduleVersion"`
 	Status            DBJobStatus     `gorm:"type:varchar(20);not null;default:queuing;check:status IN ('queuing', 'completed', 'failed')" json:"status"`
 	ErrMsg            *string         `gorm:"type:text" json:"errMsg,omitempty"`
+	ErrKind           *DBJobErrKind   `gorm:"type:varchar(20);check:err_kind IN ('execution_config', 'execution')" json:"errKind,omitempty"`
 	CreatedAt         time.Time       `gorm:"not null;default:CURRENT_TIMESTAMP" json:"createdAt"`
 	UpdatedAt         time.Time       `gorm:"not null;default:CURRENT_TIMESTAMP" json:"updatedAt"`
 }
@@ -62,8 +75,11 @@ func (j *DBJob) ToEntityJob() (*EntityJob, error) {
 		UserOperation:     userOp,
 		EntryPointAddress: common.HexToAddress(j.EntryPointAddress),
 		JobType:           j.JobType,
+		SignatureFormat:   j.SignatureFormat,
+		ModuleVersion:     j.ModuleVersion,
 		Status:            j.Status,
 		ErrMsg:            j.ErrMsg,
+		ErrKind:           j.ErrKind,
 		CreatedAt:         j.CreatedAt,
 		UpdatedAt:         j.UpdatedAt,
 	}, nil
@@ -78,8 +94,11 @@ type EntityJob struct {
 	UserOperation     erc4337.UserOperation
 	EntryPointAddress common.Address
 	JobType           DBJobType
+	SignatureFormat   string
+	ModuleVersion     string
 	Status            DBJobStatus
 	ErrMsg            *string
+	ErrKind           *DBJobErrKind
 	CreatedAt         time.Time
 	UpdatedAt         time.Time
 }
@@ -98,8 +117,11 @@ func (rj *EntityJob) ToDBJob() (*DBJob, error) {
 		UserOperation:     userOpJSON,
 		EntryPointAddress: rj.EntryPointAddress.Hex(),
 		JobType:           rj.JobType,
+		SignatureFormat:   rj.SignatureFormat,
+		ModuleVersion:     rj.ModuleVersion,
 		Status:            rj.Status,
 		ErrMsg:            rj.ErrMsg,
+		ErrKind:           rj.ErrKind,
 		CreatedAt:         rj.CreatedAt,
 		UpdatedAt:         rj.UpdatedAt,
 	}, nil
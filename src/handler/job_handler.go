@@ -5,6 +5,8 @@ import (
 	"encoding/json"
 	"errors"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/ethaccount/backend/erc4337"
 	"github.com/ethaccount/backend/src/domain"
@@ -18,12 +20,18 @@ import (
 const TimeFormat = "2006-01-02 15:04:05"
 
 type JobHandler struct {
-	jobService *service.JobService
+	jobService        *service.JobService
+	blockchainService *service.BlockchainService
+	executionService  *service.ExecutionService
 }
 
-func NewJobHandler(jobService *service.JobService) *JobHandler {
+// NewJobHandler creates the job handler; blockchainService resolves the chains of registered jobs and reads
+// the orders of listed jobs, and executionService tells the signature formats its session signer supports
+func NewJobHandler(jobService *service.JobService, blockchainService *service.BlockchainService, executionService *service.ExecutionService) *JobHandler {
 	return &JobHandler{
-		jobService: jobService,
+		jobService:        jobService,
+		blockchainService: blockchainService,
+		executionService:  executionService,
 	}
 }
 
@@ -34,38 +42,89 @@ func (s *JobHandler) logger(ctx context.Context) *zerolog.Logger {
 
 // RegisterJobRequest represents the request payload for job registration
 type RegisterJobRequest struct {
-	AccountAddress string                 `json:"accountAddress" binding:"required" example:"0x1234567890123456789012345678901234567890"`
-	ChainID        int64                  `json:"chainId" binding:"required" example:"11155111"`
-	JobID          int64                  `json:"jobId" binding:"required" example:"1"`
-	JobType        string                 `json:"jobType" binding:"required" example:"transfer"`
-	UserOperation  *erc4337.UserOperation `json:"userOperation" binding:"required"`
-	EntryPoint     string                 `json:"entryPoint" binding:"required" example:"0x5FF137D4b0FDCD49DcA30c7CF57E578a026d2789"`
+	AccountAddress  string                 `json:"accountAddress" binding:"required" example:"0x1234567890123456789012345678901234567890"`
+	ChainID         int64                  `json:"chainId" binding:"required" example:"11155111"`
+	JobID           int64                  `json:"jobId" binding:"required" example:"1"`
+	JobType         string                 `json:"jobType" binding:"required" example:"transfer"`
+	UserOperation   *erc4337.UserOperation `json:"userOperation" binding:"required"`
+	EntryPoint      string                 `json:"entryPoint" binding:"required" example:"0x5FF137D4b0FDCD49DcA30c7CF57E578a026d2789"`
+	SignatureFormat string                 `json:"signatureFormat,omitempty" example:"personal_sign" enums:"personal_sign,raw_hash,smart_sessions,multi_validator,erc1271"`
+	ModuleVersion   string                 `json:"moduleVersion,omitempty" example:"v1"`
 }
 
 // RegisterJobResponse represents the response for job registration
 type RegisterJobResponse struct {
-	JobUUID        string `json:"jobUuid" example:"550e8400-e29b-41d4-a716-446655440000"`
-	AccountAddress string `json:"accountAddress" example:"0x1234567890123456789012345678901234567890"`
-	ChainID        int64  `json:"chainId" example:"11155111"`
-	JobID          int64  `json:"jobId" example:"1"`
-	JobType        string `json:"jobType" example:"transfer"`
-	EntryPoint     string `json:"entryPoint" example:"0x5FF137D4b0FDCD49DcA30c7CF57E578a026d2789"`
-	CreatedAt      string `json:"createdAt" example:"2025-01-09 13:36:56"`
-	UpdatedAt      string `json:"updatedAt" example:"2025-01-09 13:36:56"`
-	Message        string `json:"message" example:"Job registered successfully"`
+	JobUUID         string `json:"jobUuid" example:"550e8400-e29b-41d4-a716-446655440000"`
+	AccountAddress  string `json:"accountAddress" example:"0x1234567890123456789012345678901234567890"`
+	ChainID         int64  `json:"chainId" example:"11155111"`
+	JobID           int64  `json:"jobId" example:"1"`
+	JobType         string `json:"jobType" example:"transfer"`
+	SignatureFormat string `json:"signatureFormat" example:"personal_sign"`
+	ModuleVersion   string `json:"moduleVersion" example:"v1"`
+	EntryPoint      string `json:"entryPoint" example:"0x5FF137D4b0FDCD49DcA30c7CF57E578a026d2789"`
+	CreatedAt       string `json:"createdAt" example:"2025-01-09 13:36:56"`
+	UpdatedAt       string `json:"updatedAt" example:"2025-01-09 13:36:56"`
+	Message         string `json:"message" example:"Job registered successfully"`
 }
 
 // JobResponse represents a job in API responses
 type JobResponse struct {
-	ID                string          `json:"id" example:"550e8400-e29b-41d4-a716-446655440000"`
-	AccountAddress    string          `json:"accountAddress" example:"0x1234567890123456789012345678901234567890"`
-	ChainID           int64           `json:"chainId" example:"11155111"`
-	OnChainJobID      int64           `json:"onChainJobId" example:"1"`
-	JobType           string          `json:"jobType" example:"transfer"`
-	UserOperation     json.RawMessage `json:"userOperation"`
-	EntryPointAddress string          `json:"entryPointAddress" example:"0x5FF137D4b0FDCD49DcA30c7CF57E578a026d2789"`
-	CreatedAt         string          `json:"createdAt" example:"2025-01-09 13:36:56"`
-	UpdatedAt         string          `json:"updatedAt" example:"2025-01-09 13:36:56"`
+	ID                string            `json:"id" example:"550e8400-e29b-41d4-a716-446655440000"`
+	AccountAddress    string            `json:"accountAddress" example:"0x1234567890123456789012345678901234567890"`
+	ChainID           int64             `json:"chainId" example:"11155111"`
+	OnChainJobID      int64             `json:"onChainJobId" example:"1"`
+	JobType           string            `json:"jobType" example:"transfer"`
+	SignatureFormat   string            `json:"signatureFormat" example:"personal_sign"`
+	ModuleVersion     string            `json:"moduleVersion" example:"v1"`
+	UserOperation     json.RawMessage   `json:"userOperation"`
+	EntryPointAddress string            `json:"entryPointAddress" example:"0x5FF137D4b0FDCD49DcA30c7CF57E578a026d2789"`
+	Status            string            `json:"status" example:"queuing" enums:"queuing,completed,failed"`
+	ErrMsg            *string           `json:"errMsg,omitempty"`
+	Order             *JobOrderResponse `json:"order,omitempty"`
+	CreatedAt         string            `json:"createdAt" example:"2025-01-09 13:36:56"`
+	UpdatedAt         string            `json:"updatedAt" example:"2025-01-09 13:36:56"`
+}
+
+// JobOrderResponse is the order of a job; only the field of its job type is set
+type JobOrderResponse struct {
+	Transfer *TransferOrderResponse `json:"transfer,omitempty"`
+	Swap     *SwapOrderResponse     `json:"swap,omitempty"`
+}
+
+// TransferOrderResponse is the transfer made by each execution of a transfer job
+type TransferOrderResponse struct {
+	Token     string `json:"token" example:"0x1c7D4B196Cb0C7B01d743Fbc6116a902379C7238"`
+	Recipient string `json:"recipient" example:"0x1234567890123456789012345678901234567890"`
+	Amount    string `json:"amount" example:"1000000"`
+}
+
+// SwapOrderResponse is the swap made by each execution of a swap job
+type SwapOrderResponse struct {
+	TokenIn           string `json:"tokenIn" example:"0xfFf9976782d46CC05630D1f6eBAb18b2324d6B14"`
+	TokenOut          string `json:"tokenOut" example:"0x1c7D4B196Cb0C7B01d743Fbc6116a902379C7238"`
+	AmountIn          string `json:"amountIn" example:"1000000000000000"`
+	SqrtPriceLimitX96 string `json:"sqrtPriceLimitX96" example:"0"`
+}
+
+// toJobOrderResponse converts a decoded job order to a JobOrderResponse
+func toJobOrderResponse(order *service.JobOrder) *JobOrderResponse {
+	response := &JobOrderResponse{}
+	if order.Transfer != nil {
+		response.Transfer = &TransferOrderResponse{
+			Token:     order.Transfer.Token.Hex(),
+			Recipient: order.Transfer.Recipient.Hex(),
+			Amount:    order.Transfer.Amount.String(),
+		}
+	}
+	if order.Swap != nil {
+		response.Swap = &SwapOrderResponse{
+			TokenIn:           order.Swap.TokenIn.Hex(),
+			TokenOut:          order.Swap.TokenOut.Hex(),
+			AmountIn:          order.Swap.AmountIn.String(),
+			SqrtPriceLimitX96: order.Swap.SqrtPriceLimitX96.String(),
+		}
+	}
+	return response
 }
 
 // toJobResponse converts a domain Job to a JobResponse with formatted time fields
@@ -83,13 +142,30 @@ func toJobResponse(job *domain.EntityJob) JobResponse {
 		ChainID:           job.ChainID,
 		OnChainJobID:      job.OnChainJobID,
 		JobType:           string(job.JobType),
+		SignatureFormat:   job.SignatureFormat,
+		ModuleVersion:     job.ModuleVersion,
 		UserOperation:     userOpJSON,
 		EntryPointAddress: job.EntryPointAddress.Hex(),
+		Status:            string(job.Status),
+		ErrMsg:            job.ErrMsg,
 		CreatedAt:         job.CreatedAt.Format(TimeFormat),
 		UpdatedAt:         job.UpdatedAt.Format(TimeFormat),
 	}
 }
 
+// userOperationErrorDetails converts user operation validation errors to error details with request field paths
+func userOperationErrorDetails(validationErr *erc4337.ValidationError) []ErrorDetail {
+	details := make([]ErrorDetail, len(validationErr.Fields))
+	for i, fieldErr := range validationErr.Fields {
+		field := "userOperation"
+		if fieldErr.Field != "" {
+			field += "." + fieldErr.Field
+		}
+		details[i] = ErrorDetail{Field: field, Reason: fieldErr.Reason}
+	}
+	return details
+}
+
 // RegisterJob godoc
 // @Summary Register a new job
 // @Description Register a new job with user operation for smart account management
@@ -131,6 +207,24 @@ func (h *JobHandler) RegisterJob(c *gin.Context) {
 		return
 	}
 
+	// Validate the chain and the module deployment the job was added to
+	chain, err := h.blockchainService.GetChain(req.ChainID)
+	if err != nil {
+		logger.Error().Int64("chainId", req.ChainID).Msg("unsupported chain")
+		respondWithError(c, domain.NewError(domain.ErrorCodeParameterInvalid, err, domain.WithMsg("chainId is not supported")))
+		return
+	}
+	if req.ModuleVersion == "" {
+		req.ModuleVersion, err = chain.CurrentModuleVersion(domain.DBJobType(req.JobType))
+	} else {
+		_, err = chain.SchedulerModule(domain.DBJobType(req.JobType), req.ModuleVersion)
+	}
+	if err != nil {
+		logger.Error().Err(err).Str("moduleVersion", req.ModuleVersion).Msg("invalid module version")
+		respondWithError(c, domain.NewError(domain.ErrorCodeParameterInvalid, err, domain.WithMsg("moduleVersion is not deployed on the chain")))
+		return
+	}
+
 	// Validate and parse addresses
 	if !common.IsHexAddress(req.AccountAddress) {
 		logger.Error().Str("accountAddress", req.AccountAddress).Msg("invalid account address format")
@@ -144,15 +238,58 @@ func (h *JobHandler) RegisterJob(c *gin.Context) {
 		return
 	}
 
+	// Validate signature format
+	if req.SignatureFormat == "" {
+		req.SignatureFormat = service.DefaultSignatureFormat
+	}
+	signatureFormatter, err := service.SignatureFormatters.Get(req.SignatureFormat)
+	if err != nil {
+		logger.Error().Str("signatureFormat", req.SignatureFormat).Msg("invalid signature format")
+		respondWithError(c, domain.NewError(domain.ErrorCodeParameterInvalid, err, domain.WithMsg("signatureFormat must be one of: "+strings.Join(service.SignatureFormatters.Formats(), ", "))))
+		return
+	}
+	if err := h.executionService.CheckSignatureFormat(req.SignatureFormat); err != nil {
+		logger.Error().Err(err).Str("signatureFormat", req.SignatureFormat).Msg("signature format not supported by the session signer")
+		respondWithError(c, domain.NewError(domain.ErrorCodeParameterInvalid, err, domain.WithMsg("signatureFormat is not supported by the session signer")))
+		return
+	}
+
 	accountAddress := common.HexToAddress(req.AccountAddress)
 	entryPointAddress := common.HexToAddress(req.EntryPoint)
 
+	// Validate the user operation now rather than letting a malformed job fail in the scheduler
+	err = erc4337.ValidateUserOperation(req.UserOperation, entryPointAddress, erc4337.ValidationOptions{
+		Sender:          &accountAddress,
+		RequireNonceKey: true,
+	})
+	if err == nil {
+		// The signature must be a template of the signature format, holding the dummy signature
+		if _, signatureErr := signatureFormatter.DummySignature(req.UserOperation.Signature); signatureErr != nil {
+			err = &erc4337.ValidationError{Fields: []erc4337.FieldError{{Field: "signature", Reason: signatureErr.Error()}}}
+		}
+	}
+	if err != nil {
+		details := []ErrorDetail{{Field: "entryPoint", Reason: err.Error()}}
+		var validationErr *erc4337.ValidationError
+		if errors.As(err, &validationErr) {
+			details = userOperationErrorDetails(validationErr)
+		}
+
+		logger.Error().Err(err).Msg("invalid user operation")
+		respondWithError(c, domain.NewError(domain.ErrorCodeParameterInvalid, err,
+			domain.WithMsg("userOperation is invalid"),
+			domain.WithDetail(map[string]interface{}{"fields": details})))
+		return
+	}
+
 	job, err := h.jobService.RegisterJob(
 		c.Request.Context(),
 		accountAddress,
 		req.ChainID,
 		req.JobID,
 		domain.DBJobType(req.JobType),
+		req.SignatureFormat,
+		req.ModuleVersion,
 		req.UserOperation,
 		entryPointAddress,
 	)
@@ -162,15 +299,17 @@ func (h *JobHandler) RegisterJob(c *gin.Context) {
 	}
 
 	response := RegisterJobResponse{
-		JobUUID:        job.ID.String(),
-		AccountAddress: job.AccountAddress.Hex(),
-		ChainID:        job.ChainID,
-		JobID:          job.OnChainJobID,
-		JobType:        string(job.JobType),
-		EntryPoint:     job.EntryPointAddress.Hex(),
-		CreatedAt:      job.CreatedAt.Format(TimeFormat),
-		UpdatedAt:      job.UpdatedAt.Format(TimeFormat),
-		Message:        "Job registered successfully",
+		JobUUID:         job.ID.String(),
+		AccountAddress:  job.AccountAddress.Hex(),
+		ChainID:         job.ChainID,
+		JobID:           job.OnChainJobID,
+		JobType:         string(job.JobType),
+		SignatureFormat: job.SignatureFormat,
+		ModuleVersion:   job.ModuleVersion,
+		EntryPoint:      job.EntryPointAddress.Hex(),
+		CreatedAt:       job.CreatedAt.Format(TimeFormat),
+		UpdatedAt:       job.UpdatedAt.Format(TimeFormat),
+		Message:         "Job registered successfully",
 	}
 
 	logger.Info().
@@ -185,16 +324,28 @@ func (h *JobHandler) RegisterJob(c *gin.Context) {
 
 // GetJobList godoc
 // @Summary Get all active jobs
-// @Description Retrieve a list of all active jobs in the system
+// @Description Retrieve a list of all active jobs in the system. Orders are read from the chain only when withOrder is true.
 // @Tags jobs
 // @Accept json
 // @Produce json
+// @Param withOrder query bool false "Include the decoded on-chain order of each job"
 // @Success 200 {object} StandardResponse
+// @Failure 400 {object} StandardResponse
 // @Failure 500 {object} StandardResponse
 // @Router /jobs [get]
 func (h *JobHandler) GetJobList(c *gin.Context) {
 	logger := h.logger(c.Request.Context()).With().Str("function", "GetJobList").Logger()
 
+	withOrder := false
+	if value := c.Query("withOrder"); value != "" {
+		var err error
+		withOrder, err = strconv.ParseBool(value)
+		if err != nil {
+			respondWithError(c, domain.NewError(domain.ErrorCodeParameterInvalid, err, domain.WithMsg("withOrder must be true or false")))
+			return
+		}
+	}
+
 	jobs, err := h.jobService.GetActiveJobs(c.Request.Context())
 	if err != nil {
 		logger.Error().Err(err).Msg("failed to retrieve jobs")
@@ -206,6 +357,10 @@ func (h *JobHandler) GetJobList(c *gin.Context) {
 	for i, job := range jobs {
 		jobResponses[i] = toJobResponse(job)
 	}
+	// Reading the orders makes an on-chain call for the jobs, so it is opt-in
+	if withOrder {
+		h.addJobOrders(c.Request.Context(), jobs, jobResponses)
+	}
 
 	logger.Debug().
 		Int("job_count", len(jobs)).
@@ -213,3 +368,35 @@ func (h *JobHandler) GetJobList(c *gin.Context) {
 
 	respondWithSuccess(c, jobResponses)
 }
+
+// addJobOrders reads the execution configs of the jobs and adds their decoded orders to the responses.
+// Jobs whose config cannot be read or decoded are listed without an order.
+func (h *JobHandler) addJobOrders(ctx context.Context, jobs []*domain.EntityJob, jobResponses []JobResponse) {
+	logger := h.logger(ctx).With().Str("function", "addJobOrders").Logger()
+	if len(jobs) == 0 {
+		return
+	}
+
+	configs, err := h.blockchainService.GetExecutionConfigsBatch(ctx, jobs)
+	if err != nil {
+		logger.Warn().Err(err).Msg("failed to get execution configs of jobs")
+		return
+	}
+
+	for i, job := range jobs {
+		result := configs[job.ID.String()]
+		if result.Err != nil {
+			logger.Warn().Err(result.Err).Str("job_id", job.ID.String()).Msg("failed to get execution config of job")
+			continue
+		}
+		if result.Config == nil {
+			continue
+		}
+		order, err := service.DecodeJobOrder(job.JobType, result.Config.ExecutionData)
+		if err != nil {
+			logger.Warn().Err(err).Str("job_id", job.ID.String()).Msg("failed to decode execution data of job")
+			continue
+		}
+		jobResponses[i].Order = toJobOrderResponse(order)
+	}
+}
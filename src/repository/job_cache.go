@@ -7,6 +7,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/ethaccount/backend/erc4337"
 	"github.com/ethaccount/backend/src/domain"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/go-redis/redis/v8"
@@ -30,6 +31,13 @@ type JobCache struct {
 	Status     CacheJobStatus `json:"status"`
 	Error      string         `json:"error"`
 	UpdatedAt  time.Time      `json:"updated_at"`
+
+	// The last user operation sent for the job, kept so that it can be replaced with higher fees
+	EntryPoint    common.Address         `json:"entry_point,omitempty"`
+	UserOperation *erc4337.UserOperation `json:"user_operation,omitempty"`
+	SentAt        time.Time              `json:"sent_at,omitempty"`
+	// ReplacedUserOpHashes are the user operations of the job replaced by fee bumps, oldest first
+	ReplacedUserOpHashes []common.Hash `json:"replaced_user_op_hashes,omitempty"`
 }
 
 // JobCacheRepository handles Redis operations for job scheduling and status management
@@ -202,6 +210,34 @@ func (r *JobCacheRepository) GetJobCachesByStatus(ctx context.Context, status Ca
 
 // UpdateJobCacheUserOpHash updates the userOpHash for an existing job cache
 func (r *JobCacheRepository) UpdateJobCacheUserOpHash(ctx context.Context, jobID uuid.UUID, userOpHash common.Hash) error {
+	return r.updateJobCache(ctx, jobID, func(jobCache *JobCache) {
+		jobCache.UserOpHash = userOpHash
+	})
+}
+
+// UpdateJobCacheSentUserOperation records the user operation sent for a job
+func (r *JobCacheRepository) UpdateJobCacheSentUserOperation(ctx context.Context, jobID uuid.UUID, userOpHash common.Hash, entryPoint common.Address, userOp *erc4337.UserOperation) error {
+	return r.updateJobCache(ctx, jobID, func(jobCache *JobCache) {
+		jobCache.UserOpHash = userOpHash
+		jobCache.EntryPoint = entryPoint
+		jobCache.UserOperation = userOp
+		jobCache.SentAt = time.Now()
+	})
+}
+
+// ReplaceJobCacheUserOperation records the user operation replacing the current one of a job,
+// appending the replaced userOpHash to the replacement chain
+func (r *JobCacheRepository) ReplaceJobCacheUserOperation(ctx context.Context, jobID uuid.UUID, userOpHash common.Hash, userOp *erc4337.UserOperation) error {
+	return r.updateJobCache(ctx, jobID, func(jobCache *JobCache) {
+		jobCache.ReplacedUserOpHashes = append(jobCache.ReplacedUserOpHashes, jobCache.UserOpHash)
+		jobCache.UserOpHash = userOpHash
+		jobCache.UserOperation = userOp
+		jobCache.SentAt = time.Now()
+	})
+}
+
+// updateJobCache applies an update to an existing job cache
+func (r *JobCacheRepository) updateJobCache(ctx context.Context, jobID uuid.UUID, update func(jobCache *JobCache)) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
@@ -218,8 +254,8 @@ func (r *JobCacheRepository) UpdateJobCacheUserOpHash(ctx context.Context, jobID
 		return fmt.Errorf("failed to unmarshal job cache: %w", err)
 	}
 
-	// Update userOpHash and timestamp
-	jobCache.UserOpHash = userOpHash
+	// Apply the update and refresh the timestamp
+	update(&jobCache)
 	jobCache.UpdatedAt = time.Now()
 
 	// Marshal and save back to Redis
@@ -17,7 +17,7 @@ func NewJobRepository(db *gorm.DB) *JobRepository {
 	return &JobRepository{db: db}
 }
 
-func (r *JobRepository) CreateJob(accountAddress common.Address, chainId int64, jobID int64, jobType domain.DBJobType, userOperation *erc4337.UserOperation, entryPoint common.Address) (*domain.EntityJob, error) {
+func (r *JobRepository) CreateJob(accountAddress common.Address, chainId int64, jobID int64, jobType domain.DBJobType, signatureFormat string, moduleVersion string, userOperation *erc4337.UserOperation, entryPoint common.Address) (*domain.EntityJob, error) {
 	userOpJSON, err := json.Marshal(userOperation)
 	if err != nil {
 		return nil, err
@@ -30,6 +30,8 @@ func (r *JobRepository) CreateJob(accountAddress common.Address, chainId int64,
 		UserOperation:     userOpJSON,
 		EntryPointAddress: entryPoint.Hex(),
 		JobType:           jobType,
+		SignatureFormat:   signatureFormat,
+		ModuleVersion:     moduleVersion,
 		Status:            domain.DBJobStatusQueuing,
 	}
 
@@ -95,6 +97,25 @@ func (r *JobRepository) UpdateJobStatus(id string, status domain.DBJobStatus, er
 	// If status is failed and errMsg is provided, include it in the update
 	if status == domain.DBJobStatusFailed && errMsg != nil {
 		updates["err_msg"] = *errMsg
+		updates["err_kind"] = nil
+	}
+
+	if err := r.db.Model(&domain.DBJob{}).Where("id = ?", id).Updates(updates).Error; err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// UpdateJobErrMsg sets the error message of a job by its ID, along with the kind of error, without changing its status
+// A nil errMsg clears the error message and its kind
+func (r *JobRepository) UpdateJobErrMsg(id string, errKind domain.DBJobErrKind, errMsg *string) error {
+	updates := map[string]interface{}{
+		"err_msg":  errMsg,
+		"err_kind": nil,
+	}
+	if errMsg != nil {
+		updates["err_kind"] = errKind
 	}
 
 	if err := r.db.Model(&domain.DBJob{}).Where("id = ?", id).Updates(updates).Error; err != nil {
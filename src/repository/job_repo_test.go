@@ -38,7 +38,7 @@ func TestJobRepository_RegisterJob(t *testing.T) {
 	}
 
 	// Test CreateJob
-	job, err := repo.CreateJob(accountAddress, chainId, jobID, domain.DBJobTypeTransfer, userOperation, entryPointAddress)
+	job, err := repo.CreateJob(accountAddress, chainId, jobID, domain.DBJobTypeTransfer, "personal_sign", "v1", userOperation, entryPointAddress)
 	if err != nil {
 		t.Fatalf("CreateJob failed: %v", err)
 	}
@@ -128,14 +128,23 @@ func TestJobRepository_RegisterJob_DuplicateJobID(t *testing.T) {
 	}
 
 	// Register first job
-	_, err := repo.CreateJob(accountAddress, chainId, jobID, domain.DBJobTypeTransfer, userOperation, entryPointAddress)
+	_, err := repo.CreateJob(accountAddress, chainId, jobID, domain.DBJobTypeTransfer, "personal_sign", "v1", userOperation, entryPointAddress)
 	if err != nil {
 		t.Fatalf("First CreateJob failed: %v", err)
 	}
 
 	// Try to register duplicate job (same account_address and job_id)
-	_, err = repo.CreateJob(accountAddress, chainId, jobID, domain.DBJobTypeTransfer, userOperation, entryPointAddress)
+	_, err = repo.CreateJob(accountAddress, chainId, jobID, domain.DBJobTypeTransfer, "personal_sign", "v1", userOperation, entryPointAddress)
 	if err == nil {
 		t.Error("Expected error when registering duplicate job, but got none")
 	}
+
+	// The same job ID on a redeployed module is a different job
+	job, err := repo.CreateJob(accountAddress, chainId, jobID, domain.DBJobTypeTransfer, "personal_sign", "v2", userOperation, entryPointAddress)
+	if err != nil {
+		t.Fatalf("CreateJob on another module version failed: %v", err)
+	}
+	if job.ModuleVersion != "v2" {
+		t.Errorf("Expected ModuleVersion v2, got %s", job.ModuleVersion)
+	}
 }
@@ -2,69 +2,161 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math/big"
-	"strings"
+	"net/http"
+	"net/url"
+	"slices"
 	"sync"
+	"time"
 
 	"github.com/ethaccount/backend/erc4337"
+	"github.com/ethaccount/backend/src/contracts/scheduledtransfers"
 	"github.com/ethaccount/backend/src/domain"
 
 	"github.com/ethereum/go-ethereum"
-	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/ethereum/go-ethereum/rpc"
 	"github.com/rs/zerolog"
 )
 
-const (
-	scheduledTransfersAddress = "0xA8E374779aeE60413c974b484d6509c7E4DDb6bA"
-	scheduledOrdersAddress    = "0x40dc90D670C89F322fa8b9f685770296428DCb6b"
-)
+// schedulingRevertDecoder decodes the revert data of user operations executing scheduled jobs
+var schedulingRevertDecoder = newSchedulingRevertDecoder()
+
+// ScheduledTransfers and ScheduledOrders share their custom errors (SchedulingBase and the ERC-7579 module base)
+func newSchedulingRevertDecoder() *erc4337.RevertDecoder {
+	parsedABI, err := scheduledtransfers.ScheduledTransfersMetaData.ParseABI()
+	if err != nil {
+		panic(fmt.Sprintf("invalid ScheduledTransfers ABI: %v", err))
+	}
+	return erc4337.NewRevertDecoder(*parsedABI)
+}
 
 type BlockchainConfig struct {
-	SepoliaRPCURL         string
-	ArbitrumSepoliaRPCURL string
-	BaseSepoliaRPCURL     string
-	OptimismSepoliaRPCURL string
-	PolygonAmoyRPCURL     string
+	// Supported chains; nil uses DefaultChains
+	Chains *ChainRegistry
+
+	// Gas price oracle configuration: DefaultGasPrice applies to chains without an entry in GasPrice
+	DefaultGasPrice GasPriceConfig
+	GasPrice        map[int64]GasPriceConfig
+
+	// ERC-7677 paymaster services by chain ID; chains without an entry do not refresh sponsorship
+	Paymasters map[int64]PaymasterConfig
+
+	// Additional bundler endpoints by chain ID, used in order when the primary bundler fails
+	BundlerFallbackURLs map[int64][]string
+	// Health checks and failover of the per chain bundler pools
+	BundlerPool erc4337.BundlerPoolConfig
+
+	// Self-bundling mode by chain ID; chains without an entry only use bundlers
+	SelfBundling map[int64]SelfBundlingMode
+	// Executor signs the handleOps transactions of the funded EOA when self-bundling, see NewExecutorSigner
+	Executor erc4337.TransactionSigner
+
+	// Pre-flight simulation of signed user operations before they are sent
+	Simulation SimulationConfig
+
+	// Replacement of stuck user operations: DefaultFeeBump applies to chains without an entry in FeeBump
+	DefaultFeeBump FeeBumpConfig
+	FeeBump        map[int64]FeeBumpConfig
+
+	// MulticallChunkSize is the number of executionLog reads per Multicall3 call or JSON-RPC batch;
+	// zero uses DefaultMulticallChunkSize
+	MulticallChunkSize int
+
+	// Failover, rate limiting, retries and circuit breaking of the node RPC endpoints
+	RPCTransport RPCTransportConfig
+}
 
-	// Mainnet URLs
-	ArbitrumRPCURL string
-	BaseRPCURL     string
+// SimulationConfig configures the simulation of user operations before they are sent to the bundler
+type SimulationConfig struct {
+	Enabled bool
+	// EntryPointSimulationsCode is the deployed EntryPointSimulations bytecode by EntryPoint version.
+	// Without it, v0.7 and v0.8 user operations are only simulated through handleOps, without validation data.
+	EntryPointSimulationsCode map[erc4337.EntryPointVersion][]byte
+}
+
+// SelfBundlingMode selects when the backend sends user operations to the EntryPoint itself instead of through a bundler
+type SelfBundlingMode string
+
+const (
+	SelfBundlingOff SelfBundlingMode = "off"
+	// SelfBundlingFallback sends handleOps from the executor only when every bundler of the chain fails
+	SelfBundlingFallback SelfBundlingMode = "fallback"
+	// SelfBundlingAlways sends every user operation of the chain from the executor
+	SelfBundlingAlways SelfBundlingMode = "always"
+)
+
+// PaymasterConfig configures the ERC-7677 paymaster service of a chain
+type PaymasterConfig struct {
+	URL string
+	// Context is passed to the paymaster service as is, e.g. {"sponsorshipPolicyId": "sp_..."}
+	Context map[string]interface{}
 }
 
 type BlockchainService struct {
-	SepoliaRPCURL         *string
-	ArbitrumSepoliaRPCURL *string
-	BaseSepoliaRPCURL     *string
-	OptimismSepoliaRPCURL *string
-	PolygonAmoyRPCURL     *string
+	chains *ChainRegistry
+
+	defaultGasPrice GasPriceConfig
+	gasPrice        map[int64]GasPriceConfig
+	paymasters      map[int64]PaymasterConfig
 
-	// Mainnet URLs
-	ArbitrumRPCURL *string
-	BaseRPCURL     *string
+	bundlerFallbackURLs map[int64][]string
+	bundlerPool         erc4337.BundlerPoolConfig
 
-	clientPool        map[int64]*ethclient.Client
-	bundlerClientPool map[int64]erc4337.Bundler
-	mu                sync.RWMutex
+	selfBundling map[int64]SelfBundlingMode
+	executor     erc4337.TransactionSigner
+
+	simulation SimulationConfig
+
+	defaultFeeBump FeeBumpConfig
+	feeBump        map[int64]FeeBumpConfig
+
+	multicallChunkSize int
+	multicall3Deployed map[int64]bool
+
+	rpcTransport RPCTransportConfig
+
+	clientPool          map[int64]*ethclient.Client
+	bundlerClientPool   map[int64]erc4337.Bundler
+	paymasterClientPool map[int64]erc4337.Paymaster
+	mu                  sync.RWMutex
 }
 
 func NewBlockchainService(config BlockchainConfig) *BlockchainService {
+	chains := config.Chains
+	if chains == nil {
+		chains = NewDefaultChainRegistry()
+	}
+
 	return &BlockchainService{
-		SepoliaRPCURL:         &config.SepoliaRPCURL,
-		ArbitrumSepoliaRPCURL: &config.ArbitrumSepoliaRPCURL,
-		BaseSepoliaRPCURL:     &config.BaseSepoliaRPCURL,
-		OptimismSepoliaRPCURL: &config.OptimismSepoliaRPCURL,
-		PolygonAmoyRPCURL:     &config.PolygonAmoyRPCURL,
+		chains: chains,
 
-		// Mainnet URLs
-		ArbitrumRPCURL: &config.ArbitrumRPCURL,
-		BaseRPCURL:     &config.BaseRPCURL,
+		defaultGasPrice: config.DefaultGasPrice,
+		gasPrice:        config.GasPrice,
+		paymasters:      config.Paymasters,
 
-		clientPool:        make(map[int64]*ethclient.Client),
-		bundlerClientPool: make(map[int64]erc4337.Bundler),
+		bundlerFallbackURLs: config.BundlerFallbackURLs,
+		bundlerPool:         config.BundlerPool,
+
+		selfBundling: config.SelfBundling,
+		executor:     config.Executor,
+
+		simulation: config.Simulation,
+
+		defaultFeeBump: config.DefaultFeeBump,
+		feeBump:        config.FeeBump,
+
+		multicallChunkSize: config.MulticallChunkSize,
+		multicall3Deployed: make(map[int64]bool),
+
+		rpcTransport: config.RPCTransport,
+
+		clientPool:          make(map[int64]*ethclient.Client),
+		bundlerClientPool:   make(map[int64]erc4337.Bundler),
+		paymasterClientPool: make(map[int64]erc4337.Paymaster),
 	}
 }
 
@@ -90,28 +182,12 @@ func (b *BlockchainService) GetClient(chainId int64) (*ethclient.Client, error)
 		return client, nil
 	}
 
-	var rpcUrl string
-
-	switch chainId {
-	case 11155111: // Sepolia
-		rpcUrl = *b.SepoliaRPCURL
-	case 421614: // Arbitrum Sepolia
-		rpcUrl = *b.ArbitrumSepoliaRPCURL
-	case 84532: // Base Sepolia
-		rpcUrl = *b.BaseSepoliaRPCURL
-	case 11155420: // Optimism Sepolia
-		rpcUrl = *b.OptimismSepoliaRPCURL
-	case 80002: // Polygon Amoy
-		rpcUrl = *b.PolygonAmoyRPCURL
-	case 42161: // Arbitrum One
-		rpcUrl = *b.ArbitrumRPCURL
-	case 8453: // Base
-		rpcUrl = *b.BaseRPCURL
-	default:
-		return nil, fmt.Errorf("unsupported chain id: %d", chainId)
-	}
-
-	client, err := ethclient.Dial(rpcUrl)
+	chain, err := b.chains.Get(chainId)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := b.dialNode(chain)
 	if err != nil {
 		return nil, err
 	}
@@ -124,6 +200,57 @@ func (b *BlockchainService) GetClient(chainId int64) (*ethclient.Client, error)
 	return client, nil
 }
 
+// dialNode creates the eth client of a chain. HTTP endpoints share an rpcTransport failing over between them;
+// WebSocket and IPC endpoints keep a single connection to the first URL.
+func (b *BlockchainService) dialNode(chain ChainConfig) (*ethclient.Client, error) {
+	if !allHTTPURLs(chain.RPCURLs) {
+		return ethclient.Dial(chain.RPCURLs[0])
+	}
+
+	config := b.rpcTransport
+	chainId := chain.ChainID
+	config.OnEndpointFailure = func(ctx context.Context, endpoint string, err error) {
+		b.logger(ctx).Warn().Err(err).
+			Int64("chain_id", chainId).
+			Str("rpc", endpoint).
+			Msg("RPC endpoint failed, failing over to the next endpoint")
+	}
+	config.OnCircuitOpen = func(ctx context.Context, endpoint string, cooldown time.Duration) {
+		b.logger(ctx).Error().
+			Int64("chain_id", chainId).
+			Str("rpc", endpoint).
+			Dur("cooldown", cooldown).
+			Msg("RPC endpoint keeps failing, skipping it")
+	}
+
+	transport, err := newRPCTransport(chain.RPCURLs, config, http.DefaultTransport)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create RPC transport of chain %d: %w", chainId, err)
+	}
+
+	// The transport sets the URL and credentials of each endpoint, so the client posts to the first URL without them
+	dialURL, _ := url.Parse(chain.RPCURLs[0])
+	dialURL.User = nil
+	client, err := rpc.DialOptions(context.Background(), dialURL.String(), rpc.WithHTTPClient(&http.Client{Transport: transport}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial node of chain %d: %w", chainId, err)
+	}
+	return ethclient.NewClient(client), nil
+}
+
+// SetClient registers the eth client used for a chain, replacing any pooled client.
+// Tests use it to point the service at an in-process node.
+func (b *BlockchainService) SetClient(chainId int64, client *ethclient.Client) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.clientPool == nil {
+		b.clientPool = make(map[int64]*ethclient.Client)
+	}
+	b.clientPool[chainId] = client
+	delete(b.multicall3Deployed, chainId)
+}
+
 // GetRPCClient returns the underlying RPC client from the pooled eth client
 func (b *BlockchainService) GetRPCClient(ctx context.Context, chainId int64) (*rpc.Client, error) {
 	ethClient, err := b.GetClient(chainId)
@@ -133,6 +260,15 @@ func (b *BlockchainService) GetRPCClient(ctx context.Context, chainId int64) (*r
 	return ethClient.Client(), nil
 }
 
+// GetNonce reads the next nonce of the sender for the given key from the EntryPoint
+func (b *BlockchainService) GetNonce(ctx context.Context, chainId int64, entryPoint common.Address, sender common.Address, key *big.Int) (erc4337.Nonce, error) {
+	client, err := b.GetClient(chainId)
+	if err != nil {
+		return erc4337.Nonce{}, err
+	}
+	return erc4337.GetNonce(ctx, client, entryPoint, sender, key)
+}
+
 // Close closes all client connections and cleans up the connection pool
 func (b *BlockchainService) Close() {
 	b.mu.Lock()
@@ -148,18 +284,43 @@ func (b *BlockchainService) Close() {
 		bundler.Close()
 	}
 	b.bundlerClientPool = nil
+
+	// Close paymaster clients
+	for _, paymaster := range b.paymasterClientPool {
+		paymaster.Close()
+	}
+	b.paymasterClientPool = nil
+}
+
+// Chains returns the registry of the supported chains
+func (b *BlockchainService) Chains() *ChainRegistry {
+	return b.chains
 }
 
-// getContractAddress returns the appropriate contract address based on job type
-func (b *BlockchainService) getContractAddress(jobType domain.DBJobType) (string, error) {
-	switch jobType {
-	case domain.DBJobTypeTransfer:
-		return scheduledTransfersAddress, nil
-	case domain.DBJobTypeSwap:
-		return scheduledOrdersAddress, nil
-	default:
-		return "", fmt.Errorf("unsupported job type: %s", jobType)
+// GetChain returns the configuration of a supported chain
+func (b *BlockchainService) GetChain(chainId int64) (ChainConfig, error) {
+	return b.chains.Get(chainId)
+}
+
+// getContractAddress returns the address of the scheduling module deployment a job was registered against
+func (b *BlockchainService) getContractAddress(job *domain.EntityJob) (string, error) {
+	chain, err := b.chains.Get(job.ChainID)
+	if err != nil {
+		return "", err
 	}
+	address, err := chain.SchedulerModule(job.JobType, jobModuleVersion(job))
+	if err != nil {
+		return "", err
+	}
+	return address.Hex(), nil
+}
+
+// jobModuleVersion returns the module version of a job, which is DefaultModuleVersion for jobs built without one
+func jobModuleVersion(job *domain.EntityJob) string {
+	if job.ModuleVersion == "" {
+		return DefaultModuleVersion
+	}
+	return job.ModuleVersion
 }
 
 func (b *BlockchainService) GetExecutionConfig(ctx context.Context, job *domain.EntityJob) (*domain.ExecutionConfig, error) {
@@ -179,7 +340,7 @@ func (b *BlockchainService) GetExecutionConfig(ctx context.Context, job *domain.
 	}
 
 	// Get the appropriate contract address based on job type
-	contractAddress, err := b.getContractAddress(job.JobType)
+	contractAddress, err := b.getContractAddress(job)
 	if err != nil {
 		b.logger(ctx).Error().Err(err).
 			Str("job_type", string(job.JobType)).
@@ -187,13 +348,7 @@ func (b *BlockchainService) GetExecutionConfig(ctx context.Context, job *domain.
 		return nil, err
 	}
 
-	// ABI for executionLog(address,uint256)
-	contractABI := `[{"inputs":[{"type":"address"},{"type":"uint256"}],"name":"executionLog","outputs":[{"type":"uint48"},{"type":"uint16"},{"type":"uint16"},{"type":"uint48"},{"type":"bool"},{"type":"uint48"},{"type":"bytes"}],"stateMutability":"view","type":"function"}]`
-
-	parsedABI, _ := abi.JSON(strings.NewReader(contractABI))
-
-	calldata, err := parsedABI.Pack("executionLog", job.AccountAddress, big.NewInt(int64(job.OnChainJobID)))
-
+	calldata, err := packExecutionLog(job)
 	if err != nil {
 		b.logger(ctx).Error().Err(err).
 			Str("account_address", job.AccountAddress.Hex()).
@@ -204,7 +359,7 @@ func (b *BlockchainService) GetExecutionConfig(ctx context.Context, job *domain.
 
 	// Make the call
 	addr := common.HexToAddress(contractAddress)
-	result, err := client.CallContract(context.Background(), ethereum.CallMsg{
+	result, err := client.CallContract(ctx, ethereum.CallMsg{
 		To:   &addr,
 		Data: calldata,
 	}, nil)
@@ -219,7 +374,7 @@ func (b *BlockchainService) GetExecutionConfig(ctx context.Context, job *domain.
 	}
 
 	// Unpack the result
-	unpacked, err := parsedABI.Unpack("executionLog", result)
+	config, err := unpackExecutionLog(job.JobType, result)
 	if err != nil {
 		b.logger(ctx).Error().Err(err).
 			Str("account_address", job.AccountAddress.Hex()).
@@ -228,16 +383,6 @@ func (b *BlockchainService) GetExecutionConfig(ctx context.Context, job *domain.
 		return nil, err
 	}
 
-	config := &domain.ExecutionConfig{
-		ExecuteInterval:             unpacked[0].(*big.Int),
-		NumberOfExecutions:          unpacked[1].(uint16),
-		NumberOfExecutionsCompleted: unpacked[2].(uint16),
-		StartDate:                   unpacked[3].(*big.Int),
-		IsEnabled:                   unpacked[4].(bool),
-		LastExecutionTime:           unpacked[5].(*big.Int),
-		ExecutionData:               unpacked[6].([]byte),
-	}
-
 	b.logger(ctx).Debug().
 		Str("account_address", job.AccountAddress.Hex()).
 		Int64("job_id", int64(job.OnChainJobID)).
@@ -251,24 +396,29 @@ func (b *BlockchainService) GetExecutionConfig(ctx context.Context, job *domain.
 }
 
 // GetExecutionConfigsBatch retrieves execution configs for multiple jobs in batch
-// Groups jobs by chain ID and job type, then makes batch calls for efficiency
-func (b *BlockchainService) GetExecutionConfigsBatch(ctx context.Context, jobs []*domain.EntityJob) (map[string]*domain.ExecutionConfig, error) {
+// Groups jobs by chain ID and job type, then reads them with Multicall3 aggregate3 calls, or JSON-RPC batches
+// on chains without Multicall3, of up to MulticallChunkSize jobs each.
+// Every job gets a result holding its config or an *ExecutionConfigError, so that a failing job or chain does not
+// affect the others. The returned error is only set when the context is done.
+func (b *BlockchainService) GetExecutionConfigsBatch(ctx context.Context, jobs []*domain.EntityJob) (map[string]ExecutionConfigResult, error) {
 	b.logger(ctx).Debug().
 		Int("job_count", len(jobs)).
 		Msg("getting execution configs in batch")
 
+	results := make(map[string]ExecutionConfigResult, len(jobs))
 	if len(jobs) == 0 {
-		return make(map[string]*domain.ExecutionConfig), nil
+		return results, nil
 	}
 
-	// Group jobs by chain ID and job type for batch processing
+	// Group jobs by chain ID, job type and module version for batch processing
 	type chainJobTypeKey struct {
-		chainId int64
-		jobType domain.DBJobType
+		chainId       int64
+		jobType       domain.DBJobType
+		moduleVersion string
 	}
 	jobsByChainAndType := make(map[chainJobTypeKey][]*domain.EntityJob)
 	for _, job := range jobs {
-		key := chainJobTypeKey{chainId: job.ChainID, jobType: job.JobType}
+		key := chainJobTypeKey{chainId: job.ChainID, jobType: job.JobType, moduleVersion: jobModuleVersion(job)}
 		jobsByChainAndType[key] = append(jobsByChainAndType[key], job)
 	}
 
@@ -276,128 +426,223 @@ func (b *BlockchainService) GetExecutionConfigsBatch(ctx context.Context, jobs [
 		Int("chain_type_combinations", len(jobsByChainAndType)).
 		Msg("grouped jobs by chain and type for batch processing")
 
-	results := make(map[string]*domain.ExecutionConfig)
+	failAll := func(chainTypeJobs []*domain.EntityJob, err *ExecutionConfigError) {
+		for _, job := range chainTypeJobs {
+			results[job.ID.String()] = ExecutionConfigResult{Err: err}
+		}
+	}
 
 	// Process each chain-type combination separately
 	for key, chainTypeJobs := range jobsByChainAndType {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
 		b.logger(ctx).Debug().
 			Int64("chain_id", key.chainId).
 			Str("job_type", string(key.jobType)).
+			Str("module_version", key.moduleVersion).
 			Int("jobs_for_chain_type", len(chainTypeJobs)).
 			Msg("processing jobs for chain and type")
 
-		client, err := b.GetClient(key.chainId)
+		chain, err := b.chains.Get(key.chainId)
 		if err != nil {
 			b.logger(ctx).Error().Err(err).
 				Int64("chain_id", key.chainId).
-				Msg("failed to get client for chain")
-			// Return error for unsupported chains
-			return nil, fmt.Errorf("failed to get client for chain %d: %w", key.chainId, err)
+				Int("job_count", len(chainTypeJobs)).
+				Msg("jobs are on an unsupported chain")
+			failAll(chainTypeJobs, newExecutionConfigError(ErrUnsupportedChain, key.chainId, err))
+			continue
 		}
 
-		// Get the appropriate contract address based on job type
-		contractAddress, err := b.getContractAddress(key.jobType)
+		if !isSchedulingJobType(key.jobType) {
+			b.logger(ctx).Error().
+				Int64("chain_id", key.chainId).
+				Str("job_type", string(key.jobType)).
+				Int("job_count", len(chainTypeJobs)).
+				Msg("jobs have an invalid job type")
+			failAll(chainTypeJobs, newExecutionConfigError(ErrInvalidJobType, key.chainId, fmt.Errorf("job type %q", key.jobType)))
+			continue
+		}
+
+		// Get the module deployment the jobs were registered against
+		addr, err := chain.SchedulerModule(key.jobType, key.moduleVersion)
 		if err != nil {
 			b.logger(ctx).Error().Err(err).
+				Int64("chain_id", key.chainId).
 				Str("job_type", string(key.jobType)).
-				Msg("failed to get contract address for job type")
-			return nil, fmt.Errorf("failed to get contract address for job type %s: %w", key.jobType, err)
+				Str("module_version", key.moduleVersion).
+				Msg("failed to get contract address for module version")
+			kind := ErrUnknownModuleVersion
+			if errors.Is(err, ErrUnsupportedJobType) {
+				kind = ErrUnsupportedJobType
+			}
+			failAll(chainTypeJobs, newExecutionConfigError(kind, key.chainId, err))
+			continue
 		}
 
-		// ABI for executionLog(address,uint256)
-		contractABI := `[{"inputs":[{"type":"address"},{"type":"uint256"}],"name":"executionLog","outputs":[{"type":"uint48"},{"type":"uint16"},{"type":"uint16"},{"type":"uint48"},{"type":"bool"},{"type":"uint48"},{"type":"bytes"}],"stateMutability":"view","type":"function"}]`
-		parsedABI, _ := abi.JSON(strings.NewReader(contractABI))
-
-		// Prepare batch calls
-		calls := make([]ethereum.CallMsg, len(chainTypeJobs))
+		// Prepare the executionLog reads of the jobs
+		calls := make([]contractCall, len(chainTypeJobs))
 		jobKeys := make([]string, len(chainTypeJobs))
 
 		for i, job := range chainTypeJobs {
-			calldata, err := parsedABI.Pack("executionLog", job.AccountAddress, big.NewInt(int64(job.OnChainJobID)))
-			if err != nil {
-				b.logger(ctx).Error().Err(err).
-					Str("job_id", job.ID.String()).
-					Str("account_address", job.AccountAddress.Hex()).
-					Msg("failed to pack calldata for job")
-				return nil, fmt.Errorf("failed to pack calldata for job %s: %w", job.ID.String(), err)
-			}
+			// The job type has a scheduling module, so packing cannot fail
+			calldata, _ := packExecutionLog(job)
+			calls[i] = contractCall{To: addr, Data: calldata}
+			jobKeys[i] = job.ID.String()
+		}
 
-			addr := common.HexToAddress(contractAddress)
-			calls[i] = ethereum.CallMsg{
-				To:   &addr,
-				Data: calldata,
+		// Read the execution logs in Multicall3 or JSON-RPC batches
+		callResults, err := b.callContracts(ctx, key.chainId, calls)
+		if err != nil {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return nil, ctxErr
 			}
-			jobKeys[i] = job.ID.String()
+			b.logger(ctx).Error().Err(err).
+				Int64("chain_id", key.chainId).
+				Str("job_type", string(key.jobType)).
+				Str("contract_address", addr.Hex()).
+				Msg("failed to call contract for jobs")
+			failAll(chainTypeJobs, newExecutionConfigError(ErrChainUnavailable, key.chainId, err))
+			continue
 		}
 
-		// Execute batch calls
-		for i, call := range calls {
-			result, err := client.CallContract(context.Background(), call, nil)
-			if err != nil {
-				b.logger(ctx).Error().Err(err).
+		failed := 0
+		for i, callResult := range callResults {
+			if callResult.Err != nil {
+				b.logger(ctx).Warn().Err(callResult.Err).
 					Str("job_id", jobKeys[i]).
 					Int64("chain_id", key.chainId).
 					Str("job_type", string(key.jobType)).
-					Str("contract_address", contractAddress).
+					Str("contract_address", addr.Hex()).
 					Msg("failed to call contract for job")
-				return nil, fmt.Errorf("failed to call contract for job %s: %w", jobKeys[i], err)
+				results[jobKeys[i]] = ExecutionConfigResult{Err: newExecutionConfigError(ErrExecutionLogCallFailed, key.chainId, callResult.Err)}
+				failed++
+				continue
 			}
 
 			// Unpack the result
-			unpacked, err := parsedABI.Unpack("executionLog", result)
+			config, err := unpackExecutionLog(key.jobType, callResult.Data)
 			if err != nil {
-				b.logger(ctx).Error().Err(err).
+				b.logger(ctx).Warn().Err(err).
 					Str("job_id", jobKeys[i]).
 					Msg("failed to unpack result for job")
-				return nil, fmt.Errorf("failed to unpack result for job %s: %w", jobKeys[i], err)
-			}
-
-			results[jobKeys[i]] = &domain.ExecutionConfig{
-				ExecuteInterval:             unpacked[0].(*big.Int),
-				NumberOfExecutions:          unpacked[1].(uint16),
-				NumberOfExecutionsCompleted: unpacked[2].(uint16),
-				StartDate:                   unpacked[3].(*big.Int),
-				IsEnabled:                   unpacked[4].(bool),
-				LastExecutionTime:           unpacked[5].(*big.Int),
-				ExecutionData:               unpacked[6].([]byte),
+				results[jobKeys[i]] = ExecutionConfigResult{Err: newExecutionConfigError(ErrInvalidExecutionLog, key.chainId, err)}
+				failed++
+				continue
 			}
+			results[jobKeys[i]] = ExecutionConfigResult{Config: config}
 		}
 
 		b.logger(ctx).Debug().
 			Int64("chain_id", key.chainId).
 			Str("job_type", string(key.jobType)).
 			Int("processed_jobs", len(chainTypeJobs)).
-			Msg("successfully processed all jobs for chain and type")
+			Int("failed_jobs", failed).
+			Msg("processed all jobs for chain and type")
 	}
 
 	b.logger(ctx).Info().
 		Int("total_jobs", len(jobs)).
-		Int("total_configs", len(results)).
-		Msg("successfully retrieved execution configs in batch")
+		Int("total_results", len(results)).
+		Msg("retrieved execution configs in batch")
 
 	return results, nil
 }
 
-// GetBundlerURL returns the bundler URL for a given chain ID
+// GetGasPriceConfig returns the gas price oracle configuration of a chain
+func (b *BlockchainService) GetGasPriceConfig(chainId int64) GasPriceConfig {
+	if config, exists := b.gasPrice[chainId]; exists {
+		return config
+	}
+	return b.defaultGasPrice
+}
+
+// GetFeeBumpConfig returns the replacement configuration of a chain, with defaults applied
+func (b *BlockchainService) GetFeeBumpConfig(chainId int64) FeeBumpConfig {
+	if config, exists := b.feeBump[chainId]; exists {
+		return config.withDefaults()
+	}
+	return b.defaultFeeBump.withDefaults()
+}
+
+// GetGasPriceOracle returns the gas price oracle configured for a chain
+func (b *BlockchainService) GetGasPriceOracle(ctx context.Context, chainId int64) (GasPriceOracle, error) {
+	rpcClient, err := b.GetRPCClient(ctx, chainId)
+	if err != nil {
+		return nil, err
+	}
+	bundlerCaller, err := b.getBundlerCaller(ctx, chainId)
+	if err != nil {
+		return nil, err
+	}
+
+	oracle, err := NewGasPriceOracle(rpcClient, bundlerCaller, b.GetGasPriceConfig(chainId))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gas price oracle for chain %d: %w", chainId, err)
+	}
+	return oracle, nil
+}
+
+// getBundlerCaller returns the bundler pool of a chain for bundler methods outside the Bundler interface, such as
+// gas price suggestions, so they fail over between the chain's bundler endpoints like user operations do.
+// It returns nil, meaning the node, for chains without bundler URLs and bundlers that do not accept raw calls.
+func (b *BlockchainService) getBundlerCaller(ctx context.Context, chainId int64) (erc4337.RPCCaller, error) {
+	chain, err := b.chains.Get(chainId)
+	if err != nil {
+		return nil, err
+	}
+	if len(chain.BundlerURLs) == 0 && len(b.bundlerFallbackURLs[chainId]) == 0 {
+		return nil, nil
+	}
+
+	bundler, err := b.GetBundlerClient(ctx, chainId)
+	if err != nil {
+		return nil, err
+	}
+	if caller, ok := bundler.(erc4337.RPCCaller); ok {
+		return caller, nil
+	}
+	return nil, nil
+}
+
+// GetSimulator returns the user operation simulator of a chain, or nil when simulation is disabled
+func (b *BlockchainService) GetSimulator(ctx context.Context, chainId int64) (*erc4337.Simulator, error) {
+	if !b.simulation.Enabled {
+		return nil, nil
+	}
+
+	rpcClient, err := b.GetRPCClient(ctx, chainId)
+	if err != nil {
+		return nil, err
+	}
+
+	return erc4337.NewSimulator(rpcClient, big.NewInt(chainId), erc4337.SimulatorConfig{
+		SimulationsCode: b.simulation.EntryPointSimulationsCode,
+		RevertDecoder:   schedulingRevertDecoder,
+	}), nil
+}
+
+// GetBundlerURL returns the primary bundler URL for a given chain ID, which is the node RPC URL
+// for chains without bundler URLs
 func (b *BlockchainService) GetBundlerURL(chainId int64) (string, error) {
-	switch chainId {
-	case 11155111: // Sepolia
-		return *b.SepoliaRPCURL, nil
-	case 421614: // Arbitrum Sepolia
-		return *b.ArbitrumSepoliaRPCURL, nil
-	case 84532: // Base Sepolia
-		return *b.BaseSepoliaRPCURL, nil
-	case 11155420: // Optimism Sepolia
-		return *b.OptimismSepoliaRPCURL, nil
-	case 80002: // Polygon Amoy
-		return *b.PolygonAmoyRPCURL, nil
-	case 42161: // Arbitrum One
-		return *b.ArbitrumRPCURL, nil
-	case 8453: // Base
-		return *b.BaseRPCURL, nil
-	default:
+	chain, err := b.chains.Get(chainId)
+	if err != nil {
 		return "", fmt.Errorf("unsupported chain id for bundler: %d", chainId)
 	}
+	return chain.bundlerURLs()[0], nil
+}
+
+// SetBundlerClient registers the bundler used for a chain, replacing any pooled bundler client.
+// Tests use it to inject an in-memory bundler such as bundlertest.Bundler.
+func (b *BlockchainService) SetBundlerClient(chainId int64, bundler erc4337.Bundler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.bundlerClientPool == nil {
+		b.bundlerClientPool = make(map[int64]erc4337.Bundler)
+	}
+	b.bundlerClientPool[chainId] = bundler
 }
 
 // GetBundlerClient returns a bundler client for a given chain ID
@@ -412,6 +657,20 @@ func (b *BlockchainService) GetBundlerClient(ctx context.Context, chainId int64)
 	}
 	b.mu.RUnlock()
 
+	// The self bundler uses the pooled eth client, so it is created before taking the lock
+	var selfBundler *erc4337.SelfBundler
+	selfBundlingMode := b.selfBundling[chainId]
+	if selfBundlingMode == SelfBundlingFallback || selfBundlingMode == SelfBundlingAlways {
+		var err error
+		selfBundler, err = b.newSelfBundler(chainId)
+		if err != nil {
+			b.logger(ctx).Error().Err(err).
+				Int64("chain_id", chainId).
+				Msg("failed to create self bundler")
+			return nil, err
+		}
+	}
+
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
@@ -420,36 +679,178 @@ func (b *BlockchainService) GetBundlerClient(ctx context.Context, chainId int64)
 		return bundler, nil
 	}
 
+	if b.bundlerClientPool == nil {
+		b.bundlerClientPool = make(map[int64]erc4337.Bundler)
+	}
+
+	if selfBundlingMode == SelfBundlingAlways {
+		b.bundlerClientPool[chainId] = selfBundler
+
+		b.logger(ctx).Info().
+			Int64("chain_id", chainId).
+			Str("executor", selfBundler.Address().Hex()).
+			Msg("self-bundling user operations")
+
+		return selfBundler, nil
+	}
+
 	b.logger(ctx).Debug().
 		Int64("chain_id", chainId).
-		Msg("creating new bundler client")
+		Msg("creating new bundler pool")
 
-	bundlerURL, err := b.GetBundlerURL(chainId)
+	chain, err := b.chains.Get(chainId)
 	if err != nil {
 		b.logger(ctx).Error().Err(err).
 			Int64("chain_id", chainId).
-			Msg("failed to get bundler URL")
+			Msg("failed to get bundler URLs")
 		return nil, err
 	}
 
-	bundlerClient, err := erc4337.DialContext(ctx, bundlerURL)
+	// The bundlers of the chain come first, followed by the fallbacks in configured order
+	var endpoints []erc4337.BundlerEndpoint
+	bundlerURLs := append(slices.Clone(chain.bundlerURLs()), b.bundlerFallbackURLs[chainId]...)
+	for _, endpointURL := range bundlerURLs {
+		bundlerClient, err := erc4337.DialContext(ctx, endpointURL)
+		if err != nil {
+			b.logger(ctx).Error().Err(err).
+				Str("bundler", bundlerEndpointName(endpointURL)).
+				Int64("chain_id", chainId).
+				Msg("failed to create bundler client")
+			continue
+		}
+		endpoints = append(endpoints, erc4337.BundlerEndpoint{Name: bundlerEndpointName(endpointURL), Bundler: bundlerClient})
+	}
+	if selfBundler != nil {
+		endpoints = append(endpoints, erc4337.BundlerEndpoint{Name: "self-bundler", Bundler: selfBundler, LastResort: true})
+	}
+	if len(endpoints) == 0 {
+		return nil, fmt.Errorf("failed to create bundler client for chain %d: no reachable bundler endpoint", chainId)
+	}
+
+	poolConfig := b.bundlerPool
+	logger := b.logger(ctx).With().Int64("chain_id", chainId).Logger()
+	poolConfig.OnEndpointFailure = func(endpoint string, method string, err error) {
+		logger.Warn().Err(err).
+			Str("bundler", endpoint).
+			Str("method", method).
+			Msg("bundler endpoint failed, failing over to the next endpoint")
+	}
+
+	bundlerPool, err := erc4337.NewBundlerPool(big.NewInt(chainId), endpoints, poolConfig)
 	if err != nil {
-		b.logger(ctx).Error().Err(err).
-			Str("bundler_url", bundlerURL).
-			Int64("chain_id", chainId).
-			Msg("failed to create bundler client")
-		return nil, fmt.Errorf("failed to create bundler client for chain %d: %w", chainId, err)
+		for _, endpoint := range endpoints {
+			endpoint.Bundler.Close()
+		}
+		return nil, fmt.Errorf("failed to create bundler pool for chain %d: %w", chainId, err)
 	}
 
-	if b.bundlerClientPool == nil {
-		b.bundlerClientPool = make(map[int64]erc4337.Bundler)
+	b.bundlerClientPool[chainId] = bundlerPool
+
+	b.logger(ctx).Debug().
+		Int64("chain_id", chainId).
+		Int("endpoints", len(endpoints)).
+		Msg("successfully created and cached bundler pool")
+
+	return bundlerPool, nil
+}
+
+// newSelfBundler creates the self bundler of a chain, which sends handleOps from the executor through the chain's node
+func (b *BlockchainService) newSelfBundler(chainId int64) (*erc4337.SelfBundler, error) {
+	if b.executor == nil {
+		return nil, fmt.Errorf("self-bundling on chain %d requires an executor signer", chainId)
+	}
+
+	client, err := b.GetClient(chainId)
+	if err != nil {
+		return nil, err
+	}
+
+	return erc4337.NewSelfBundler(client, big.NewInt(chainId), b.executor, erc4337.SelfBundlerConfig{}), nil
+}
+
+// GetBundlerStatus returns the health of the bundler endpoints of a chain, or nil if the chain has no bundler pool
+func (b *BlockchainService) GetBundlerStatus(chainId int64) []erc4337.BundlerEndpointStatus {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if bundlerPool, ok := b.bundlerClientPool[chainId].(*erc4337.BundlerPool); ok {
+		return bundlerPool.Status()
+	}
+	return nil
+}
+
+// bundlerEndpointName returns the host of a bundler URL, which unlike the full URL does not contain API keys
+func bundlerEndpointName(rawurl string) string {
+	if parsed, err := url.Parse(rawurl); err == nil && parsed.Host != "" {
+		return parsed.Host
+	}
+	return "bundler"
+}
+
+// GetPaymasterContext returns the ERC-7677 context configured for a chain
+func (b *BlockchainService) GetPaymasterContext(chainId int64) map[string]interface{} {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.paymasters[chainId].Context
+}
+
+// SetPaymasterClient registers the paymaster service used for a chain, replacing any pooled paymaster client.
+// Tests use it to inject a local paymaster service.
+func (b *BlockchainService) SetPaymasterClient(chainId int64, paymaster erc4337.Paymaster, pmContext map[string]interface{}) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.paymasterClientPool == nil {
+		b.paymasterClientPool = make(map[int64]erc4337.Paymaster)
+	}
+	b.paymasterClientPool[chainId] = paymaster
+
+	if b.paymasters == nil {
+		b.paymasters = make(map[int64]PaymasterConfig)
+	}
+	config := b.paymasters[chainId]
+	config.Context = pmContext
+	b.paymasters[chainId] = config
+}
+
+// GetPaymasterClient returns the ERC-7677 paymaster client for a chain, or nil if the chain has no paymaster service
+func (b *BlockchainService) GetPaymasterClient(ctx context.Context, chainId int64) (erc4337.Paymaster, error) {
+	b.mu.RLock()
+	if paymaster, exists := b.paymasterClientPool[chainId]; exists {
+		b.mu.RUnlock()
+		return paymaster, nil
+	}
+	config, configured := b.paymasters[chainId]
+	b.mu.RUnlock()
+
+	if !configured || config.URL == "" {
+		return nil, nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// Double-check pattern
+	if paymaster, exists := b.paymasterClientPool[chainId]; exists {
+		return paymaster, nil
 	}
-	b.bundlerClientPool[chainId] = bundlerClient
 
 	b.logger(ctx).Debug().
 		Int64("chain_id", chainId).
-		Str("bundler_url", bundlerURL).
-		Msg("successfully created and cached bundler client")
+		Msg("creating new paymaster client")
+
+	paymasterClient, err := erc4337.DialPaymasterContext(ctx, config.URL)
+	if err != nil {
+		b.logger(ctx).Error().Err(err).
+			Int64("chain_id", chainId).
+			Msg("failed to create paymaster client")
+		return nil, fmt.Errorf("failed to create paymaster client for chain %d: %w", chainId, err)
+	}
+
+	if b.paymasterClientPool == nil {
+		b.paymasterClientPool = make(map[int64]erc4337.Paymaster)
+	}
+	b.paymasterClientPool[chainId] = paymasterClient
 
-	return bundlerClient, nil
+	return paymasterClient, nil
 }
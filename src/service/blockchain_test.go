@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"math/big"
 	"strings"
 	"testing"
@@ -36,26 +37,44 @@ func createUserOperationFromJSON(jsonStr string) erc4337.UserOperation {
 	return userOp
 }
 
-func getBlockchainService() *BlockchainService {
-	sepoliaRpcUrl := testutil.GetEnv("SEPOLIA_RPC_URL")
-	arbitrumSepoliaRpcUrl := testutil.GetEnv("ARBITRUM_SEPOLIA_RPC_URL")
-	baseSepoliaRpcUrl := testutil.GetEnv("BASE_SEPOLIA_RPC_URL")
-	optimismSepoliaRpcUrl := testutil.GetEnv("OPTIMISM_SEPOLIA_RPC_URL")
-	polygonAmoyRpcUrl := testutil.GetEnv("POLYGON_AMOY_RPC_URL")
+// getBlockchainService builds a service against the live testnet RPCs and
+// skips the test when their URLs are not configured.
+func getBlockchainService(t *testing.T) *BlockchainService {
+	t.Helper()
+
+	rpcURLEnvKeys := map[int64]string{
+		11155111: "SEPOLIA_RPC_URL",
+		421614:   "ARBITRUM_SEPOLIA_RPC_URL",
+		84532:    "BASE_SEPOLIA_RPC_URL",
+		11155420: "OPTIMISM_SEPOLIA_RPC_URL",
+		80002:    "POLYGON_AMOY_RPC_URL",
+	}
+
+	var chains []ChainConfig
+	for _, chain := range DefaultChains() {
+		if key, exists := rpcURLEnvKeys[chain.ChainID]; exists {
+			rpcURL, ok := testutil.LookupEnv(key)
+			if !ok {
+				t.Skipf("%s not set, skipping live RPC test", key)
+			}
+			chain.RPCURLs = []string{rpcURL}
+			chains = append(chains, chain)
+		}
+	}
+	registry, err := NewChainRegistry(chains)
+	if err != nil {
+		t.Fatalf("failed to create chain registry: %v", err)
+	}
 
 	blockchainService := NewBlockchainService(BlockchainConfig{
-		SepoliaRPCURL:         sepoliaRpcUrl,
-		ArbitrumSepoliaRPCURL: arbitrumSepoliaRpcUrl,
-		BaseSepoliaRPCURL:     baseSepoliaRpcUrl,
-		OptimismSepoliaRPCURL: optimismSepoliaRpcUrl,
-		PolygonAmoyRPCURL:     polygonAmoyRpcUrl,
+		Chains: registry,
 	})
 
 	return blockchainService
 }
 
 func TestGetExecutionConfig(t *testing.T) {
-	blockchainService := getBlockchainService()
+	blockchainService := getBlockchainService(t)
 
 	// Create test job with the provided data
 	job := &domain.EntityJob{
@@ -123,7 +142,7 @@ func TestGetExecutionConfig(t *testing.T) {
 
 func TestGetExecutionConfig_UnsupportedChain(t *testing.T) {
 	ctx := context.Background()
-	blockchainService := getBlockchainService()
+	blockchainService := getBlockchainService(t)
 
 	job := &domain.EntityJob{
 		ID:                uuid.New(),
@@ -155,7 +174,7 @@ func TestGetExecutionConfig_UnsupportedChain(t *testing.T) {
 
 func TestGetExecutionConfigsBatch_EmptyInput(t *testing.T) {
 	ctx := context.Background()
-	blockchainService := getBlockchainService()
+	blockchainService := getBlockchainService(t)
 
 	// Test with empty job slice
 	configs, err := blockchainService.GetExecutionConfigsBatch(ctx, []*domain.EntityJob{})
@@ -176,7 +195,7 @@ func TestGetExecutionConfigsBatch_EmptyInput(t *testing.T) {
 
 func TestGetExecutionConfigsBatch_SingleJob(t *testing.T) {
 	ctx := context.Background()
-	blockchainService := getBlockchainService()
+	blockchainService := getBlockchainService(t)
 
 	// Create single test job
 	job := &domain.EntityJob{
@@ -202,10 +221,14 @@ func TestGetExecutionConfigsBatch_SingleJob(t *testing.T) {
 	}
 
 	// Verify the config exists for our job
-	config, exists := configs[job.ID.String()]
+	result, exists := configs[job.ID.String()]
 	if !exists {
 		t.Fatal("Config not found for job ID")
 	}
+	if result.Err != nil {
+		t.Fatalf("Failed to get config for job: %v", result.Err)
+	}
+	config := result.Config
 
 	// Verify config structure is properly populated
 	if config.ExecuteInterval == nil {
@@ -236,7 +259,7 @@ func TestGetExecutionConfigsBatch_SingleJob(t *testing.T) {
 
 func TestGetExecutionConfigsBatch_MultipleJobsSameChain(t *testing.T) {
 	ctx := context.Background()
-	blockchainService := getBlockchainService()
+	blockchainService := getBlockchainService(t)
 
 	// Create multiple test jobs on the same chain
 	jobs := []*domain.EntityJob{
@@ -273,11 +296,16 @@ func TestGetExecutionConfigsBatch_MultipleJobsSameChain(t *testing.T) {
 
 	// Verify each job has a config
 	for _, job := range jobs {
-		config, exists := configs[job.ID.String()]
+		result, exists := configs[job.ID.String()]
 		if !exists {
 			t.Errorf("Config not found for job ID %s", job.ID.String())
 			continue
 		}
+		if result.Err != nil {
+			t.Errorf("Failed to get config for job %s: %v", job.ID.String(), result.Err)
+			continue
+		}
+		config := result.Config
 
 		// Basic validation
 		if config.ExecuteInterval == nil {
@@ -291,7 +319,7 @@ func TestGetExecutionConfigsBatch_MultipleJobsSameChain(t *testing.T) {
 
 func TestGetExecutionConfigsBatch_MultipleJobsDifferentChains(t *testing.T) {
 	ctx := context.Background()
-	blockchainService := getBlockchainService()
+	blockchainService := getBlockchainService(t)
 
 	// Create test jobs on different chains
 	jobs := []*domain.EntityJob{
@@ -328,11 +356,16 @@ func TestGetExecutionConfigsBatch_MultipleJobsDifferentChains(t *testing.T) {
 
 	// Verify each job has a config
 	for _, job := range jobs {
-		config, exists := configs[job.ID.String()]
+		result, exists := configs[job.ID.String()]
 		if !exists {
 			t.Errorf("Config not found for job ID %s on chain %d", job.ID.String(), job.ChainID)
 			continue
 		}
+		if result.Err != nil {
+			t.Errorf("Failed to get config for job %s on chain %d: %v", job.ID.String(), job.ChainID, result.Err)
+			continue
+		}
+		config := result.Config
 
 		// Basic validation
 		if config.ExecuteInterval == nil {
@@ -346,7 +379,7 @@ func TestGetExecutionConfigsBatch_MultipleJobsDifferentChains(t *testing.T) {
 
 func TestGetExecutionConfigsBatch_UnsupportedChain(t *testing.T) {
 	ctx := context.Background()
-	blockchainService := getBlockchainService()
+	blockchainService := getBlockchainService(t)
 
 	// Create test job with unsupported chain
 	job := &domain.EntityJob{
@@ -358,27 +391,31 @@ func TestGetExecutionConfigsBatch_UnsupportedChain(t *testing.T) {
 		EntryPointAddress: common.HexToAddress("0x0000000071727De22E5E9d8BAf0edAc6f37da032"),
 	}
 
-	// Call GetExecutionConfigsBatch - should fail
+	// Call GetExecutionConfigsBatch - the job should fail on its own
 	configs, err := blockchainService.GetExecutionConfigsBatch(ctx, []*domain.EntityJob{job})
+	if err != nil {
+		t.Fatalf("GetExecutionConfigsBatch failed: %v", err)
+	}
 
-	// Verify it returns an error
-	if err == nil {
+	// Verify the job has an unsupported chain error
+	result := configs[job.ID.String()]
+	if result.Err == nil {
 		t.Fatal("Expected error for unsupported chain ID, got nil")
 	}
-
-	if configs != nil {
-		t.Fatal("Expected nil configs for unsupported chain ID")
+	// The chain can be added to the chain registry, so the job is not failed
+	if !errors.Is(result.Err, ErrUnsupportedChain) || result.Err.Permanent() {
+		t.Errorf("Expected an unsupported chain error that is not permanent, got: %v", result.Err)
 	}
 
 	// Verify error message contains chain info
-	if !strings.Contains(err.Error(), "chain 1") {
-		t.Errorf("Expected error to mention chain 1, got: %s", err.Error())
+	if !strings.Contains(result.Err.Error(), "chain 1") {
+		t.Errorf("Expected error to mention chain 1, got: %s", result.Err.Error())
 	}
 }
 
 func TestGetExecutionConfigsBatch_MixedValidInvalidChains(t *testing.T) {
 	ctx := context.Background()
-	blockchainService := getBlockchainService()
+	blockchainService := getBlockchainService(t)
 
 	// Create jobs with mixed valid and invalid chains
 	jobs := []*domain.EntityJob{
@@ -400,20 +437,28 @@ func TestGetExecutionConfigsBatch_MixedValidInvalidChains(t *testing.T) {
 		},
 	}
 
-	// Call GetExecutionConfigsBatch - should fail due to invalid chain
+	// Call GetExecutionConfigsBatch - the invalid chain should not affect the valid one
 	configs, err := blockchainService.GetExecutionConfigsBatch(ctx, jobs)
+	if err != nil {
+		t.Fatalf("GetExecutionConfigsBatch failed: %v", err)
+	}
 
-	// Verify it returns an error
-	if err == nil {
-		t.Fatal("Expected error for mixed valid/invalid chains, got nil")
+	if len(configs) != len(jobs) {
+		t.Fatalf("Expected %d results, got %d", len(jobs), len(configs))
 	}
 
-	if configs != nil {
-		t.Fatal("Expected nil configs for mixed valid/invalid chains")
+	// Verify the valid chain job has a config
+	valid := configs[jobs[0].ID.String()]
+	if valid.Err != nil || valid.Config == nil {
+		t.Errorf("Expected config for job on valid chain, got error: %v", valid.Err)
 	}
 
-	// Verify error message mentions the invalid chain
-	if !strings.Contains(err.Error(), "chain 1") {
-		t.Errorf("Expected error to mention invalid chain 1, got: %s", err.Error())
+	// Verify the invalid chain job has an error mentioning its chain
+	invalid := configs[jobs[1].ID.String()]
+	if invalid.Err == nil || !errors.Is(invalid.Err, ErrUnsupportedChain) {
+		t.Fatalf("Expected unsupported chain error for job on invalid chain, got: %v", invalid.Err)
+	}
+	if !strings.Contains(invalid.Err.Error(), "chain 1") {
+		t.Errorf("Expected error to mention invalid chain 1, got: %s", invalid.Err.Error())
 	}
 }
@@ -0,0 +1,259 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/ethaccount/backend/erc4337"
+	"github.com/ethaccount/backend/src/domain"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// DefaultModuleVersion is the version of the first scheduling module deployments, recorded on jobs registered
+// before module versions existed
+const DefaultModuleVersion = "v1"
+
+// Addresses of the DefaultModuleVersion scheduling modules, deployed at the same address on every default chain
+var (
+	scheduledTransfersAddress = common.HexToAddress("0xA8E374779aeE60413c974b484d6509c7E4DDb6bA")
+	scheduledOrdersAddress    = common.HexToAddress("0x40dc90D670C89F322fa8b9f685770296428DCb6b")
+)
+
+// ModuleDeployments are the deployments of a scheduling module on a chain by module version.
+// Deployments are never removed when a module is redeployed, so that jobs registered against them keep being read.
+type ModuleDeployments struct {
+	// Current is the version new jobs are registered against
+	Current string
+	// Addresses are the module addresses by version
+	Addresses map[string]common.Address
+}
+
+// singleModuleDeployment returns the deployments of a module deployed once, as DefaultModuleVersion
+func singleModuleDeployment(address common.Address) ModuleDeployments {
+	return ModuleDeployments{Current: DefaultModuleVersion, Addresses: map[string]common.Address{DefaultModuleVersion: address}}
+}
+
+// ChainConfig describes a chain on which jobs are executed
+type ChainConfig struct {
+	ChainID int64
+	Name    string
+	// RPCURLs are the node endpoints of the chain in failover order
+	RPCURLs []string
+	// BundlerURLs are the bundler endpoints of the chain in failover order, which may be a different provider
+	// than the node, e.g. Pimlico or a self-hosted Rundler. Chains without bundler URLs use their first
+	// RPC URL for both APIs, which needs a provider serving both, such as Alchemy.
+	BundlerURLs []string
+	// EntryPoints are the EntryPoint contracts jobs on the chain may use
+	EntryPoints []common.Address
+	// SchedulerModules are the deployments of the scheduling modules by job type
+	SchedulerModules map[domain.DBJobType]ModuleDeployments
+	// BlockTime is the average time between blocks, used to poll for receipts
+	BlockTime time.Duration
+	Testnet   bool
+}
+
+// SupportsEntryPoint reports whether jobs on the chain may use the entry point
+func (c ChainConfig) SupportsEntryPoint(entryPoint common.Address) bool {
+	return slices.Contains(c.EntryPoints, entryPoint)
+}
+
+// SchedulerModule returns the address of the scheduling module deployment executing jobs of the given type
+// registered against the given module version. The error wraps ErrUnsupportedJobType or ErrUnknownModuleVersion.
+func (c ChainConfig) SchedulerModule(jobType domain.DBJobType, moduleVersion string) (common.Address, error) {
+	deployments, exists := c.SchedulerModules[jobType]
+	if !exists {
+		return common.Address{}, fmt.Errorf("%w on chain %d: %s", ErrUnsupportedJobType, c.ChainID, jobType)
+	}
+	address, exists := deployments.Addresses[moduleVersion]
+	if !exists {
+		return common.Address{}, fmt.Errorf("%w on chain %d: %s %q", ErrUnknownModuleVersion, c.ChainID, jobType, moduleVersion)
+	}
+	return address, nil
+}
+
+// CurrentModuleVersion returns the module version new jobs of the given type are registered against
+func (c ChainConfig) CurrentModuleVersion(jobType domain.DBJobType) (string, error) {
+	deployments, exists := c.SchedulerModules[jobType]
+	if !exists {
+		return "", fmt.Errorf("%w on chain %d: %s", ErrUnsupportedJobType, c.ChainID, jobType)
+	}
+	return deployments.Current, nil
+}
+
+// bundlerURLs returns the bundler endpoints of the chain, falling back to the RPC URL
+func (c ChainConfig) bundlerURLs() []string {
+	if len(c.BundlerURLs) > 0 {
+		return c.BundlerURLs
+	}
+	return c.RPCURLs[:1]
+}
+
+// defaultEntryPoints are the canonical EntryPoint deployments supported by the default chains
+var defaultEntryPoints = []common.Address{erc4337.EntryPointV06, erc4337.EntryPointV07, erc4337.EntryPointV08}
+
+// DefaultChains returns the chains supported out of the box, using public nodes as RPC URLs
+func DefaultChains() []ChainConfig {
+	chains := []ChainConfig{
+		{ChainID: 11155111, Name: "Sepolia", RPCURLs: []string{"https://ethereum-sepolia-rpc.publicnode.com"}, BlockTime: 12 * time.Second, Testnet: true},
+		{ChainID: 421614, Name: "Arbitrum Sepolia", RPCURLs: []string{"https://arbitrum-sepolia-rpc.publicnode.com"}, BlockTime: 250 * time.Millisecond, Testnet: true},
+		{ChainID: 84532, Name: "Base Sepolia", RPCURLs: []string{"https://base-sepolia-rpc.publicnode.com"}, BlockTime: 2 * time.Second, Testnet: true},
+		{ChainID: 11155420, Name: "Optimism Sepolia", RPCURLs: []string{"https://optimism-sepolia-rpc.publicnode.com"}, BlockTime: 2 * time.Second, Testnet: true},
+		{ChainID: 80002, Name: "Polygon Amoy", RPCURLs: []string{"https://polygon-amoy-rpc.publicnode.com"}, BlockTime: 2 * time.Second, Testnet: true},
+		{ChainID: 42161, Name: "Arbitrum One", RPCURLs: []string{"https://arbitrum-one-rpc.publicnode.com"}, BlockTime: 250 * time.Millisecond},
+		{ChainID: 8453, Name: "Base", RPCURLs: []string{"https://base-rpc.publicnode.com"}, BlockTime: 2 * time.Second},
+	}
+
+	for i := range chains {
+		chains[i].EntryPoints = slices.Clone(defaultEntryPoints)
+		chains[i].SchedulerModules = map[domain.DBJobType]ModuleDeployments{
+			domain.DBJobTypeTransfer: singleModuleDeployment(scheduledTransfersAddress),
+			domain.DBJobTypeSwap:     singleModuleDeployment(scheduledOrdersAddress),
+		}
+	}
+	return chains
+}
+
+// ChainRegistry holds the configuration of the supported chains by chain ID
+type ChainRegistry struct {
+	chains map[int64]ChainConfig
+	order  []int64
+}
+
+// NewChainRegistry validates the chains and creates a registry of them
+func NewChainRegistry(chains []ChainConfig) (*ChainRegistry, error) {
+	registry := &ChainRegistry{chains: make(map[int64]ChainConfig, len(chains))}
+
+	for _, chain := range chains {
+		if chain.ChainID <= 0 {
+			return nil, fmt.Errorf("chain %q has an invalid chain id: %d", chain.Name, chain.ChainID)
+		}
+		if _, exists := registry.chains[chain.ChainID]; exists {
+			return nil, fmt.Errorf("chain %d is configured more than once", chain.ChainID)
+		}
+		if len(chain.RPCURLs) == 0 {
+			return nil, fmt.Errorf("chain %d has no RPC URL", chain.ChainID)
+		}
+		if len(chain.EntryPoints) == 0 {
+			return nil, fmt.Errorf("chain %d has no entry point", chain.ChainID)
+		}
+		for _, entryPoint := range chain.EntryPoints {
+			if _, err := erc4337.GetEntryPointVersion(entryPoint); err != nil {
+				return nil, fmt.Errorf("chain %d: %w", chain.ChainID, err)
+			}
+		}
+		if chain.BlockTime < 0 {
+			return nil, fmt.Errorf("chain %d has a negative block time", chain.ChainID)
+		}
+		for jobType, deployments := range chain.SchedulerModules {
+			if _, exists := deployments.Addresses[deployments.Current]; !exists {
+				return nil, fmt.Errorf("chain %d has no address for the current %s module version %q", chain.ChainID, jobType, deployments.Current)
+			}
+		}
+
+		registry.chains[chain.ChainID] = chain
+		registry.order = append(registry.order, chain.ChainID)
+	}
+
+	return registry, nil
+}
+
+// NewDefaultChainRegistry creates a registry of DefaultChains
+func NewDefaultChainRegistry() *ChainRegistry {
+	registry, err := NewChainRegistry(DefaultChains())
+	if err != nil {
+		panic(fmt.Sprintf("invalid default chains: %v", err))
+	}
+	return registry
+}
+
+// Get returns the configuration of a chain
+func (r *ChainRegistry) Get(chainId int64) (ChainConfig, error) {
+	chain, exists := r.chains[chainId]
+	if !exists {
+		return ChainConfig{}, fmt.Errorf("unsupported chain id: %d", chainId)
+	}
+	return chain, nil
+}
+
+// Chains returns the configured chains in configuration order
+func (r *ChainRegistry) Chains() []ChainConfig {
+	chains := make([]ChainConfig, 0, len(r.order))
+	for _, chainId := range r.order {
+		chains = append(chains, r.chains[chainId])
+	}
+	return chains
+}
+
+// chainConfigJSON is the JSON form of ChainConfig, with the block time as a duration string such as "2s"
+type chainConfigJSON struct {
+	ChainID          int64                                      `json:"chainId"`
+	Name             string                                     `json:"name"`
+	RPCURLs          []string                                   `json:"rpcUrls"`
+	BundlerURLs      []string                                   `json:"bundlerUrls"`
+	EntryPoints      []common.Address                           `json:"entryPoints"`
+	SchedulerModules map[domain.DBJobType]moduleDeploymentsJSON `json:"schedulerModules"`
+	BlockTime        string                                     `json:"blockTime"`
+	Testnet          bool                                       `json:"testnet"`
+}
+
+// moduleDeploymentsJSON is the JSON form of ModuleDeployments: either the address of a module deployed once,
+// as DefaultModuleVersion, or {"current": "v2", "addresses": {"v1": "0x...", "v2": "0x..."}}
+type moduleDeploymentsJSON ModuleDeployments
+
+func (m *moduleDeploymentsJSON) UnmarshalJSON(data []byte) error {
+	var address common.Address
+	if err := json.Unmarshal(data, &address); err == nil {
+		*m = moduleDeploymentsJSON(singleModuleDeployment(address))
+		return nil
+	}
+
+	var deployments struct {
+		Current   string                    `json:"current"`
+		Addresses map[string]common.Address `json:"addresses"`
+	}
+	if err := json.Unmarshal(data, &deployments); err != nil {
+		return err
+	}
+	*m = moduleDeploymentsJSON{Current: deployments.Current, Addresses: deployments.Addresses}
+	return nil
+}
+
+// ParseChainConfigs parses a JSON array of chains, e.g.
+//
+//	[{"chainId": 8453, "name": "Base", "rpcUrls": ["https://..."], "bundlerUrls": ["https://..."],
+//	  "entryPoints": ["0x0000000071727De22E5E9d8BAf0edAc6f37da032"],
+//	  "schedulerModules": {"transfer": "0x...", "swap": {"current": "v2", "addresses": {"v1": "0x...", "v2": "0x..."}}},
+//	  "blockTime": "2s", "testnet": false}]
+func ParseChainConfigs(data []byte) ([]ChainConfig, error) {
+	var raw []chainConfigJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse chains: %w", err)
+	}
+
+	chains := make([]ChainConfig, 0, len(raw))
+	for _, r := range raw {
+		chain := ChainConfig{
+			ChainID:          r.ChainID,
+			Name:             r.Name,
+			RPCURLs:          r.RPCURLs,
+			BundlerURLs:      r.BundlerURLs,
+			EntryPoints:      r.EntryPoints,
+			SchedulerModules: make(map[domain.DBJobType]ModuleDeployments, len(r.SchedulerModules)),
+			Testnet:          r.Testnet,
+		}
+		for jobType, deployments := range r.SchedulerModules {
+			chain.SchedulerModules[jobType] = ModuleDeployments(deployments)
+		}
+		if r.BlockTime != "" {
+			blockTime, err := time.ParseDuration(r.BlockTime)
+			if err != nil {
+				return nil, fmt.Errorf("invalid block time of chain %d: %w", r.ChainID, err)
+			}
+			chain.BlockTime = blockTime
+		}
+		chains = append(chains, chain)
+	}
+	return chains, nil
+}
@@ -0,0 +1,114 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ethaccount/backend/erc4337"
+	"github.com/ethaccount/backend/src/domain"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefaultChainRegistry(t *testing.T) {
+	registry := NewDefaultChainRegistry()
+
+	chainIDs := make([]int64, 0)
+	for _, chain := range registry.Chains() {
+		chainIDs = append(chainIDs, chain.ChainID)
+	}
+	assert.Equal(t, []int64{11155111, 421614, 84532, 11155420, 80002, 42161, 8453}, chainIDs)
+
+	base, err := registry.Get(8453)
+	require.NoError(t, err)
+	assert.Equal(t, "Base", base.Name)
+	assert.False(t, base.Testnet)
+	assert.True(t, base.SupportsEntryPoint(erc4337.EntryPointV07))
+	assert.Equal(t, []string{"https://base-rpc.publicnode.com"}, base.bundlerURLs())
+
+	module, err := base.SchedulerModule(domain.DBJobTypeSwap, DefaultModuleVersion)
+	require.NoError(t, err)
+	assert.Equal(t, scheduledOrdersAddress, module)
+
+	_, err = registry.Get(1)
+	assert.ErrorContains(t, err, "unsupported chain id: 1")
+}
+
+func TestParseChainConfigs(t *testing.T) {
+	chains, err := ParseChainConfigs([]byte(`[{
+		"chainId": 10,
+		"name": "OP Mainnet",
+		"rpcUrls": ["https://node.example", "https://backup.example"],
+		"bundlerUrls": ["https://bundler.example"],
+		"entryPoints": ["0x0000000071727De22E5E9d8BAf0edAc6f37da032"],
+		"schedulerModules": {
+			"transfer": "0x1111111111111111111111111111111111111111",
+			"swap": {"current": "v2", "addresses": {"v1": "0x2222222222222222222222222222222222222222", "v2": "0x3333333333333333333333333333333333333333"}}
+		},
+		"blockTime": "2s"
+	}]`))
+	require.NoError(t, err)
+
+	registry, err := NewChainRegistry(chains)
+	require.NoError(t, err)
+
+	chain, err := registry.Get(10)
+	require.NoError(t, err)
+	assert.Equal(t, "OP Mainnet", chain.Name)
+	assert.Equal(t, 2*time.Second, chain.BlockTime)
+	assert.Equal(t, []string{"https://bundler.example"}, chain.bundlerURLs())
+	assert.True(t, chain.SupportsEntryPoint(erc4337.EntryPointV07))
+	assert.False(t, chain.SupportsEntryPoint(erc4337.EntryPointV06))
+
+	// A plain address is deployed once as the default version
+	module, err := chain.SchedulerModule(domain.DBJobTypeTransfer, DefaultModuleVersion)
+	require.NoError(t, err)
+	assert.Equal(t, common.HexToAddress("0x1111111111111111111111111111111111111111"), module)
+	current, err := chain.CurrentModuleVersion(domain.DBJobTypeTransfer)
+	require.NoError(t, err)
+	assert.Equal(t, DefaultModuleVersion, current)
+
+	// Jobs of older versions keep reading their own deployment
+	current, err = chain.CurrentModuleVersion(domain.DBJobTypeSwap)
+	require.NoError(t, err)
+	assert.Equal(t, "v2", current)
+	module, err = chain.SchedulerModule(domain.DBJobTypeSwap, "v1")
+	require.NoError(t, err)
+	assert.Equal(t, common.HexToAddress("0x2222222222222222222222222222222222222222"), module)
+	module, err = chain.SchedulerModule(domain.DBJobTypeSwap, "v2")
+	require.NoError(t, err)
+	assert.Equal(t, common.HexToAddress("0x3333333333333333333333333333333333333333"), module)
+	_, err = chain.SchedulerModule(domain.DBJobTypeSwap, "v3")
+	assert.ErrorIs(t, err, ErrUnknownModuleVersion)
+	assert.ErrorContains(t, err, `unknown module version on chain 10: swap "v3"`)
+	_, err = chain.SchedulerModule("stake", DefaultModuleVersion)
+	assert.ErrorIs(t, err, ErrUnsupportedJobType)
+
+	_, err = ParseChainConfigs([]byte(`[{"chainId": 10, "blockTime": "soon"}]`))
+	assert.ErrorContains(t, err, "invalid block time of chain 10")
+}
+
+func TestNewChainRegistry_Invalid(t *testing.T) {
+	valid := ChainConfig{ChainID: 10, RPCURLs: []string{"https://node.example"}, EntryPoints: []common.Address{erc4337.EntryPointV07}}
+
+	noRPC := valid
+	noRPC.RPCURLs = nil
+	_, err := NewChainRegistry([]ChainConfig{noRPC})
+	assert.ErrorContains(t, err, "has no RPC URL")
+
+	unknownEntryPoint := valid
+	unknownEntryPoint.EntryPoints = []common.Address{common.HexToAddress("0x01")}
+	_, err = NewChainRegistry([]ChainConfig{unknownEntryPoint})
+	assert.ErrorContains(t, err, "unsupported entry point")
+
+	_, err = NewChainRegistry([]ChainConfig{valid, valid})
+	assert.ErrorContains(t, err, "configured more than once")
+
+	missingCurrent := valid
+	missingCurrent.SchedulerModules = map[domain.DBJobType]ModuleDeployments{
+		domain.DBJobTypeTransfer: {Current: "v2", Addresses: map[string]common.Address{"v1": scheduledTransfersAddress}},
+	}
+	_, err = NewChainRegistry([]ChainConfig{missingCurrent})
+	assert.ErrorContains(t, err, `has no address for the current transfer module version "v2"`)
+}
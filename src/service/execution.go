@@ -1,167 +1,113 @@
 package service
 
 import (
+	"bytes"
 	"context"
-	"crypto/ecdsa"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"math/big"
 
 	"github.com/ethaccount/backend/erc4337"
+	"github.com/ethaccount/backend/erc4337/erc7579"
 	"github.com/ethaccount/backend/src/domain"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
-	"github.com/ethereum/go-ethereum/crypto"
-	"github.com/ethereum/go-ethereum/rpc"
 	"github.com/rs/zerolog"
 )
 
-type ExecutionService struct {
-	blockchainService *BlockchainService
-	privateKey        *ecdsa.PrivateKey
-}
-
-func NewExecutionService(blockchainService *BlockchainService, privateKeyHex string) (*ExecutionService, error) {
-	// Parse private key
-	privateKey, err := crypto.HexToECDSA(privateKeyHex)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse private key: %w", err)
+// ErrInvalidJob is returned by ExecuteJob for jobs that can never be executed,
+// such as jobs with an unsupported entry point or an invalid signature template
+var ErrInvalidJob = errors.New("invalid job")
+
+// IsTransientExecutionError reports whether a job whose execution failed with err may succeed on a later attempt.
+// Only errors of the job or its user operation are permanent: invalid jobs, and bundler, EntryPoint and simulation
+// errors that are not transient. Node, bundler and paymaster outages, including timeouts, HTTP errors such as
+// 429 and 5xx, and every endpoint of a bundler pool failing, are transient.
+func IsTransientExecutionError(err error) bool {
+	if errors.Is(err, ErrInvalidJob) {
+		return false
 	}
-
-	return &ExecutionService{
-		blockchainService: blockchainService,
-		privateKey:        privateKey,
-	}, nil
-}
-
-// logger wraps the execution context with component info
-func (s *ExecutionService) logger(ctx context.Context) *zerolog.Logger {
-	l := zerolog.Ctx(ctx).With().Str("service", "execution").Logger()
-	return &l
-}
-
-// personalSignHash creates an Ethereum signed message hash
-func personalSignHash(data []byte) common.Hash {
-	msg := fmt.Sprintf("\x19Ethereum Signed Message:\n%d%s", len(data), data)
-	return crypto.Keccak256Hash([]byte(msg))
-}
-
-// Block represents a block header with baseFeePerGas
-type Block struct {
-	BaseFeePerGas string `json:"baseFeePerGas"`
-}
-
-// extractNonceKey extracts the nonce key by removing the trailing 8 bytes (64 bits)
-func extractNonceKey(nonce *hexutil.Big) (*big.Int, error) {
-	if nonce == nil {
-		return nil, fmt.Errorf("nonce is nil")
+	if errors.Is(err, ErrNonceConsumed) || errors.Is(err, erc4337.ErrAllEndpointsFailed) {
+		return true
 	}
 
-	nonceBytes := nonce.ToInt().Bytes()
-	if len(nonceBytes) < 8 {
-		return nil, fmt.Errorf("nonce too short to extract key")
+	var bundlerErr *erc4337.BundlerError
+	if errors.As(err, &bundlerErr) {
+		return bundlerErr.Transient()
 	}
-
-	// Pad to 32 bytes if needed
-	paddedNonce := make([]byte, 32)
-	copy(paddedNonce[32-len(nonceBytes):], nonceBytes)
-
-	// Extract key by taking first 24 bytes (removing trailing 8 bytes)
-	keyBytes := paddedNonce[:24]
-	key := new(big.Int).SetBytes(keyBytes)
-
-	return key, nil
-}
-
-// getCurrentNonce calls the entrypoint contract's getNonce function
-func getCurrentNonce(ctx context.Context, rpcClient *rpc.Client, sender common.Address, key *big.Int) (*big.Int, error) {
-	// Prepare the call data for getNonce(address,uint192)
-	// Function selector: getNonce(address,uint192) = 0x35567e1a
-	callData := "0x35567e1a"
-
-	// Encode sender address (32 bytes)
-	senderBytes := make([]byte, 32)
-	copy(senderBytes[12:], sender.Bytes())
-	callData += fmt.Sprintf("%x", senderBytes)
-
-	// Encode key (32 bytes, but only 24 bytes are significant for uint192)
-	keyBytes := make([]byte, 32)
-	key.FillBytes(keyBytes)
-	callData += fmt.Sprintf("%x", keyBytes)
-
-	// Make the eth_call
-	var result string
-	err := rpcClient.CallContext(ctx, &result, "eth_call", map[string]interface{}{
-		"to":   erc4337.EntryPointV07,
-		"data": callData,
-	}, "latest")
-
-	if err != nil {
-		return nil, fmt.Errorf("failed to call getNonce: %w", err)
+	var simulationErr *erc4337.SimulationError
+	if errors.As(err, &simulationErr) {
+		return simulationErr.Transient()
 	}
-
-	// Parse the result
-	nonce := new(big.Int)
-	if err := nonce.UnmarshalText([]byte(result)); err != nil {
-		return nil, fmt.Errorf("failed to parse nonce result: %w", err)
+	var aaErr *erc4337.AAError
+	if errors.As(err, &aaErr) {
+		return aaErr.Transient()
 	}
-
-	return nonce, nil
+	// Transport errors such as timeouts, refused connections and HTTP errors, and failures of the node or the
+	// paymaster service. Jobs failing with them for too long are failed after maxJobExecutionAttempts.
+	return true
 }
 
-// getMaxFeePerGas fetches the latest block and max priority fee, then calculates maxFeePerGas
-func getMaxFeePerGas(ctx context.Context, rpcClient *rpc.Client) (*big.Int, *big.Int, error) {
-	var blockResult *Block
-	var maxPriorityFeeResult string
+type ExecutionService struct {
+	blockchainService   *BlockchainService
+	signer              Signer
+	nonceManager        *NonceManager
+	signatureFormatters *SignatureFormatterRegistry
+}
 
-	batch := []rpc.BatchElem{
-		{
-			Method: "eth_getBlockByNumber",
-			Args:   []interface{}{"latest", false},
-			Result: &blockResult,
-		},
-		{
-			Method: "rundler_maxPriorityFeePerGas",
-			Args:   []interface{}{},
-			Result: &maxPriorityFeeResult,
-		},
+func NewExecutionService(blockchainService *BlockchainService, signer Signer) *ExecutionService {
+	return &ExecutionService{
+		blockchainService:   blockchainService,
+		signer:              signer,
+		nonceManager:        NewNonceManager(blockchainService, NonceManagerConfig{}),
+		signatureFormatters: SignatureFormatters,
 	}
+}
 
-	if err := rpcClient.BatchCallContext(ctx, batch); err != nil {
-		return nil, nil, fmt.Errorf("failed to make batch RPC calls: %w", err)
-	}
+// DummySignature is the 65-byte placeholder standing for the session key signature in the signature of job user
+// operations. The frontend signs everything else, and the job's signature formatter replaces it at execution time.
+var DummySignature = hexutil.MustDecode("0xfffffffffffffffffffffffffffffff0000000000000000000000000000000007aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa1c")
 
-	// Check for individual call errors
-	if batch[0].Error != nil {
-		return nil, nil, fmt.Errorf("eth_getBlockByNumber failed: %w", batch[0].Error)
-	}
-	if batch[1].Error != nil {
-		return nil, nil, fmt.Errorf("rundler_maxPriorityFeePerGas failed: %w", batch[1].Error)
-	}
+// SignerAddress returns the address of the session key signing user operations
+func (s *ExecutionService) SignerAddress() common.Address {
+	return s.signer.Address()
+}
 
-	// Parse baseFeePerGas
-	baseFeePerGas := new(big.Int)
-	if err := baseFeePerGas.UnmarshalText([]byte(blockResult.BaseFeePerGas)); err != nil {
-		return nil, nil, fmt.Errorf("failed to parse baseFeePerGas: %w", err)
+// CheckSignatureFormat returns an error when the session key cannot sign user operations of the signature format
+func (s *ExecutionService) CheckSignatureFormat(format string) error {
+	if _, err := s.signatureFormatters.Get(format); err != nil {
+		return err
 	}
-
-	// Parse maxPriorityFeePerGas
-	maxPriorityFeePerGas := new(big.Int)
-	if err := maxPriorityFeePerGas.UnmarshalText([]byte(maxPriorityFeeResult)); err != nil {
-		return nil, nil, fmt.Errorf("failed to parse maxPriorityFeePerGas: %w", err)
+	if format == SignatureFormatRawHash {
+		if _, ok := s.signer.(HashSigner); !ok {
+			return fmt.Errorf("signature format %s requires a session signer able to sign raw hashes", format)
+		}
 	}
+	return nil
+}
 
-	// Calculate maxFeePerGas: (baseFeePerGas * 150 / 100) + maxPriorityFeePerGas
-	maxFeePerGas := new(big.Int)
-	maxFeePerGas.Mul(baseFeePerGas, big.NewInt(150))
-	maxFeePerGas.Div(maxFeePerGas, big.NewInt(100))
-	maxFeePerGas.Add(maxFeePerGas, maxPriorityFeePerGas)
+// ReleaseNonce frees the nonce of a sent user operation that the bundler dropped, so that the next job of the
+// sender on the same nonce key can use it
+func (s *ExecutionService) ReleaseNonce(chainId int64, entryPoint common.Address, userOp *erc4337.UserOperation) error {
+	return s.nonceManager.ReleaseSent(chainId, entryPoint, userOp.Sender, userOp.Nonce.ToInt())
+}
+
+// logger wraps the execution context with component info
+func (s *ExecutionService) logger(ctx context.Context) *zerolog.Logger {
+	l := zerolog.Ctx(ctx).With().Str("service", "execution").Logger()
+	return &l
+}
 
-	return maxFeePerGas, maxPriorityFeePerGas, nil
+// SentUserOperation is a signed user operation accepted by the bundler
+type SentUserOperation struct {
+	UserOpHash    common.Hash
+	EntryPoint    common.Address
+	UserOperation erc4337.UserOperation
 }
 
 // ExecuteJob signs the user operation and sends it to the bundler
-func (s *ExecutionService) ExecuteJob(ctx context.Context, job domain.EntityJob) (*common.Hash, error) {
+func (s *ExecutionService) ExecuteJob(ctx context.Context, job domain.EntityJob) (*SentUserOperation, error) {
 	s.logger(ctx).Info().
 		Str("job_id", job.ID.String()).
 		Str("account_address", job.AccountAddress.Hex()).
@@ -171,6 +117,49 @@ func (s *ExecutionService) ExecuteJob(ctx context.Context, job domain.EntityJob)
 
 	// Get user operation from job - direct access instead of GetUserOperation
 	userOp := job.UserOperation
+	entryPointAddress := job.EntryPointAddress
+
+	// Resolve the entry point version, which determines the hashing and RPC format
+	entryPointVersion, err := erc4337.GetEntryPointVersion(entryPointAddress)
+	if err != nil {
+		s.logger(ctx).Error().Err(err).
+			Str("job_id", job.ID.String()).
+			Str("entry_point", entryPointAddress.Hex()).
+			Msg("unsupported entry point")
+		return nil, fmt.Errorf("%w: failed to resolve entry point version: %w", ErrInvalidJob, err)
+	}
+
+	// Only entry points configured for the chain may be used
+	chain, err := s.blockchainService.GetChain(job.ChainID)
+	if err != nil {
+		return nil, err
+	}
+	if !chain.SupportsEntryPoint(entryPointAddress) {
+		s.logger(ctx).Error().
+			Str("job_id", job.ID.String()).
+			Int64("chain_id", job.ChainID).
+			Str("entry_point", entryPointAddress.Hex()).
+			Msg("entry point not configured for chain")
+		return nil, fmt.Errorf("%w: entry point %s is not supported on chain %s", ErrInvalidJob, entryPointAddress.Hex(), chain.Name)
+	}
+
+	// Log what the user operation will execute on the account
+	if execution, err := erc7579.DecodeExecute(userOp.CallData); err == nil {
+		for i, call := range execution.Calls {
+			s.logger(ctx).Debug().
+				Str("job_id", job.ID.String()).
+				Str("call_type", execution.Mode.CallType().String()).
+				Int("call_index", i).
+				Str("target", call.Target.Hex()).
+				Str("value", call.Value.String()).
+				Str("data", hexutil.Encode(call.Data)).
+				Msg("decoded execution call")
+		}
+	} else {
+		s.logger(ctx).Debug().Err(err).
+			Str("job_id", job.ID.String()).
+			Msg("user operation call data is not an ERC-7579 execution")
+	}
 
 	// Get bundler client
 	bundlerClient, err := s.blockchainService.GetBundlerClient(ctx, job.ChainID)
@@ -182,79 +171,109 @@ func (s *ExecutionService) ExecuteJob(ctx context.Context, job domain.EntityJob)
 		return nil, fmt.Errorf("failed to get bundler client: %w", err)
 	}
 
-	// Get RPC client from the blockchain service pool
-	rpcClient, err := s.blockchainService.GetRPCClient(ctx, job.ChainID)
+	// Make sure the bundler accepts user operations for this entry point before signing anything.
+	// The bundler pool caches the entry points of its endpoints, so this is not a round trip per job.
+	supported, err := erc4337.SupportsEntryPoint(ctx, bundlerClient, entryPointAddress)
 	if err != nil {
 		s.logger(ctx).Error().Err(err).
 			Str("job_id", job.ID.String()).
 			Int64("chain_id", job.ChainID).
-			Msg("failed to get RPC client")
-		return nil, fmt.Errorf("failed to get RPC client: %w", err)
+			Msg("failed to get supported entry points")
+		return nil, fmt.Errorf("failed to get supported entry points: %w", err)
+	}
+	if !supported {
+		s.logger(ctx).Error().
+			Str("job_id", job.ID.String()).
+			Int64("chain_id", job.ChainID).
+			Str("entry_point", entryPointAddress.Hex()).
+			Msg("entry point not supported by bundler")
+		return nil, fmt.Errorf("%w: entry point %s is not supported by the bundler for chain %d", ErrInvalidJob, entryPointAddress.Hex(), job.ChainID)
 	}
 
-	// Extract nonce key and get current nonce from entrypoint
-	nonceKey, err := extractNonceKey(userOp.Nonce)
+	// Reserve the next nonce of the job's nonce key. The key selects the validator (e.g. the session key)
+	// and jobs of the same account on different keys do not wait for each other.
+	jobNonce, err := erc4337.ParseNonce(userOp.Nonce.ToInt())
 	if err != nil {
 		s.logger(ctx).Error().Err(err).
 			Str("job_id", job.ID.String()).
-			Msg("failed to extract nonce key")
-		return nil, fmt.Errorf("failed to extract nonce key: %w", err)
+			Msg("failed to parse nonce")
+		return nil, fmt.Errorf("%w: failed to parse nonce: %w", ErrInvalidJob, err)
 	}
 
-	s.logger(ctx).Debug().
-		Str("job_id", job.ID.String()).
-		Str("nonce_key", "0x"+hex.EncodeToString(nonceKey.Bytes())).
-		Msg("extracted nonce key")
-
-	currentNonce, err := getCurrentNonce(ctx, rpcClient, userOp.Sender, nonceKey)
+	nonceReservation, err := s.nonceManager.Reserve(ctx, job.ChainID, entryPointAddress, userOp.Sender, jobNonce.Key)
 	if err != nil {
 		s.logger(ctx).Error().Err(err).
 			Str("job_id", job.ID.String()).
-			Msg("failed to get current nonce")
-		return nil, fmt.Errorf("failed to get current nonce: %w", err)
+			Msg("failed to reserve nonce")
+		return nil, fmt.Errorf("failed to reserve nonce: %w", err)
 	}
+	// Frees the nonce for the next job unless the user operation is sent
+	defer nonceReservation.Release()
 
 	s.logger(ctx).Debug().
 		Str("job_id", job.ID.String()).
-		Str("current_nonce", "0x"+hex.EncodeToString(currentNonce.Bytes())).
-		Msg("current nonce from entrypoint")
+		Str("nonce_key", "0x"+jobNonce.Key.Text(16)).
+		Uint64("nonce_sequence", nonceReservation.Nonce().Sequence).
+		Msg("reserved nonce")
 
-	// Update user operation with current nonce
-	userOp.Nonce = (*hexutil.Big)(currentNonce)
+	// Update user operation with the reserved nonce
+	userOp.Nonce = (*hexutil.Big)(nonceReservation.Nonce().BigInt())
 
-	// Extract leading signature (part before dummy signature)
-	// The dummy signature is 65 bytes (130 hex chars), so we need to extract everything before it
-	dummySignature := "fffffffffffffffffffffffffffffff0000000000000000000000000000000007aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa1c"
-	dummySignatureBytes, err := hex.DecodeString(dummySignature)
+	// Keep the template signature of the job and estimate gas with the dummy signature of its format
+	signatureFormatter, err := s.signatureFormatters.Get(job.SignatureFormat)
 	if err != nil {
 		s.logger(ctx).Error().Err(err).
 			Str("job_id", job.ID.String()).
-			Msg("failed to decode dummy signature")
-		return nil, fmt.Errorf("failed to decode dummy signature: %w", err)
+			Msg("failed to get signature formatter")
+		return nil, fmt.Errorf("%w: failed to get signature formatter: %w", ErrInvalidJob, err)
 	}
 
-	// Find the dummy signature in the user operation signature
-	userOpSignatureHex := hex.EncodeToString(userOp.Signature)
-	dummyIndex := len(userOpSignatureHex) - len(dummySignature)
-	if dummyIndex < 0 || userOpSignatureHex[dummyIndex:] != dummySignature {
-		s.logger(ctx).Error().
+	signatureTemplate := bytes.Clone(userOp.Signature)
+	userOp.Signature, err = signatureFormatter.DummySignature(signatureTemplate)
+	if err != nil {
+		s.logger(ctx).Error().Err(err).
 			Str("job_id", job.ID.String()).
-			Str("signature", userOpSignatureHex).
-			Str("expected_dummy", dummySignature).
-			Msg("dummy signature not found at expected position")
-		return nil, fmt.Errorf("dummy signature not found at expected position in user operation signature")
+			Str("signature_format", job.SignatureFormat).
+			Str("signature", hex.EncodeToString(signatureTemplate)).
+			Msg("invalid signature template")
+		return nil, fmt.Errorf("%w: invalid signature template: %w", ErrInvalidJob, err)
 	}
 
-	// Extract leading signature (everything before the dummy signature)
-	leadingSignature := userOp.Signature[:len(userOp.Signature)-len(dummySignatureBytes)]
+	// Refresh sponsorship for sponsored jobs when the chain has an ERC-7677 paymaster service
+	var paymasterClient erc4337.Paymaster
+	var paymasterStubData *erc4337.PaymasterStubData
+	if userOp.Paymaster != nil {
+		paymasterClient, err = s.blockchainService.GetPaymasterClient(ctx, job.ChainID)
+		if err != nil {
+			s.logger(ctx).Error().Err(err).
+				Str("job_id", job.ID.String()).
+				Int64("chain_id", job.ChainID).
+				Msg("failed to get paymaster client")
+			return nil, fmt.Errorf("failed to get paymaster client: %w", err)
+		}
+	}
 
-	s.logger(ctx).Debug().
-		Str("job_id", job.ID.String()).
-		Str("leading_signature", hex.EncodeToString(leadingSignature)).
-		Msg("extracted leading signature")
+	if paymasterClient != nil {
+		paymasterStubData, err = paymasterClient.GetPaymasterStubData(ctx, &userOp, entryPointAddress, big.NewInt(job.ChainID), s.blockchainService.GetPaymasterContext(job.ChainID))
+		if err != nil {
+			s.logger(ctx).Error().Err(err).
+				Str("job_id", job.ID.String()).
+				Msg("failed to get paymaster stub data")
+			return nil, fmt.Errorf("failed to get paymaster stub data: %w", err)
+		}
+		paymasterStubData.Apply(&userOp)
+
+		logEvent := s.logger(ctx).Debug().
+			Str("job_id", job.ID.String()).
+			Str("paymaster", userOp.Paymaster.Hex()).
+			Bool("is_final", paymasterStubData.IsFinal)
+		if paymasterStubData.Sponsor != nil {
+			logEvent = logEvent.Str("sponsor", paymasterStubData.Sponsor.Name)
+		}
+		logEvent.Msg("applied paymaster stub data")
+	}
 
-	// Estimate gas values (userOp.Signature already contains dummy signature from frontend)
-	entryPointAddress := job.EntryPointAddress
+	// Estimate gas values (userOp.Signature holds the dummy signature)
 	estimates, err := bundlerClient.EstimateUserOperationGas(ctx, &userOp, entryPointAddress)
 	if err != nil {
 		s.logger(ctx).Error().Err(err).
@@ -263,14 +282,24 @@ func (s *ExecutionService) ExecuteJob(ctx context.Context, job domain.EntityJob)
 		return nil, fmt.Errorf("failed to estimate user operation gas: %w", err)
 	}
 
-	// Get gas fees
-	maxFeePerGas, maxPriorityFeePerGas, err := getMaxFeePerGas(ctx, rpcClient)
+	// Get gas fees from the chain's gas price oracle
+	gasPriceOracle, err := s.blockchainService.GetGasPriceOracle(ctx, job.ChainID)
+	if err != nil {
+		s.logger(ctx).Error().Err(err).
+			Str("job_id", job.ID.String()).
+			Int64("chain_id", job.ChainID).
+			Msg("failed to get gas price oracle")
+		return nil, fmt.Errorf("failed to get gas price oracle: %w", err)
+	}
+
+	gasPrice, err := gasPriceOracle.SuggestGasPrice(ctx)
 	if err != nil {
 		s.logger(ctx).Error().Err(err).
 			Str("job_id", job.ID.String()).
 			Msg("failed to get gas fees")
 		return nil, fmt.Errorf("failed to get gas fees: %w", err)
 	}
+	maxFeePerGas, maxPriorityFeePerGas := gasPrice.MaxFeePerGas, gasPrice.MaxPriorityFeePerGas
 
 	s.logger(ctx).Debug().
 		Str("job_id", job.ID.String()).
@@ -288,13 +317,30 @@ func (s *ExecutionService) ExecuteJob(ctx context.Context, job domain.EntityJob)
 	userOp.MaxFeePerGas = (*hexutil.Big)(maxFeePerGas)
 	userOp.MaxPriorityFeePerGas = (*hexutil.Big)(maxPriorityFeePerGas)
 
-	// Only set paymaster fields if we're using a paymaster
-	if userOp.Paymaster != nil {
+	// Only set paymaster fields if we're using a paymaster (v0.6 has no separate paymaster gas limits)
+	if userOp.Paymaster != nil && entryPointVersion != erc4337.EntryPointVersionV06 && estimates.PaymasterVerificationGasLimit != nil {
 		userOp.PaymasterVerificationGasLimit = (*hexutil.Big)(estimates.PaymasterVerificationGasLimit)
 	}
 
+	// Replace the stub with the final paymaster data now that gas values are known
+	if paymasterClient != nil && !paymasterStubData.IsFinal {
+		paymasterData, err := paymasterClient.GetPaymasterData(ctx, &userOp, entryPointAddress, big.NewInt(job.ChainID), s.blockchainService.GetPaymasterContext(job.ChainID))
+		if err != nil {
+			s.logger(ctx).Error().Err(err).
+				Str("job_id", job.ID.String()).
+				Msg("failed to get paymaster data")
+			return nil, fmt.Errorf("failed to get paymaster data: %w", err)
+		}
+		paymasterData.Apply(&userOp)
+
+		s.logger(ctx).Debug().
+			Str("job_id", job.ID.String()).
+			Str("paymaster", userOp.Paymaster.Hex()).
+			Msg("applied paymaster data")
+	}
+
 	// Calculate user operation hash for signing
-	hash, err := userOp.GetUserOpHashV07(big.NewInt(job.ChainID))
+	hash, err := userOp.GetUserOpHash(entryPointAddress, big.NewInt(job.ChainID))
 	if err != nil {
 		s.logger(ctx).Error().Err(err).
 			Str("job_id", job.ID.String()).
@@ -306,17 +352,18 @@ func (s *ExecutionService) ExecuteJob(ctx context.Context, job domain.EntityJob)
 	s.logger(ctx).Debug().
 		Str("job_id", job.ID.String()).
 		Str("user_op_hash", hash.Hex()).
+		Str("entry_point_version", string(entryPointVersion)).
 		Msg("calculated user operation hash")
 
 	// Log signer address
-	signerAddress := crypto.PubkeyToAddress(s.privateKey.PublicKey)
 	s.logger(ctx).Info().
 		Str("job_id", job.ID.String()).
-		Str("signer_address", signerAddress.Hex()).
+		Str("signer_address", s.signer.Address().Hex()).
+		Str("signature_format", job.SignatureFormat).
 		Msg("signing user operation")
 
-	// Sign the user operation hash
-	signature, err := crypto.Sign(personalSignHash(hash.Bytes()).Bytes(), s.privateKey)
+	// Sign the user operation hash and build the final signature from the template
+	userOp.Signature, err = signatureFormatter.Sign(ctx, s.signer, signatureTemplate, hash)
 	if err != nil {
 		s.logger(ctx).Error().Err(err).
 			Str("job_id", job.ID.String()).
@@ -325,36 +372,180 @@ func (s *ExecutionService) ExecuteJob(ctx context.Context, job domain.EntityJob)
 		return nil, fmt.Errorf("failed to sign user operation hash: %w", err)
 	}
 
-	// Adjust signature format for Ethereum (recovery ID + 27)
-	signature[64] += 27
-
-	s.logger(ctx).Debug().
-		Str("job_id", job.ID.String()).
-		Str("signature", "0x"+hex.EncodeToString(signature)).
-		Msg("generated signature")
-
-	// Replace dummy signature with real signature (preserve leading signature prefix)
-	userOp.Signature = append(leadingSignature, signature...)
-
 	s.logger(ctx).Debug().
 		Str("job_id", job.ID.String()).
 		Str("final_signature", hex.EncodeToString(userOp.Signature)).
 		Msg("user operation signed successfully")
 
+	// Simulate the signed user operation so that jobs that cannot succeed are not sent
+	simulator, err := s.blockchainService.GetSimulator(ctx, job.ChainID)
+	if err != nil {
+		s.logger(ctx).Error().Err(err).
+			Str("job_id", job.ID.String()).
+			Int64("chain_id", job.ChainID).
+			Msg("failed to get simulator")
+		return nil, fmt.Errorf("failed to get simulator: %w", err)
+	}
+
+	if simulator != nil {
+		simulation, err := simulator.Simulate(ctx, &userOp, entryPointAddress)
+		if err != nil {
+			// The bundler validates the user operation as well, so a simulation that could not run does not block the job
+			s.logger(ctx).Warn().Err(err).
+				Str("job_id", job.ID.String()).
+				Msg("failed to simulate user operation, sending it without simulation")
+		} else if err := simulation.Err(); err != nil {
+			s.logger(ctx).Error().Err(err).
+				Str("job_id", job.ID.String()).
+				Interface("user_op", userOp).
+				Msg("user operation simulation failed, skipping job")
+			return nil, err
+		} else {
+			logEvent := s.logger(ctx).Debug().
+				Str("job_id", job.ID.String()).
+				Bool("execution_simulated", simulation.ExecutionSimulated)
+			if validation := simulation.Validation; validation != nil {
+				logEvent = logEvent.
+					Str("pre_op_gas", validation.PreOpGas.String()).
+					Uint64("valid_after", validation.ValidAfter).
+					Uint64("valid_until", validation.ValidUntil)
+			}
+			logEvent.Msg("user operation simulation succeeded")
+		}
+	}
+
+	// Make sure no other user operation used the nonce while this one was prepared
+	if err := nonceReservation.Check(ctx); err != nil {
+		s.logger(ctx).Warn().Err(err).
+			Str("job_id", job.ID.String()).
+			Msg("nonce check failed")
+		return nil, err
+	}
+
 	// Send the user operation
 	userOpHash, err := bundlerClient.SendUserOperation(ctx, &userOp, entryPointAddress)
 	if err != nil {
+		// The cached nonce is stale when the bundler rejects it
+		var aaErr *erc4337.AAError
+		if errors.As(err, &aaErr) && aaErr.Code == "AA25" {
+			if invalidateErr := nonceReservation.Invalidate(ctx); invalidateErr != nil {
+				s.logger(ctx).Warn().Err(invalidateErr).
+					Str("job_id", job.ID.String()).
+					Msg("failed to read nonce again after it was rejected")
+			}
+		}
+
 		s.logger(ctx).Error().Err(err).
 			Str("job_id", job.ID.String()).
 			Interface("user_op", userOp).
 			Msg("failed to send user operation")
 		return nil, fmt.Errorf("failed to send user operation: %w", err)
 	}
+	nonceReservation.MarkSent()
 
 	s.logger(ctx).Info().
 		Str("job_id", job.ID.String()).
 		Str("user_op_hash", userOpHash.Hex()).
 		Msg("job executed successfully")
 
-	return &userOpHash, nil
+	return &SentUserOperation{
+		UserOpHash:    userOpHash,
+		EntryPoint:    entryPointAddress,
+		UserOperation: userOp,
+	}, nil
+}
+
+// ReplaceUserOperation re-signs a user operation sent for a job with the same nonce and raised fees and sends it to
+// the bundler, which replaces the pending one. The gas limits and calldata are kept, only the fees, paymaster data
+// and signature change.
+func (s *ExecutionService) ReplaceUserOperation(ctx context.Context, job domain.EntityJob, sent *SentUserOperation) (*SentUserOperation, error) {
+	chainId := job.ChainID
+	userOp := sent.UserOperation
+	entryPointAddress := sent.EntryPoint
+
+	if userOp.MaxFeePerGas == nil || userOp.MaxPriorityFeePerGas == nil {
+		return nil, fmt.Errorf("user operation %s has no fees to bump", sent.UserOpHash.Hex())
+	}
+
+	// The job's template signature is signed again with the new userOpHash
+	signatureFormatter, err := s.signatureFormatters.Get(job.SignatureFormat)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get signature formatter: %w", err)
+	}
+
+	bundlerClient, err := s.blockchainService.GetBundlerClient(ctx, chainId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bundler client: %w", err)
+	}
+
+	gasPriceOracle, err := s.blockchainService.GetGasPriceOracle(ctx, chainId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get gas price oracle: %w", err)
+	}
+	suggested, err := gasPriceOracle.SuggestGasPrice(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get gas fees: %w", err)
+	}
+
+	gasPrice, err := bumpGasPrice(&GasPrice{
+		MaxFeePerGas:         userOp.MaxFeePerGas.ToInt(),
+		MaxPriorityFeePerGas: userOp.MaxPriorityFeePerGas.ToInt(),
+	}, suggested, s.blockchainService.GetFeeBumpConfig(chainId).Percent, s.blockchainService.GetGasPriceConfig(chainId))
+	if err != nil {
+		return nil, fmt.Errorf("failed to bump gas fees: %w", err)
+	}
+
+	s.logger(ctx).Debug().
+		Str("user_op_hash", sent.UserOpHash.Hex()).
+		Str("max_fee_per_gas", gasPrice.MaxFeePerGas.String()).
+		Str("max_priority_fee_per_gas", gasPrice.MaxPriorityFeePerGas.String()).
+		Msg("bumped gas fees")
+
+	userOp.MaxFeePerGas = (*hexutil.Big)(gasPrice.MaxFeePerGas)
+	userOp.MaxPriorityFeePerGas = (*hexutil.Big)(gasPrice.MaxPriorityFeePerGas)
+
+	// The paymaster signature covers the fees, so sponsored user operations need new paymaster data
+	if userOp.Paymaster != nil {
+		paymasterClient, err := s.blockchainService.GetPaymasterClient(ctx, chainId)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get paymaster client: %w", err)
+		}
+		if paymasterClient != nil {
+			paymasterData, err := paymasterClient.GetPaymasterData(ctx, &userOp, entryPointAddress, big.NewInt(chainId), s.blockchainService.GetPaymasterContext(chainId))
+			if err != nil {
+				return nil, fmt.Errorf("failed to get paymaster data: %w", err)
+			}
+			paymasterData.Apply(&userOp)
+		}
+	}
+
+	hash, err := userOp.GetUserOpHash(entryPointAddress, big.NewInt(chainId))
+	if err != nil {
+		return nil, fmt.Errorf("failed to calculate user operation hash: %w", err)
+	}
+
+	userOp.Signature, err = signatureFormatter.Sign(ctx, s.signer, job.UserOperation.Signature, hash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign user operation hash: %w", err)
+	}
+
+	userOpHash, err := bundlerClient.SendUserOperation(ctx, &userOp, entryPointAddress)
+	if err != nil {
+		s.logger(ctx).Error().Err(err).
+			Str("user_op_hash", sent.UserOpHash.Hex()).
+			Interface("user_op", userOp).
+			Msg("failed to send replacement user operation")
+		return nil, fmt.Errorf("failed to send replacement user operation: %w", err)
+	}
+
+	s.logger(ctx).Info().
+		Str("replaced_user_op_hash", sent.UserOpHash.Hex()).
+		Str("user_op_hash", userOpHash.Hex()).
+		Msg("user operation replaced")
+
+	return &SentUserOperation{
+		UserOpHash:    userOpHash,
+		EntryPoint:    entryPointAddress,
+		UserOperation: userOp,
+	}, nil
 }
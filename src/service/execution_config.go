@@ -0,0 +1,63 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ethaccount/backend/src/domain"
+)
+
+// Kinds of errors reading the execution config of a job, matched with errors.Is
+var (
+	// ErrUnsupportedChain is returned for jobs on a chain missing from the chain registry
+	ErrUnsupportedChain = errors.New("unsupported chain")
+	// ErrInvalidJobType is returned for jobs whose type is not a scheduling job type
+	ErrInvalidJobType = errors.New("invalid job type")
+	// ErrUnsupportedJobType is returned for jobs whose type has no scheduling module on their chain
+	ErrUnsupportedJobType = errors.New("unsupported job type")
+	// ErrUnknownModuleVersion is returned for jobs registered against a module deployment missing from their chain
+	ErrUnknownModuleVersion = errors.New("unknown module version")
+	// ErrChainUnavailable is returned for every job of a chain whose node could not be reached
+	ErrChainUnavailable = errors.New("chain unavailable")
+	// ErrExecutionLogCallFailed is returned when the executionLog call of a job failed or reverted
+	ErrExecutionLogCallFailed = errors.New("executionLog call failed")
+	// ErrInvalidExecutionLog is returned when the executionLog result of a job could not be decoded
+	ErrInvalidExecutionLog = errors.New("invalid executionLog result")
+)
+
+// ExecutionConfigError is the error reading the execution config of a job
+type ExecutionConfigError struct {
+	// Kind is one of the Err* kinds above
+	Kind    error
+	ChainID int64
+	Err     error
+}
+
+func (e *ExecutionConfigError) Error() string {
+	// Errors of the chain registry already describe their kind and chain
+	if errors.Is(e.Err, e.Kind) {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("%s on chain %d: %s", e.Kind, e.ChainID, e.Err)
+}
+
+func (e *ExecutionConfigError) Unwrap() []error {
+	return []error{e.Kind, e.Err}
+}
+
+// Permanent reports whether the error comes from the job itself, so that reading its config can never succeed.
+// Chains and module deployments missing from the chain registry are fixed by configuration, and node outages
+// and failing calls may succeed on a later attempt, so these errors are not permanent.
+func (e *ExecutionConfigError) Permanent() bool {
+	return e.Kind == ErrInvalidJobType
+}
+
+// ExecutionConfigResult is the execution config of a job, or the error reading it
+type ExecutionConfigResult struct {
+	Config *domain.ExecutionConfig
+	Err    *ExecutionConfigError
+}
+
+func newExecutionConfigError(kind error, chainId int64, err error) *ExecutionConfigError {
+	return &ExecutionConfigError{Kind: kind, ChainID: chainId, Err: err}
+}
@@ -0,0 +1,330 @@
+package service
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/ethaccount/backend/erc4337"
+	"github.com/ethaccount/backend/erc4337/bundlertest"
+	"github.com/ethaccount/backend/src/domain"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testChainID int64 = 11155111
+
+// fakeEthAPI serves the eth_* methods ExecuteJob needs from the node
+type fakeEthAPI struct {
+	nonce *big.Int
+}
+
+func (api *fakeEthAPI) Call(args map[string]interface{}, block string) (string, error) {
+	return fmt.Sprintf("0x%064x", api.nonce), nil
+}
+
+func (api *fakeEthAPI) GetBlockByNumber(number string, full bool) (*Block, error) {
+	return &Block{BaseFeePerGas: "0x3b9aca00"}, nil
+}
+
+// fakeRundlerAPI serves rundler_maxPriorityFeePerGas
+type fakeRundlerAPI struct{}
+
+func (api *fakeRundlerAPI) MaxPriorityFeePerGas() (string, error) {
+	return "0x5f5e100", nil
+}
+
+// newOfflineExecutionService wires an ExecutionService to an in-process node and an in-memory bundler
+func newOfflineExecutionService(t *testing.T, nonce *big.Int) (*ExecutionService, *bundlertest.Bundler, string) {
+	server := rpc.NewServer()
+	require.NoError(t, server.RegisterName("eth", &fakeEthAPI{nonce: nonce}))
+	require.NoError(t, server.RegisterName("rundler", &fakeRundlerAPI{}))
+	t.Cleanup(server.Stop)
+
+	blockchainService := NewBlockchainService(BlockchainConfig{})
+	blockchainService.SetClient(testChainID, ethclient.NewClient(rpc.DialInProc(server)))
+
+	bundler := bundlertest.New(testChainID)
+	blockchainService.SetBundlerClient(testChainID, bundler)
+	t.Cleanup(blockchainService.Close)
+
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+
+	executionService := NewExecutionService(blockchainService, NewLocalSignerFromKey(key))
+
+	return executionService, bundler, crypto.PubkeyToAddress(key.PublicKey).Hex()
+}
+
+func newOfflineJob(t *testing.T, entryPoint common.Address) domain.EntityJob {
+	leadingSignature := "0011223344"
+	dummySignature := "fffffffffffffffffffffffffffffff0000000000000000000000000000000007aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa1c"
+	signature, err := hex.DecodeString(leadingSignature + dummySignature)
+	require.NoError(t, err)
+
+	// Nonce key 0x01 followed by a 64-bit sequence
+	nonce, ok := new(big.Int).SetString("10000000000000000", 16)
+	require.True(t, ok)
+
+	return domain.EntityJob{
+		ID:             uuid.New(),
+		AccountAddress: common.HexToAddress("0x47d6a8a65cba9b61b194dac740aa192a7a1e91e1"),
+		ChainID:        testChainID,
+		OnChainJobID:   1,
+		UserOperation: erc4337.UserOperation{
+			Sender:    common.HexToAddress("0x47d6a8a65cba9b61b194dac740aa192a7a1e91e1"),
+			Nonce:     (*hexutil.Big)(nonce),
+			CallData:  hexutil.Bytes{0x12, 0x34},
+			Signature: signature,
+		},
+		EntryPointAddress: entryPoint,
+		JobType:           domain.DBJobTypeTransfer,
+		Status:            domain.DBJobStatusQueuing,
+	}
+}
+
+func TestExecuteJob_Offline(t *testing.T) {
+	for _, entryPoint := range []common.Address{erc4337.EntryPointV06, erc4337.EntryPointV07, erc4337.EntryPointV08} {
+		t.Run(entryPoint.Hex(), func(t *testing.T) {
+			currentNonce, _ := new(big.Int).SetString("10000000000000005", 16)
+			executionService, bundler, signer := newOfflineExecutionService(t, currentNonce)
+			ctx := context.Background()
+
+			result, err := executionService.ExecuteJob(ctx, newOfflineJob(t, entryPoint))
+			require.NoError(t, err)
+			require.NotNil(t, result)
+			userOpHash := &result.UserOpHash
+
+			sent := bundler.Sent()
+			require.Len(t, sent, 1)
+			assert.Equal(t, *userOpHash, sent[0].UserOpHash)
+			assert.Equal(t, entryPoint, sent[0].EntryPoint)
+			assert.Equal(t, entryPoint, result.EntryPoint)
+			assert.Equal(t, sent[0].UserOperation, result.UserOperation)
+
+			op := sent[0].UserOperation
+			assert.Equal(t, currentNonce, op.Nonce.ToInt())
+			assert.Equal(t, bundlertest.DefaultGasEstimates.CallGasLimit, op.CallGasLimit)
+			assert.Equal(t, big.NewInt(100000000), op.MaxPriorityFeePerGas.ToInt())
+			// (1 gwei * 150 / 100) + 0.1 gwei
+			assert.Equal(t, big.NewInt(1600000000), op.MaxFeePerGas.ToInt())
+
+			// Leading signature is preserved and the dummy signature is replaced by the signer's signature
+			require.Len(t, op.Signature, 5+65)
+			assert.Equal(t, "0011223344", hex.EncodeToString(op.Signature[:5]))
+			signature := make([]byte, 65)
+			copy(signature, op.Signature[5:])
+			signature[64] -= 27
+			pub, err := crypto.SigToPub(personalSignHash(userOpHash.Bytes()).Bytes(), signature)
+			require.NoError(t, err)
+			assert.Equal(t, signer, crypto.PubkeyToAddress(*pub).Hex())
+
+			receipt, err := bundler.IncludeSuccess(*userOpHash)
+			require.NoError(t, err)
+			assert.True(t, receipt.Success)
+
+			fetched, err := bundler.GetUserOperationReceipt(ctx, *userOpHash)
+			require.NoError(t, err)
+			assert.Equal(t, receipt, fetched)
+		})
+	}
+}
+
+func TestExecuteJob_Offline_ConsecutiveNonces(t *testing.T) {
+	currentNonce, _ := new(big.Int).SetString("10000000000000005", 16)
+	executionService, bundler, _ := newOfflineExecutionService(t, currentNonce)
+
+	// The second job of the account is sent before the first one is included, so it takes the next sequence
+	for i := 0; i < 2; i++ {
+		_, err := executionService.ExecuteJob(context.Background(), newOfflineJob(t, erc4337.EntryPointV07))
+		require.NoError(t, err)
+	}
+
+	sent := bundler.Sent()
+	require.Len(t, sent, 2)
+	assert.Equal(t, currentNonce, sent[0].UserOperation.Nonce.ToInt())
+	assert.Equal(t, new(big.Int).Add(currentNonce, common.Big1), sent[1].UserOperation.Nonce.ToInt())
+}
+
+func TestExecuteJob_Offline_BundlerErrors(t *testing.T) {
+	nonce, _ := new(big.Int).SetString("10000000000000000", 16)
+
+	t.Run("unsupported entry point", func(t *testing.T) {
+		executionService, bundler, _ := newOfflineExecutionService(t, nonce)
+		bundler.SetSupportedEntryPoints(erc4337.EntryPointV07)
+
+		_, err := executionService.ExecuteJob(context.Background(), newOfflineJob(t, erc4337.EntryPointV08))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "is not supported by the bundler")
+		assert.Empty(t, bundler.Sent())
+		assert.ErrorIs(t, err, ErrInvalidJob)
+		assert.False(t, IsTransientExecutionError(err))
+	})
+
+	t.Run("send rejected", func(t *testing.T) {
+		executionService, bundler, _ := newOfflineExecutionService(t, nonce)
+		bundler.InjectError("eth_sendUserOperation", &bundlertest.RPCError{
+			Code:    -32500,
+			Message: "AA21 didn't pay prefund",
+		})
+
+		_, err := executionService.ExecuteJob(context.Background(), newOfflineJob(t, erc4337.EntryPointV07))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "bundler RPC error in eth_sendUserOperation: AA21 didn't pay prefund")
+		assert.Empty(t, bundler.Sent())
+
+		var aaErr *erc4337.AAError
+		require.True(t, errors.As(err, &aaErr))
+		assert.Equal(t, "AA21", aaErr.Code)
+		assert.True(t, erc4337.IsTransientBundlerError(err))
+	})
+}
+
+func TestIsTransientExecutionError(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		transient bool
+	}{
+		{"invalid job", fmt.Errorf("%w: invalid signature template", ErrInvalidJob), false},
+		{"nonce consumed", fmt.Errorf("%w: 0x1", ErrNonceConsumed), true},
+		{"transient AA error", erc4337.HandleRPCError(&bundlertest.RPCError{Code: -32500, Message: "AA21 didn't pay prefund"}, "eth_sendUserOperation"), true},
+		{"permanent AA error", erc4337.HandleRPCError(&bundlertest.RPCError{Code: -32500, Message: "AA24 signature error"}, "eth_sendUserOperation"), false},
+		{"invalid params", erc4337.HandleRPCError(&bundlertest.RPCError{Code: -32602, Message: "invalid user operation"}, "eth_estimateUserOperationGas"), false},
+		{"transient simulation error", &erc4337.SimulationError{Reason: "AA21 didn't pay prefund", AA: erc4337.ParseAAError("AA21")}, true},
+		{"permanent simulation error", &erc4337.SimulationError{Reason: "execution reverted"}, false},
+		{"transport error", erc4337.HandleRPCError(&net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}, "eth_sendUserOperation"), true},
+		{"timeout", fmt.Errorf("failed to get gas fees: %w", context.DeadlineExceeded), true},
+		{"rate limited", rpc.HTTPError{StatusCode: http.StatusTooManyRequests}, true},
+		{"bad gateway", fmt.Errorf("failed to get paymaster data: %w", rpc.HTTPError{StatusCode: http.StatusBadGateway}), true},
+		{"all bundler endpoints failed", fmt.Errorf("%w in eth_sendUserOperation: %w", erc4337.ErrAllEndpointsFailed,
+			erc4337.HandleRPCError(&bundlertest.RPCError{Code: -32601, Message: "method not found"}, "eth_sendUserOperation")), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.transient, IsTransientExecutionError(tt.err))
+		})
+	}
+}
+
+// fakePaymasterAPI is a local ERC-7677 paymaster service
+type fakePaymasterAPI struct {
+	paymaster common.Address
+	contexts  []map[string]interface{}
+}
+
+func (api *fakePaymasterAPI) GetPaymasterStubData(op json.RawMessage, entryPoint common.Address, chainId hexutil.Big, pmContext map[string]interface{}) *erc4337.PaymasterStubData {
+	api.contexts = append(api.contexts, pmContext)
+	return &erc4337.PaymasterStubData{
+		Paymaster:                     &api.paymaster,
+		PaymasterData:                 hexutil.Bytes{0x00},
+		PaymasterVerificationGasLimit: (*hexutil.Big)(big.NewInt(30000)),
+		PaymasterPostOpGasLimit:       (*hexutil.Big)(big.NewInt(20000)),
+	}
+}
+
+func (api *fakePaymasterAPI) GetPaymasterData(op json.RawMessage, entryPoint common.Address, chainId hexutil.Big, pmContext map[string]interface{}) *erc4337.PaymasterData {
+	api.contexts = append(api.contexts, pmContext)
+	return &erc4337.PaymasterData{
+		Paymaster:     &api.paymaster,
+		PaymasterData: hexutil.Bytes{0xaa, 0xbb},
+	}
+}
+
+func TestExecuteJob_Offline_Paymaster(t *testing.T) {
+	currentNonce, _ := new(big.Int).SetString("10000000000000005", 16)
+	executionService, bundler, _ := newOfflineExecutionService(t, currentNonce)
+
+	pmAPI := &fakePaymasterAPI{paymaster: common.HexToAddress("0xabcdefabcdefabcdefabcdefabcdefabcdefabcd")}
+	server := rpc.NewServer()
+	require.NoError(t, server.RegisterName("pm", pmAPI))
+	t.Cleanup(server.Stop)
+	pmContext := map[string]interface{}{"sponsorshipPolicyId": "sp_test"}
+	executionService.blockchainService.SetPaymasterClient(testChainID, erc4337.NewPaymasterClient(rpc.DialInProc(server)), pmContext)
+
+	// Job template sponsored by a paymaster whose signature has expired
+	job := newOfflineJob(t, erc4337.EntryPointV07)
+	stalePaymaster := common.HexToAddress("0x1111111111111111111111111111111111111111")
+	job.UserOperation.Paymaster = &stalePaymaster
+	job.UserOperation.PaymasterData = hexutil.Bytes{0x01}
+
+	result, err := executionService.ExecuteJob(context.Background(), job)
+	require.NoError(t, err)
+
+	sent := bundler.Sent()
+	require.Len(t, sent, 1)
+	assert.Equal(t, result.UserOpHash, sent[0].UserOpHash)
+
+	op := sent[0].UserOperation
+	require.NotNil(t, op.Paymaster)
+	assert.Equal(t, pmAPI.paymaster, *op.Paymaster)
+	assert.Equal(t, hexutil.Bytes{0xaa, 0xbb}, op.PaymasterData)
+	assert.Equal(t, big.NewInt(20000), op.PaymasterPostOpGasLimit.ToInt())
+	assert.Equal(t, []map[string]interface{}{pmContext, pmContext}, pmAPI.contexts)
+}
+
+func TestExecuteJob_Offline_NoPaymasterService(t *testing.T) {
+	currentNonce, _ := new(big.Int).SetString("10000000000000005", 16)
+	executionService, bundler, _ := newOfflineExecutionService(t, currentNonce)
+
+	// Without a paymaster service the stored paymaster data is sent as is
+	job := newOfflineJob(t, erc4337.EntryPointV07)
+	paymaster := common.HexToAddress("0x1111111111111111111111111111111111111111")
+	job.UserOperation.Paymaster = &paymaster
+	job.UserOperation.PaymasterData = hexutil.Bytes{0x01}
+
+	_, err := executionService.ExecuteJob(context.Background(), job)
+	require.NoError(t, err)
+
+	sent := bundler.Sent()
+	require.Len(t, sent, 1)
+	assert.Equal(t, paymaster, *sent[0].UserOperation.Paymaster)
+	assert.Equal(t, hexutil.Bytes{0x01}, sent[0].UserOperation.PaymasterData)
+}
+
+func TestReplaceUserOperation_Offline(t *testing.T) {
+	currentNonce, _ := new(big.Int).SetString("10000000000000005", 16)
+	executionService, bundler, _ := newOfflineExecutionService(t, currentNonce)
+	ctx := context.Background()
+
+	job := newOfflineJob(t, erc4337.EntryPointV07)
+	sent, err := executionService.ExecuteJob(ctx, job)
+	require.NoError(t, err)
+
+	// Sending the same user operation again is not a valid replacement
+	_, err = bundler.SendUserOperation(ctx, &sent.UserOperation, sent.EntryPoint)
+	require.ErrorContains(t, err, "replacement underpriced")
+
+	replacement, err := executionService.ReplaceUserOperation(ctx, job, sent)
+	require.NoError(t, err)
+	assert.NotEqual(t, sent.UserOpHash, replacement.UserOpHash)
+
+	// Same nonce and leading signature, both fees raised by 10% as the suggested fees did not change
+	op := replacement.UserOperation
+	assert.Equal(t, currentNonce, op.Nonce.ToInt())
+	assert.Equal(t, big.NewInt(110000000), op.MaxPriorityFeePerGas.ToInt())
+	assert.Equal(t, big.NewInt(1760000000), op.MaxFeePerGas.ToInt())
+	assert.Equal(t, "0011223344", hex.EncodeToString(op.Signature[:5]))
+	assert.NotEqual(t, sent.UserOperation.Signature, op.Signature)
+
+	// The bundler keeps only the replacement
+	pending, err := bundler.GetUserOperationByHash(ctx, sent.UserOpHash)
+	require.NoError(t, err)
+	assert.Nil(t, pending)
+	pending, err = bundler.GetUserOperationByHash(ctx, replacement.UserOpHash)
+	require.NoError(t, err)
+	require.NotNil(t, pending)
+	assert.True(t, pending.IsPending())
+}
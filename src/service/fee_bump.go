@@ -0,0 +1,80 @@
+package service
+
+import (
+	"fmt"
+	"math/big"
+	"time"
+)
+
+// Default fee bump configuration
+const (
+	DefaultFeeBumpAfter = 5 * time.Minute
+	// DefaultFeeBumpPercent is the minimum fee increase bundlers require to replace a user operation
+	// with the same sender and nonce (ERC-7562 and the reference bundler use 10%)
+	DefaultFeeBumpPercent         = 10
+	DefaultFeeBumpMaxReplacements = 3
+)
+
+// FeeBumpConfig configures the replacement of user operations that stay in the bundler mempool,
+// e.g. because the gas price rose after they were sent.
+// Zero values are replaced by the defaults above.
+type FeeBumpConfig struct {
+	// Disabled turns off fee bumping on the chain
+	Disabled bool
+	// After is how long a user operation may stay pending before it is replaced
+	After time.Duration
+	// Percent is the increase of both maxFeePerGas and maxPriorityFeePerGas over the replaced user operation
+	Percent int64
+	// MaxReplacements bounds how many times the user operation of a job is replaced
+	MaxReplacements int
+}
+
+// withDefaults returns a copy of the config with zero values replaced by defaults
+func (c FeeBumpConfig) withDefaults() FeeBumpConfig {
+	if c.After == 0 {
+		c.After = DefaultFeeBumpAfter
+	}
+	if c.Percent == 0 {
+		c.Percent = DefaultFeeBumpPercent
+	}
+	if c.MaxReplacements == 0 {
+		c.MaxReplacements = DefaultFeeBumpMaxReplacements
+	}
+	return c
+}
+
+// bumpGasPrice returns the fees of a user operation replacing one sent with the given fees. Both fees are raised by
+// at least the bump percent (rounded up), since bundlers reject replacements that raise only one of them, and follow
+// the suggested fees when those rose further. Fees above the gas price caps are an error rather than being capped,
+// because a capped replacement would be rejected by the bundler.
+func bumpGasPrice(sent *GasPrice, suggested *GasPrice, percent int64, gasPriceConfig GasPriceConfig) (*GasPrice, error) {
+	maxPriorityFeePerGas := bigMax(mulPercentCeil(sent.MaxPriorityFeePerGas, 100+percent), suggested.MaxPriorityFeePerGas)
+	maxFeePerGas := bigMax(mulPercentCeil(sent.MaxFeePerGas, 100+percent), suggested.MaxFeePerGas)
+	maxFeePerGas = bigMax(maxFeePerGas, maxPriorityFeePerGas)
+
+	if limit := gasPriceConfig.MaxFeePerGasCap; limit != nil && maxFeePerGas.Cmp(limit) > 0 {
+		return nil, fmt.Errorf("replacement maxFeePerGas %s exceeds the cap %s", maxFeePerGas, limit)
+	}
+	if limit := gasPriceConfig.MaxPriorityFeePerGasCap; limit != nil && maxPriorityFeePerGas.Cmp(limit) > 0 {
+		return nil, fmt.Errorf("replacement maxPriorityFeePerGas %s exceeds the cap %s", maxPriorityFeePerGas, limit)
+	}
+
+	return &GasPrice{
+		MaxFeePerGas:         maxFeePerGas,
+		MaxPriorityFeePerGas: maxPriorityFeePerGas,
+	}, nil
+}
+
+// mulPercentCeil is mulPercent rounded up
+func mulPercentCeil(v *big.Int, percent int64) *big.Int {
+	result := new(big.Int).Mul(v, big.NewInt(percent))
+	result.Add(result, big.NewInt(99))
+	return result.Div(result, big.NewInt(100))
+}
+
+func bigMax(a, b *big.Int) *big.Int {
+	if a.Cmp(b) >= 0 {
+		return new(big.Int).Set(a)
+	}
+	return new(big.Int).Set(b)
+}
@@ -0,0 +1,38 @@
+package service
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBumpGasPrice(t *testing.T) {
+	sent := &GasPrice{MaxFeePerGas: big.NewInt(1000), MaxPriorityFeePerGas: big.NewInt(101)}
+
+	// Both fees rise by at least the bump percent, rounded up
+	gasPrice, err := bumpGasPrice(sent, &GasPrice{MaxFeePerGas: big.NewInt(900), MaxPriorityFeePerGas: big.NewInt(50)}, 10, GasPriceConfig{})
+	require.NoError(t, err)
+	assert.Equal(t, big.NewInt(1100), gasPrice.MaxFeePerGas)
+	assert.Equal(t, big.NewInt(112), gasPrice.MaxPriorityFeePerGas)
+
+	// The suggested fees are used when the gas price rose further
+	gasPrice, err = bumpGasPrice(sent, &GasPrice{MaxFeePerGas: big.NewInt(2000), MaxPriorityFeePerGas: big.NewInt(300)}, 10, GasPriceConfig{})
+	require.NoError(t, err)
+	assert.Equal(t, big.NewInt(2000), gasPrice.MaxFeePerGas)
+	assert.Equal(t, big.NewInt(300), gasPrice.MaxPriorityFeePerGas)
+
+	// A replacement above the caps would be rejected by the bundler once capped
+	_, err = bumpGasPrice(sent, sent, 10, GasPriceConfig{MaxFeePerGasCap: big.NewInt(1050)})
+	assert.ErrorContains(t, err, "exceeds the cap")
+}
+
+func TestFeeBumpConfig_Defaults(t *testing.T) {
+	blockchainService := NewBlockchainService(BlockchainConfig{
+		FeeBump: map[int64]FeeBumpConfig{testChainID: {Percent: 25}},
+	})
+
+	assert.Equal(t, FeeBumpConfig{After: DefaultFeeBumpAfter, Percent: 25, MaxReplacements: DefaultFeeBumpMaxReplacements}, blockchainService.GetFeeBumpConfig(testChainID))
+	assert.Equal(t, FeeBumpConfig{After: DefaultFeeBumpAfter, Percent: DefaultFeeBumpPercent, MaxReplacements: DefaultFeeBumpMaxReplacements}, blockchainService.GetFeeBumpConfig(1))
+}
@@ -0,0 +1,343 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethaccount/backend/erc4337"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// GasPriceOracleType selects how gas fees are suggested for a chain
+type GasPriceOracleType string
+
+const (
+	// GasPriceOracleRundler uses the latest base fee and rundler_maxPriorityFeePerGas (Alchemy)
+	GasPriceOracleRundler GasPriceOracleType = "rundler"
+	// GasPriceOracleMaxPriorityFee uses the latest base fee and eth_maxPriorityFeePerGas
+	GasPriceOracleMaxPriorityFee GasPriceOracleType = "eth_maxPriorityFeePerGas"
+	// GasPriceOracleFeeHistory uses the pending base fee and a reward percentile of eth_feeHistory
+	GasPriceOracleFeeHistory GasPriceOracleType = "fee_history"
+	// GasPriceOraclePimlico uses pimlico_getUserOperationGasPrice
+	GasPriceOraclePimlico GasPriceOracleType = "pimlico"
+	// GasPriceOracleLegacy uses eth_gasPrice for chains without a base fee
+	GasPriceOracleLegacy GasPriceOracleType = "legacy"
+)
+
+// Default gas price configuration, matching the historical behavior of the scheduler
+const (
+	DefaultGasPriceOracle               = GasPriceOracleRundler
+	DefaultBaseFeeMultiplierPercent     = 150
+	DefaultPriorityFeeMultiplierPercent = 100
+	DefaultFeeHistoryBlocks             = 10
+	DefaultFeeHistoryPercentile         = 50
+	DefaultPimlicoSpeed                 = "standard"
+)
+
+// GasPriceConfig configures the gas price oracle of a chain.
+// Zero values are replaced by the defaults above.
+type GasPriceConfig struct {
+	Oracle GasPriceOracleType
+
+	// maxFeePerGas = baseFee * BaseFeeMultiplierPercent / 100 + maxPriorityFeePerGas
+	BaseFeeMultiplierPercent int64
+	// maxPriorityFeePerGas = suggested priority fee * PriorityFeeMultiplierPercent / 100
+	PriorityFeeMultiplierPercent int64
+
+	// Upper bounds applied after the multipliers (nil means no cap)
+	MaxFeePerGasCap         *big.Int
+	MaxPriorityFeePerGasCap *big.Int
+
+	// eth_feeHistory parameters
+	FeeHistoryBlocks     int
+	FeeHistoryPercentile float64
+
+	// pimlico_getUserOperationGasPrice tier: slow, standard or fast
+	PimlicoSpeed string
+}
+
+// withDefaults returns a copy of the config with zero values replaced by defaults
+func (c GasPriceConfig) withDefaults() GasPriceConfig {
+	if c.Oracle == "" {
+		c.Oracle = DefaultGasPriceOracle
+	}
+	if c.BaseFeeMultiplierPercent == 0 {
+		c.BaseFeeMultiplierPercent = DefaultBaseFeeMultiplierPercent
+	}
+	if c.PriorityFeeMultiplierPercent == 0 {
+		c.PriorityFeeMultiplierPercent = DefaultPriorityFeeMultiplierPercent
+	}
+	if c.FeeHistoryBlocks == 0 {
+		c.FeeHistoryBlocks = DefaultFeeHistoryBlocks
+	}
+	if c.FeeHistoryPercentile == 0 {
+		c.FeeHistoryPercentile = DefaultFeeHistoryPercentile
+	}
+	if c.PimlicoSpeed == "" {
+		c.PimlicoSpeed = DefaultPimlicoSpeed
+	}
+	return c
+}
+
+// GasPrice holds the EIP-1559 fee fields of a user operation
+type GasPrice struct {
+	MaxFeePerGas         *big.Int
+	MaxPriorityFeePerGas *big.Int
+}
+
+// GasPriceOracle suggests gas fees for user operations on a chain
+type GasPriceOracle interface {
+	SuggestGasPrice(ctx context.Context) (*GasPrice, error)
+}
+
+// NewGasPriceOracle creates the oracle selected by the config. Node methods (eth_*) are sent to nodeClient and
+// bundler methods (rundler_maxPriorityFeePerGas, pimlico_getUserOperationGasPrice) to bundlerClient,
+// which may be nil when the node provider also serves the bundler API. When the bundler does not implement
+// the method, the oracle falls back to eth_maxPriorityFeePerGas on the node.
+func NewGasPriceOracle(nodeClient *rpc.Client, bundlerClient erc4337.RPCCaller, config GasPriceConfig) (GasPriceOracle, error) {
+	config = config.withDefaults()
+	if bundlerClient == nil {
+		bundlerClient = nodeClient
+	}
+
+	switch config.Oracle {
+	case GasPriceOracleRundler:
+		return &priorityFeeOracle{client: nodeClient, feeClient: bundlerClient, config: config, method: "rundler_maxPriorityFeePerGas"}, nil
+	case GasPriceOracleMaxPriorityFee:
+		return &priorityFeeOracle{client: nodeClient, feeClient: nodeClient, config: config, method: "eth_maxPriorityFeePerGas"}, nil
+	case GasPriceOracleFeeHistory:
+		return &feeHistoryOracle{client: nodeClient, config: config}, nil
+	case GasPriceOraclePimlico:
+		switch config.PimlicoSpeed {
+		case "slow", "standard", "fast":
+		default:
+			return nil, fmt.Errorf("unsupported pimlico gas price speed: %s", config.PimlicoSpeed)
+		}
+		return &pimlicoOracle{client: bundlerClient, nodeClient: nodeClient, config: config}, nil
+	case GasPriceOracleLegacy:
+		return &legacyOracle{client: nodeClient, config: config}, nil
+	default:
+		return nil, fmt.Errorf("unsupported gas price oracle: %s", config.Oracle)
+	}
+}
+
+// applyMultipliers computes the fees from a base fee and a suggested priority fee, then applies the caps
+func (c GasPriceConfig) applyMultipliers(baseFee *big.Int, priorityFee *big.Int) *GasPrice {
+	maxPriorityFeePerGas := mulPercent(priorityFee, c.PriorityFeeMultiplierPercent)
+	maxFeePerGas := mulPercent(baseFee, c.BaseFeeMultiplierPercent)
+	maxFeePerGas.Add(maxFeePerGas, maxPriorityFeePerGas)
+
+	return c.applyCaps(&GasPrice{
+		MaxFeePerGas:         maxFeePerGas,
+		MaxPriorityFeePerGas: maxPriorityFeePerGas,
+	})
+}
+
+// applyCaps bounds the fees by the configured caps and keeps maxPriorityFeePerGas <= maxFeePerGas
+func (c GasPriceConfig) applyCaps(gasPrice *GasPrice) *GasPrice {
+	if c.MaxFeePerGasCap != nil && gasPrice.MaxFeePerGas.Cmp(c.MaxFeePerGasCap) > 0 {
+		gasPrice.MaxFeePerGas = new(big.Int).Set(c.MaxFeePerGasCap)
+	}
+	if c.MaxPriorityFeePerGasCap != nil && gasPrice.MaxPriorityFeePerGas.Cmp(c.MaxPriorityFeePerGasCap) > 0 {
+		gasPrice.MaxPriorityFeePerGas = new(big.Int).Set(c.MaxPriorityFeePerGasCap)
+	}
+	if gasPrice.MaxPriorityFeePerGas.Cmp(gasPrice.MaxFeePerGas) > 0 {
+		gasPrice.MaxPriorityFeePerGas = new(big.Int).Set(gasPrice.MaxFeePerGas)
+	}
+	return gasPrice
+}
+
+func mulPercent(v *big.Int, percent int64) *big.Int {
+	result := new(big.Int).Mul(v, big.NewInt(percent))
+	return result.Div(result, big.NewInt(100))
+}
+
+// Block represents a block header with baseFeePerGas
+type Block struct {
+	BaseFeePerGas string `json:"baseFeePerGas"`
+}
+
+// parseBaseFee parses the block's baseFeePerGas, returning nil for chains without a base fee
+func parseBaseFee(block *Block) (*big.Int, error) {
+	if block == nil {
+		return nil, fmt.Errorf("latest block not found")
+	}
+	if block.BaseFeePerGas == "" {
+		return nil, nil
+	}
+
+	baseFeePerGas := new(big.Int)
+	if err := baseFeePerGas.UnmarshalText([]byte(block.BaseFeePerGas)); err != nil {
+		return nil, fmt.Errorf("failed to parse baseFeePerGas: %w", err)
+	}
+	return baseFeePerGas, nil
+}
+
+// priorityFeeOracle combines the latest base fee with a priority fee RPC method
+// (rundler_maxPriorityFeePerGas or eth_maxPriorityFeePerGas)
+type priorityFeeOracle struct {
+	client *rpc.Client
+	// feeClient serves the priority fee method: the node for eth_maxPriorityFeePerGas, the bundler for rundler_*
+	feeClient erc4337.RPCCaller
+	config    GasPriceConfig
+	method    string
+}
+
+func (o *priorityFeeOracle) SuggestGasPrice(ctx context.Context) (*GasPrice, error) {
+	var blockResult *Block
+	var maxPriorityFeeResult hexutil.Big
+	var feeErr error
+
+	if o.feeClient == o.client {
+		batch := []rpc.BatchElem{
+			{
+				Method: "eth_getBlockByNumber",
+				Args:   []interface{}{"latest", false},
+				Result: &blockResult,
+			},
+			{
+				Method: o.method,
+				Args:   []interface{}{},
+				Result: &maxPriorityFeeResult,
+			},
+		}
+
+		if err := o.client.BatchCallContext(ctx, batch); err != nil {
+			return nil, fmt.Errorf("failed to make batch RPC calls: %w", err)
+		}
+
+		// Check for individual call errors
+		if batch[0].Error != nil {
+			return nil, fmt.Errorf("eth_getBlockByNumber failed: %w", batch[0].Error)
+		}
+		feeErr = batch[1].Error
+	} else {
+		// The node and the bundler are different endpoints, so the calls cannot be batched
+		if err := o.client.CallContext(ctx, &blockResult, "eth_getBlockByNumber", "latest", false); err != nil {
+			return nil, fmt.Errorf("eth_getBlockByNumber failed: %w", err)
+		}
+		feeErr = o.feeClient.CallContext(ctx, &maxPriorityFeeResult, o.method)
+	}
+
+	if feeErr != nil && erc4337.IsMethodNotFound(feeErr) && o.method != "eth_maxPriorityFeePerGas" {
+		// No bundler endpoint serves the bundler method, use the node's suggestion instead
+		feeErr = o.client.CallContext(ctx, &maxPriorityFeeResult, "eth_maxPriorityFeePerGas")
+	}
+	if feeErr != nil {
+		return nil, fmt.Errorf("%s failed: %w", o.method, feeErr)
+	}
+
+	baseFeePerGas, err := parseBaseFee(blockResult)
+	if err != nil {
+		return nil, err
+	}
+	if baseFeePerGas == nil {
+		// Chain without EIP-1559
+		return (&legacyOracle{client: o.client, config: o.config}).SuggestGasPrice(ctx)
+	}
+
+	return o.config.applyMultipliers(baseFeePerGas, maxPriorityFeeResult.ToInt()), nil
+}
+
+// feeHistory is the result of eth_feeHistory
+type feeHistory struct {
+	BaseFeePerGas []*hexutil.Big   `json:"baseFeePerGas"`
+	Reward        [][]*hexutil.Big `json:"reward"`
+}
+
+// feeHistoryOracle uses the base fee of the pending block and the average reward percentile of recent blocks
+type feeHistoryOracle struct {
+	client *rpc.Client
+	config GasPriceConfig
+}
+
+func (o *feeHistoryOracle) SuggestGasPrice(ctx context.Context) (*GasPrice, error) {
+	var result feeHistory
+	err := o.client.CallContext(ctx, &result, "eth_feeHistory",
+		hexutil.Uint(o.config.FeeHistoryBlocks), "latest", []float64{o.config.FeeHistoryPercentile})
+	if err != nil {
+		return nil, fmt.Errorf("eth_feeHistory failed: %w", err)
+	}
+
+	if len(result.BaseFeePerGas) == 0 {
+		return nil, fmt.Errorf("eth_feeHistory returned no base fees")
+	}
+
+	// The last base fee is the one of the next block
+	baseFeePerGas := result.BaseFeePerGas[len(result.BaseFeePerGas)-1]
+	if baseFeePerGas == nil || baseFeePerGas.ToInt().Sign() == 0 {
+		// Chain without EIP-1559
+		return (&legacyOracle{client: o.client, config: o.config}).SuggestGasPrice(ctx)
+	}
+
+	priorityFee := new(big.Int)
+	count := 0
+	for _, rewards := range result.Reward {
+		if len(rewards) == 0 || rewards[0] == nil {
+			continue
+		}
+		priorityFee.Add(priorityFee, rewards[0].ToInt())
+		count++
+	}
+	if count > 0 {
+		priorityFee.Div(priorityFee, big.NewInt(int64(count)))
+	}
+
+	return o.config.applyMultipliers(baseFeePerGas.ToInt(), priorityFee), nil
+}
+
+// pimlicoGasPrice is one tier of the pimlico_getUserOperationGasPrice result
+type pimlicoGasPrice struct {
+	MaxFeePerGas         *hexutil.Big `json:"maxFeePerGas"`
+	MaxPriorityFeePerGas *hexutil.Big `json:"maxPriorityFeePerGas"`
+}
+
+// pimlicoOracle uses the fees suggested by a Pimlico bundler, which already include the bundler's margin
+type pimlicoOracle struct {
+	client erc4337.RPCCaller
+	// nodeClient suggests the fees when no bundler endpoint implements pimlico_getUserOperationGasPrice
+	nodeClient *rpc.Client
+	config     GasPriceConfig
+}
+
+func (o *pimlicoOracle) SuggestGasPrice(ctx context.Context) (*GasPrice, error) {
+	var result map[string]pimlicoGasPrice
+	if err := o.client.CallContext(ctx, &result, "pimlico_getUserOperationGasPrice"); err != nil {
+		if erc4337.IsMethodNotFound(err) {
+			fallback := &priorityFeeOracle{client: o.nodeClient, feeClient: o.nodeClient, config: o.config, method: "eth_maxPriorityFeePerGas"}
+			return fallback.SuggestGasPrice(ctx)
+		}
+		return nil, fmt.Errorf("pimlico_getUserOperationGasPrice failed: %w", err)
+	}
+
+	tier, ok := result[o.config.PimlicoSpeed]
+	if !ok || tier.MaxFeePerGas == nil || tier.MaxPriorityFeePerGas == nil {
+		return nil, fmt.Errorf("pimlico_getUserOperationGasPrice returned no %s gas price", o.config.PimlicoSpeed)
+	}
+
+	return o.config.applyCaps(&GasPrice{
+		MaxFeePerGas:         new(big.Int).Set(tier.MaxFeePerGas.ToInt()),
+		MaxPriorityFeePerGas: new(big.Int).Set(tier.MaxPriorityFeePerGas.ToInt()),
+	}), nil
+}
+
+// legacyOracle uses eth_gasPrice for both fee fields, for chains without a base fee
+type legacyOracle struct {
+	client *rpc.Client
+	config GasPriceConfig
+}
+
+func (o *legacyOracle) SuggestGasPrice(ctx context.Context) (*GasPrice, error) {
+	var result hexutil.Big
+	if err := o.client.CallContext(ctx, &result, "eth_gasPrice"); err != nil {
+		return nil, fmt.Errorf("eth_gasPrice failed: %w", err)
+	}
+
+	gasPrice := mulPercent(result.ToInt(), o.config.PriorityFeeMultiplierPercent)
+	return o.config.applyCaps(&GasPrice{
+		MaxFeePerGas:         gasPrice,
+		MaxPriorityFeePerGas: new(big.Int).Set(gasPrice),
+	}), nil
+}
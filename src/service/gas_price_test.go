@@ -0,0 +1,259 @@
+package service
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethaccount/backend/erc4337"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const gwei = 1_000_000_000
+
+func hexBig(v int64) *hexutil.Big {
+	return (*hexutil.Big)(big.NewInt(v))
+}
+
+// fakeGasEthAPI serves the eth_* gas price methods
+type fakeGasEthAPI struct {
+	baseFeePerGas string
+}
+
+func (api *fakeGasEthAPI) GetBlockByNumber(number string, full bool) (*Block, error) {
+	return &Block{BaseFeePerGas: api.baseFeePerGas}, nil
+}
+
+func (api *fakeGasEthAPI) MaxPriorityFeePerGas() *hexutil.Big {
+	return hexBig(gwei / 5)
+}
+
+func (api *fakeGasEthAPI) FeeHistory(blockCount hexutil.Uint, newestBlock string, percentiles []float64) *feeHistory {
+	return &feeHistory{
+		BaseFeePerGas: []*hexutil.Big{hexBig(gwei), hexBig(gwei * 11 / 10), hexBig(gwei * 12 / 10)},
+		Reward:        [][]*hexutil.Big{{hexBig(gwei / 10)}, {hexBig(gwei * 3 / 10)}},
+	}
+}
+
+func (api *fakeGasEthAPI) GasPrice() *hexutil.Big {
+	return hexBig(2 * gwei)
+}
+
+// fakeGasRundlerAPI serves rundler_maxPriorityFeePerGas
+type fakeGasRundlerAPI struct{}
+
+func (api *fakeGasRundlerAPI) MaxPriorityFeePerGas() *hexutil.Big {
+	return hexBig(gwei / 10)
+}
+
+// fakeGasPimlicoAPI serves pimlico_getUserOperationGasPrice
+type fakeGasPimlicoAPI struct{}
+
+func (api *fakeGasPimlicoAPI) GetUserOperationGasPrice() map[string]pimlicoGasPrice {
+	return map[string]pimlicoGasPrice{
+		"slow":     {MaxFeePerGas: hexBig(2 * gwei), MaxPriorityFeePerGas: hexBig(gwei / 10)},
+		"standard": {MaxFeePerGas: hexBig(3 * gwei), MaxPriorityFeePerGas: hexBig(gwei / 2)},
+		"fast":     {MaxFeePerGas: hexBig(4 * gwei), MaxPriorityFeePerGas: hexBig(gwei)},
+	}
+}
+
+func newGasPriceTestClient(t *testing.T, baseFeePerGas string) *rpc.Client {
+	server := rpc.NewServer()
+	require.NoError(t, server.RegisterName("eth", &fakeGasEthAPI{baseFeePerGas: baseFeePerGas}))
+	require.NoError(t, server.RegisterName("rundler", &fakeGasRundlerAPI{}))
+	require.NoError(t, server.RegisterName("pimlico", &fakeGasPimlicoAPI{}))
+	t.Cleanup(server.Stop)
+
+	client := rpc.DialInProc(server)
+	t.Cleanup(client.Close)
+	return client
+}
+
+func TestGasPriceOracle(t *testing.T) {
+	tests := []struct {
+		name                 string
+		baseFeePerGas        string
+		config               GasPriceConfig
+		maxFeePerGas         int64
+		maxPriorityFeePerGas int64
+	}{
+		{
+			name:          "default rundler",
+			baseFeePerGas: "0x3b9aca00", // 1 gwei
+			config:        GasPriceConfig{},
+			// 1 gwei * 150% + 0.1 gwei
+			maxFeePerGas:         gwei*15/10 + gwei/10,
+			maxPriorityFeePerGas: gwei / 10,
+		},
+		{
+			name:          "eth_maxPriorityFeePerGas with multipliers",
+			baseFeePerGas: "0x3b9aca00",
+			config: GasPriceConfig{
+				Oracle:                       GasPriceOracleMaxPriorityFee,
+				BaseFeeMultiplierPercent:     200,
+				PriorityFeeMultiplierPercent: 120,
+			},
+			// 1 gwei * 200% + 0.2 gwei * 120%
+			maxFeePerGas:         2*gwei + gwei*24/100,
+			maxPriorityFeePerGas: gwei * 24 / 100,
+		},
+		{
+			name:          "fee history",
+			baseFeePerGas: "0x3b9aca00",
+			config:        GasPriceConfig{Oracle: GasPriceOracleFeeHistory},
+			// next base fee 1.2 gwei * 150% + average reward 0.2 gwei
+			maxFeePerGas:         gwei*18/10 + gwei/5,
+			maxPriorityFeePerGas: gwei / 5,
+		},
+		{
+			name:                 "pimlico",
+			baseFeePerGas:        "0x3b9aca00",
+			config:               GasPriceConfig{Oracle: GasPriceOraclePimlico},
+			maxFeePerGas:         3 * gwei,
+			maxPriorityFeePerGas: gwei / 2,
+		},
+		{
+			name:                 "pimlico fast",
+			baseFeePerGas:        "0x3b9aca00",
+			config:               GasPriceConfig{Oracle: GasPriceOraclePimlico, PimlicoSpeed: "fast"},
+			maxFeePerGas:         4 * gwei,
+			maxPriorityFeePerGas: gwei,
+		},
+		{
+			name:                 "legacy",
+			baseFeePerGas:        "",
+			config:               GasPriceConfig{Oracle: GasPriceOracleLegacy},
+			maxFeePerGas:         2 * gwei,
+			maxPriorityFeePerGas: 2 * gwei,
+		},
+		{
+			name:                 "falls back to eth_gasPrice without base fee",
+			baseFeePerGas:        "",
+			config:               GasPriceConfig{Oracle: GasPriceOracleRundler},
+			maxFeePerGas:         2 * gwei,
+			maxPriorityFeePerGas: 2 * gwei,
+		},
+		{
+			name:          "caps",
+			baseFeePerGas: "0x3b9aca00",
+			config: GasPriceConfig{
+				MaxFeePerGasCap:         big.NewInt(gwei * 15 / 10),
+				MaxPriorityFeePerGasCap: big.NewInt(gwei / 20),
+			},
+			maxFeePerGas:         gwei * 15 / 10,
+			maxPriorityFeePerGas: gwei / 20,
+		},
+		{
+			name:                 "priority fee never exceeds max fee",
+			baseFeePerGas:        "",
+			config:               GasPriceConfig{Oracle: GasPriceOracleLegacy, MaxFeePerGasCap: big.NewInt(gwei)},
+			maxFeePerGas:         gwei,
+			maxPriorityFeePerGas: gwei,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oracle, err := NewGasPriceOracle(newGasPriceTestClient(t, tt.baseFeePerGas), nil, tt.config)
+			require.NoError(t, err)
+
+			gasPrice, err := oracle.SuggestGasPrice(context.Background())
+			require.NoError(t, err)
+			assert.Equal(t, big.NewInt(tt.maxFeePerGas).String(), gasPrice.MaxFeePerGas.String())
+			assert.Equal(t, big.NewInt(tt.maxPriorityFeePerGas).String(), gasPrice.MaxPriorityFeePerGas.String())
+		})
+	}
+}
+
+func TestGasPriceOracle_SeparateBundler(t *testing.T) {
+	// The node has no bundler namespaces and the bundler no eth namespace
+	nodeServer := rpc.NewServer()
+	require.NoError(t, nodeServer.RegisterName("eth", &fakeGasEthAPI{baseFeePerGas: "0x3b9aca00"}))
+	t.Cleanup(nodeServer.Stop)
+	nodeClient := rpc.DialInProc(nodeServer)
+	t.Cleanup(nodeClient.Close)
+
+	bundlerServer := rpc.NewServer()
+	require.NoError(t, bundlerServer.RegisterName("rundler", &fakeGasRundlerAPI{}))
+	require.NoError(t, bundlerServer.RegisterName("pimlico", &fakeGasPimlicoAPI{}))
+	t.Cleanup(bundlerServer.Stop)
+	bundlerClient := rpc.DialInProc(bundlerServer)
+	t.Cleanup(bundlerClient.Close)
+
+	for _, oracleType := range []GasPriceOracleType{GasPriceOracleRundler, GasPriceOraclePimlico, GasPriceOracleFeeHistory, GasPriceOracleMaxPriorityFee} {
+		t.Run(string(oracleType), func(t *testing.T) {
+			oracle, err := NewGasPriceOracle(nodeClient, bundlerClient, GasPriceConfig{Oracle: oracleType})
+			require.NoError(t, err)
+
+			_, err = oracle.SuggestGasPrice(context.Background())
+			require.NoError(t, err)
+		})
+	}
+
+	// The rundler priority fee comes from the bundler, the base fee from the node
+	oracle, err := NewGasPriceOracle(nodeClient, bundlerClient, GasPriceConfig{})
+	require.NoError(t, err)
+	gasPrice, err := oracle.SuggestGasPrice(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, big.NewInt(gwei*15/10+gwei/10).String(), gasPrice.MaxFeePerGas.String())
+	assert.Equal(t, big.NewInt(gwei/10).String(), gasPrice.MaxPriorityFeePerGas.String())
+}
+
+// newGasPriceTestBundler creates a bundler client serving only the given bundler namespaces
+func newGasPriceTestBundler(t *testing.T, namespaces map[string]interface{}) erc4337.BundlerEndpoint {
+	server := rpc.NewServer()
+	for name, api := range namespaces {
+		require.NoError(t, server.RegisterName(name, api))
+	}
+	t.Cleanup(server.Stop)
+	return erc4337.BundlerEndpoint{Bundler: erc4337.NewBundlerClient(rpc.DialInProc(server))}
+}
+
+func TestGasPriceOracle_BundlerPool(t *testing.T) {
+	nodeServer := rpc.NewServer()
+	require.NoError(t, nodeServer.RegisterName("eth", &fakeGasEthAPI{baseFeePerGas: "0x3b9aca00"}))
+	t.Cleanup(nodeServer.Stop)
+	nodeClient := rpc.DialInProc(nodeServer)
+	t.Cleanup(nodeClient.Close)
+
+	newPool := func(endpoints ...erc4337.BundlerEndpoint) *erc4337.BundlerPool {
+		pool, err := erc4337.NewBundlerPool(nil, endpoints, erc4337.BundlerPoolConfig{HealthCheckInterval: -1})
+		require.NoError(t, err)
+		t.Cleanup(pool.Close)
+		return pool
+	}
+	suggest := func(bundler erc4337.RPCCaller, config GasPriceConfig) *GasPrice {
+		oracle, err := NewGasPriceOracle(nodeClient, bundler, config)
+		require.NoError(t, err)
+		gasPrice, err := oracle.SuggestGasPrice(context.Background())
+		require.NoError(t, err)
+		return gasPrice
+	}
+
+	// Each bundler method is served by the endpoint implementing it
+	pool := newPool(
+		newGasPriceTestBundler(t, map[string]interface{}{"pimlico": &fakeGasPimlicoAPI{}}),
+		newGasPriceTestBundler(t, map[string]interface{}{"rundler": &fakeGasRundlerAPI{}}),
+	)
+	assert.Equal(t, big.NewInt(gwei/10).String(), suggest(pool, GasPriceConfig{}).MaxPriorityFeePerGas.String())
+	assert.Equal(t, big.NewInt(gwei/2).String(), suggest(pool, GasPriceConfig{Oracle: GasPriceOraclePimlico}).MaxPriorityFeePerGas.String())
+
+	// Without a bundler implementing the method, the node's eth_maxPriorityFeePerGas is used
+	pool = newPool(newGasPriceTestBundler(t, nil))
+	for _, oracleType := range []GasPriceOracleType{GasPriceOracleRundler, GasPriceOraclePimlico} {
+		gasPrice := suggest(pool, GasPriceConfig{Oracle: oracleType})
+		assert.Equal(t, big.NewInt(gwei*15/10+gwei/5).String(), gasPrice.MaxFeePerGas.String(), oracleType)
+		assert.Equal(t, big.NewInt(gwei/5).String(), gasPrice.MaxPriorityFeePerGas.String(), oracleType)
+	}
+}
+
+func TestNewGasPriceOracle_InvalidConfig(t *testing.T) {
+	_, err := NewGasPriceOracle(nil, nil, GasPriceConfig{Oracle: "unknown"})
+	assert.Error(t, err)
+
+	_, err = NewGasPriceOracle(nil, nil, GasPriceConfig{Oracle: GasPriceOraclePimlico, PimlicoSpeed: "instant"})
+	assert.Error(t, err)
+}
@@ -27,16 +27,18 @@ func (s *JobService) logger(ctx context.Context) *zerolog.Logger {
 }
 
 // RegisterJob creates a new job registration
-func (s *JobService) RegisterJob(ctx context.Context, accountAddress common.Address, chainId int64, jobID int64, jobType domain.DBJobType, userOperation *erc4337.UserOperation, entryPoint common.Address) (*domain.EntityJob, error) {
+func (s *JobService) RegisterJob(ctx context.Context, accountAddress common.Address, chainId int64, jobID int64, jobType domain.DBJobType, signatureFormat string, moduleVersion string, userOperation *erc4337.UserOperation, entryPoint common.Address) (*domain.EntityJob, error) {
 	s.logger(ctx).Info().
 		Str("function", "RegisterJob").
 		Str("accountAddress", accountAddress.Hex()).
 		Int64("chainId", chainId).
 		Int64("onChainJobId", jobID).
 		Str("jobType", string(jobType)).
+		Str("signatureFormat", signatureFormat).
+		Str("moduleVersion", moduleVersion).
 		Msg("Registering new job")
 
-	job, err := s.jobRepo.CreateJob(accountAddress, chainId, jobID, jobType, userOperation, entryPoint)
+	job, err := s.jobRepo.CreateJob(accountAddress, chainId, jobID, jobType, signatureFormat, moduleVersion, userOperation, entryPoint)
 	if err != nil {
 		return nil, err
 	}
@@ -110,3 +112,20 @@ func (s *JobService) UpdateJobStatus(ctx context.Context, id string, status doma
 		Msg("successfully updated job status")
 	return nil
 }
+
+// UpdateJobErrMsg records the error message of a job and its kind without changing its status; a nil errMsg clears them
+func (s *JobService) UpdateJobErrMsg(ctx context.Context, id string, errKind domain.DBJobErrKind, errMsg *string) error {
+	err := s.jobRepo.UpdateJobErrMsg(id, errKind, errMsg)
+	if err != nil {
+		s.logger(ctx).Error().Err(err).
+			Str("job_id", id).
+			Msg("failed to update job error message in repository")
+		return err
+	}
+
+	s.logger(ctx).Debug().
+		Str("job_id", id).
+		Bool("cleared", errMsg == nil).
+		Msg("successfully updated job error message")
+	return nil
+}
@@ -0,0 +1,198 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// DefaultMulticallChunkSize is the number of calls batched into one aggregate3 call or JSON-RPC batch
+const DefaultMulticallChunkSize = 500
+
+// Multicall3Address is the address of Multicall3, deployed with the same address on most chains
+var Multicall3Address = common.HexToAddress("0xcA11bde05977b3631167028862bE2a173976CA11")
+
+// aggregate3((address target, bool allowFailure, bytes callData)[]) of Multicall3
+const multicall3ABI = `[{"inputs":[{"components":[{"name":"target","type":"address"},{"name":"allowFailure","type":"bool"},{"name":"callData","type":"bytes"}],"name":"calls","type":"tuple[]"}],"name":"aggregate3","outputs":[{"components":[{"name":"success","type":"bool"},{"name":"returnData","type":"bytes"}],"name":"returnData","type":"tuple[]"}],"stateMutability":"payable","type":"function"}]`
+
+var aggregate3Method = func() abi.Method {
+	parsedABI, err := abi.JSON(strings.NewReader(multicall3ABI))
+	if err != nil {
+		panic(fmt.Sprintf("invalid Multicall3 ABI: %v", err))
+	}
+	return parsedABI.Methods["aggregate3"]
+}()
+
+// multicall3Call is a call of aggregate3
+type multicall3Call struct {
+	Target       common.Address
+	AllowFailure bool
+	CallData     []byte
+}
+
+// multicall3Result is the result of a call of aggregate3
+type multicall3Result struct {
+	Success    bool
+	ReturnData []byte
+}
+
+// contractCall is a read-only call of a contract
+type contractCall struct {
+	To   common.Address
+	Data []byte
+}
+
+// contractCallResult is the return data of a contractCall, or the error of the call when it failed
+type contractCallResult struct {
+	Data []byte
+	Err  error
+}
+
+// callContracts makes read-only calls on a chain in chunks of the configured size. Each chunk is one Multicall3
+// aggregate3 call, or one JSON-RPC batch of eth_call on chains without Multicall3. A failing call does not fail
+// the others: its error is set in its result. The returned error is for chunks that could not be sent at all.
+func (b *BlockchainService) callContracts(ctx context.Context, chainId int64, calls []contractCall) ([]contractCallResult, error) {
+	client, err := b.GetClient(chainId)
+	if err != nil {
+		return nil, err
+	}
+
+	useMulticall, err := b.hasMulticall3(ctx, chainId)
+	if err != nil {
+		return nil, err
+	}
+
+	chunkSize := b.multicallChunkSize
+	if chunkSize <= 0 {
+		chunkSize = DefaultMulticallChunkSize
+	}
+
+	results := make([]contractCallResult, 0, len(calls))
+	for start := 0; start < len(calls); start += chunkSize {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		chunk := calls[start:min(start+chunkSize, len(calls))]
+		var chunkResults []contractCallResult
+		if useMulticall {
+			chunkResults, err = aggregate3(ctx, client, chunk)
+		} else {
+			chunkResults, err = batchCallContracts(ctx, client.Client(), chunk)
+		}
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, chunkResults...)
+	}
+
+	return results, nil
+}
+
+// hasMulticall3 reports whether Multicall3 is deployed on a chain, caching the answer
+func (b *BlockchainService) hasMulticall3(ctx context.Context, chainId int64) (bool, error) {
+	b.mu.RLock()
+	deployed, exists := b.multicall3Deployed[chainId]
+	b.mu.RUnlock()
+	if exists {
+		return deployed, nil
+	}
+
+	client, err := b.GetClient(chainId)
+	if err != nil {
+		return false, err
+	}
+	code, err := client.CodeAt(ctx, Multicall3Address, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to get Multicall3 code on chain %d: %w", chainId, err)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.multicall3Deployed == nil {
+		b.multicall3Deployed = make(map[int64]bool)
+	}
+	b.multicall3Deployed[chainId] = len(code) > 0
+	return len(code) > 0, nil
+}
+
+// aggregate3 makes the calls in one Multicall3 aggregate3 call that allows each of them to fail
+func aggregate3(ctx context.Context, caller ethereum.ContractCaller, calls []contractCall) ([]contractCallResult, error) {
+	multicallCalls := make([]multicall3Call, len(calls))
+	for i, call := range calls {
+		multicallCalls[i] = multicall3Call{Target: call.To, AllowFailure: true, CallData: call.Data}
+	}
+
+	args, err := aggregate3Method.Inputs.Pack(multicallCalls)
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack aggregate3: %w", err)
+	}
+
+	multicall := Multicall3Address
+	output, err := caller.CallContract(ctx, ethereum.CallMsg{
+		To:   &multicall,
+		Data: append(bytes.Clone(aggregate3Method.ID), args...),
+	}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call aggregate3: %w", err)
+	}
+
+	unpacked, err := aggregate3Method.Outputs.Unpack(output)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unpack aggregate3 result: %w", err)
+	}
+	var multicallResults []multicall3Result
+	if err := aggregate3Method.Outputs.Copy(&multicallResults, unpacked); err != nil {
+		return nil, fmt.Errorf("failed to unpack aggregate3 result: %w", err)
+	}
+	if len(multicallResults) != len(calls) {
+		return nil, fmt.Errorf("aggregate3 returned %d results for %d calls", len(multicallResults), len(calls))
+	}
+
+	results := make([]contractCallResult, len(calls))
+	for i, result := range multicallResults {
+		if !result.Success {
+			results[i].Err = fmt.Errorf("call to %s reverted: %s", calls[i].To.Hex(), schedulingRevertDecoder.Decode(result.ReturnData))
+			continue
+		}
+		results[i].Data = result.ReturnData
+	}
+	return results, nil
+}
+
+// batchCallContracts makes the calls as one JSON-RPC batch of eth_call
+func batchCallContracts(ctx context.Context, client *rpc.Client, calls []contractCall) ([]contractCallResult, error) {
+	outputs := make([]hexutil.Bytes, len(calls))
+	batch := make([]rpc.BatchElem, len(calls))
+	for i, call := range calls {
+		batch[i] = rpc.BatchElem{
+			Method: "eth_call",
+			Args: []interface{}{
+				map[string]interface{}{"to": call.To, "input": hexutil.Bytes(call.Data)},
+				"latest",
+			},
+			Result: &outputs[i],
+		}
+	}
+
+	if err := client.BatchCallContext(ctx, batch); err != nil {
+		return nil, fmt.Errorf("failed to make batch eth_call: %w", err)
+	}
+
+	results := make([]contractCallResult, len(calls))
+	for i := range batch {
+		if batch[i].Error != nil {
+			results[i].Err = fmt.Errorf("call to %s failed: %w", calls[i].To.Hex(), batch[i].Error)
+			continue
+		}
+		results[i].Data = outputs[i]
+	}
+	return results, nil
+}
@@ -0,0 +1,303 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethaccount/backend/erc4337"
+	"github.com/ethaccount/backend/src/domain"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testExecutionLogMethod = func() abi.Method {
+	parsedABI, _ := abi.JSON(strings.NewReader(`[{"inputs":[{"type":"address"},{"type":"uint256"}],"name":"executionLog","outputs":[{"type":"uint48"},{"type":"uint16"},{"type":"uint16"},{"type":"uint48"},{"type":"bool"},{"type":"uint48"},{"type":"bytes"}],"stateMutability":"view","type":"function"}]`))
+	return parsedABI.Methods["executionLog"]
+}()
+
+// fakeMulticallEthAPI answers executionLog calls, directly or through Multicall3 aggregate3.
+// The executionLog of a job reports the on-chain job ID as its number of completed executions.
+type fakeMulticallEthAPI struct {
+	multicallDeployed bool
+	// failJobID makes the executionLog call of that job revert
+	failJobID int64
+
+	// calledModules are the modules executionLog was called on, directly or through aggregate3
+	calledModules []common.Address
+
+	aggregate3Calls int
+	directCalls     int
+}
+
+func (api *fakeMulticallEthAPI) GetCode(address common.Address, block string) hexutil.Bytes {
+	if address == Multicall3Address && api.multicallDeployed {
+		return hexutil.Bytes{0x60, 0x80}
+	}
+	return hexutil.Bytes{}
+}
+
+func (api *fakeMulticallEthAPI) Call(args map[string]interface{}, block string) (hexutil.Bytes, error) {
+	data, err := hexutil.Decode(args["input"].(string))
+	if err != nil {
+		return nil, err
+	}
+
+	to := common.HexToAddress(args["to"].(string))
+	if to != Multicall3Address {
+		api.directCalls++
+		api.calledModules = append(api.calledModules, to)
+		return api.executionLog(data)
+	}
+
+	api.aggregate3Calls++
+	unpacked, err := aggregate3Method.Inputs.Unpack(data[4:])
+	if err != nil {
+		return nil, err
+	}
+	var calls []multicall3Call
+	if err := aggregate3Method.Inputs.Copy(&calls, unpacked); err != nil {
+		return nil, err
+	}
+
+	results := make([]multicall3Result, len(calls))
+	for i, call := range calls {
+		api.calledModules = append(api.calledModules, call.Target)
+		output, err := api.executionLog(call.CallData)
+		results[i] = multicall3Result{Success: err == nil, ReturnData: output}
+	}
+	return aggregate3Method.Outputs.Pack(results)
+}
+
+func (api *fakeMulticallEthAPI) executionLog(data []byte) (hexutil.Bytes, error) {
+	args, err := testExecutionLogMethod.Inputs.Unpack(data[4:])
+	if err != nil {
+		return nil, err
+	}
+	jobID := args[1].(*big.Int)
+	if jobID.Int64() == api.failJobID {
+		return nil, errors.New("execution reverted")
+	}
+	return testExecutionLogMethod.Outputs.Pack(big.NewInt(60), uint16(100), uint16(jobID.Uint64()), big.NewInt(1), true, big.NewInt(0), []byte{0xab})
+}
+
+func newMulticallTestService(t *testing.T, api *fakeMulticallEthAPI, chunkSize int) *BlockchainService {
+	server := rpc.NewServer()
+	require.NoError(t, server.RegisterName("eth", api))
+	t.Cleanup(server.Stop)
+
+	blockchainService := NewBlockchainService(BlockchainConfig{MulticallChunkSize: chunkSize})
+	blockchainService.SetClient(testChainID, ethclient.NewClient(rpc.DialInProc(server)))
+	t.Cleanup(blockchainService.Close)
+	return blockchainService
+}
+
+func newMulticallTestJobs(count int) []*domain.EntityJob {
+	jobs := make([]*domain.EntityJob, count)
+	for i := range jobs {
+		jobs[i] = &domain.EntityJob{
+			ID:             uuid.New(),
+			AccountAddress: common.HexToAddress("0x47d6a8a65cba9b61b194dac740aa192a7a1e91e1"),
+			ChainID:        testChainID,
+			OnChainJobID:   int64(i + 1),
+			JobType:        domain.DBJobTypeTransfer,
+		}
+	}
+	return jobs
+}
+
+func TestGetExecutionConfigsBatch_Multicall(t *testing.T) {
+	api := &fakeMulticallEthAPI{multicallDeployed: true}
+	blockchainService := newMulticallTestService(t, api, 2)
+	jobs := newMulticallTestJobs(5)
+
+	configs, err := blockchainService.GetExecutionConfigsBatch(context.Background(), jobs)
+	require.NoError(t, err)
+
+	// Five jobs in chunks of two
+	assert.Equal(t, 3, api.aggregate3Calls)
+	assert.Zero(t, api.directCalls)
+	require.Len(t, configs, len(jobs))
+	for _, job := range jobs {
+		result := configs[job.ID.String()]
+		require.Nil(t, result.Err)
+		config := result.Config
+		require.NotNil(t, config)
+		assert.Equal(t, uint16(job.OnChainJobID), config.NumberOfExecutionsCompleted)
+		assert.Equal(t, []byte{0xab}, config.ExecutionData)
+	}
+}
+
+func TestGetExecutionConfigsBatch_JSONRPCBatchFallback(t *testing.T) {
+	api := &fakeMulticallEthAPI{}
+	blockchainService := newMulticallTestService(t, api, 0)
+	jobs := newMulticallTestJobs(3)
+
+	configs, err := blockchainService.GetExecutionConfigsBatch(context.Background(), jobs)
+	require.NoError(t, err)
+
+	assert.Zero(t, api.aggregate3Calls)
+	assert.Equal(t, 3, api.directCalls)
+	require.Len(t, configs, len(jobs))
+	assert.Equal(t, uint16(3), configs[jobs[2].ID.String()].Config.NumberOfExecutionsCompleted)
+}
+
+func TestGetExecutionConfigsBatch_PerJobErrors(t *testing.T) {
+	api := &fakeMulticallEthAPI{multicallDeployed: true, failJobID: 2}
+	blockchainService := newMulticallTestService(t, api, 0)
+	jobs := newMulticallTestJobs(3)
+
+	unsupportedChain := newMulticallTestJobs(1)[0]
+	unsupportedChain.ChainID = 1
+	invalidJobType := newMulticallTestJobs(1)[0]
+	invalidJobType.JobType = "unknown"
+	unknownModuleVersion := newMulticallTestJobs(1)[0]
+	unknownModuleVersion.ModuleVersion = "v9"
+	jobs = append(jobs, unsupportedChain, invalidJobType, unknownModuleVersion)
+
+	configs, err := blockchainService.GetExecutionConfigsBatch(context.Background(), jobs)
+	require.NoError(t, err)
+	require.Len(t, configs, len(jobs))
+
+	// The reverting job does not fail the others of its chain
+	assert.NotNil(t, configs[jobs[0].ID.String()].Config)
+	assert.NotNil(t, configs[jobs[2].ID.String()].Config)
+
+	reverted := configs[jobs[1].ID.String()]
+	assert.Nil(t, reverted.Config)
+	require.NotNil(t, reverted.Err)
+	assert.ErrorIs(t, reverted.Err, ErrExecutionLogCallFailed)
+	assert.False(t, reverted.Err.Permanent())
+
+	chainErr := configs[unsupportedChain.ID.String()].Err
+	require.NotNil(t, chainErr)
+	assert.ErrorIs(t, chainErr, ErrUnsupportedChain)
+	assert.Equal(t, int64(1), chainErr.ChainID)
+	assert.False(t, chainErr.Permanent())
+
+	// Only the job type comes from the job itself and fails it
+	jobTypeErr := configs[invalidJobType.ID.String()].Err
+	require.NotNil(t, jobTypeErr)
+	assert.ErrorIs(t, jobTypeErr, ErrInvalidJobType)
+	assert.True(t, jobTypeErr.Permanent())
+
+	versionErr := configs[unknownModuleVersion.ID.String()].Err
+	require.NotNil(t, versionErr)
+	assert.ErrorIs(t, versionErr, ErrUnknownModuleVersion)
+	assert.False(t, versionErr.Permanent())
+}
+
+func TestGetExecutionConfigsBatch_ModuleVersions(t *testing.T) {
+	v2Address := common.HexToAddress("0x2222222222222222222222222222222222222222")
+	chain := ChainConfig{
+		ChainID:     testChainID,
+		RPCURLs:     []string{"https://node.example"},
+		EntryPoints: []common.Address{erc4337.EntryPointV07},
+		SchedulerModules: map[domain.DBJobType]ModuleDeployments{
+			domain.DBJobTypeTransfer: {
+				Current:   "v2",
+				Addresses: map[string]common.Address{DefaultModuleVersion: scheduledTransfersAddress, "v2": v2Address},
+			},
+		},
+	}
+	registry, err := NewChainRegistry([]ChainConfig{chain})
+	require.NoError(t, err)
+
+	server := rpc.NewServer()
+	api := &fakeMulticallEthAPI{multicallDeployed: true}
+	require.NoError(t, server.RegisterName("eth", api))
+	t.Cleanup(server.Stop)
+	blockchainService := NewBlockchainService(BlockchainConfig{Chains: registry})
+	blockchainService.SetClient(testChainID, ethclient.NewClient(rpc.DialInProc(server)))
+	t.Cleanup(blockchainService.Close)
+
+	// Jobs registered before versions existed have no module version and read the default deployment
+	jobs := newMulticallTestJobs(3)
+	jobs[0].ModuleVersion = DefaultModuleVersion
+	jobs[2].ModuleVersion = "v2"
+
+	configs, err := blockchainService.GetExecutionConfigsBatch(context.Background(), jobs)
+	require.NoError(t, err)
+	for _, job := range jobs {
+		require.Nil(t, configs[job.ID.String()].Err)
+	}
+
+	// One aggregate3 call per deployment
+	assert.Equal(t, 2, api.aggregate3Calls)
+	assert.ElementsMatch(t, []common.Address{scheduledTransfersAddress, scheduledTransfersAddress, v2Address}, api.calledModules)
+
+	// The chain has no module for swaps yet, which the chain registry can fix
+	swap := newMulticallTestJobs(1)[0]
+	swap.JobType = domain.DBJobTypeSwap
+	configs, err = blockchainService.GetExecutionConfigsBatch(context.Background(), []*domain.EntityJob{swap})
+	require.NoError(t, err)
+	swapErr := configs[swap.ID.String()].Err
+	require.NotNil(t, swapErr)
+	assert.ErrorIs(t, swapErr, ErrUnsupportedJobType)
+	assert.False(t, swapErr.Permanent())
+}
+
+func TestGetExecutionConfigsBatch_ChainUnavailable(t *testing.T) {
+	blockchainService := NewBlockchainService(BlockchainConfig{})
+	t.Cleanup(blockchainService.Close)
+
+	// A client whose node is gone
+	server := rpc.NewServer()
+	client := rpc.DialInProc(server)
+	client.Close()
+	blockchainService.SetClient(testChainID, ethclient.NewClient(client))
+
+	jobs := newMulticallTestJobs(2)
+	configs, err := blockchainService.GetExecutionConfigsBatch(context.Background(), jobs)
+	require.NoError(t, err)
+
+	for _, job := range jobs {
+		result := configs[job.ID.String()]
+		require.NotNil(t, result.Err)
+		assert.ErrorIs(t, result.Err, ErrChainUnavailable)
+		assert.False(t, result.Err.Permanent())
+	}
+}
+
+func TestCallContracts_PerCallFailure(t *testing.T) {
+	for _, deployed := range []bool{true, false} {
+		api := &fakeMulticallEthAPI{multicallDeployed: deployed, failJobID: 2}
+		blockchainService := newMulticallTestService(t, api, 0)
+
+		var calls []contractCall
+		for _, job := range newMulticallTestJobs(3) {
+			data, err := testExecutionLogMethod.Inputs.Pack(job.AccountAddress, big.NewInt(job.OnChainJobID))
+			require.NoError(t, err)
+			calls = append(calls, contractCall{To: scheduledTransfersAddress, Data: append(bytes.Clone(testExecutionLogMethod.ID), data...)})
+		}
+
+		results, err := blockchainService.callContracts(context.Background(), testChainID, calls)
+		require.NoError(t, err)
+		require.Len(t, results, 3)
+		assert.NoError(t, results[0].Err)
+		assert.Error(t, results[1].Err)
+		assert.NoError(t, results[2].Err)
+		assert.NotEmpty(t, results[2].Data)
+	}
+}
+
+func TestCallContracts_ContextCanceled(t *testing.T) {
+	api := &fakeMulticallEthAPI{multicallDeployed: true}
+	blockchainService := newMulticallTestService(t, api, 0)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := blockchainService.GetExecutionConfigsBatch(ctx, newMulticallTestJobs(1))
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Zero(t, api.aggregate3Calls)
+}
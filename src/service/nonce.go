@@ -0,0 +1,252 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethaccount/backend/erc4337"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	// DefaultNonceCacheTTL is how long a nonce read from the EntryPoint is reused before it is read again
+	DefaultNonceCacheTTL = 12 * time.Second
+	// DefaultNonceReservationTTL is how long the nonce of a sent user operation stays reserved while the
+	// EntryPoint has not consumed it, after which the user operation is assumed to be dropped. Nonces of user
+	// operations reported dropped by the bundler are released earlier with ReleaseSent.
+	DefaultNonceReservationTTL = 10 * time.Minute
+)
+
+// ErrNonceConsumed is returned when the reserved nonce was used on-chain by another user operation
+var ErrNonceConsumed = errors.New("nonce already consumed by another user operation")
+
+// NonceReader reads the next nonce of an account key from the EntryPoint; BlockchainService implements it
+type NonceReader interface {
+	GetNonce(ctx context.Context, chainId int64, entryPoint common.Address, sender common.Address, key *big.Int) (erc4337.Nonce, error)
+}
+
+// NonceManagerConfig configures a NonceManager. Zero values use the defaults above.
+type NonceManagerConfig struct {
+	CacheTTL       time.Duration
+	ReservationTTL time.Duration
+}
+
+// nonceSlotID identifies the nonce sequence of an account key on an EntryPoint
+type nonceSlotID struct {
+	chainId    int64
+	entryPoint common.Address
+	sender     common.Address
+	key        string
+}
+
+// nonceSlot tracks the EntryPoint nonce of a sequence and the sequence numbers reserved by in-flight user operations
+type nonceSlot struct {
+	onChain   uint64
+	fetchedAt time.Time
+	reserved  map[uint64]*NonceReservation
+}
+
+// NonceManager hands out the nonces of user operations. Nonces are tracked per (sender, key), so that
+// several user operations of one account can be in flight on different keys, and consecutive user operations
+// on the same key get consecutive sequence numbers before the previous ones are included.
+type NonceManager struct {
+	reader NonceReader
+	config NonceManagerConfig
+
+	slots map[nonceSlotID]*nonceSlot
+	mu    sync.Mutex
+}
+
+// NewNonceManager creates a nonce manager reading nonces with the given reader
+func NewNonceManager(reader NonceReader, config NonceManagerConfig) *NonceManager {
+	if config.CacheTTL == 0 {
+		config.CacheTTL = DefaultNonceCacheTTL
+	}
+	if config.ReservationTTL == 0 {
+		config.ReservationTTL = DefaultNonceReservationTTL
+	}
+	return &NonceManager{
+		reader: reader,
+		config: config,
+		slots:  make(map[nonceSlotID]*nonceSlot),
+	}
+}
+
+func newNonceSlotID(chainId int64, entryPoint common.Address, sender common.Address, key *big.Int) nonceSlotID {
+	if key == nil {
+		key = new(big.Int)
+	}
+	return nonceSlotID{chainId: chainId, entryPoint: entryPoint, sender: sender, key: key.Text(16)}
+}
+
+// Reserve reserves the lowest sequence of the key that is neither used on-chain nor reserved by another user operation.
+// The reservation must be released if the user operation is not sent, or marked as sent otherwise.
+func (m *NonceManager) Reserve(ctx context.Context, chainId int64, entryPoint common.Address, sender common.Address, key *big.Int) (*NonceReservation, error) {
+	if key == nil {
+		key = new(big.Int)
+	}
+	id := newNonceSlotID(chainId, entryPoint, sender, key)
+	if err := m.refresh(ctx, id, key, false); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	slot := m.slots[id]
+	sequence := slot.onChain
+	for slot.reserved[sequence] != nil {
+		sequence++
+	}
+
+	reservation := &NonceReservation{
+		manager: m,
+		id:      id,
+		nonce:   erc4337.Nonce{Key: new(big.Int).Set(key), Sequence: sequence},
+	}
+	slot.reserved[sequence] = reservation
+	return reservation, nil
+}
+
+// refresh reads the nonce of the slot from the EntryPoint unless the cached one is fresh enough
+func (m *NonceManager) refresh(ctx context.Context, id nonceSlotID, key *big.Int, force bool) error {
+	m.mu.Lock()
+	slot, exists := m.slots[id]
+	if !exists {
+		slot = &nonceSlot{reserved: make(map[uint64]*NonceReservation)}
+		m.slots[id] = slot
+	}
+	fresh := !slot.fetchedAt.IsZero() && time.Since(slot.fetchedAt) < m.config.CacheTTL
+	m.mu.Unlock()
+
+	if fresh && !force {
+		return nil
+	}
+
+	nonce, err := m.reader.GetNonce(ctx, id.chainId, id.entryPoint, id.sender, key)
+	if err != nil {
+		return fmt.Errorf("failed to get nonce: %w", err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Nonces only go up, so a slower concurrent read must not move the slot back
+	slot.onChain = max(slot.onChain, nonce.Sequence)
+	slot.fetchedAt = time.Now()
+
+	for sequence, reservation := range slot.reserved {
+		switch {
+		case sequence < slot.onChain:
+			// Included if this backend sent it, otherwise another user operation took the nonce
+			reservation.consumed = !reservation.sent
+			delete(slot.reserved, sequence)
+		case reservation.sent && time.Since(reservation.sentAt) > m.config.ReservationTTL:
+			// Sent long ago and still not included, so the user operation was dropped
+			delete(slot.reserved, sequence)
+		}
+	}
+	return nil
+}
+
+// ReleaseSent frees the nonce of a sent user operation that the bundler dropped, identified by its full nonce,
+// so that the next user operation on the key reuses it instead of getting a sequence the bundler rejects with AA25.
+// The nonce is read from the EntryPoint again on the next reservation, in case it was dropped for a used nonce.
+func (m *NonceManager) ReleaseSent(chainId int64, entryPoint common.Address, sender common.Address, nonce *big.Int) error {
+	parsed, err := erc4337.ParseNonce(nonce)
+	if err != nil {
+		return fmt.Errorf("failed to parse nonce: %w", err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	slot, exists := m.slots[newNonceSlotID(chainId, entryPoint, sender, parsed.Key)]
+	if !exists {
+		return nil
+	}
+	if reservation := slot.reserved[parsed.Sequence]; reservation != nil && reservation.sent {
+		reservation.released = true
+		delete(slot.reserved, parsed.Sequence)
+	}
+	slot.fetchedAt = time.Time{}
+	return nil
+}
+
+// NonceReservation is a nonce handed out to a user operation that is being executed
+type NonceReservation struct {
+	manager *NonceManager
+	id      nonceSlotID
+	nonce   erc4337.Nonce
+
+	// Guarded by the manager mutex
+	sent     bool
+	sentAt   time.Time
+	consumed bool
+	released bool
+}
+
+// Nonce returns the reserved nonce
+func (r *NonceReservation) Nonce() erc4337.Nonce {
+	return r.nonce
+}
+
+// Check reads the nonce from the EntryPoint again and returns ErrNonceConsumed if another user operation used the
+// reserved nonce in the meantime. Call it right before sending the user operation.
+func (r *NonceReservation) Check(ctx context.Context) error {
+	if err := r.manager.refresh(ctx, r.id, r.nonce.Key, true); err != nil {
+		return err
+	}
+
+	r.manager.mu.Lock()
+	defer r.manager.mu.Unlock()
+	if r.consumed {
+		return fmt.Errorf("%w: %s", ErrNonceConsumed, r.nonce)
+	}
+	return nil
+}
+
+// MarkSent keeps the nonce reserved for the sent user operation until the EntryPoint consumes it
+func (r *NonceReservation) MarkSent() {
+	r.manager.mu.Lock()
+	defer r.manager.mu.Unlock()
+	r.sent = true
+	r.sentAt = time.Now()
+}
+
+// Invalidate releases a nonce rejected by the bundler (AA25) and reads the nonce of the key from the EntryPoint again.
+// The nonces of the other sent user operations on the key stay reserved until the EntryPoint consumes them
+// or their reservation expires, since the bundler may still include them.
+func (r *NonceReservation) Invalidate(ctx context.Context) error {
+	r.Release()
+
+	if err := r.manager.refresh(ctx, r.id, r.nonce.Key, true); err != nil {
+		// Read the nonce again on the next reservation rather than reusing the stale one
+		r.manager.mu.Lock()
+		if slot, exists := r.manager.slots[r.id]; exists {
+			slot.fetchedAt = time.Time{}
+		}
+		r.manager.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// Release frees the nonce of a user operation that was not sent, so that the next user operation on the key reuses it.
+// It does nothing once the reservation is marked as sent, so it can be deferred.
+func (r *NonceReservation) Release() {
+	r.manager.mu.Lock()
+	defer r.manager.mu.Unlock()
+	if r.sent || r.released {
+		return
+	}
+	r.released = true
+
+	if slot, exists := r.manager.slots[r.id]; exists && slot.reserved[r.nonce.Sequence] == r {
+		delete(slot.reserved, r.nonce.Sequence)
+	}
+}
@@ -0,0 +1,117 @@
+package service
+
+import (
+	"context"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethaccount/backend/erc4337"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeNonceReader returns the EntryPoint nonce of each key and counts the reads
+type fakeNonceReader struct {
+	mu        sync.Mutex
+	sequences map[string]uint64
+	reads     int
+}
+
+func (r *fakeNonceReader) GetNonce(ctx context.Context, chainId int64, entryPoint common.Address, sender common.Address, key *big.Int) (erc4337.Nonce, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.reads++
+	return erc4337.Nonce{Key: key, Sequence: r.sequences[key.String()]}, nil
+}
+
+func (r *fakeNonceReader) set(key int64, sequence uint64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sequences[big.NewInt(key).String()] = sequence
+}
+
+var nonceTestSender = common.HexToAddress("0x47d6a8a65cba9b61b194dac740aa192a7a1e91e1")
+
+func reserveNonce(t *testing.T, m *NonceManager, key int64) *NonceReservation {
+	reservation, err := m.Reserve(context.Background(), testChainID, erc4337.EntryPointV07, nonceTestSender, big.NewInt(key))
+	require.NoError(t, err)
+	return reservation
+}
+
+func TestNonceManager_Reserve(t *testing.T) {
+	reader := &fakeNonceReader{sequences: map[string]uint64{"1": 3, "2": 7}}
+	m := NewNonceManager(reader, NonceManagerConfig{})
+
+	// Consecutive user operations on one key get consecutive sequences
+	first := reserveNonce(t, m, 1)
+	second := reserveNonce(t, m, 1)
+	assert.Equal(t, uint64(3), first.Nonce().Sequence)
+	assert.Equal(t, uint64(4), second.Nonce().Sequence)
+	assert.Equal(t, big.NewInt(1), second.Nonce().Key)
+
+	// Other keys of the account have their own sequence
+	other := reserveNonce(t, m, 2)
+	assert.Equal(t, uint64(7), other.Nonce().Sequence)
+
+	// The cached nonce is reused within the cache TTL
+	assert.Equal(t, 2, reader.reads)
+
+	// A released nonce is handed out again, a sent one is not
+	first.Release()
+	second.MarkSent()
+	second.Release()
+	assert.Equal(t, uint64(3), reserveNonce(t, m, 1).Nonce().Sequence)
+	assert.Equal(t, uint64(5), reserveNonce(t, m, 1).Nonce().Sequence)
+}
+
+func TestNonceManager_Consumed(t *testing.T) {
+	reader := &fakeNonceReader{sequences: map[string]uint64{"1": 3}}
+	m := NewNonceManager(reader, NonceManagerConfig{})
+
+	sent := reserveNonce(t, m, 1)
+	sent.MarkSent()
+	pending := reserveNonce(t, m, 1)
+	require.Equal(t, uint64(4), pending.Nonce().Sequence)
+	require.NoError(t, pending.Check(context.Background()))
+
+	// Both nonces are used on-chain: the sent one by its own user operation, the pending one by another
+	reader.set(1, 5)
+	err := pending.Check(context.Background())
+	assert.ErrorIs(t, err, ErrNonceConsumed)
+	assert.NoError(t, sent.Check(context.Background()))
+
+	assert.Equal(t, uint64(5), reserveNonce(t, m, 1).Nonce().Sequence)
+}
+
+func TestNonceManager_DroppedAndInvalidated(t *testing.T) {
+	reader := &fakeNonceReader{sequences: map[string]uint64{"1": 3}}
+	m := NewNonceManager(reader, NonceManagerConfig{CacheTTL: time.Nanosecond, ReservationTTL: time.Millisecond})
+
+	// A sent user operation that is never included releases its nonce after the reservation TTL
+	reserveNonce(t, m, 1).MarkSent()
+	time.Sleep(5 * time.Millisecond)
+	assert.Equal(t, uint64(3), reserveNonce(t, m, 1).Nonce().Sequence)
+
+	// A nonce rejected by the bundler is released and the nonce read again,
+	// while the other sent user operations of the key keep theirs
+	m = NewNonceManager(reader, NonceManagerConfig{})
+	reserveNonce(t, m, 1).MarkSent()
+	rejected := reserveNonce(t, m, 1)
+	require.Equal(t, uint64(4), rejected.Nonce().Sequence)
+	reads := reader.reads
+	require.NoError(t, rejected.Invalidate(context.Background()))
+	assert.Equal(t, reads+1, reader.reads)
+	assert.Equal(t, uint64(4), reserveNonce(t, m, 1).Nonce().Sequence)
+
+	// A user operation reported dropped by the bundler releases its nonce right away and the nonce is read again
+	m = NewNonceManager(reader, NonceManagerConfig{})
+	dropped := reserveNonce(t, m, 1)
+	dropped.MarkSent()
+	reads = reader.reads
+	require.NoError(t, m.ReleaseSent(testChainID, erc4337.EntryPointV07, nonceTestSender, dropped.Nonce().BigInt()))
+	assert.Equal(t, uint64(3), reserveNonce(t, m, 1).Nonce().Sequence)
+	assert.Equal(t, reads+1, reader.reads)
+}
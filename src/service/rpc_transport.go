@@ -0,0 +1,467 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultRPCMaxRetries       = 2
+	DefaultRPCRetryBaseDelay   = 250 * time.Millisecond
+	DefaultRPCRetryMaxDelay    = 5 * time.Second
+	DefaultRPCBreakerThreshold = 5
+	DefaultRPCBreakerCooldown  = 30 * time.Second
+)
+
+// errRPCCircuitOpen is returned for endpoints skipped because their circuit breaker is open
+var errRPCCircuitOpen = errors.New("circuit breaker open")
+
+// RPCTransportConfig configures the HTTP transport of the node RPC clients. Zero values use the defaults above.
+type RPCTransportConfig struct {
+	// RateLimit is the maximum number of requests per second sent to each endpoint; zero disables rate limiting
+	RateLimit float64
+	// RateBurst is the number of requests an endpoint may receive at once; zero uses the rate limit rounded up
+	RateBurst int
+	// MaxRetries is the number of times a request is retried once every endpoint failed; a negative value disables retries
+	MaxRetries int
+	// Retries wait an exponential delay from RetryBaseDelay up to RetryMaxDelay, with random jitter
+	RetryBaseDelay time.Duration
+	RetryMaxDelay  time.Duration
+	// BreakerThreshold is the number of consecutive failures after which an endpoint is skipped
+	BreakerThreshold int
+	// BreakerCooldown is how long an endpoint is skipped before a single request probes it again
+	BreakerCooldown time.Duration
+
+	// OnEndpointFailure is called when a request to an endpoint fails and the transport fails over
+	OnEndpointFailure func(ctx context.Context, endpoint string, err error)
+	// OnCircuitOpen is called when the circuit breaker of an endpoint opens
+	OnCircuitOpen func(ctx context.Context, endpoint string, cooldown time.Duration)
+}
+
+func (c RPCTransportConfig) withDefaults() RPCTransportConfig {
+	if c.RateLimit > 0 && c.RateBurst <= 0 {
+		c.RateBurst = max(1, int(c.RateLimit+0.999))
+	}
+	if c.MaxRetries == 0 {
+		c.MaxRetries = DefaultRPCMaxRetries
+	}
+	if c.RetryBaseDelay <= 0 {
+		c.RetryBaseDelay = DefaultRPCRetryBaseDelay
+	}
+	if c.RetryMaxDelay <= 0 {
+		c.RetryMaxDelay = DefaultRPCRetryMaxDelay
+	}
+	if c.BreakerThreshold <= 0 {
+		c.BreakerThreshold = DefaultRPCBreakerThreshold
+	}
+	if c.BreakerCooldown <= 0 {
+		c.BreakerCooldown = DefaultRPCBreakerCooldown
+	}
+	return c
+}
+
+// rpcTransport is an http.RoundTripper sending JSON-RPC requests to the node endpoints of a chain.
+// Requests go to the first endpoint whose circuit breaker is closed and fail over to the next ones
+// on transport errors, rate limiting and server errors, including those idempotent requests get as
+// JSON-RPC errors of a 200 response. When every endpoint failed, the request is
+// retried after a jittered exponential delay.
+//
+// The rpc.Client dialed with it is unaware of the endpoints: it posts to the first URL, and the
+// transport rewrites the URL of each attempt.
+type rpcTransport struct {
+	config    RPCTransportConfig
+	base      http.RoundTripper
+	endpoints []*rpcEndpoint
+}
+
+type rpcEndpoint struct {
+	name    string
+	url     *url.URL
+	limiter *tokenBucket // nil without a rate limit
+
+	mu                  sync.Mutex
+	consecutiveFailures int
+	openUntil           time.Time
+	// probing is set while the single request allowed through a half-open breaker is in flight
+	probing bool
+}
+
+// newRPCTransport creates a transport over the HTTP endpoints in failover order, sending through base
+func newRPCTransport(rawurls []string, config RPCTransportConfig, base http.RoundTripper) (*rpcTransport, error) {
+	if len(rawurls) == 0 {
+		return nil, errors.New("rpc transport requires at least one endpoint")
+	}
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	t := &rpcTransport{config: config.withDefaults(), base: base}
+	for _, rawurl := range rawurls {
+		parsed, err := url.Parse(rawurl)
+		if err != nil {
+			return nil, fmt.Errorf("invalid RPC URL %s: %w", rpcEndpointName(rawurl), err)
+		}
+		if parsed.Scheme != "http" && parsed.Scheme != "https" {
+			return nil, fmt.Errorf("RPC URL %s is not an HTTP URL", rpcEndpointName(rawurl))
+		}
+		endpoint := &rpcEndpoint{name: rpcEndpointName(rawurl), url: parsed}
+		if t.config.RateLimit > 0 {
+			endpoint.limiter = newTokenBucket(t.config.RateLimit, t.config.RateBurst)
+		}
+		t.endpoints = append(t.endpoints, endpoint)
+	}
+	return t, nil
+}
+
+// rpcEndpointName returns the host of an RPC URL, which unlike the path and query holds no API key
+func rpcEndpointName(rawurl string) string {
+	if parsed, err := url.Parse(rawurl); err == nil && parsed.Host != "" {
+		return parsed.Host
+	}
+	return "rpc"
+}
+
+// allHTTPURLs reports whether every URL is an HTTP URL, which the transport requires
+func allHTTPURLs(rawurls []string) bool {
+	for _, rawurl := range rawurls {
+		parsed, err := url.Parse(rawurl)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+			return false
+		}
+	}
+	return len(rawurls) > 0
+}
+
+func (t *rpcTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	idempotent := !hasNonIdempotentMethod(body)
+
+	for attempt := 0; ; attempt++ {
+		resp, retry, err := t.roundTrip(req, body, idempotent)
+		if !retry || attempt >= t.config.MaxRetries {
+			return resp, err
+		}
+
+		timer := time.NewTimer(t.retryDelay(attempt))
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// roundTrip sends the request to the endpoints in failover order until one answers.
+// It reports whether the request may be retried when none did.
+func (t *rpcTransport) roundTrip(req *http.Request, body []byte, idempotent bool) (*http.Response, bool, error) {
+	ctx := req.Context()
+	var errs []error
+	tried := false
+
+	for _, endpoint := range t.endpoints {
+		if !endpoint.allow(time.Now(), t.config.BreakerThreshold) {
+			errs = append(errs, fmt.Errorf("%s: %w", endpoint.name, errRPCCircuitOpen))
+			continue
+		}
+		if endpoint.limiter != nil {
+			if err := endpoint.limiter.wait(ctx); err != nil {
+				endpoint.release()
+				return nil, false, err
+			}
+		}
+
+		tried = true
+		resp, err := t.base.RoundTrip(endpoint.request(req, body))
+		if err != nil && ctx.Err() != nil {
+			// The caller gave up, which says nothing about the endpoint
+			endpoint.release()
+			return nil, false, err
+		}
+		var failure error
+		switch {
+		case err != nil:
+			failure = err
+		case isRetryableStatus(resp.StatusCode):
+			failure = fmt.Errorf("HTTP %s", resp.Status)
+		case idempotent && resp.StatusCode == http.StatusOK:
+			// Providers also report overload and lagging nodes as JSON-RPC errors of a 200 response
+			failure = inspectRPCResponse(resp)
+		}
+		if failure == nil {
+			endpoint.record(nil, t.config.BreakerThreshold, t.config.BreakerCooldown)
+			return resp, false, nil
+		}
+
+		t.recordFailure(ctx, endpoint, failure)
+		errs = append(errs, fmt.Errorf("%s: %w", endpoint.name, failure))
+
+		// A transaction may have reached the node unless it was refused before being read
+		if !idempotent && !isRejectedUnread(resp, err) {
+			return resp, false, err
+		}
+		if resp != nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+	}
+
+	return nil, tried, fmt.Errorf("all RPC endpoints failed: %w", errors.Join(errs...))
+}
+
+// retryDelay returns the delay before a retry: the exponential delay of the attempt, half of which is random
+func (t *rpcTransport) retryDelay(attempt int) time.Duration {
+	delay := t.config.RetryMaxDelay
+	if attempt < 30 {
+		delay = min(t.config.RetryBaseDelay<<attempt, t.config.RetryMaxDelay)
+	}
+	return delay/2 + rand.N(delay/2+1)
+}
+
+func (t *rpcTransport) recordFailure(ctx context.Context, endpoint *rpcEndpoint, err error) {
+	opened := endpoint.record(err, t.config.BreakerThreshold, t.config.BreakerCooldown)
+	if t.config.OnEndpointFailure != nil {
+		t.config.OnEndpointFailure(ctx, endpoint.name, err)
+	}
+	if opened && t.config.OnCircuitOpen != nil {
+		t.config.OnCircuitOpen(ctx, endpoint.name, t.config.BreakerCooldown)
+	}
+}
+
+// allow reports whether a request may be sent to the endpoint. Once the cooldown of an open breaker
+// has passed, a single request is let through to probe the endpoint.
+func (e *rpcEndpoint) allow(now time.Time, threshold int) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.consecutiveFailures < threshold {
+		return true
+	}
+	if now.Before(e.openUntil) || e.probing {
+		return false
+	}
+	e.probing = true
+	return true
+}
+
+// release ends a request allowed by allow without a result, such as a request canceled by the caller
+func (e *rpcEndpoint) release() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.probing = false
+}
+
+// record updates the breaker of the endpoint after a request and reports whether the failure opened it
+func (e *rpcEndpoint) record(err error, threshold int, cooldown time.Duration) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.probing = false
+	if err == nil {
+		e.consecutiveFailures = 0
+		return false
+	}
+	e.consecutiveFailures++
+	if e.consecutiveFailures < threshold {
+		return false
+	}
+	// A failed probe opens the breaker again
+	e.openUntil = time.Now().Add(cooldown)
+	return true
+}
+
+// request returns the request sent to the endpoint, with its URL and credentials
+func (e *rpcEndpoint) request(req *http.Request, body []byte) *http.Request {
+	out := req.Clone(req.Context())
+
+	endpointURL := *e.url
+	endpointURL.User = nil
+	out.URL = &endpointURL
+	out.Host = ""
+	if e.url.User != nil {
+		password, _ := e.url.User.Password()
+		out.SetBasicAuth(e.url.User.Username(), password)
+	}
+
+	out.Body = io.NopCloser(bytes.NewReader(body))
+	out.GetBody = func() (io.ReadCloser, error) { return io.NopCloser(bytes.NewReader(body)), nil }
+	out.ContentLength = int64(len(body))
+	return out
+}
+
+// isRetryableStatus reports whether an HTTP status is caused by the endpoint rather than the request
+func isRetryableStatus(status int) bool {
+	switch status {
+	case http.StatusRequestTimeout,
+		http.StatusTooManyRequests,
+		http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// inspectRPCResponse reads the body of a JSON-RPC response, which it puts back for the caller,
+// and returns an error when the response, or every response of the batch, holds a retryable error.
+// A batch with some answered requests is returned to the caller, which sees the errors of the others.
+func inspectRPCResponse(resp *http.Response) error {
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	type message struct {
+		Error *struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+
+	var msgs []message
+	if trimmed := bytes.TrimSpace(body); len(trimmed) > 0 && trimmed[0] == '[' {
+		if err := json.Unmarshal(trimmed, &msgs); err != nil {
+			return nil
+		}
+	} else {
+		var msg message
+		if err := json.Unmarshal(trimmed, &msg); err != nil {
+			return nil
+		}
+		msgs = append(msgs, msg)
+	}
+
+	if len(msgs) == 0 {
+		return nil
+	}
+	for _, msg := range msgs {
+		if msg.Error == nil || !isRetryableRPCError(msg.Error.Code, msg.Error.Message) {
+			return nil
+		}
+	}
+	return fmt.Errorf("JSON-RPC error %d: %s", msgs[0].Error.Code, msgs[0].Error.Message)
+}
+
+// isRetryableRPCError reports whether a JSON-RPC error is caused by the endpoint rather than the request:
+// an exceeded limit, an internal error or a node that has not synced the requested block yet
+func isRetryableRPCError(code int, message string) bool {
+	switch code {
+	case -32005, // limit exceeded
+		-32603: // internal error
+		return true
+	}
+	message = strings.ToLower(message)
+	return strings.Contains(message, "header not found") ||
+		strings.Contains(message, "rate limit") ||
+		strings.Contains(message, "limit exceeded")
+}
+
+// isRejectedUnread reports whether a failed request surely did not reach the node:
+// the connection could not be opened or the provider rate limited it
+func isRejectedUnread(resp *http.Response, err error) bool {
+	if resp != nil {
+		return resp.StatusCode == http.StatusTooManyRequests
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// nonIdempotentMethods are not sent again once they may have reached a node, since sending a
+// transaction twice fails with "already known" or "nonce too low" instead of returning its hash
+var nonIdempotentMethods = map[string]bool{
+	"eth_sendRawTransaction": true,
+	"eth_sendTransaction":    true,
+}
+
+// hasNonIdempotentMethod reports whether a JSON-RPC request or batch calls a non-idempotent method
+func hasNonIdempotentMethod(body []byte) bool {
+	type message struct {
+		Method string `json:"method"`
+	}
+
+	var msgs []message
+	if trimmed := bytes.TrimSpace(body); len(trimmed) > 0 && trimmed[0] == '[' {
+		if err := json.Unmarshal(trimmed, &msgs); err != nil {
+			return false
+		}
+	} else {
+		var msg message
+		if err := json.Unmarshal(trimmed, &msg); err != nil {
+			return false
+		}
+		msgs = append(msgs, msg)
+	}
+
+	for _, msg := range msgs {
+		if nonIdempotentMethods[msg.Method] {
+			return true
+		}
+	}
+	return false
+}
+
+// tokenBucket limits the rate of requests to an endpoint
+type tokenBucket struct {
+	rate  float64 // tokens per second
+	burst float64
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	return &tokenBucket{rate: rate, burst: float64(burst), tokens: float64(burst), last: time.Now()}
+}
+
+// wait takes a token, waiting until one is available or the context is done
+func (b *tokenBucket) wait(ctx context.Context) error {
+	b.mu.Lock()
+	now := time.Now()
+	b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+	// The token is reserved right away, so that concurrent waiters queue up behind each other
+	b.tokens--
+	delay := time.Duration(0)
+	if b.tokens < 0 {
+		delay = time.Duration(-b.tokens / b.rate * float64(time.Second))
+	}
+	b.mu.Unlock()
+
+	if delay == 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		b.mu.Lock()
+		b.tokens = min(b.burst, b.tokens+1)
+		b.mu.Unlock()
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
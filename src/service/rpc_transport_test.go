@@ -0,0 +1,245 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type rpcTransportTestAPI struct{}
+
+func (rpcTransportTestAPI) ChainId() hexutil.Uint64 {
+	return hexutil.Uint64(testChainID)
+}
+
+func (rpcTransportTestAPI) SendRawTransaction(tx hexutil.Bytes) error {
+	return nil
+}
+
+// rpcTransportTestNode is a node endpoint answering with the given status while failing is set,
+// or with a JSON-RPC error of the given code in a 200 response while rpcErrorCode is set
+type rpcTransportTestNode struct {
+	*httptest.Server
+	failing      atomic.Bool
+	status       atomic.Int32
+	rpcErrorCode atomic.Int32
+	requests     atomic.Int32
+}
+
+func newRPCTransportTestNode(t *testing.T, status int) *rpcTransportTestNode {
+	server := rpc.NewServer()
+	require.NoError(t, server.RegisterName("eth", rpcTransportTestAPI{}))
+	t.Cleanup(server.Stop)
+
+	node := &rpcTransportTestNode{}
+	node.status.Store(int32(status))
+	node.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		node.requests.Add(1)
+		if node.failing.Load() {
+			w.WriteHeader(int(node.status.Load()))
+			return
+		}
+		if code := node.rpcErrorCode.Load(); code != 0 {
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","id":1,"error":{"code":%d,"message":"rpc error"}}`, code)
+			return
+		}
+		server.ServeHTTP(w, r)
+	}))
+	t.Cleanup(node.Close)
+	return node
+}
+
+func dialRPCTransportTest(t *testing.T, config RPCTransportConfig, nodes ...*rpcTransportTestNode) *rpc.Client {
+	var urls []string
+	for _, node := range nodes {
+		urls = append(urls, node.URL)
+	}
+	transport, err := newRPCTransport(urls, config, nil)
+	require.NoError(t, err)
+
+	client, err := rpc.DialOptions(context.Background(), urls[0], rpc.WithHTTPClient(&http.Client{Transport: transport}))
+	require.NoError(t, err)
+	t.Cleanup(client.Close)
+	return client
+}
+
+func TestRPCTransport_Failover(t *testing.T) {
+	primary := newRPCTransportTestNode(t, http.StatusTooManyRequests)
+	backup := newRPCTransportTestNode(t, http.StatusServiceUnavailable)
+	primary.failing.Store(true)
+
+	var failures []string
+	client := dialRPCTransportTest(t, RPCTransportConfig{
+		OnEndpointFailure: func(ctx context.Context, endpoint string, err error) {
+			failures = append(failures, endpoint)
+		},
+	}, primary, backup)
+
+	var chainId hexutil.Uint64
+	require.NoError(t, client.CallContext(context.Background(), &chainId, "eth_chainId"))
+	assert.Equal(t, hexutil.Uint64(testChainID), chainId)
+	assert.Equal(t, int32(1), primary.requests.Load())
+	assert.Equal(t, int32(1), backup.requests.Load())
+	assert.Equal(t, []string{primary.Listener.Addr().String()}, failures)
+}
+
+func TestRPCTransport_Retry(t *testing.T) {
+	node := newRPCTransportTestNode(t, http.StatusBadGateway)
+	node.failing.Store(true)
+
+	client := dialRPCTransportTest(t, RPCTransportConfig{MaxRetries: 2, RetryBaseDelay: time.Millisecond}, node)
+
+	var chainId hexutil.Uint64
+	err := client.CallContext(context.Background(), &chainId, "eth_chainId")
+	assert.ErrorContains(t, err, "all RPC endpoints failed")
+	assert.Equal(t, int32(3), node.requests.Load())
+
+	// The node recovers before the retries run out
+	node.requests.Store(0)
+	go func() {
+		time.Sleep(5 * time.Millisecond)
+		node.failing.Store(false)
+	}()
+	client = dialRPCTransportTest(t, RPCTransportConfig{MaxRetries: 10, RetryBaseDelay: 10 * time.Millisecond}, node)
+	require.NoError(t, client.CallContext(context.Background(), &chainId, "eth_chainId"))
+}
+
+func TestRPCTransport_CircuitBreaker(t *testing.T) {
+	primary := newRPCTransportTestNode(t, http.StatusInternalServerError)
+	backup := newRPCTransportTestNode(t, http.StatusInternalServerError)
+	primary.failing.Store(true)
+
+	var opened []string
+	client := dialRPCTransportTest(t, RPCTransportConfig{
+		BreakerThreshold: 2,
+		BreakerCooldown:  50 * time.Millisecond,
+		OnCircuitOpen: func(ctx context.Context, endpoint string, cooldown time.Duration) {
+			opened = append(opened, endpoint)
+		},
+	}, primary, backup)
+
+	var chainId hexutil.Uint64
+	for range 4 {
+		require.NoError(t, client.CallContext(context.Background(), &chainId, "eth_chainId"))
+	}
+	// The primary is skipped once its breaker opened
+	assert.Equal(t, int32(2), primary.requests.Load())
+	assert.Equal(t, int32(4), backup.requests.Load())
+	assert.Equal(t, []string{primary.Listener.Addr().String()}, opened)
+
+	// After the cooldown a request probes the recovered primary, which closes the breaker
+	primary.failing.Store(false)
+	time.Sleep(60 * time.Millisecond)
+	for range 2 {
+		require.NoError(t, client.CallContext(context.Background(), &chainId, "eth_chainId"))
+	}
+	assert.Equal(t, int32(4), primary.requests.Load())
+	assert.Equal(t, int32(4), backup.requests.Load())
+}
+
+func TestRPCTransport_NonIdempotent(t *testing.T) {
+	primary := newRPCTransportTestNode(t, http.StatusBadGateway)
+	backup := newRPCTransportTestNode(t, http.StatusBadGateway)
+	primary.failing.Store(true)
+
+	client := dialRPCTransportTest(t, RPCTransportConfig{RetryBaseDelay: time.Millisecond}, primary, backup)
+
+	// The gateway may have passed the transaction on, so it is not sent again
+	err := client.CallContext(context.Background(), nil, "eth_sendRawTransaction", hexutil.Bytes{0x01})
+	var httpErr rpc.HTTPError
+	require.ErrorAs(t, err, &httpErr)
+	assert.Equal(t, http.StatusBadGateway, httpErr.StatusCode)
+	assert.Equal(t, int32(1), primary.requests.Load())
+	assert.Zero(t, backup.requests.Load())
+
+	// A rate limited transaction was not read by the node
+	primary.status.Store(http.StatusTooManyRequests)
+	require.NoError(t, client.CallContext(context.Background(), nil, "eth_sendRawTransaction", hexutil.Bytes{0x01}))
+	assert.Equal(t, int32(1), backup.requests.Load())
+}
+
+func TestRPCTransport_RetryableRPCError(t *testing.T) {
+	primary := newRPCTransportTestNode(t, http.StatusOK)
+	backup := newRPCTransportTestNode(t, http.StatusOK)
+	primary.rpcErrorCode.Store(-32005)
+
+	client := dialRPCTransportTest(t, RPCTransportConfig{RetryBaseDelay: time.Millisecond}, primary, backup)
+
+	var chainId hexutil.Uint64
+	require.NoError(t, client.CallContext(context.Background(), &chainId, "eth_chainId"))
+	assert.Equal(t, hexutil.Uint64(testChainID), chainId)
+	assert.Equal(t, int32(1), primary.requests.Load())
+	assert.Equal(t, int32(1), backup.requests.Load())
+
+	// An error caused by the request is returned as is
+	primary.rpcErrorCode.Store(-32602)
+	err := client.CallContext(context.Background(), &chainId, "eth_chainId")
+	var rpcErr rpc.Error
+	require.ErrorAs(t, err, &rpcErr)
+	assert.Equal(t, -32602, rpcErr.ErrorCode())
+	assert.Equal(t, int32(1), backup.requests.Load())
+
+	// A transaction is not sent again whatever the node answered
+	primary.rpcErrorCode.Store(-32603)
+	err = client.CallContext(context.Background(), nil, "eth_sendRawTransaction", hexutil.Bytes{0x01})
+	require.ErrorAs(t, err, &rpcErr)
+	assert.Equal(t, -32603, rpcErr.ErrorCode())
+	assert.Equal(t, int32(1), backup.requests.Load())
+}
+
+func TestInspectRPCResponse(t *testing.T) {
+	inspect := func(body string) error {
+		resp := &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body))}
+		err := inspectRPCResponse(resp)
+		// The body is put back for the caller
+		read, readErr := io.ReadAll(resp.Body)
+		require.NoError(t, readErr)
+		assert.Equal(t, body, string(read))
+		return err
+	}
+
+	assert.NoError(t, inspect(`{"jsonrpc":"2.0","id":1,"result":"0x1"}`))
+	assert.NoError(t, inspect(`{"jsonrpc":"2.0","id":1,"error":{"code":3,"message":"execution reverted"}}`))
+	assert.Error(t, inspect(`{"jsonrpc":"2.0","id":1,"error":{"code":-32005,"message":"limit exceeded"}}`))
+	assert.Error(t, inspect(`{"jsonrpc":"2.0","id":1,"error":{"code":-32000,"message":"header not found"}}`))
+	assert.NoError(t, inspect(`[{"jsonrpc":"2.0","id":1,"result":"0x1"},{"jsonrpc":"2.0","id":2,"error":{"code":3,"message":"execution reverted"}}]`))
+	// A batch only fails over when none of its requests was answered
+	assert.NoError(t, inspect(`[{"jsonrpc":"2.0","id":1,"result":"0x1"},{"jsonrpc":"2.0","id":2,"error":{"code":-32603,"message":"header not found"}}]`))
+	assert.Error(t, inspect(`[{"jsonrpc":"2.0","id":1,"error":{"code":-32005,"message":"limit exceeded"}},{"jsonrpc":"2.0","id":2,"error":{"code":-32603,"message":"header not found"}}]`))
+	assert.NoError(t, inspect(`[]`))
+	assert.NoError(t, inspect(`not json`))
+}
+
+func TestHasNonIdempotentMethod(t *testing.T) {
+	assert.False(t, hasNonIdempotentMethod([]byte(`{"jsonrpc":"2.0","id":1,"method":"eth_call","params":[]}`)))
+	assert.True(t, hasNonIdempotentMethod([]byte(`{"jsonrpc":"2.0","id":1,"method":"eth_sendRawTransaction","params":["0x01"]}`)))
+	assert.True(t, hasNonIdempotentMethod([]byte(` [{"method":"eth_call"},{"method":"eth_sendRawTransaction"}]`)))
+	assert.False(t, hasNonIdempotentMethod([]byte(`not json`)))
+}
+
+func TestTokenBucket(t *testing.T) {
+	bucket := newTokenBucket(20, 2)
+
+	// The burst is available at once, then tokens come every 50ms
+	start := time.Now()
+	for range 3 {
+		require.NoError(t, bucket.wait(context.Background()))
+	}
+	assert.GreaterOrEqual(t, time.Since(start), 40*time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, bucket.wait(ctx), context.Canceled)
+}
@@ -2,6 +2,8 @@ package service
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"sync"
 	"time"
 
@@ -17,22 +19,126 @@ import (
 type CombinedJob struct {
 	EntityJob       domain.EntityJob
 	ExecutionConfig domain.ExecutionConfig
+	// Order is decoded from the execution data; nil if it could not be decoded
+	Order *JobOrder
+}
+
+// JobQueue is the queue of jobs to execute and the cache of their execution status,
+// implemented by repository.JobCacheRepository
+type JobQueue interface {
+	EnqueueJob(ctx context.Context, job domain.EntityJob) error
+	// DequeueJob returns redis.Nil when no job was queued before the timeout
+	DequeueJob(ctx context.Context, timeout time.Duration) (*domain.EntityJob, error)
+	GetJobCache(ctx context.Context, jobID uuid.UUID) (*repository.JobCache, error)
+	GetJobCachesByStatus(ctx context.Context, status repository.CacheJobStatus) ([]*repository.JobCache, error)
+	GetCacheStatistics(ctx context.Context) (*repository.CacheStatistics, error)
+	AddJobCache(ctx context.Context, jobCache *repository.JobCache) error
+	SetJobStatus(ctx context.Context, jobID uuid.UUID, status repository.CacheJobStatus, message *string) error
+	SetJobStatusFailed(ctx context.Context, jobID uuid.UUID, errorMessage string) error
+	UpdateJobCacheSentUserOperation(ctx context.Context, jobID uuid.UUID, userOpHash common.Hash, entryPoint common.Address, userOp *erc4337.UserOperation) error
+	ReplaceJobCacheUserOperation(ctx context.Context, jobID uuid.UUID, userOpHash common.Hash, userOp *erc4337.UserOperation) error
+	DeleteJobCache(ctx context.Context, jobID uuid.UUID) error
+}
+
+// JobStore is the storage of registered jobs, implemented by JobService
+type JobStore interface {
+	GetActiveJobs(ctx context.Context) ([]*domain.EntityJob, error)
+	GetJobByID(ctx context.Context, id string) (*domain.EntityJob, error)
+	UpdateJobStatus(ctx context.Context, id string, status domain.DBJobStatus, errMsg *string) error
+	UpdateJobErrMsg(ctx context.Context, id string, errKind domain.DBJobErrKind, errMsg *string) error
 }
 
 // JobScheduler manages job scheduling and execution
 type JobScheduler struct {
-	jobCache          *repository.JobCacheRepository
+	jobCache          JobQueue
 	ctx               context.Context
 	cancel            context.CancelFunc
 	wg                sync.WaitGroup
 	pollingInterval   int
-	jobService        *JobService
+	jobService        JobStore
 	executionService  *ExecutionService
 	blockchainService *BlockchainService
+
+	// receiptWatchers poll the receipts of pending jobs per chain; watchedJobs maps their user operations to jobs
+	receiptWatchers map[int64]*erc4337.ReceiptWatcher
+	watchedJobs     map[common.Hash]watchedJob
+	watchMu         sync.Mutex
+
+	// chainBackoff skips chains whose execution configs could not be read
+	chainBackoff *pollBackoff[int64]
+	// jobBackoff skips jobs whose execution failed with a transient error
+	jobBackoff *pollBackoff[uuid.UUID]
+}
+
+// maxBackoffPolls caps the number of polls a failing chain or job is skipped for
+const maxBackoffPolls = 32
+
+// maxJobExecutionAttempts is the number of consecutive transient execution failures after which a job is failed
+const maxJobExecutionAttempts = 10
+
+// pollBackoff skips failing chains or jobs for exponentially more polls after each consecutive failure:
+// 0, 1, 3, 7, ... up to maxBackoffPolls
+type pollBackoff[K comparable] struct {
+	entries map[K]*pollBackoffState
+	mu      sync.Mutex
+}
+
+type pollBackoffState struct {
+	failures int
+	// skipPolls is the number of polls left to skip
+	skipPolls int
+}
+
+func newPollBackoff[K comparable]() *pollBackoff[K] {
+	return &pollBackoff[K]{entries: make(map[K]*pollBackoffState)}
+}
+
+// skip reports whether a poll should skip the entry, counting down the polls it is skipped for
+func (b *pollBackoff[K]) skip(key K) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	state, exists := b.entries[key]
+	if !exists || state.skipPolls == 0 {
+		return false
+	}
+	state.skipPolls--
+	return true
+}
+
+// fail records a failure of the entry and returns its consecutive failures and the polls it is skipped for
+func (b *pollBackoff[K]) fail(key K) (int, int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	state, exists := b.entries[key]
+	if !exists {
+		state = &pollBackoffState{}
+		b.entries[key] = state
+	}
+	state.failures++
+	state.skipPolls = min(1<<min(state.failures-1, 30)-1, maxBackoffPolls)
+	return state.failures, state.skipPolls
+}
+
+// succeed resets the failures of the entry and reports whether it was failing
+func (b *pollBackoff[K]) succeed(key K) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	_, exists := b.entries[key]
+	delete(b.entries, key)
+	return exists
+}
+
+// watchedJob is the job of a watched user operation
+type watchedJob struct {
+	jobID   uuid.UUID
+	chainID int64
 }
 
 // NewJobScheduler creates a new job scheduler instance
-func NewJobScheduler(ctx context.Context, jobCache *repository.JobCacheRepository, pollingInterval int, jobService *JobService, executionService *ExecutionService, blockchainService *BlockchainService) *JobScheduler {
+func NewJobScheduler(ctx context.Context, jobCache JobQueue, pollingInterval int, jobService JobStore, executionService *ExecutionService, blockchainService *BlockchainService) *JobScheduler {
 	ctx, cancel := context.WithCancel(ctx)
 
 	return &JobScheduler{
@@ -43,6 +149,10 @@ func NewJobScheduler(ctx context.Context, jobCache *repository.JobCacheRepositor
 		jobService:        jobService,
 		executionService:  executionService,
 		blockchainService: blockchainService,
+		receiptWatchers:   make(map[int64]*erc4337.ReceiptWatcher),
+		watchedJobs:       make(map[common.Hash]watchedJob),
+		chainBackoff:      newPollBackoff[int64](),
+		jobBackoff:        newPollBackoff[uuid.UUID](),
 	}
 }
 
@@ -66,6 +176,19 @@ func (js *JobScheduler) Start() {
 func (js *JobScheduler) Stop() {
 	js.cancel()
 	js.wg.Wait()
+
+	// Close waits for the watcher loops, whose results take watchMu, so the watchers are closed without holding it
+	js.watchMu.Lock()
+	watchers := make([]*erc4337.ReceiptWatcher, 0, len(js.receiptWatchers))
+	for _, watcher := range js.receiptWatchers {
+		watchers = append(watchers, watcher)
+	}
+	js.receiptWatchers = make(map[int64]*erc4337.ReceiptWatcher)
+	js.watchMu.Unlock()
+
+	for _, watcher := range watchers {
+		watcher.Close()
+	}
 }
 
 // pollJobs polls for jobs to execute every pollingInterval seconds
@@ -157,7 +280,13 @@ func (js *JobScheduler) pollJobLogic() {
 		return
 	}
 
-	// Step 4: Fetch Execution Config
+	// Step 4: Fetch Execution Config, skipping chains and jobs backing off after failures
+	jobs = js.skipBackedOffJobs(js.skipBackedOffChains(jobs))
+	if len(jobs) == 0 {
+		logger.Info().Msg("No active jobs on available chains")
+		return
+	}
+
 	jobsToExecute, err := js.fetchExecutionConfigsAndFilterJobs(jobs)
 	if err != nil {
 		logger.Error().Err(err).Msg("Failed to fetch execution configs and filter jobs")
@@ -196,6 +325,8 @@ func (js *JobScheduler) pollJobLogic() {
 
 		logger.Info().
 			Str("jobID", job.EntityJob.ID.String()).
+			Str("jobType", string(job.EntityJob.JobType)).
+			Object("order", job.Order).
 			Msg("Job added to cache and enqueued successfully")
 	}
 }
@@ -206,31 +337,71 @@ func (js *JobScheduler) executeJobLogic(job domain.EntityJob) {
 	logger.Info().Str("jobID", job.ID.String()).Msg("Executing job...")
 
 	// Execute Job
-	actualUserOpHash, err := js.executionService.ExecuteJob(js.ctx, job)
+	sent, err := js.executionService.ExecuteJob(js.ctx, job)
 
 	// Update Job Status based on execution result
-	if err != nil {
-		// Execution failed - update cache with failed status and error message
+	if err != nil && IsTransientExecutionError(err) {
+		// Transient failure (e.g. unfunded account, throttled paymaster, node or bundler outage, or a nonce taken
+		// by another user operation) - record it on the job and remove it from cache so that it stays queuing and is retried after backing off
+		failures, skipPolls := js.jobBackoff.fail(job.ID)
+		errMsg := fmt.Sprintf("execution failed: %s (attempt %d of %d)", err, failures, maxJobExecutionAttempts)
+
+		if failures >= maxJobExecutionAttempts {
+			js.jobBackoff.succeed(job.ID)
+			logger.Error().Str("jobID", job.ID.String()).Err(err).Int("attempts", failures).Msg("Job execution kept failing, marking as failed")
+			if err := js.jobCache.SetJobStatusFailed(js.ctx, job.ID, errMsg); err != nil {
+				logger.Error().Err(err).Msgf("Failed to set failed job status for %s", job.ID)
+			}
+			return
+		}
+
+		logEvent := logger.Warn().Str("jobID", job.ID.String()).Err(err).Int("attempts", failures).Int("skipped_polls", skipPolls)
+		var aaErr *erc4337.AAError
+		if errors.As(err, &aaErr) {
+			logEvent = logEvent.Str("aa_code", aaErr.Code)
+		}
+		logEvent.Msg("Job execution failed with a transient error, retrying after backing off")
+
+		if err := js.jobService.UpdateJobErrMsg(js.ctx, job.ID.String(), domain.DBJobErrKindExecution, &errMsg); err != nil {
+			logger.Error().Err(err).Str("jobID", job.ID.String()).Msg("Failed to record execution error of job")
+		}
+		if err := js.jobCache.DeleteJobCache(js.ctx, job.ID); err != nil {
+			logger.Error().Err(err).Msgf("Failed to remove job %s from cache for retry", job.ID)
+		}
+	} else if err != nil {
+		// Execution failed permanently (invalid job, or user operation rejected by the bundler) - update cache with failed status and error message
+		js.jobBackoff.succeed(job.ID)
 		errMsg := err.Error()
 		logger.Error().Str("jobID", job.ID.String()).Err(err).Msg("Job execution failed")
 
 		if err := js.jobCache.SetJobStatusFailed(js.ctx, job.ID, errMsg); err != nil {
 			logger.Error().Err(err).Msgf("Failed to set failed job status for %s", job.ID)
 		}
-	} else if actualUserOpHash != nil {
+	} else if sent != nil {
 		// Execution successful - user operation sent to network
 		// Keep status as pending, receipt checker will determine final success/failure
 		logger.Info().
 			Str("jobID", job.ID.String()).
-			Str("actualUserOpHash", actualUserOpHash.Hex()).
+			Str("actualUserOpHash", sent.UserOpHash.Hex()).
 			Msg("Job executed successfully, user operation sent to network")
 
-		// Update the userOpHash in cache with the actual hash from execution
-		if err := js.jobCache.UpdateJobCacheUserOpHash(js.ctx, job.ID, *actualUserOpHash); err != nil {
+		// The job no longer fails, so an execution error recorded by an earlier attempt no longer applies
+		if js.jobBackoff.succeed(job.ID) || hasErrKind(&job, domain.DBJobErrKindExecution) {
+			if err := js.jobService.UpdateJobErrMsg(js.ctx, job.ID.String(), domain.DBJobErrKindExecution, nil); err != nil {
+				logger.Error().Err(err).Str("jobID", job.ID.String()).Msg("Failed to clear execution error of job")
+			}
+		}
+
+		// Update the userOpHash in cache with the actual hash from execution, along with the user operation
+		// so that it can be replaced if it gets stuck
+		if err := js.jobCache.UpdateJobCacheSentUserOperation(js.ctx, job.ID, sent.UserOpHash, sent.EntryPoint, &sent.UserOperation); err != nil {
 			logger.Error().Err(err).
 				Str("jobID", job.ID.String()).
-				Str("actualUserOpHash", actualUserOpHash.Hex()).
+				Str("actualUserOpHash", sent.UserOpHash.Hex()).
 				Msg("Failed to update userOpHash in cache")
+		} else if watcher, err := js.getReceiptWatcher(job.ChainID); err == nil {
+			// Watch the receipt right away rather than from the next poll
+			js.watchJobReceipt(watcher, job.ID, job.ChainID, sent.UserOpHash)
 		}
 	} else {
 		// This shouldn't happen - successful execution should return userOpHash
@@ -243,6 +414,8 @@ func (js *JobScheduler) executeJobLogic(job domain.EntityJob) {
 }
 
 // fetchExecutionConfigsAndFilterJobs fetches execution configs in batch and filters jobs
+// Jobs whose config could not be read are skipped: permanent errors fail the job, while transient ones are
+// recorded on the queuing job and retried by the next poll. Chains that could not be reached are backed off.
 func (js *JobScheduler) fetchExecutionConfigsAndFilterJobs(jobs []*domain.EntityJob) ([]CombinedJob, error) {
 	logger := js.logger(js.ctx).With().Str("function", "fetchExecutionConfigsAndFilterJobs").Logger()
 
@@ -253,8 +426,11 @@ func (js *JobScheduler) fetchExecutionConfigsAndFilterJobs(jobs []*domain.Entity
 		return nil, err
 	}
 
+	js.updateChainBackoff(jobs, executionConfigs)
+
 	// Create CombinedJob structs and filter jobs that are ready to execute or completed
 	var jobsToExecute []CombinedJob
+	failedJobs := 0
 	for _, jobModel := range jobs {
 		// Filter out jobs that are already in cache
 		if js.isJobInCache(jobModel.ID) {
@@ -262,11 +438,25 @@ func (js *JobScheduler) fetchExecutionConfigsAndFilterJobs(jobs []*domain.Entity
 			continue
 		}
 
-		config, exists := executionConfigs[jobModel.ID.String()]
+		result, exists := executionConfigs[jobModel.ID.String()]
 		if !exists {
 			logger.Warn().Str("job_id", jobModel.ID.String()).Msg("No execution config found for job")
 			continue
 		}
+		if result.Err != nil {
+			failedJobs++
+			js.handleExecutionConfigError(jobModel, result.Err)
+			continue
+		}
+		config := result.Config
+
+		// The config was read again, so a config error recorded by an earlier poll no longer applies.
+		// Execution errors are cleared once the job executes.
+		if hasErrKind(jobModel, domain.DBJobErrKindExecutionConfig) {
+			if err := js.jobService.UpdateJobErrMsg(js.ctx, jobModel.ID.String(), domain.DBJobErrKindExecutionConfig, nil); err != nil {
+				logger.Error().Err(err).Str("job_id", jobModel.ID.String()).Msg("Failed to clear execution config error of job")
+			}
+		}
 
 		// Create CombinedJob struct
 		job := CombinedJob{
@@ -291,19 +481,121 @@ func (js *JobScheduler) fetchExecutionConfigsAndFilterJobs(jobs []*domain.Entity
 
 		// Check if job is ready to execute
 		if config.IsTimeToExecute() {
+			if order, err := DecodeJobOrder(jobModel.JobType, config.ExecutionData); err != nil {
+				logger.Warn().Err(err).Str("job_id", jobModel.ID.String()).Msg("Failed to decode execution data of job")
+			} else {
+				job.Order = order
+			}
 			jobsToExecute = append(jobsToExecute, job)
 		}
 	}
 
 	logger.Info().
 		Int("total_jobs", len(jobs)).
-		Int("jobs_with_configs", len(executionConfigs)).
+		Int("jobs_with_configs", len(executionConfigs)-failedJobs).
+		Int("jobs_with_errors", failedJobs).
 		Int("jobs_to_execute", len(jobsToExecute)).
 		Msg("Processed execution configs and filtered jobs")
 
 	return jobsToExecute, nil
 }
 
+// handleExecutionConfigError surfaces the error reading the execution config of a job through its status.
+// Errors of the job itself fail it; the others, including chains and module versions missing from the
+// chain registry, are recorded as the error message of the job, which keeps queuing until they are fixed.
+func (js *JobScheduler) handleExecutionConfigError(job *domain.EntityJob, configErr *ExecutionConfigError) {
+	logger := js.logger(js.ctx).With().Str("function", "handleExecutionConfigError").Logger()
+	errMsg := fmt.Sprintf("failed to read execution config: %s", configErr)
+
+	if configErr.Permanent() {
+		logger.Error().Err(configErr).Str("job_id", job.ID.String()).Msg("Execution config of job cannot be read, marking as failed")
+		if err := js.jobCache.SetJobStatusFailed(js.ctx, job.ID, errMsg); err != nil {
+			logger.Error().Err(err).Msgf("Failed to set failed job status for %s", job.ID)
+		}
+		return
+	}
+
+	logger.Warn().Err(configErr).Str("job_id", job.ID.String()).Msg("Failed to read execution config of job, retrying on next poll")
+	if hasErrKind(job, domain.DBJobErrKindExecutionConfig) && *job.ErrMsg == errMsg {
+		return
+	}
+	if err := js.jobService.UpdateJobErrMsg(js.ctx, job.ID.String(), domain.DBJobErrKindExecutionConfig, &errMsg); err != nil {
+		logger.Error().Err(err).Str("job_id", job.ID.String()).Msg("Failed to record execution config error of job")
+	}
+}
+
+// hasErrKind reports whether the error message recorded on a job is of the given kind
+func hasErrKind(job *domain.EntityJob, kind domain.DBJobErrKind) bool {
+	return job.ErrMsg != nil && job.ErrKind != nil && *job.ErrKind == kind
+}
+
+// skipBackedOffJobs returns the jobs that are not backing off after transient execution failures
+func (js *JobScheduler) skipBackedOffJobs(jobs []*domain.EntityJob) []*domain.EntityJob {
+	available := make([]*domain.EntityJob, 0, len(jobs))
+	for _, job := range jobs {
+		if js.jobBackoff.skip(job.ID) {
+			js.logger(js.ctx).Debug().Str("job_id", job.ID.String()).Msg("Job is backing off after execution failures, skipping")
+			continue
+		}
+		available = append(available, job)
+	}
+	return available
+}
+
+// skipBackedOffChains returns the jobs whose chain is not backing off after failing to read execution configs
+func (js *JobScheduler) skipBackedOffChains(jobs []*domain.EntityJob) []*domain.EntityJob {
+	logger := js.logger(js.ctx).With().Str("function", "skipBackedOffChains").Logger()
+
+	skipped := make(map[int64]bool)
+	available := make([]*domain.EntityJob, 0, len(jobs))
+	for _, job := range jobs {
+		skip, checked := skipped[job.ChainID]
+		if !checked {
+			skip = js.chainBackoff.skip(job.ChainID)
+			skipped[job.ChainID] = skip
+			if skip {
+				logger.Warn().Int64("chain_id", job.ChainID).Msg("Chain is backing off after failures, skipping its jobs")
+			}
+		}
+		if !skip {
+			available = append(available, job)
+		}
+	}
+	return available
+}
+
+// updateChainBackoff backs off the chains that could not be reached and resets the others
+func (js *JobScheduler) updateChainBackoff(jobs []*domain.EntityJob, executionConfigs map[string]ExecutionConfigResult) {
+	logger := js.logger(js.ctx).With().Str("function", "updateChainBackoff").Logger()
+
+	unavailable := make(map[int64]error)
+	for _, job := range jobs {
+		result := executionConfigs[job.ID.String()]
+		if _, exists := unavailable[job.ChainID]; !exists {
+			unavailable[job.ChainID] = nil
+		}
+		if result.Err != nil && errors.Is(result.Err, ErrChainUnavailable) {
+			unavailable[job.ChainID] = result.Err
+		}
+	}
+
+	for chainId, chainErr := range unavailable {
+		if chainErr == nil {
+			if js.chainBackoff.succeed(chainId) {
+				logger.Info().Int64("chain_id", chainId).Msg("Chain is available again")
+			}
+			continue
+		}
+
+		failures, skipPolls := js.chainBackoff.fail(chainId)
+		logger.Error().Err(chainErr).
+			Int64("chain_id", chainId).
+			Int("consecutive_failures", failures).
+			Int("skipped_polls", skipPolls).
+			Msg("Failed to read execution configs on chain, backing off")
+	}
+}
+
 // isJobInCache checks if a job exists in the Redis cache (regardless of status)
 func (js *JobScheduler) isJobInCache(jobID uuid.UUID) bool {
 	_, err := js.jobCache.GetJobCache(js.ctx, jobID)
@@ -427,110 +719,296 @@ func (js *JobScheduler) checkReceiptsForPendingJobs() {
 	}
 }
 
-// checkReceiptsForChain checks receipts for all jobs on a specific chain
+// checkReceiptsForChain makes sure the receipt watcher of a chain watches every pending job on it,
+// and replaces the user operations that stayed pending for too long
 func (js *JobScheduler) checkReceiptsForChain(chainID int64, jobs []*repository.JobCache) {
 	logger := js.logger(js.ctx).With().
 		Str("function", "checkReceiptsForChain").
 		Int64("chain_id", chainID).
 		Logger()
 
-	// Get bundler client for this chain
-	bundlerClient, err := js.blockchainService.GetBundlerClient(js.ctx, chainID)
+	watcher, err := js.getReceiptWatcher(chainID)
 	if err != nil {
 		logger.Error().Err(err).
 			Int64("chain_id", chainID).
-			Msg("Failed to get bundler client")
+			Msg("Failed to get receipt watcher")
 		return
 	}
 
+	for _, job := range jobs {
+		// Check if UserOpHash is valid (not zero)
+		if job.UserOpHash == (common.Hash{}) {
+			logger.Error().
+				Str("job_id", job.JobID.String()).
+				Msg("Job has empty user operation hash, marking as failed")
+
+			errorMsg := "Job has empty user operation hash"
+			if err := js.jobCache.SetJobStatusFailed(js.ctx, job.JobID, errorMsg); err != nil {
+				logger.Error().Err(err).
+					Str("job_id", job.JobID.String()).
+					Msg("Failed to mark job with invalid userOpHash as failed")
+			}
+			continue
+		}
+
+		js.watchJobReceipt(watcher, job.JobID, chainID, job.UserOpHash)
+		js.replaceStuckUserOperation(watcher, chainID, job)
+	}
+
 	logger.Debug().
 		Int64("chain_id", chainID).
 		Int("jobs_count", len(jobs)).
-		Msg("Checking receipts for jobs on chain")
+		Int("watching", watcher.Pending()).
+		Msg("Watching receipts for jobs on chain")
+}
 
-	// Check receipts for each job
-	for _, job := range jobs {
-		js.checkSingleJobReceipt(bundlerClient, job)
+// getReceiptWatcher returns the receipt watcher of a chain, creating it on first use.
+// Receipts are confirmed with the EntryPoint logs of the chain's node before they update the job cache.
+func (js *JobScheduler) getReceiptWatcher(chainID int64) (*erc4337.ReceiptWatcher, error) {
+	js.watchMu.Lock()
+	defer js.watchMu.Unlock()
+
+	if watcher, exists := js.receiptWatchers[chainID]; exists {
+		return watcher, nil
+	}
+
+	bundlerClient, err := js.blockchainService.GetBundlerClient(js.ctx, chainID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bundler client: %w", err)
+	}
+	client, err := js.blockchainService.GetClient(chainID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get blockchain client: %w", err)
+	}
+	chain, err := js.blockchainService.GetChain(chainID)
+	if err != nil {
+		return nil, err
 	}
+
+	watcher := erc4337.NewReceiptWatcher(bundlerClient, erc4337.ReceiptWatcherConfig{
+		// A receipt cannot appear before the next block, but fast chains are not polled more than once a second
+		PollInterval: max(chain.BlockTime, erc4337.DefaultReceiptPollInterval),
+		// Back off to the polling interval at most, which is how often receipts were checked before
+		MaxPollInterval: time.Duration(js.pollingInterval) * time.Second,
+		LogConfirmer:    client,
+		OnResult:        js.handleReceiptResult,
+	})
+	js.receiptWatchers[chainID] = watcher
+	return watcher, nil
 }
 
-// checkSingleJobReceipt checks the receipt for a single job
-func (js *JobScheduler) checkSingleJobReceipt(bundlerClient interface{}, job *repository.JobCache) {
+// watchJobReceipt starts watching the user operation of a job unless it is already watched
+func (js *JobScheduler) watchJobReceipt(watcher *erc4337.ReceiptWatcher, jobID uuid.UUID, chainID int64, userOpHash common.Hash) {
+	js.watchMu.Lock()
+	defer js.watchMu.Unlock()
+
+	if _, watching := js.watchedJobs[userOpHash]; watching {
+		return
+	}
+	js.watchedJobs[userOpHash] = watchedJob{jobID: jobID, chainID: chainID}
+	watcher.Watch(userOpHash)
+}
+
+// unwatchJobReceipt stops watching a user operation without a result, e.g. once it is replaced
+func (js *JobScheduler) unwatchJobReceipt(watcher *erc4337.ReceiptWatcher, userOpHash common.Hash) {
+	js.watchMu.Lock()
+	delete(js.watchedJobs, userOpHash)
+	js.watchMu.Unlock()
+
+	watcher.Unwatch(userOpHash)
+}
+
+// replaceStuckUserOperation re-sends the user operation of a job with higher fees when it has been pending for longer
+// than the fee bump threshold of the chain. The replaced userOpHash is kept in the job cache.
+func (js *JobScheduler) replaceStuckUserOperation(watcher *erc4337.ReceiptWatcher, chainID int64, job *repository.JobCache) {
+	feeBump := js.blockchainService.GetFeeBumpConfig(chainID)
+	if feeBump.Disabled || job.UserOperation == nil || job.SentAt.IsZero() || time.Since(job.SentAt) < feeBump.After {
+		return
+	}
+
 	logger := js.logger(js.ctx).With().
-		Str("function", "checkSingleJobReceipt").
+		Str("function", "replaceStuckUserOperation").
 		Str("job_id", job.JobID.String()).
+		Str("user_op_hash", job.UserOpHash.Hex()).
+		Int("replacements", len(job.ReplacedUserOpHashes)).
 		Logger()
 
-	// Cast to BundlerClient to access GetUserOperationReceipt
-	client, ok := bundlerClient.(*erc4337.BundlerClient)
-	if !ok {
-		logger.Error().Msg("Failed to cast bundler client to BundlerClient type")
+	if len(job.ReplacedUserOpHashes) >= feeBump.MaxReplacements {
+		logger.Debug().Msg("User operation still pending after the maximum number of replacements")
 		return
 	}
 
-	// Check if UserOpHash is valid (not zero)
-	if job.UserOpHash == (common.Hash{}) {
-		logger.Error().
-			Str("job_id", job.JobID.String()).
-			Msg("Job has empty user operation hash, marking as failed")
+	// Nothing to replace once the bundler no longer has the user operation in its mempool
+	bundlerClient, err := js.blockchainService.GetBundlerClient(js.ctx, chainID)
+	if err != nil {
+		logger.Error().Err(err).Msg("Failed to get bundler client")
+		return
+	}
+	pending, err := bundlerClient.GetUserOperationByHash(js.ctx, job.UserOpHash)
+	if err != nil {
+		logger.Warn().Err(err).Msg("Failed to get pending user operation")
+		return
+	}
+	if pending == nil || !pending.IsPending() {
+		return
+	}
 
-		errorMsg := "Job has empty user operation hash"
-		if err := js.jobCache.SetJobStatusFailed(js.ctx, job.JobID, errorMsg); err != nil {
-			logger.Error().Err(err).
-				Str("job_id", job.JobID.String()).
-				Msg("Failed to mark job with invalid userOpHash as failed")
-		}
+	logger.Info().
+		Dur("pending_for", time.Since(job.SentAt)).
+		Msg("User operation pending for too long, replacing it with higher fees")
+
+	// The replacement is signed from the job's template signature
+	entityJob, err := js.jobService.GetJobByID(js.ctx, job.JobID.String())
+	if err != nil {
+		logger.Error().Err(err).Msg("Failed to get job")
 		return
 	}
 
-	// Get the receipt
-	receipt, err := client.GetUserOperationReceipt(js.ctx, job.UserOpHash)
+	// The bundler drops the replaced user operation as soon as the replacement arrives, which must not fail the job
+	js.unwatchJobReceipt(watcher, job.UserOpHash)
+
+	replacement, err := js.executionService.ReplaceUserOperation(js.ctx, *entityJob, &SentUserOperation{
+		UserOpHash:    job.UserOpHash,
+		EntryPoint:    job.EntryPoint,
+		UserOperation: *job.UserOperation,
+	})
 	if err != nil {
+		// Keep watching the user operation and retry the replacement on the next poll
+		logger.Warn().Err(err).Msg("Failed to replace user operation")
+		js.watchJobReceipt(watcher, job.JobID, chainID, job.UserOpHash)
+		return
+	}
+
+	if err := js.jobCache.ReplaceJobCacheUserOperation(js.ctx, job.JobID, replacement.UserOpHash, &replacement.UserOperation); err != nil {
 		logger.Error().Err(err).
-			Str("job_id", job.JobID.String()).
-			Str("user_op_hash", job.UserOpHash.Hex()).
-			Msg("Failed to get user operation receipt")
+			Str("replacement_user_op_hash", replacement.UserOpHash.Hex()).
+			Msg("Failed to record replacement user operation in cache")
+	}
+
+	js.watchJobReceipt(watcher, job.JobID, chainID, replacement.UserOpHash)
+}
+
+// findReplacedReceipt returns the receipt of a replaced user operation of a job that was included after all,
+// e.g. when it was bundled before its replacement reached the bundler
+func (js *JobScheduler) findReplacedReceipt(watched watchedJob) (*erc4337.UserOperationReceipt, error) {
+	jobCache, err := js.jobCache.GetJobCache(js.ctx, watched.jobID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get job cache: %w", err)
+	}
+	if len(jobCache.ReplacedUserOpHashes) == 0 {
+		return nil, nil
+	}
+
+	bundlerClient, err := js.blockchainService.GetBundlerClient(js.ctx, watched.chainID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bundler client: %w", err)
+	}
+	for _, userOpHash := range jobCache.ReplacedUserOpHashes {
+		receipt, err := bundlerClient.GetUserOperationReceipt(js.ctx, userOpHash)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get user operation receipt: %w", err)
+		}
+		if receipt != nil {
+			return receipt, nil
+		}
+	}
+	return nil, nil
+}
+
+// handleReceiptResult updates the job cache with the result of a watched user operation
+func (js *JobScheduler) handleReceiptResult(result erc4337.ReceiptResult) {
+	// Results arriving during shutdown are left to the next run, which watches the pending jobs again
+	if js.ctx.Err() != nil {
 		return
 	}
 
-	// Handle receipt result
-	if receipt == nil {
-		// Receipt not found yet, job is still pending
-		logger.Debug().
-			Str("job_id", job.JobID.String()).
-			Str("user_op_hash", job.UserOpHash.Hex()).
-			Msg("Receipt not found yet, job still pending")
+	js.watchMu.Lock()
+	watched, exists := js.watchedJobs[result.UserOpHash]
+	delete(js.watchedJobs, result.UserOpHash)
+	js.watchMu.Unlock()
+
+	if !exists {
 		return
 	}
+	jobID := watched.jobID
 
-	// Receipt found - check if it's successful
-	logger.Info().
-		Str("job_id", job.JobID.String()).
-		Str("user_op_hash", receipt.UserOpHash.Hex()).
-		Bool("success", receipt.Success).
-		Msg("Receipt found for pending job")
+	logger := js.logger(js.ctx).With().
+		Str("function", "handleReceiptResult").
+		Str("job_id", jobID.String()).
+		Str("user_op_hash", result.UserOpHash.Hex()).
+		Logger()
+
+	switch {
+	case result.Err != nil:
+		// The job stays pending and is watched again on the next poll
+		logger.Warn().Err(result.Err).Msg("Stopped watching user operation receipt")
+
+	case result.Dropped:
+		// A replaced user operation of the job may have been included instead
+		receipt, err := js.findReplacedReceipt(watched)
+		if err != nil {
+			logger.Warn().Err(err).Msg("Failed to check replaced user operations of dropped job")
+		} else if receipt != nil {
+			logger.Info().
+				Str("included_user_op_hash", receipt.UserOpHash.Hex()).
+				Msg("Replaced user operation included instead of its replacement")
+			js.handleJobReceipt(logger, jobID, receipt)
+			return
+		}
+
+		// The bundler no longer knows the user operation, so it was dropped from the mempool
+		logger.Warn().Msg("User operation dropped by bundler, marking job as failed")
+		js.releaseDroppedNonce(logger, watched)
+
+		errorMsg := "User operation dropped from bundler mempool"
+		if err := js.jobCache.SetJobStatusFailed(js.ctx, jobID, errorMsg); err != nil {
+			logger.Error().Err(err).Msg("Failed to update dropped job status in cache")
+		}
+
+	default:
+		js.handleJobReceipt(logger, jobID, result.Receipt)
+	}
+}
+
+// releaseDroppedNonce frees the nonce of the dropped user operation of a job, which would otherwise stay reserved
+// and make the next jobs of the sender on the same key use a sequence after it
+func (js *JobScheduler) releaseDroppedNonce(logger zerolog.Logger, watched watchedJob) {
+	jobCache, err := js.jobCache.GetJobCache(js.ctx, watched.jobID)
+	if err != nil {
+		logger.Warn().Err(err).Msg("Failed to get job cache to release nonce of dropped user operation")
+		return
+	}
+	if jobCache.UserOperation == nil {
+		return
+	}
+	if err := js.executionService.ReleaseNonce(watched.chainID, jobCache.EntryPoint, jobCache.UserOperation); err != nil {
+		logger.Warn().Err(err).Msg("Failed to release nonce of dropped user operation")
+	}
+}
+
+// handleJobReceipt completes a job from the receipt of its user operation
+func (js *JobScheduler) handleJobReceipt(logger zerolog.Logger, jobID uuid.UUID, receipt *erc4337.UserOperationReceipt) {
+	switch {
+	case receipt.Success:
+		logger.Info().Bool("success", true).Msg("Receipt found for pending job")
 
-	if receipt.Success {
 		// Job completed successfully, remove from cache
-		if err := js.jobCache.DeleteJobCache(js.ctx, job.JobID); err != nil {
-			logger.Error().Err(err).
-				Str("job_id", job.JobID.String()).
-				Msg("Failed to delete successful job status from cache")
+		if err := js.jobCache.DeleteJobCache(js.ctx, jobID); err != nil {
+			logger.Error().Err(err).Msg("Failed to delete successful job status from cache")
 		} else {
-			logger.Info().
-				Str("job_id", job.JobID.String()).
-				Msg("Successfully completed job removed from cache")
+			logger.Info().Msg("Successfully completed job removed from cache")
 		}
-	} else {
-		// Job failed, update status
-		errorMsg := "User operation failed on-chain"
-		if err := js.jobCache.SetJobStatusFailed(js.ctx, job.JobID, errorMsg); err != nil {
-			logger.Error().Err(err).
-				Str("job_id", job.JobID.String()).
-				Msg("Failed to update failed job status in cache")
+
+	default:
+		logger.Info().Bool("success", false).Msg("Receipt found for pending job")
+
+		// Job failed, store the decoded revert reason
+		errorMsg := receipt.FailureReason(schedulingRevertDecoder)
+		if err := js.jobCache.SetJobStatusFailed(js.ctx, jobID, errorMsg); err != nil {
+			logger.Error().Err(err).Msg("Failed to update failed job status in cache")
 		} else {
 			logger.Info().
-				Str("job_id", job.JobID.String()).
+				Str("reason", errorMsg).
 				Msg("Job marked as failed due to on-chain failure")
 		}
 	}
@@ -0,0 +1,598 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethaccount/backend/erc4337"
+	"github.com/ethaccount/backend/erc4337/bundlertest"
+	"github.com/ethaccount/backend/src/domain"
+	"github.com/ethaccount/backend/src/repository"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPollBackoff(t *testing.T) {
+	backoff := newPollBackoff[int64]()
+	assert.False(t, backoff.skip(testChainID))
+
+	// Skipped for 0, 1, 3 and 7 polls after consecutive failures
+	for i, expected := range []int{0, 1, 3, 7} {
+		failures, skipPolls := backoff.fail(testChainID)
+		assert.Equal(t, i+1, failures)
+		assert.Equal(t, expected, skipPolls)
+
+		for range expected {
+			assert.True(t, backoff.skip(testChainID))
+		}
+		assert.False(t, backoff.skip(testChainID))
+	}
+
+	// Other chains are not affected
+	assert.False(t, backoff.skip(84532))
+
+	for range 10 {
+		backoff.fail(testChainID)
+	}
+	_, skipPolls := backoff.fail(testChainID)
+	assert.Equal(t, maxBackoffPolls, skipPolls)
+
+	assert.True(t, backoff.succeed(testChainID))
+	assert.False(t, backoff.skip(testChainID))
+	assert.False(t, backoff.succeed(testChainID))
+}
+
+// fakeSchedulerEthAPI is the node of the offline scheduler tests: it serves the EntryPoint nonce, the executionLog of
+// the scheduling modules and the logs of the user operations included by the in-memory bundler
+type fakeSchedulerEthAPI struct {
+	*fakeMulticallEthAPI
+	nonce   *big.Int
+	bundler *bundlertest.Bundler
+}
+
+func (api *fakeSchedulerEthAPI) Call(args map[string]interface{}, block string) (hexutil.Bytes, error) {
+	if _, err := erc4337.GetEntryPointVersion(common.HexToAddress(args["to"].(string))); err == nil {
+		return common.BigToHash(api.nonce).Bytes(), nil
+	}
+	return api.fakeMulticallEthAPI.Call(args, block)
+}
+
+func (api *fakeSchedulerEthAPI) GetBlockByNumber(number string, full bool) (*Block, error) {
+	return &Block{BaseFeePerGas: "0x3b9aca00"}, nil
+}
+
+type fakeLogFilter struct {
+	FromBlock *hexutil.Big     `json:"fromBlock"`
+	ToBlock   *hexutil.Big     `json:"toBlock"`
+	Addresses []common.Address `json:"address"`
+	Topics    [][]common.Hash  `json:"topics"`
+}
+
+func (api *fakeSchedulerEthAPI) GetLogs(filter fakeLogFilter) ([]types.Log, error) {
+	return api.bundler.FilterLogs(context.Background(), ethereum.FilterQuery{
+		FromBlock: (*big.Int)(filter.FromBlock),
+		ToBlock:   (*big.Int)(filter.ToBlock),
+		Addresses: filter.Addresses,
+		Topics:    filter.Topics,
+	})
+}
+
+// fakeJobQueue is an in-memory JobQueue behaving like repository.JobCacheRepository
+type fakeJobQueue struct {
+	mu     sync.Mutex
+	queue  []domain.EntityJob
+	caches map[uuid.UUID]repository.JobCache
+}
+
+func newFakeJobQueue() *fakeJobQueue {
+	return &fakeJobQueue{caches: make(map[uuid.UUID]repository.JobCache)}
+}
+
+func (q *fakeJobQueue) EnqueueJob(ctx context.Context, job domain.EntityJob) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.queue = append(q.queue, job)
+	return nil
+}
+
+func (q *fakeJobQueue) DequeueJob(ctx context.Context, timeout time.Duration) (*domain.EntityJob, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.queue) == 0 {
+		return nil, redis.Nil
+	}
+	job := q.queue[0]
+	q.queue = q.queue[1:]
+	return &job, nil
+}
+
+func (q *fakeJobQueue) GetJobCache(ctx context.Context, jobID uuid.UUID) (*repository.JobCache, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	jobCache, exists := q.caches[jobID]
+	if !exists {
+		return nil, redis.Nil
+	}
+	return &jobCache, nil
+}
+
+func (q *fakeJobQueue) GetJobCachesByStatus(ctx context.Context, status repository.CacheJobStatus) ([]*repository.JobCache, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var jobCaches []*repository.JobCache
+	for _, jobCache := range q.caches {
+		if jobCache.Status == status {
+			jobCaches = append(jobCaches, &jobCache)
+		}
+	}
+	return jobCaches, nil
+}
+
+func (q *fakeJobQueue) GetCacheStatistics(ctx context.Context) (*repository.CacheStatistics, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return &repository.CacheStatistics{TotalCount: len(q.caches)}, nil
+}
+
+func (q *fakeJobQueue) AddJobCache(ctx context.Context, jobCache *repository.JobCache) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	jobCache.UpdatedAt = time.Now()
+	q.caches[jobCache.JobID] = *jobCache
+	return nil
+}
+
+// SetJobStatus replaces the job cache like the repository does, dropping the chain and user operation
+func (q *fakeJobQueue) SetJobStatus(ctx context.Context, jobID uuid.UUID, status repository.CacheJobStatus, message *string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	jobCache := repository.JobCache{JobID: jobID, Status: status, UpdatedAt: time.Now()}
+	if message != nil {
+		jobCache.Error = *message
+	}
+	q.caches[jobID] = jobCache
+	return nil
+}
+
+func (q *fakeJobQueue) SetJobStatusFailed(ctx context.Context, jobID uuid.UUID, errorMessage string) error {
+	return q.SetJobStatus(ctx, jobID, repository.CacheStatusFailed, &errorMessage)
+}
+
+func (q *fakeJobQueue) UpdateJobCacheSentUserOperation(ctx context.Context, jobID uuid.UUID, userOpHash common.Hash, entryPoint common.Address, userOp *erc4337.UserOperation) error {
+	return q.update(jobID, func(jobCache *repository.JobCache) {
+		jobCache.UserOpHash = userOpHash
+		jobCache.EntryPoint = entryPoint
+		jobCache.UserOperation = userOp
+		jobCache.SentAt = time.Now()
+	})
+}
+
+func (q *fakeJobQueue) ReplaceJobCacheUserOperation(ctx context.Context, jobID uuid.UUID, userOpHash common.Hash, userOp *erc4337.UserOperation) error {
+	return q.update(jobID, func(jobCache *repository.JobCache) {
+		jobCache.ReplacedUserOpHashes = append(jobCache.ReplacedUserOpHashes, jobCache.UserOpHash)
+		jobCache.UserOpHash = userOpHash
+		jobCache.UserOperation = userOp
+		jobCache.SentAt = time.Now()
+	})
+}
+
+func (q *fakeJobQueue) update(jobID uuid.UUID, update func(jobCache *repository.JobCache)) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	jobCache, exists := q.caches[jobID]
+	if !exists {
+		return fmt.Errorf("failed to get existing job cache: %w", redis.Nil)
+	}
+	update(&jobCache)
+	jobCache.UpdatedAt = time.Now()
+	q.caches[jobID] = jobCache
+	return nil
+}
+
+func (q *fakeJobQueue) DeleteJobCache(ctx context.Context, jobID uuid.UUID) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	delete(q.caches, jobID)
+	return nil
+}
+
+func (q *fakeJobQueue) cache(jobID uuid.UUID) (repository.JobCache, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	jobCache, exists := q.caches[jobID]
+	return jobCache, exists
+}
+
+// fakeJobStore is an in-memory JobStore behaving like JobService
+type fakeJobStore struct {
+	mu   sync.Mutex
+	jobs map[uuid.UUID]*domain.EntityJob
+}
+
+func newFakeJobStore(jobs ...domain.EntityJob) *fakeJobStore {
+	store := &fakeJobStore{jobs: make(map[uuid.UUID]*domain.EntityJob)}
+	for _, job := range jobs {
+		store.jobs[job.ID] = &job
+	}
+	return store
+}
+
+func (s *fakeJobStore) GetActiveJobs(ctx context.Context) ([]*domain.EntityJob, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var jobs []*domain.EntityJob
+	for _, job := range s.jobs {
+		if job.Status == domain.DBJobStatusQueuing {
+			copied := *job
+			jobs = append(jobs, &copied)
+		}
+	}
+	return jobs, nil
+}
+
+func (s *fakeJobStore) GetJobByID(ctx context.Context, id string) (*domain.EntityJob, error) {
+	job, err := s.job(id)
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (s *fakeJobStore) UpdateJobStatus(ctx context.Context, id string, status domain.DBJobStatus, errMsg *string) error {
+	return s.update(id, func(job *domain.EntityJob) {
+		job.Status = status
+		if status == domain.DBJobStatusFailed && errMsg != nil {
+			job.ErrMsg = errMsg
+			job.ErrKind = nil
+		}
+	})
+}
+
+func (s *fakeJobStore) UpdateJobErrMsg(ctx context.Context, id string, errKind domain.DBJobErrKind, errMsg *string) error {
+	return s.update(id, func(job *domain.EntityJob) {
+		job.ErrMsg = errMsg
+		job.ErrKind = nil
+		if errMsg != nil {
+			job.ErrKind = &errKind
+		}
+	})
+}
+
+func (s *fakeJobStore) update(id string, update func(job *domain.EntityJob)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job, exists := s.jobs[uuid.MustParse(id)]
+	if !exists {
+		return fmt.Errorf("job %s not found", id)
+	}
+	update(job)
+	return nil
+}
+
+func (s *fakeJobStore) job(id string) (domain.EntityJob, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job, exists := s.jobs[uuid.MustParse(id)]
+	if !exists {
+		return domain.EntityJob{}, fmt.Errorf("job %s not found", id)
+	}
+	return *job, nil
+}
+
+// newOfflineScheduler wires a JobScheduler to in-memory job storage, an in-process node and an in-memory bundler
+func newOfflineScheduler(t *testing.T, feeBump FeeBumpConfig, jobs ...domain.EntityJob) (*JobScheduler, *fakeJobQueue, *fakeJobStore, *bundlertest.Bundler) {
+	currentNonce, _ := new(big.Int).SetString("10000000000000005", 16)
+	bundler := bundlertest.New(testChainID)
+
+	server := rpc.NewServer()
+	require.NoError(t, server.RegisterName("eth", &fakeSchedulerEthAPI{
+		fakeMulticallEthAPI: &fakeMulticallEthAPI{},
+		nonce:               currentNonce,
+		bundler:             bundler,
+	}))
+	require.NoError(t, server.RegisterName("rundler", &fakeRundlerAPI{}))
+	t.Cleanup(server.Stop)
+
+	// Receipts are polled every second rather than every 12-second block of Sepolia
+	chains := DefaultChains()
+	for i := range chains {
+		chains[i].BlockTime = 0
+	}
+	registry, err := NewChainRegistry(chains)
+	require.NoError(t, err)
+
+	blockchainService := NewBlockchainService(BlockchainConfig{Chains: registry, DefaultFeeBump: feeBump})
+	blockchainService.SetClient(testChainID, ethclient.NewClient(rpc.DialInProc(server)))
+	blockchainService.SetBundlerClient(testChainID, bundler)
+	t.Cleanup(blockchainService.Close)
+
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	executionService := NewExecutionService(blockchainService, NewLocalSignerFromKey(key))
+
+	queue := newFakeJobQueue()
+	store := newFakeJobStore(jobs...)
+	scheduler := NewJobScheduler(context.Background(), queue, 1, store, executionService, blockchainService)
+	t.Cleanup(scheduler.Stop)
+	return scheduler, queue, store, bundler
+}
+
+// newSchedulerJob returns a queuing job of its own account
+func newSchedulerJob(t *testing.T, onChainJobID int64) domain.EntityJob {
+	job := newOfflineJob(t, erc4337.EntryPointV07)
+	account := common.BigToAddress(big.NewInt(0x1000 + onChainJobID))
+	job.AccountAddress = account
+	job.UserOperation.Sender = account
+	job.OnChainJobID = onChainJobID
+	return job
+}
+
+// processQueuedJobs executes the queued jobs like processJobs does
+func processQueuedJobs(t *testing.T, js *JobScheduler) {
+	for {
+		job, err := js.jobCache.DequeueJob(js.ctx, 0)
+		if errors.Is(err, redis.Nil) {
+			return
+		}
+		require.NoError(t, err)
+		js.executeJobLogic(*job)
+	}
+}
+
+func TestJobScheduler_Offline(t *testing.T) {
+	included := newSchedulerJob(t, 1)
+	reverted := newSchedulerJob(t, 2)
+	dropped := newSchedulerJob(t, 3)
+	js, queue, store, bundler := newOfflineScheduler(t, FeeBumpConfig{Disabled: true}, included, reverted, dropped)
+
+	// The poll enqueues the jobs whose execution is due
+	js.pollJobLogic()
+	for _, job := range []domain.EntityJob{included, reverted, dropped} {
+		jobCache, exists := queue.cache(job.ID)
+		require.True(t, exists)
+		assert.Equal(t, repository.CacheStatusPending, jobCache.Status)
+		assert.Equal(t, common.Hash{}, jobCache.UserOpHash)
+	}
+
+	// Executing them sends their user operations and records them in the cache
+	processQueuedJobs(t, js)
+	sent := bundler.Sent()
+	require.Len(t, sent, 3)
+	userOpHashes := make(map[uuid.UUID]common.Hash)
+	for _, job := range []domain.EntityJob{included, reverted, dropped} {
+		jobCache, exists := queue.cache(job.ID)
+		require.True(t, exists)
+		assert.Equal(t, repository.CacheStatusPending, jobCache.Status)
+		assert.NotEqual(t, common.Hash{}, jobCache.UserOpHash)
+		require.NotNil(t, jobCache.UserOperation)
+		assert.Equal(t, job.AccountAddress, jobCache.UserOperation.Sender)
+		userOpHashes[job.ID] = jobCache.UserOpHash
+	}
+
+	// Error(string) "boom"
+	revertReason := hexutil.MustDecode("0x08c379a0" +
+		"0000000000000000000000000000000000000000000000000000000000000020" +
+		"0000000000000000000000000000000000000000000000000000000000000004" +
+		"626f6f6d00000000000000000000000000000000000000000000000000000000")
+	_, err := bundler.IncludeSuccess(userOpHashes[included.ID])
+	require.NoError(t, err)
+	_, err = bundler.IncludeRevertReason(userOpHashes[reverted.ID], revertReason)
+	require.NoError(t, err)
+	bundler.Drop(userOpHashes[dropped.ID])
+
+	// The receipt watcher settles the jobs in the cache
+	require.Eventually(t, func() bool {
+		_, includedPending := queue.cache(included.ID)
+		revertedCache, _ := queue.cache(reverted.ID)
+		droppedCache, _ := queue.cache(dropped.ID)
+		return !includedPending &&
+			revertedCache.Status == repository.CacheStatusFailed &&
+			droppedCache.Status == repository.CacheStatusFailed
+	}, 5*time.Second, 10*time.Millisecond)
+
+	revertedCache, _ := queue.cache(reverted.ID)
+	assert.Equal(t, "User operation reverted: boom", revertedCache.Error)
+	droppedCache, _ := queue.cache(dropped.ID)
+	assert.Equal(t, "User operation dropped from bundler mempool", droppedCache.Error)
+
+	// The next poll syncs the failed jobs to the database and enqueues the next execution of the included job
+	js.pollJobLogic()
+	for job, errMsg := range map[uuid.UUID]string{
+		reverted.ID: "User operation reverted: boom",
+		dropped.ID:  "User operation dropped from bundler mempool",
+	} {
+		stored := mustStoredJob(t, store, job)
+		assert.Equal(t, domain.DBJobStatusFailed, stored.Status)
+		require.NotNil(t, stored.ErrMsg)
+		assert.Equal(t, errMsg, *stored.ErrMsg)
+		_, exists := queue.cache(job)
+		assert.False(t, exists)
+	}
+
+	stored := mustStoredJob(t, store, included.ID)
+	assert.Equal(t, domain.DBJobStatusQueuing, stored.Status)
+	assert.Nil(t, stored.ErrMsg)
+	jobCache, exists := queue.cache(included.ID)
+	require.True(t, exists)
+	assert.Equal(t, repository.CacheStatusPending, jobCache.Status)
+
+	// Stopping the scheduler closes the receipt watchers
+	stopped := make(chan struct{})
+	go func() {
+		js.Stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("scheduler did not stop")
+	}
+}
+
+func TestJobScheduler_Offline_TransientExecutionError(t *testing.T) {
+	job := newSchedulerJob(t, 1)
+	js, queue, store, bundler := newOfflineScheduler(t, FeeBumpConfig{Disabled: true}, job)
+	bundler.InjectError("eth_sendUserOperation", &bundlertest.RPCError{Code: -32500, Message: "AA21 didn't pay prefund"})
+
+	js.pollJobLogic()
+	processQueuedJobs(t, js)
+
+	// The job stays queuing with the error recorded, and is skipped by the next poll while backing off
+	stored := mustStoredJob(t, store, job.ID)
+	assert.Equal(t, domain.DBJobStatusQueuing, stored.Status)
+	require.NotNil(t, stored.ErrMsg)
+	require.NotNil(t, stored.ErrKind)
+	assert.Equal(t, domain.DBJobErrKindExecution, *stored.ErrKind)
+	assert.Contains(t, *stored.ErrMsg, "AA21 didn't pay prefund (attempt 1 of 10)")
+	_, exists := queue.cache(job.ID)
+	assert.False(t, exists)
+
+	// Skipped for no poll after the first failure, so it is retried right away
+	bundler.ClearError("eth_sendUserOperation")
+	js.pollJobLogic()
+	processQueuedJobs(t, js)
+	assert.Len(t, bundler.Sent(), 1)
+	stored = mustStoredJob(t, store, job.ID)
+	assert.Nil(t, stored.ErrMsg)
+	assert.Nil(t, stored.ErrKind)
+}
+
+func TestJobScheduler_Offline_RecordedErrKind(t *testing.T) {
+	configErrJob := newSchedulerJob(t, 1)
+	configErrKind := domain.DBJobErrKindExecutionConfig
+	configErrMsg := "failed to read execution config: chain unavailable"
+	configErrJob.ErrMsg = &configErrMsg
+	configErrJob.ErrKind = &configErrKind
+	execErrJob := newSchedulerJob(t, 2)
+	execErrKind := domain.DBJobErrKindExecution
+	execErrMsg := "execution failed: bundler unavailable (attempt 1 of 10)"
+	execErrJob.ErrMsg = &execErrMsg
+	execErrJob.ErrKind = &execErrKind
+	js, _, store, _ := newOfflineScheduler(t, FeeBumpConfig{Disabled: true}, configErrJob, execErrJob)
+
+	// Reading the execution configs clears the config error, while the execution error stays until the job executes
+	js.pollJobLogic()
+	stored := mustStoredJob(t, store, configErrJob.ID)
+	assert.Nil(t, stored.ErrMsg)
+	assert.Nil(t, stored.ErrKind)
+	stored = mustStoredJob(t, store, execErrJob.ID)
+	require.NotNil(t, stored.ErrMsg)
+	assert.Equal(t, execErrMsg, *stored.ErrMsg)
+
+	processQueuedJobs(t, js)
+	assert.Nil(t, mustStoredJob(t, store, execErrJob.ID).ErrMsg)
+}
+
+func TestJobScheduler_Offline_DroppedReleasesNonce(t *testing.T) {
+	job := newSchedulerJob(t, 1)
+	js, queue, _, bundler := newOfflineScheduler(t, FeeBumpConfig{Disabled: true}, job)
+
+	js.pollJobLogic()
+	processQueuedJobs(t, js)
+	sent := bundler.Sent()
+	require.Len(t, sent, 1)
+
+	bundler.Drop(sent[0].UserOpHash)
+	require.Eventually(t, func() bool {
+		jobCache, _ := queue.cache(job.ID)
+		return jobCache.Status == repository.CacheStatusFailed
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// The next job of the sender on the same key reuses the nonce of the dropped user operation
+	next := newSchedulerJob(t, 2)
+	next.AccountAddress = job.AccountAddress
+	next.UserOperation.Sender = job.UserOperation.Sender
+	nextSent, err := js.executionService.ExecuteJob(context.Background(), next)
+	require.NoError(t, err)
+	assert.Equal(t, sent[0].UserOperation.Nonce, nextSent.UserOperation.Nonce)
+}
+
+func TestJobScheduler_Offline_BundlerTransportError(t *testing.T) {
+	job := newSchedulerJob(t, 1)
+	js, queue, store, bundler := newOfflineScheduler(t, FeeBumpConfig{Disabled: true}, job)
+	bundler.InjectError("eth_estimateUserOperationGas", &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")})
+
+	js.pollJobLogic()
+	processQueuedJobs(t, js)
+
+	// An unreachable bundler does not fail the job, which is retried once the bundler is back
+	stored := mustStoredJob(t, store, job.ID)
+	assert.Equal(t, domain.DBJobStatusQueuing, stored.Status)
+	require.NotNil(t, stored.ErrMsg)
+	assert.Contains(t, *stored.ErrMsg, "connection refused (attempt 1 of 10)")
+	_, exists := queue.cache(job.ID)
+	assert.False(t, exists)
+
+	bundler.ClearError("eth_estimateUserOperationGas")
+	js.pollJobLogic()
+	processQueuedJobs(t, js)
+	assert.Len(t, bundler.Sent(), 1)
+	assert.Equal(t, domain.DBJobStatusQueuing, mustStoredJob(t, store, job.ID).Status)
+}
+
+func TestJobScheduler_Offline_PermanentExecutionError(t *testing.T) {
+	job := newSchedulerJob(t, 1)
+	js, queue, _, bundler := newOfflineScheduler(t, FeeBumpConfig{Disabled: true}, job)
+	bundler.InjectError("eth_sendUserOperation", &bundlertest.RPCError{Code: -32507, Message: "AA24 signature error"})
+
+	js.pollJobLogic()
+	processQueuedJobs(t, js)
+
+	jobCache, exists := queue.cache(job.ID)
+	require.True(t, exists)
+	assert.Equal(t, repository.CacheStatusFailed, jobCache.Status)
+	assert.Contains(t, jobCache.Error, "AA24 signature error")
+}
+
+func TestJobScheduler_Offline_ReplaceStuckUserOperation(t *testing.T) {
+	job := newSchedulerJob(t, 1)
+	js, queue, _, bundler := newOfflineScheduler(t, FeeBumpConfig{After: time.Millisecond}, job)
+
+	js.pollJobLogic()
+	processQueuedJobs(t, js)
+	jobCache, exists := queue.cache(job.ID)
+	require.True(t, exists)
+	stuck := jobCache.UserOpHash
+
+	// The next poll replaces the user operation that stayed pending with higher fees
+	time.Sleep(5 * time.Millisecond)
+	js.pollJobLogic()
+
+	sent := bundler.Sent()
+	require.Len(t, sent, 2)
+	jobCache, _ = queue.cache(job.ID)
+	replacement := jobCache.UserOpHash
+	assert.Equal(t, sent[1].UserOpHash, replacement)
+	assert.Equal(t, []common.Hash{stuck}, jobCache.ReplacedUserOpHashes)
+	assert.Equal(t, sent[0].UserOperation.Nonce, sent[1].UserOperation.Nonce)
+	assert.Equal(t, big.NewInt(110000000), sent[1].UserOperation.MaxPriorityFeePerGas.ToInt())
+
+	// The bundler dropped the replaced user operation, which does not fail the job, and the replacement completes it
+	_, err := bundler.IncludeSuccess(replacement)
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		_, exists := queue.cache(job.ID)
+		return !exists
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func mustStoredJob(t *testing.T, store *fakeJobStore, id uuid.UUID) domain.EntityJob {
+	job, err := store.job(id.String())
+	require.NoError(t, err)
+	return job
+}
@@ -0,0 +1,115 @@
+package service
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ethaccount/backend/src/contracts/scheduledorders"
+	"github.com/ethaccount/backend/src/contracts/scheduledtransfers"
+	"github.com/ethaccount/backend/src/domain"
+	"github.com/rs/zerolog"
+)
+
+// Bindings of the scheduling modules
+var (
+	scheduledTransfers = scheduledtransfers.NewScheduledTransfers()
+	scheduledOrders    = scheduledorders.NewScheduledOrders()
+)
+
+// isSchedulingJobType reports whether a job type has a scheduling module, on some chain at least
+func isSchedulingJobType(jobType domain.DBJobType) bool {
+	return jobType == domain.DBJobTypeTransfer || jobType == domain.DBJobTypeSwap
+}
+
+// packExecutionLog encodes the executionLog call of a job on the scheduling module of its type
+func packExecutionLog(job *domain.EntityJob) ([]byte, error) {
+	jobId := big.NewInt(job.OnChainJobID)
+	switch job.JobType {
+	case domain.DBJobTypeTransfer:
+		return scheduledTransfers.PackExecutionLog(job.AccountAddress, jobId), nil
+	case domain.DBJobTypeSwap:
+		return scheduledOrders.PackExecutionLog(job.AccountAddress, jobId), nil
+	default:
+		return nil, fmt.Errorf("unsupported job type: %s", job.JobType)
+	}
+}
+
+// unpackExecutionLog decodes the executionLog result of a job of the given type
+func unpackExecutionLog(jobType domain.DBJobType, data []byte) (*domain.ExecutionConfig, error) {
+	switch jobType {
+	case domain.DBJobTypeTransfer:
+		log, err := scheduledTransfers.UnpackExecutionLog(data)
+		if err != nil {
+			return nil, err
+		}
+		return &domain.ExecutionConfig{
+			ExecuteInterval:             log.ExecuteInterval,
+			NumberOfExecutions:          log.NumberOfExecutions,
+			NumberOfExecutionsCompleted: log.NumberOfExecutionsCompleted,
+			StartDate:                   log.StartDate,
+			IsEnabled:                   log.IsEnabled,
+			LastExecutionTime:           log.LastExecutionTime,
+			ExecutionData:               log.ExecutionData,
+		}, nil
+	case domain.DBJobTypeSwap:
+		log, err := scheduledOrders.UnpackExecutionLog(data)
+		if err != nil {
+			return nil, err
+		}
+		return &domain.ExecutionConfig{
+			ExecuteInterval:             log.ExecuteInterval,
+			NumberOfExecutions:          log.NumberOfExecutions,
+			NumberOfExecutionsCompleted: log.NumberOfExecutionsCompleted,
+			StartDate:                   log.StartDate,
+			IsEnabled:                   log.IsEnabled,
+			LastExecutionTime:           log.LastExecutionTime,
+			ExecutionData:               log.ExecutionData,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported job type: %s", jobType)
+	}
+}
+
+// JobOrder is what each execution of a job does, decoded from its execution data.
+// Only the field of the job type is set.
+type JobOrder struct {
+	Transfer *scheduledtransfers.ExecutionData
+	Swap     *scheduledorders.ExecutionData
+}
+
+// DecodeJobOrder decodes the execution data of a job of the given type
+func DecodeJobOrder(jobType domain.DBJobType, executionData []byte) (*JobOrder, error) {
+	switch jobType {
+	case domain.DBJobTypeTransfer:
+		transfer, err := scheduledtransfers.DecodeExecutionData(executionData)
+		if err != nil {
+			return nil, err
+		}
+		return &JobOrder{Transfer: transfer}, nil
+	case domain.DBJobTypeSwap:
+		swap, err := scheduledorders.DecodeExecutionData(executionData)
+		if err != nil {
+			return nil, err
+		}
+		return &JobOrder{Swap: swap}, nil
+	default:
+		return nil, fmt.Errorf("unsupported job type: %s", jobType)
+	}
+}
+
+// MarshalZerologObject logs the fields of the order
+func (o *JobOrder) MarshalZerologObject(e *zerolog.Event) {
+	switch {
+	case o == nil:
+		return
+	case o.Transfer != nil:
+		e.Str("token", o.Transfer.Token.Hex()).
+			Str("recipient", o.Transfer.Recipient.Hex()).
+			Str("amount", o.Transfer.Amount.String())
+	case o.Swap != nil:
+		e.Str("token_in", o.Swap.TokenIn.Hex()).
+			Str("token_out", o.Swap.TokenOut.Hex()).
+			Str("amount_in", o.Swap.AmountIn.String()).
+			Str("sqrt_price_limit_x96", o.Swap.SqrtPriceLimitX96.String())
+	}
+}
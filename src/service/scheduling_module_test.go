@@ -0,0 +1,62 @@
+package service
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethaccount/backend/src/contracts/scheduledorders"
+	"github.com/ethaccount/backend/src/contracts/scheduledtransfers"
+	"github.com/ethaccount/backend/src/domain"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecutionLog(t *testing.T) {
+	job := newMulticallTestJobs(1)[0]
+	job.OnChainJobID = 7
+
+	for _, jobType := range []domain.DBJobType{domain.DBJobTypeTransfer, domain.DBJobTypeSwap} {
+		job.JobType = jobType
+		calldata, err := packExecutionLog(job)
+		require.NoError(t, err)
+		assert.Equal(t, testExecutionLogMethod.ID, calldata[:4])
+
+		output, err := (&fakeMulticallEthAPI{}).executionLog(calldata)
+		require.NoError(t, err)
+		config, err := unpackExecutionLog(jobType, output)
+		require.NoError(t, err)
+		assert.Equal(t, uint16(7), config.NumberOfExecutionsCompleted)
+		assert.Equal(t, []byte{0xab}, config.ExecutionData)
+	}
+
+	job.JobType = "unknown"
+	_, err := packExecutionLog(job)
+	assert.ErrorContains(t, err, "unsupported job type: unknown")
+}
+
+func TestDecodeJobOrder(t *testing.T) {
+	token := common.HexToAddress("0x1c7d4b196cb0c7b01d743fbc6116a902379c7238")
+	recipient := common.HexToAddress("0x1234567890123456789012345678901234567890")
+
+	transferData, err := (&scheduledtransfers.ExecutionData{Recipient: recipient, Token: token, Amount: big.NewInt(5)}).Encode()
+	require.NoError(t, err)
+	order, err := DecodeJobOrder(domain.DBJobTypeTransfer, transferData)
+	require.NoError(t, err)
+	require.NotNil(t, order.Transfer)
+	assert.Nil(t, order.Swap)
+	assert.Equal(t, recipient, order.Transfer.Recipient)
+
+	swapData, err := (&scheduledorders.ExecutionData{TokenIn: token, TokenOut: recipient, AmountIn: big.NewInt(5), SqrtPriceLimitX96: big.NewInt(0)}).Encode()
+	require.NoError(t, err)
+	order, err = DecodeJobOrder(domain.DBJobTypeSwap, swapData)
+	require.NoError(t, err)
+	require.NotNil(t, order.Swap)
+	assert.Equal(t, token, order.Swap.TokenIn)
+
+	// Transfer execution data is one word short of swap execution data
+	_, err = DecodeJobOrder(domain.DBJobTypeSwap, transferData)
+	assert.Error(t, err)
+	_, err = DecodeJobOrder("unknown", transferData)
+	assert.ErrorContains(t, err, "unsupported job type: unknown")
+}
@@ -0,0 +1,309 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// Signature formats of job user operations, recorded on each job
+const (
+	// SignatureFormatPersonalSign replaces the dummy signature at the end of the template with a personal_sign
+	// signature of the userOpHash, e.g. for the ECDSA and Ownable validators
+	SignatureFormatPersonalSign = "personal_sign"
+	// SignatureFormatRawHash replaces the dummy signature at the end of the template with an ECDSA signature
+	// of the raw userOpHash, for validators that do not apply the EIP-191 prefix
+	SignatureFormatRawHash = "raw_hash"
+	// SignatureFormatSmartSessions signs for a Smart Sessions permission in USE mode: 0x00 || permissionId || signature
+	SignatureFormatSmartSessions = "smart_sessions"
+	// SignatureFormatMultiValidator signs the entries of a multi-validator (MultiFactor) signature,
+	// abi.encode((bytes32 packedValidatorAndId, bytes data)[]), whose data is the dummy signature
+	SignatureFormatMultiValidator = "multi_validator"
+	// SignatureFormatERC1271 signs for a contract signer validating the session key with isValidSignature.
+	// The template ends with abi.encode(address signer, bytes signature) holding the dummy signature.
+	SignatureFormatERC1271 = "erc1271"
+
+	// DefaultSignatureFormat is used by jobs registered without a signature format
+	DefaultSignatureFormat = SignatureFormatPersonalSign
+)
+
+// SignatureFormatter builds the signature of job user operations for the validator module checking them.
+// Jobs store a template signature in which a dummy signature stands for the session key signature.
+type SignatureFormatter interface {
+	// DummySignature checks the template signature of a job and returns the signature to estimate and simulate
+	// the user operation with, which has the length of the final signature
+	DummySignature(template []byte) ([]byte, error)
+	// Sign signs the userOpHash with the session key and returns the final signature built from the template
+	Sign(ctx context.Context, signer Signer, template []byte, userOpHash common.Hash) ([]byte, error)
+}
+
+// SignatureFormatterRegistry holds the signature formatters by format name
+type SignatureFormatterRegistry struct {
+	formatters map[string]SignatureFormatter
+	mu         sync.RWMutex
+}
+
+// SignatureFormatters is the registry used by the job API and the execution service.
+// Custom formatters registered on it are available to jobs registered afterwards.
+var SignatureFormatters = NewSignatureFormatterRegistry()
+
+// NewSignatureFormatterRegistry creates a registry with the built-in signature formats
+func NewSignatureFormatterRegistry() *SignatureFormatterRegistry {
+	return &SignatureFormatterRegistry{
+		formatters: map[string]SignatureFormatter{
+			SignatureFormatPersonalSign:   &dummySuffixFormatter{sign: signPersonal},
+			SignatureFormatRawHash:        &dummySuffixFormatter{sign: signRawHash},
+			SignatureFormatSmartSessions:  &dummySuffixFormatter{sign: signPersonal, checkLeading: checkSmartSessionsUse},
+			SignatureFormatMultiValidator: &multiValidatorFormatter{sign: signPersonal},
+			SignatureFormatERC1271:        &erc1271Formatter{sign: signPersonal},
+		},
+	}
+}
+
+// Register adds or replaces the formatter of a signature format
+func (r *SignatureFormatterRegistry) Register(format string, formatter SignatureFormatter) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.formatters[format] = formatter
+}
+
+// Get returns the formatter of a signature format; an empty format is the default one
+func (r *SignatureFormatterRegistry) Get(format string) (SignatureFormatter, error) {
+	if format == "" {
+		format = DefaultSignatureFormat
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	formatter, exists := r.formatters[format]
+	if !exists {
+		return nil, fmt.Errorf("unsupported signature format: %s", format)
+	}
+	return formatter, nil
+}
+
+// Formats returns the registered signature formats in alphabetical order
+func (r *SignatureFormatterRegistry) Formats() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	formats := make([]string, 0, len(r.formatters))
+	for format := range r.formatters {
+		formats = append(formats, format)
+	}
+	sort.Strings(formats)
+	return formats
+}
+
+// signFunc produces the 65-byte session key signature of a userOpHash
+type signFunc func(ctx context.Context, signer Signer, userOpHash common.Hash) ([]byte, error)
+
+func signPersonal(ctx context.Context, signer Signer, userOpHash common.Hash) ([]byte, error) {
+	return signer.SignPersonalMessage(ctx, userOpHash.Bytes())
+}
+
+func signRawHash(ctx context.Context, signer Signer, userOpHash common.Hash) ([]byte, error) {
+	hashSigner, ok := signer.(HashSigner)
+	if !ok {
+		return nil, fmt.Errorf("signer %s cannot sign raw hashes", signer.Address().Hex())
+	}
+	return hashSigner.SignHash(ctx, userOpHash)
+}
+
+// dummySuffixFormatter handles templates made of a leading signature followed by DummySignature,
+// which is replaced by the session key signature
+type dummySuffixFormatter struct {
+	sign signFunc
+	// checkLeading, if set, validates the leading signature
+	checkLeading func(leading []byte) error
+}
+
+func (f *dummySuffixFormatter) leadingSignature(template []byte) ([]byte, error) {
+	if !bytes.HasSuffix(template, DummySignature) {
+		return nil, fmt.Errorf("signature must end with the dummy signature %s", hexutil.Encode(DummySignature))
+	}
+	leading := template[:len(template)-len(DummySignature)]
+	if f.checkLeading != nil {
+		if err := f.checkLeading(leading); err != nil {
+			return nil, err
+		}
+	}
+	return leading, nil
+}
+
+func (f *dummySuffixFormatter) DummySignature(template []byte) ([]byte, error) {
+	if _, err := f.leadingSignature(template); err != nil {
+		return nil, err
+	}
+	return template, nil
+}
+
+func (f *dummySuffixFormatter) Sign(ctx context.Context, signer Signer, template []byte, userOpHash common.Hash) ([]byte, error) {
+	leading, err := f.leadingSignature(template)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := f.sign(ctx, signer, userOpHash)
+	if err != nil {
+		return nil, err
+	}
+	return append(bytes.Clone(leading), signature...), nil
+}
+
+// smartSessionsModeUse is the SmartSessionMode selecting an enabled permission
+const smartSessionsModeUse = 0x00
+
+// checkSmartSessionsUse checks the mode and permission ID in front of a Smart Sessions signature
+func checkSmartSessionsUse(leading []byte) error {
+	if len(leading) != 1+common.HashLength {
+		return fmt.Errorf("smart sessions signature must be the mode, the permission ID and the dummy signature")
+	}
+	if leading[0] != smartSessionsModeUse {
+		return fmt.Errorf("unsupported smart sessions mode 0x%02x, only USE (0x00) is supported", leading[0])
+	}
+	return nil
+}
+
+var (
+	multiValidatorArgs = func() abi.Arguments {
+		validatorsType, err := abi.NewType("tuple[]", "", []abi.ArgumentMarshaling{
+			{Name: "packedValidatorAndId", Type: "bytes32"},
+			{Name: "data", Type: "bytes"},
+		})
+		if err != nil {
+			panic(fmt.Sprintf("invalid multi-validator signature type: %v", err))
+		}
+		return abi.Arguments{{Type: validatorsType}}
+	}()
+
+	erc1271Args = func() abi.Arguments {
+		addressType, err := abi.NewType("address", "", nil)
+		if err != nil {
+			panic(fmt.Sprintf("invalid ERC-1271 signature type: %v", err))
+		}
+		bytesType, err := abi.NewType("bytes", "", nil)
+		if err != nil {
+			panic(fmt.Sprintf("invalid ERC-1271 signature type: %v", err))
+		}
+		return abi.Arguments{{Type: addressType}, {Type: bytesType}}
+	}()
+)
+
+// multiValidatorEntry is a validator of a multi-validator signature and its signature data
+type multiValidatorEntry struct {
+	PackedValidatorAndId [32]byte
+	Data                 []byte
+}
+
+// multiValidatorFormatter signs every entry of a multi-validator signature holding the dummy signature
+type multiValidatorFormatter struct {
+	sign signFunc
+}
+
+// decodeEntries decodes the validators and signatures of a multi-validator signature
+func (f *multiValidatorFormatter) decodeEntries(signature []byte) ([]multiValidatorEntry, error) {
+	unpacked, err := multiValidatorArgs.Unpack(signature)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode multi-validator signature: %w", err)
+	}
+	var entries []multiValidatorEntry
+	if err := multiValidatorArgs.Copy(&entries, unpacked); err != nil {
+		return nil, fmt.Errorf("failed to decode multi-validator signature: %w", err)
+	}
+	return entries, nil
+}
+
+// decode decodes a multi-validator template, which must have an entry holding the dummy signature
+func (f *multiValidatorFormatter) decode(template []byte) ([]multiValidatorEntry, error) {
+	entries, err := f.decodeEntries(template)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range entries {
+		if bytes.Equal(entry.Data, DummySignature) {
+			return entries, nil
+		}
+	}
+	return nil, fmt.Errorf("multi-validator signature has no entry with the dummy signature %s", hexutil.Encode(DummySignature))
+}
+
+func (f *multiValidatorFormatter) DummySignature(template []byte) ([]byte, error) {
+	if _, err := f.decode(template); err != nil {
+		return nil, err
+	}
+	return template, nil
+}
+
+func (f *multiValidatorFormatter) Sign(ctx context.Context, signer Signer, template []byte, userOpHash common.Hash) ([]byte, error) {
+	entries, err := f.decode(template)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := f.sign(ctx, signer, userOpHash)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range entries {
+		if bytes.Equal(entries[i].Data, DummySignature) {
+			entries[i].Data = signature
+		}
+	}
+	return multiValidatorArgs.Pack(entries)
+}
+
+// erc1271SignatureLength is the length of abi.encode(address, bytes) holding a 65-byte signature
+const erc1271SignatureLength = 3*32 + 96
+
+// erc1271Formatter replaces the dummy signature wrapped with the address of the contract signer
+// at the end of the template
+type erc1271Formatter struct {
+	sign signFunc
+}
+
+func (f *erc1271Formatter) decode(template []byte) ([]byte, common.Address, error) {
+	if len(template) < erc1271SignatureLength {
+		return nil, common.Address{}, fmt.Errorf("signature must end with abi.encode(address signer, bytes signature)")
+	}
+	leading := template[:len(template)-erc1271SignatureLength]
+
+	unpacked, err := erc1271Args.Unpack(template[len(leading):])
+	if err != nil {
+		return nil, common.Address{}, fmt.Errorf("failed to decode contract signature: %w", err)
+	}
+	if !bytes.Equal(unpacked[1].([]byte), DummySignature) {
+		return nil, common.Address{}, fmt.Errorf("contract signature must wrap the dummy signature %s", hexutil.Encode(DummySignature))
+	}
+	return leading, unpacked[0].(common.Address), nil
+}
+
+func (f *erc1271Formatter) DummySignature(template []byte) ([]byte, error) {
+	if _, _, err := f.decode(template); err != nil {
+		return nil, err
+	}
+	return template, nil
+}
+
+func (f *erc1271Formatter) Sign(ctx context.Context, signer Signer, template []byte, userOpHash common.Hash) ([]byte, error) {
+	leading, contractSigner, err := f.decode(template)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := f.sign(ctx, signer, userOpHash)
+	if err != nil {
+		return nil, err
+	}
+
+	wrapped, err := erc1271Args.Pack(contractSigner, signature)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode contract signature: %w", err)
+	}
+	return append(bytes.Clone(leading), wrapped...), nil
+}
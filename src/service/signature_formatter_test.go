@@ -0,0 +1,165 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recoverSigner returns the address that produced a 65-byte signature with V in {27, 28} over the hash
+func recoverSigner(t *testing.T, hash common.Hash, signature []byte) common.Address {
+	require.Len(t, signature, crypto.SignatureLength)
+	sig := bytes.Clone(signature)
+	sig[64] -= 27
+	pub, err := crypto.SigToPub(hash.Bytes(), sig)
+	require.NoError(t, err)
+	return crypto.PubkeyToAddress(*pub)
+}
+
+func TestSignatureFormatters_DummySuffix(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	signer := NewLocalSignerFromKey(key)
+	userOpHash := crypto.Keccak256Hash([]byte("user operation"))
+	permissionID := common.HexToHash("0x0102")
+
+	tests := []struct {
+		format  string
+		leading []byte
+		hash    common.Hash
+	}{
+		{SignatureFormatPersonalSign, []byte{0x00, 0x11}, personalSignHash(userOpHash.Bytes())},
+		{SignatureFormatRawHash, []byte{0x00, 0x11}, userOpHash},
+		{SignatureFormatSmartSessions, append([]byte{smartSessionsModeUse}, permissionID.Bytes()...), personalSignHash(userOpHash.Bytes())},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			formatter, err := SignatureFormatters.Get(tt.format)
+			require.NoError(t, err)
+
+			template := append(bytes.Clone(tt.leading), DummySignature...)
+			dummy, err := formatter.DummySignature(template)
+			require.NoError(t, err)
+			assert.Equal(t, template, dummy)
+
+			signature, err := formatter.Sign(context.Background(), signer, template, userOpHash)
+			require.NoError(t, err)
+			require.Len(t, signature, len(template))
+			assert.Equal(t, tt.leading, signature[:len(tt.leading)])
+			assert.Equal(t, signer.Address(), recoverSigner(t, tt.hash, signature[len(tt.leading):]))
+
+			// The template is not modified
+			assert.True(t, bytes.HasSuffix(template, DummySignature))
+
+			_, err = formatter.DummySignature(tt.leading)
+			assert.ErrorContains(t, err, "must end with the dummy signature")
+		})
+	}
+
+	// Smart Sessions only supports permissions that are already enabled
+	formatter, err := SignatureFormatters.Get(SignatureFormatSmartSessions)
+	require.NoError(t, err)
+	_, err = formatter.DummySignature(append(append([]byte{0x01}, permissionID.Bytes()...), DummySignature...))
+	assert.ErrorContains(t, err, "only USE (0x00) is supported")
+}
+
+func TestSignatureFormatters_MultiValidator(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	signer := NewLocalSignerFromKey(key)
+	userOpHash := crypto.Keccak256Hash([]byte("user operation"))
+
+	otherSignature := hexutil.MustDecode("0xaabbcc")
+	template, err := multiValidatorArgs.Pack([]multiValidatorEntry{
+		{PackedValidatorAndId: common.HexToHash("0x01"), Data: otherSignature},
+		{PackedValidatorAndId: common.HexToHash("0x02"), Data: DummySignature},
+	})
+	require.NoError(t, err)
+
+	formatter, err := SignatureFormatters.Get(SignatureFormatMultiValidator)
+	require.NoError(t, err)
+	signature, err := formatter.Sign(context.Background(), signer, template, userOpHash)
+	require.NoError(t, err)
+	assert.Len(t, signature, len(template))
+
+	entries, err := (&multiValidatorFormatter{}).decodeEntries(signature)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, otherSignature, entries[0].Data)
+	assert.Equal(t, signer.Address(), recoverSigner(t, personalSignHash(userOpHash.Bytes()), entries[1].Data))
+
+	// The signed entries no longer hold the dummy signature
+	_, err = formatter.DummySignature(signature)
+	assert.ErrorContains(t, err, "no entry with the dummy signature")
+}
+
+func TestSignatureFormatters_ERC1271(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	signer := NewLocalSignerFromKey(key)
+	userOpHash := crypto.Keccak256Hash([]byte("user operation"))
+	contractSigner := common.HexToAddress("0x1234567890123456789012345678901234567890")
+
+	wrapped, err := erc1271Args.Pack(contractSigner, DummySignature)
+	require.NoError(t, err)
+	require.Len(t, wrapped, erc1271SignatureLength)
+	leading := []byte{0x00, 0x11}
+	template := append(bytes.Clone(leading), wrapped...)
+
+	formatter, err := SignatureFormatters.Get(SignatureFormatERC1271)
+	require.NoError(t, err)
+	signature, err := formatter.Sign(context.Background(), signer, template, userOpHash)
+	require.NoError(t, err)
+	require.Len(t, signature, len(template))
+	assert.Equal(t, leading, signature[:len(leading)])
+
+	unpacked, err := erc1271Args.Unpack(signature[len(leading):])
+	require.NoError(t, err)
+	assert.Equal(t, contractSigner, unpacked[0])
+	assert.Equal(t, signer.Address(), recoverSigner(t, personalSignHash(userOpHash.Bytes()), unpacked[1].([]byte)))
+}
+
+func TestSignatureFormatterRegistry(t *testing.T) {
+	registry := NewSignatureFormatterRegistry()
+
+	formatter, err := registry.Get("")
+	require.NoError(t, err)
+	assert.IsType(t, &dummySuffixFormatter{}, formatter)
+
+	_, err = registry.Get("unknown")
+	assert.ErrorContains(t, err, "unsupported signature format: unknown")
+
+	registry.Register("custom", &dummySuffixFormatter{sign: signPersonal})
+	assert.Equal(t, []string{"custom", "erc1271", "multi_validator", "personal_sign", "raw_hash", "smart_sessions"}, registry.Formats())
+
+	// The remote signer cannot sign raw hashes
+	rawHash, err := registry.Get(SignatureFormatRawHash)
+	require.NoError(t, err)
+	_, err = rawHash.Sign(context.Background(), &RemoteSigner{}, DummySignature, common.Hash{})
+	assert.ErrorContains(t, err, "cannot sign raw hashes")
+}
+
+func TestExecutionService_CheckSignatureFormat(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	local := NewExecutionService(nil, NewLocalSignerFromKey(key))
+	remoteSigner, err := newFakeRemoteSigner(t, key, crypto.PubkeyToAddress(key.PublicKey))
+	require.NoError(t, err)
+	remote := NewExecutionService(nil, remoteSigner)
+
+	for _, format := range SignatureFormatters.Formats() {
+		assert.NoError(t, local.CheckSignatureFormat(format), format)
+	}
+	assert.NoError(t, remote.CheckSignatureFormat(SignatureFormatPersonalSign))
+	assert.NoError(t, remote.CheckSignatureFormat(""))
+
+	// A remote signer only signs EIP-191 messages
+	assert.ErrorContains(t, remote.CheckSignatureFormat(SignatureFormatRawHash), "raw hashes")
+	assert.ErrorContains(t, local.CheckSignatureFormat("unknown"), "unsupported signature format")
+}
@@ -0,0 +1,317 @@
+package service
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ethaccount/backend/erc4337"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Signer signs user operation hashes on behalf of the session key
+type Signer interface {
+	// Address returns the address of the signing key
+	Address() common.Address
+	// SignPersonalMessage signs data with the EIP-191 personal_sign prefix.
+	// The returned signature is 65 bytes [R || S || V] with V in {27, 28}.
+	SignPersonalMessage(ctx context.Context, data []byte) ([]byte, error)
+}
+
+// HashSigner is implemented by signers that can sign a raw 32-byte hash without the EIP-191 prefix.
+// The remote signer cannot, since eth_sign always applies the prefix.
+type HashSigner interface {
+	// SignHash returns the 65-byte signature [R || S || V] of the hash with V in {27, 28}
+	SignHash(ctx context.Context, hash common.Hash) ([]byte, error)
+}
+
+// SignerType selects the Signer implementation
+type SignerType string
+
+const (
+	SignerTypeLocal    SignerType = "local"
+	SignerTypeKeystore SignerType = "keystore"
+	SignerTypeRemote   SignerType = "remote"
+)
+
+type SignerConfig struct {
+	Type SignerType
+
+	// Local signer
+	PrivateKey string
+
+	// Keystore signer
+	KeystorePath     string
+	KeystorePassword string
+
+	// Remote signer
+	RemoteURL     string
+	RemoteAddress string
+}
+
+// NewSigner creates the Signer selected by the config
+func NewSigner(ctx context.Context, config SignerConfig) (Signer, error) {
+	switch config.Type {
+	case SignerTypeLocal, "":
+		return NewLocalSigner(config.PrivateKey)
+	case SignerTypeKeystore:
+		return NewKeystoreSigner(config.KeystorePath, config.KeystorePassword)
+	case SignerTypeRemote:
+		if !common.IsHexAddress(config.RemoteAddress) {
+			return nil, fmt.Errorf("invalid remote signer address: %q", config.RemoteAddress)
+		}
+		return NewRemoteSigner(ctx, config.RemoteURL, common.HexToAddress(config.RemoteAddress))
+	default:
+		return nil, fmt.Errorf("unsupported signer type: %s", config.Type)
+	}
+}
+
+// NewExecutorSigner creates the signer of the self-bundling executor, which signs the handleOps transactions.
+// The executor key is funded, so only keystore and remote signers are supported.
+func NewExecutorSigner(ctx context.Context, config SignerConfig) (erc4337.TransactionSigner, error) {
+	switch config.Type {
+	case SignerTypeKeystore:
+		return NewKeystoreSigner(config.KeystorePath, config.KeystorePassword)
+	case SignerTypeRemote:
+		if !common.IsHexAddress(config.RemoteAddress) {
+			return nil, fmt.Errorf("invalid remote signer address: %q", config.RemoteAddress)
+		}
+		return NewRemoteSigner(ctx, config.RemoteURL, common.HexToAddress(config.RemoteAddress))
+	default:
+		return nil, fmt.Errorf("unsupported executor signer type: %q (must be keystore or remote)", config.Type)
+	}
+}
+
+// personalSignHash creates an Ethereum signed message hash
+func personalSignHash(data []byte) common.Hash {
+	msg := fmt.Sprintf("\x19Ethereum Signed Message:\n%d%s", len(data), data)
+	return crypto.Keccak256Hash([]byte(msg))
+}
+
+// verifyPersonalSignature checks that the signature over data was produced by the expected address
+func verifyPersonalSignature(data []byte, signature []byte, expected common.Address) error {
+	if len(signature) != crypto.SignatureLength {
+		return fmt.Errorf("invalid signature length: %d", len(signature))
+	}
+
+	sig := make([]byte, crypto.SignatureLength)
+	copy(sig, signature)
+	if sig[64] >= 27 {
+		sig[64] -= 27
+	}
+
+	pubKey, err := crypto.SigToPub(personalSignHash(data).Bytes(), sig)
+	if err != nil {
+		return fmt.Errorf("failed to recover signer: %w", err)
+	}
+	if recovered := crypto.PubkeyToAddress(*pubKey); recovered != expected {
+		return fmt.Errorf("signature recovered to %s, expected %s", recovered.Hex(), expected.Hex())
+	}
+	return nil
+}
+
+// LocalSigner signs with a private key held in memory
+type LocalSigner struct {
+	privateKey *ecdsa.PrivateKey
+	address    common.Address
+}
+
+// NewLocalSigner creates a signer from a hex encoded private key, with or without 0x prefix
+func NewLocalSigner(privateKeyHex string) (*LocalSigner, error) {
+	privateKey, err := crypto.HexToECDSA(strings.TrimPrefix(privateKeyHex, "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key: %w", err)
+	}
+	return NewLocalSignerFromKey(privateKey), nil
+}
+
+// NewLocalSignerFromKey creates a signer from a parsed private key
+func NewLocalSignerFromKey(privateKey *ecdsa.PrivateKey) *LocalSigner {
+	return &LocalSigner{
+		privateKey: privateKey,
+		address:    crypto.PubkeyToAddress(privateKey.PublicKey),
+	}
+}
+
+func (s *LocalSigner) Address() common.Address {
+	return s.address
+}
+
+func (s *LocalSigner) SignPersonalMessage(ctx context.Context, data []byte) ([]byte, error) {
+	return s.SignHash(ctx, personalSignHash(data))
+}
+
+func (s *LocalSigner) SignHash(ctx context.Context, hash common.Hash) ([]byte, error) {
+	signature, err := crypto.Sign(hash.Bytes(), s.privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign message: %w", err)
+	}
+
+	// Adjust signature format for Ethereum (recovery ID + 27)
+	signature[64] += 27
+	return signature, nil
+}
+
+func (s *LocalSigner) SignTransaction(ctx context.Context, tx *types.Transaction, chainId *big.Int) (*types.Transaction, error) {
+	signed, err := types.SignTx(tx, types.LatestSignerForChainID(chainId), s.privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign transaction: %w", err)
+	}
+	return signed, nil
+}
+
+// NewKeystoreSigner decrypts a go-ethereum keystore (V3 JSON) file and signs with the decrypted key
+func NewKeystoreSigner(path string, password string) (*LocalSigner, error) {
+	keyJSON, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keystore file: %w", err)
+	}
+
+	key, err := keystore.DecryptKey(keyJSON, password)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt keystore file: %w", err)
+	}
+
+	return NewLocalSignerFromKey(key.PrivateKey), nil
+}
+
+// RemoteSigner signs through a remote signer speaking the Ethereum JSON-RPC signing API,
+// such as web3signer in eth1 mode (eth_accounts, eth_sign). The key never leaves the remote signer.
+type RemoteSigner struct {
+	client  *rpc.Client
+	address common.Address
+}
+
+// remoteSignerTimeout bounds each request to the remote signer
+const remoteSignerTimeout = 10 * time.Second
+
+// NewRemoteSigner connects to the remote signer and checks that it manages the address
+func NewRemoteSigner(ctx context.Context, url string, address common.Address) (*RemoteSigner, error) {
+	client, err := rpc.DialContext(ctx, url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to remote signer: %w", err)
+	}
+	return newRemoteSigner(ctx, client, address)
+}
+
+func newRemoteSigner(ctx context.Context, client *rpc.Client, address common.Address) (*RemoteSigner, error) {
+	ctx, cancel := context.WithTimeout(ctx, remoteSignerTimeout)
+	defer cancel()
+
+	var accounts []common.Address
+	if err := client.CallContext(ctx, &accounts, "eth_accounts"); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to list remote signer accounts: %w", err)
+	}
+
+	for _, account := range accounts {
+		if account == address {
+			return &RemoteSigner{client: client, address: address}, nil
+		}
+	}
+
+	client.Close()
+	return nil, fmt.Errorf("remote signer does not manage address %s", address.Hex())
+}
+
+func (s *RemoteSigner) Address() common.Address {
+	return s.address
+}
+
+func (s *RemoteSigner) SignPersonalMessage(ctx context.Context, data []byte) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, remoteSignerTimeout)
+	defer cancel()
+
+	var signature hexutil.Bytes
+	if err := s.client.CallContext(ctx, &signature, "eth_sign", s.address, hexutil.Bytes(data)); err != nil {
+		return nil, fmt.Errorf("remote signer eth_sign failed: %w", err)
+	}
+	if len(signature) != crypto.SignatureLength {
+		return nil, fmt.Errorf("remote signer returned invalid signature length: %d", len(signature))
+	}
+
+	// Some signers return the raw recovery ID
+	if signature[64] < 27 {
+		signature[64] += 27
+	}
+
+	// Never forward a signature that was not produced by the configured key
+	if err := verifyPersonalSignature(data, signature, s.address); err != nil {
+		return nil, fmt.Errorf("remote signer returned an invalid signature: %w", err)
+	}
+
+	return signature, nil
+}
+
+// remoteSignerTransaction is the transaction argument of eth_signTransaction
+type remoteSignerTransaction struct {
+	From                 common.Address  `json:"from"`
+	To                   *common.Address `json:"to,omitempty"`
+	Gas                  hexutil.Uint64  `json:"gas"`
+	MaxFeePerGas         *hexutil.Big    `json:"maxFeePerGas"`
+	MaxPriorityFeePerGas *hexutil.Big    `json:"maxPriorityFeePerGas"`
+	Value                *hexutil.Big    `json:"value"`
+	Nonce                hexutil.Uint64  `json:"nonce"`
+	Data                 hexutil.Bytes   `json:"data"`
+	ChainID              *hexutil.Big    `json:"chainId"`
+}
+
+// SignTransaction signs an EIP-1559 transaction with eth_signTransaction, which returns the RLP encoded signed transaction
+func (s *RemoteSigner) SignTransaction(ctx context.Context, tx *types.Transaction, chainId *big.Int) (*types.Transaction, error) {
+	if tx.Type() != types.DynamicFeeTxType {
+		return nil, fmt.Errorf("remote signer only signs EIP-1559 transactions, got type %d", tx.Type())
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, remoteSignerTimeout)
+	defer cancel()
+
+	args := remoteSignerTransaction{
+		From:                 s.address,
+		To:                   tx.To(),
+		Gas:                  hexutil.Uint64(tx.Gas()),
+		MaxFeePerGas:         (*hexutil.Big)(tx.GasFeeCap()),
+		MaxPriorityFeePerGas: (*hexutil.Big)(tx.GasTipCap()),
+		Value:                (*hexutil.Big)(tx.Value()),
+		Nonce:                hexutil.Uint64(tx.Nonce()),
+		Data:                 tx.Data(),
+		ChainID:              (*hexutil.Big)(chainId),
+	}
+	var raw hexutil.Bytes
+	if err := s.client.CallContext(ctx, &raw, "eth_signTransaction", args); err != nil {
+		return nil, fmt.Errorf("remote signer eth_signTransaction failed: %w", err)
+	}
+
+	signed := new(types.Transaction)
+	if err := signed.UnmarshalBinary(raw); err != nil {
+		return nil, fmt.Errorf("remote signer returned an invalid transaction: %w", err)
+	}
+
+	// Never send a transaction that differs from the requested one or was not signed by the configured key
+	signer := types.LatestSignerForChainID(chainId)
+	if signer.Hash(signed) != signer.Hash(tx) {
+		return nil, fmt.Errorf("remote signer returned a different transaction")
+	}
+	from, err := types.Sender(signer, signed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to recover transaction signer: %w", err)
+	}
+	if from != s.address {
+		return nil, fmt.Errorf("transaction signed by %s, expected %s", from.Hex(), s.address.Hex())
+	}
+
+	return signed, nil
+}
+
+// Close closes the connection to the remote signer
+func (s *RemoteSigner) Close() {
+	s.client.Close()
+}
@@ -0,0 +1,213 @@
+package service
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRemoteSignerAPI is a local stand-in for a web3signer-style remote signer
+type fakeRemoteSignerAPI struct {
+	key *ecdsa.PrivateKey
+}
+
+func (api *fakeRemoteSignerAPI) Accounts() []common.Address {
+	return []common.Address{crypto.PubkeyToAddress(api.key.PublicKey)}
+}
+
+func (api *fakeRemoteSignerAPI) Sign(address common.Address, data hexutil.Bytes) (hexutil.Bytes, error) {
+	// Return the raw recovery ID to check that the signer normalizes V
+	return crypto.Sign(personalSignHash(data).Bytes(), api.key)
+}
+
+func (api *fakeRemoteSignerAPI) SignTransaction(args remoteSignerTransaction) (hexutil.Bytes, error) {
+	tx, err := types.SignNewTx(api.key, types.LatestSignerForChainID(args.ChainID.ToInt()), &types.DynamicFeeTx{
+		ChainID:   args.ChainID.ToInt(),
+		Nonce:     uint64(args.Nonce),
+		GasTipCap: args.MaxPriorityFeePerGas.ToInt(),
+		GasFeeCap: args.MaxFeePerGas.ToInt(),
+		Gas:       uint64(args.Gas),
+		To:        args.To,
+		Value:     args.Value.ToInt(),
+		Data:      args.Data,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tx.MarshalBinary()
+}
+
+// newTestTransaction returns an unsigned handleOps-like transaction
+func newTestTransaction() *types.Transaction {
+	to := common.HexToAddress("0x0000000071727De22E5E9d8BAf0edAc6f37da032")
+	return types.NewTx(&types.DynamicFeeTx{
+		ChainID:   big.NewInt(testChainID),
+		Nonce:     3,
+		GasTipCap: big.NewInt(100000000),
+		GasFeeCap: big.NewInt(1600000000),
+		Gas:       240000,
+		To:        &to,
+		Data:      []byte{0x76, 0x5e, 0x82, 0x7f},
+	})
+}
+
+// assertSignedBy checks that a signed transaction is the unsigned one signed by the address
+func assertSignedBy(t *testing.T, unsigned, signed *types.Transaction, address common.Address) {
+	signer := types.LatestSignerForChainID(big.NewInt(testChainID))
+	assert.Equal(t, signer.Hash(unsigned), signer.Hash(signed))
+	from, err := types.Sender(signer, signed)
+	require.NoError(t, err)
+	assert.Equal(t, address, from)
+}
+
+func newFakeRemoteSigner(t *testing.T, key *ecdsa.PrivateKey, address common.Address) (*RemoteSigner, error) {
+	server := rpc.NewServer()
+	require.NoError(t, server.RegisterName("eth", &fakeRemoteSignerAPI{key: key}))
+	t.Cleanup(server.Stop)
+
+	return newRemoteSigner(context.Background(), rpc.DialInProc(server), address)
+}
+
+func TestLocalSigner(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	address := crypto.PubkeyToAddress(key.PublicKey)
+
+	signer, err := NewLocalSigner(hexutil.Encode(crypto.FromECDSA(key)))
+	require.NoError(t, err)
+	assert.Equal(t, address, signer.Address())
+
+	data := crypto.Keccak256([]byte("user operation hash"))
+	signature, err := signer.SignPersonalMessage(context.Background(), data)
+	require.NoError(t, err)
+	require.Len(t, signature, 65)
+	assert.Contains(t, []byte{27, 28}, signature[64])
+	assert.NoError(t, verifyPersonalSignature(data, signature, address))
+
+	_, err = NewLocalSigner("not a key")
+	assert.Error(t, err)
+}
+
+func TestKeystoreSigner(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	address := crypto.PubkeyToAddress(key.PublicKey)
+
+	keyJSON, err := keystore.EncryptKey(&keystore.Key{
+		Id:         uuid.New(),
+		Address:    address,
+		PrivateKey: key,
+	}, "password", keystore.LightScryptN, keystore.LightScryptP)
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "keystore.json")
+	require.NoError(t, os.WriteFile(path, keyJSON, 0600))
+
+	signer, err := NewSigner(context.Background(), SignerConfig{
+		Type:             SignerTypeKeystore,
+		KeystorePath:     path,
+		KeystorePassword: "password",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, address, signer.Address())
+
+	data := crypto.Keccak256([]byte("user operation hash"))
+	signature, err := signer.SignPersonalMessage(context.Background(), data)
+	require.NoError(t, err)
+	assert.NoError(t, verifyPersonalSignature(data, signature, address))
+
+	// The executor signer signs transactions with the same keystore
+	executor, err := NewExecutorSigner(context.Background(), SignerConfig{
+		Type:             SignerTypeKeystore,
+		KeystorePath:     path,
+		KeystorePassword: "password",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, address, executor.Address())
+	tx := newTestTransaction()
+	signed, err := executor.SignTransaction(context.Background(), tx, big.NewInt(testChainID))
+	require.NoError(t, err)
+	assertSignedBy(t, tx, signed, address)
+
+	_, err = NewKeystoreSigner(path, "wrong password")
+	assert.Error(t, err)
+}
+
+func TestRemoteSigner(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	address := crypto.PubkeyToAddress(key.PublicKey)
+	data := crypto.Keccak256([]byte("user operation hash"))
+
+	t.Run("signs with the remote key", func(t *testing.T) {
+		signer, err := newFakeRemoteSigner(t, key, address)
+		require.NoError(t, err)
+		defer signer.Close()
+		assert.Equal(t, address, signer.Address())
+
+		signature, err := signer.SignPersonalMessage(context.Background(), data)
+		require.NoError(t, err)
+		assert.Contains(t, []byte{27, 28}, signature[64])
+		assert.NoError(t, verifyPersonalSignature(data, signature, address))
+	})
+
+	t.Run("signs transactions with the remote key", func(t *testing.T) {
+		signer, err := newFakeRemoteSigner(t, key, address)
+		require.NoError(t, err)
+		defer signer.Close()
+
+		tx := newTestTransaction()
+		signed, err := signer.SignTransaction(context.Background(), tx, big.NewInt(testChainID))
+		require.NoError(t, err)
+		assertSignedBy(t, tx, signed, address)
+	})
+
+	t.Run("address not managed by the remote signer", func(t *testing.T) {
+		_, err := newFakeRemoteSigner(t, key, common.HexToAddress("0x1234567890123456789012345678901234567890"))
+		assert.Error(t, err)
+	})
+
+	t.Run("signature from another key is rejected", func(t *testing.T) {
+		otherKey, err := crypto.GenerateKey()
+		require.NoError(t, err)
+
+		signer, err := newFakeRemoteSigner(t, key, address)
+		require.NoError(t, err)
+		defer signer.Close()
+
+		// Swap the key behind the remote signer after the address check
+		server := rpc.NewServer()
+		require.NoError(t, server.RegisterName("eth", &fakeRemoteSignerAPI{key: otherKey}))
+		t.Cleanup(server.Stop)
+		signer.client = rpc.DialInProc(server)
+
+		_, err = signer.SignPersonalMessage(context.Background(), data)
+		assert.Error(t, err)
+		_, err = signer.SignTransaction(context.Background(), newTestTransaction(), big.NewInt(testChainID))
+		assert.Error(t, err)
+	})
+}
+
+func TestNewSigner_InvalidConfig(t *testing.T) {
+	_, err := NewSigner(context.Background(), SignerConfig{Type: "hsm"})
+	assert.Error(t, err)
+
+	_, err = NewSigner(context.Background(), SignerConfig{Type: SignerTypeRemote, RemoteURL: "http://localhost:9000", RemoteAddress: "not an address"})
+	assert.Error(t, err)
+	// The funded executor key is never a raw private key
+	_, err = NewExecutorSigner(context.Background(), SignerConfig{Type: SignerTypeLocal, PrivateKey: "0x01"})
+	assert.Error(t, err)
+}
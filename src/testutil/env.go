@@ -16,3 +16,12 @@ func GetEnv(key string) string {
 
 	return os.Getenv(key)
 }
+
+// LookupEnv is like GetEnv but reports whether the variable is set to a
+// non-empty value instead of panicking when the .env file is missing.
+func LookupEnv(key string) (string, bool) {
+	_ = godotenv.Load(filepath.Join(utils.FindProjectRoot(), ".env"))
+
+	value := os.Getenv(key)
+	return value, value != ""
+}